@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	"github.com/bookingcom/shipper/pkg/webhook"
+)
+
+var (
+	masterURL       = flag.String("master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	kubeconfig      = flag.String("kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	resync          = flag.Duration("resync", 0*time.Second, "Informer's cache re-sync in Go's duration format.")
+	webhookCertPath = flag.String("webhook-cert", "", "Path to the TLS certificate for the webhook.")
+	webhookKeyPath  = flag.String("webhook-key", "", "Path to the TLS private key for the webhook.")
+	webhookBindAddr = flag.String("webhook-addr", "0.0.0.0", "Addr to bind the webhook.")
+	webhookBindPort = flag.String("webhook-port", "9443", "Port to bind the webhook.")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	klog.Infof("Starting shipper-pod-webhook on %s:%s", *webhookBindAddr, *webhookBindPort)
+	defer klog.Info("Stopping shipper-pod-webhook")
+
+	restCfg, err := clientcmd.BuildConfigFromFlags(*masterURL, *kubeconfig)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	restCfg.UserAgent = webhook.PodTrafficAgentName
+
+	kubeClient, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	stopCh := setupSignalHandler()
+
+	// Only release anchors are of any interest to the webhook, so
+	// there's no point in caching any other ConfigMaps.
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
+		kubeClient, *resync,
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = shipper.ReleaseLabel
+		}),
+	)
+
+	wh := webhook.NewPodTrafficWebhook(
+		*webhookBindAddr,
+		*webhookBindPort,
+		*webhookKeyPath,
+		*webhookCertPath,
+		kubeInformerFactory,
+	)
+
+	kubeInformerFactory.Start(stopCh)
+
+	wh.Run(stopCh)
+}
+
+func setupSignalHandler() <-chan struct{} {
+	stopCh := make(chan struct{})
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigCh
+		close(stopCh)
+		<-sigCh
+		os.Exit(1) // Second signal. Exit directly.
+	}()
+
+	return stopCh
+}
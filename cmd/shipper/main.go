@@ -14,10 +14,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
-	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -60,6 +61,7 @@ var controllers = []string{
 const defaultRESTTimeout time.Duration = 10 * time.Second
 const defaultResync time.Duration = 0 * time.Second
 const defaultHeartbeat time.Duration = 5 * time.Second
+const defaultChartCacheSize int64 = 1 << 30
 
 var (
 	masterURL           = flag.String("master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
@@ -70,6 +72,7 @@ var (
 	workers             = flag.Int("workers", 2, "Number of workers to start for each controller.")
 	metricsAddr         = flag.String("metrics-addr", ":8889", "Addr to expose /metrics on.")
 	chartCacheDir       = flag.String("cachedir", filepath.Join(os.TempDir(), "chart-cache"), "location for the local cache of downloaded charts")
+	chartCacheSize      = flag.Int64("cachesize", defaultChartCacheSize, "maximum size in bytes of the local cache of downloaded charts, or 0 for no limit")
 	resync              = flag.Duration("resync", defaultResync, "Informer's cache re-sync in Go's duration format.")
 	restTimeout         = flag.Duration("rest-timeout", defaultRESTTimeout, "Timeout value for management and target REST clients. Does not affect informer watches.")
 	webhookCertPath     = flag.String("webhook-cert", "", "Path to the TLS certificate for the webhook controller.")
@@ -94,9 +97,10 @@ type cfg struct {
 	restCfg     *rest.Config
 	restTimeout *time.Duration
 
-	kubeInformerFactory    informers.SharedInformerFactory
-	shipperInformerFactory shipperinformers.SharedInformerFactory
-	resync                 *time.Duration
+	kubeInformerFactory       informers.SharedInformerFactory
+	valuesFromInformerFactory informers.SharedInformerFactory
+	shipperInformerFactory    shipperinformers.SharedInformerFactory
+	resync                    *time.Duration
 
 	recorder func(string) record.EventRecorder
 
@@ -104,6 +108,8 @@ type cfg struct {
 
 	chartVersionResolver repo.ChartVersionResolver
 	chartFetcher         repo.ChartFetcher
+	chartVerifier        repo.ChartVerifier
+	verifiedChartFetcher repo.VerifiedChartFetcher
 
 	certPath, keyPath string
 	ns                string
@@ -136,7 +142,17 @@ func main() {
 	stopCh := setupSignalHandler()
 	metricsReadyCh := make(chan struct{})
 
-	kubeInformerFactory := informers.NewSharedInformerFactory(informerKubeClient, 0*time.Second)
+	// kubeInformerFactory only watches Shipper's own namespace, and
+	// valuesFromInformerFactory only the ConfigMaps and Secrets holding
+	// values for applications, so the rest of them in the management
+	// cluster aren't cached.
+	kubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(
+		informerKubeClient, 0*time.Second, informers.WithNamespace(*ns))
+	valuesFromInformerFactory := informers.NewFilteredSharedInformerFactory(
+		informerKubeClient, 0*time.Second, metav1.NamespaceAll,
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = labels.Set{shipper.ValuesFromLabel: shipper.True}.String()
+		})
 	shipperInformerFactory := shipperinformers.NewSharedInformerFactory(informerShipperClient, *resync)
 
 	shipperscheme.AddToScheme(scheme.Scheme)
@@ -154,7 +170,7 @@ func main() {
 
 	enabledControllers := buildEnabledControllers(*enabledControllers, *disabledControllers)
 
-	secretInformer := corev1informers.New(kubeInformerFactory, *ns, nil).Secrets()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
 	store := clusterclientstore.NewStore(
 		func(clusterName string, ua string, config *rest.Config) (kubernetes.Interface, error) {
 			klog.V(8).Infof("Building a client for Cluster %q, UserAgent %q", clusterName, ua)
@@ -173,23 +189,32 @@ func main() {
 		wg.Done()
 	}()
 
-	klog.V(1).Infof("Chart cache stored at %q", *chartCacheDir)
+	klog.V(1).Infof("Chart cache stored at %q, limited to %d bytes", *chartCacheDir, *chartCacheSize)
 	klog.V(1).Infof("REST client timeout is %s", *restTimeout)
 
+	cacheFactory, err := repo.DefaultFileCacheFactory(*chartCacheDir, *chartCacheSize)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
 	repoCatalog := repo.NewCatalog(
-		repo.DefaultFileCacheFactory(*chartCacheDir),
+		cacheFactory,
 		repo.DefaultRemoteFetcher,
+		repo.NewCredentialStore(secretInformer, *ns),
 		stopCh,
 	)
 
+	keyrings := repo.NewKeyringStore(secretInformer, *ns)
+
 	cfg := &cfg{
 		enabledControllers: enabledControllers,
 		restCfg:            baseRestCfg,
 		restTimeout:        restTimeout,
 
-		kubeInformerFactory:    kubeInformerFactory,
-		shipperInformerFactory: shipperInformerFactory,
-		resync:                 resync,
+		kubeInformerFactory:       kubeInformerFactory,
+		valuesFromInformerFactory: valuesFromInformerFactory,
+		shipperInformerFactory:    shipperInformerFactory,
+		resync:                    resync,
 
 		recorder: recorder,
 
@@ -197,6 +222,8 @@ func main() {
 
 		chartVersionResolver: repo.ResolveChartVersionFunc(repoCatalog),
 		chartFetcher:         repo.FetchChartFunc(repoCatalog),
+		chartVerifier:        repo.VerifyChartFunc(repoCatalog, keyrings),
+		verifiedChartFetcher: repo.FetchVerifiedChartFunc(repoCatalog, keyrings),
 
 		ns:      *ns,
 		workers: *workers,
@@ -244,6 +271,7 @@ func runMetrics(cfg *metricsCfg) {
 	prometheus.MustRegister(cfg.restLatency.Summary, cfg.restResult.Counter)
 	prometheus.MustRegister(cfg.certExpire.GetMetrics()...)
 	prometheus.MustRegister(instrumentedclient.GetMetrics()...)
+	prometheus.MustRegister(repo.GetMetrics()...)
 
 	srv := http.Server{
 		Addr: *metricsAddr,
@@ -322,6 +350,7 @@ func runControllers(cfg *cfg) {
 	close(cfg.metrics.readyCh)
 
 	go cfg.kubeInformerFactory.Start(cfg.stopCh)
+	go cfg.valuesFromInformerFactory.Start(cfg.stopCh)
 	go cfg.shipperInformerFactory.Start(cfg.stopCh)
 
 	doneCh := make(chan struct{})
@@ -374,7 +403,11 @@ func startApplicationController(cfg *cfg) (bool, error) {
 
 	c := application.NewController(
 		client.NewShipperClientOrDie(cfg.restCfg, application.AgentName, cfg.restTimeout),
+		client.NewKubeClientOrDie(cfg.restCfg, application.AgentName, cfg.restTimeout),
 		cfg.shipperInformerFactory,
+		cfg.kubeInformerFactory,
+		cfg.valuesFromInformerFactory,
+		cfg.ns,
 		cfg.chartVersionResolver,
 		cfg.recorder(application.AgentName),
 	)
@@ -398,6 +431,7 @@ func startReleaseController(cfg *cfg) (bool, error) {
 		client.NewShipperClientOrDie(cfg.restCfg, release.AgentName, cfg.restTimeout),
 		cfg.shipperInformerFactory,
 		cfg.chartFetcher,
+		cfg.chartVerifier,
 		cfg.recorder(release.AgentName),
 	)
 
@@ -434,9 +468,11 @@ func startInstallationController(cfg *cfg) (bool, error) {
 	c := installation.NewController(
 		client.NewShipperClientOrDie(cfg.restCfg, installation.AgentName, cfg.restTimeout),
 		cfg.shipperInformerFactory,
+		cfg.kubeInformerFactory,
+		cfg.ns,
 		cfg.store,
 		dynamicClientBuilderFunc,
-		cfg.chartFetcher,
+		cfg.verifiedChartFetcher,
 		cfg.recorder(installation.AgentName),
 	)
 
@@ -475,10 +511,19 @@ func startTrafficController(cfg *cfg) (bool, error) {
 		return false, nil
 	}
 
+	dynamicClientBuilderFunc := func(clusterName string, config *rest.Config) (dynamic.Interface, error) {
+		if cfg.restTimeout != nil {
+			config.Timeout = *cfg.restTimeout
+		}
+
+		return dynamic.NewForConfig(config)
+	}
+
 	c := traffic.NewController(
 		client.NewShipperClientOrDie(cfg.restCfg, traffic.AgentName, cfg.restTimeout),
 		cfg.shipperInformerFactory,
 		cfg.store,
+		dynamicClientBuilderFunc,
 		cfg.recorder(traffic.AgentName),
 	)
 
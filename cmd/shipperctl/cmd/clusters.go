@@ -20,6 +20,7 @@ import (
 	"github.com/bookingcom/shipper/cmd/shipperctl/tls"
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	"github.com/bookingcom/shipper/pkg/crds"
+	"github.com/bookingcom/shipper/pkg/version"
 )
 
 var (
@@ -35,6 +36,8 @@ var (
 
 	webhookFailurePolicyIgnore bool
 
+	podWebhookImage string
+
 	setupCmd = &cobra.Command{
 		Use:   "setup",
 		Short: "setup Shipper in clusters",
@@ -64,9 +67,12 @@ const (
 	level1Padding      = "    "
 
 	validatingWebhookName = "shipper-validating-webhook"
+	podWebhookName        = "shipper-pod-webhook"
 
 	managementClusterRoleName         = "shipper:management-cluster"
 	managementClusterRoleBindingName  = "shipper:management-cluster"
+	managementValuesRoleName          = "shipper:management-values"
+	managementValuesRoleBindingName   = "shipper:management-values"
 	applicationClusterRoleName        = "cluster-admin" // needs to be able to install any kind of Helm chart
 	applicationClusterRoleBindingName = "shipper:application-cluster"
 )
@@ -88,6 +94,7 @@ func init() {
 	setupMgmtCmd.Flags().BoolVar(&webhookFailurePolicyIgnore, "webhook-ignore", false, "set shippers validating webhook failure policy to Ignore")
 
 	joinCmd.Flags().StringVar(&applicationClusterServiceAccount, "application-cluster-service-account", shipper.ShipperApplicationServiceAccount, "the name of the service account Shipper will use for the application cluster")
+	joinCmd.Flags().StringVar(&podWebhookImage, "pod-webhook-image", defaultPodWebhookImage(), "the image of the webhook that labels new pods for traffic in application clusters")
 
 	joinCmd.Flags().StringVarP(&clustersYaml, fileFlagName, "f", "clusters.yaml", "the path to an YAML file containing application cluster configuration")
 	err := joinCmd.MarkFlagFilename(fileFlagName, "yaml")
@@ -188,6 +195,14 @@ func setupManagementCluster(cmd *cobra.Command, configurator *configurator.Clust
 		return err
 	}
 
+	if err := createManagementValuesRole(cmd, configurator); err != nil {
+		return err
+	}
+
+	if err := createManagementValuesRoleBinding(cmd, configurator); err != nil {
+		return err
+	}
+
 	if err := createValidatingWebhookSecret(cmd, configurator); err != nil {
 		return err
 	}
@@ -216,6 +231,26 @@ func setupApplicationCluster(cmd *cobra.Command, configurator *configurator.Clus
 		return err
 	}
 
+	if err := createPodWebhookSecret(cmd, configurator); err != nil {
+		return err
+	}
+
+	if err := createPodWebhookService(cmd, configurator); err != nil {
+		return err
+	}
+
+	if err := createPodWebhookRBAC(cmd, configurator); err != nil {
+		return err
+	}
+
+	if err := createPodWebhookDeployment(cmd, configurator); err != nil {
+		return err
+	}
+
+	if err := createPodWebhookConfiguration(cmd, configurator); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -278,6 +313,10 @@ func createOrUpdateCrds(cmd *cobra.Command, configurator *configurator.Cluster)
 		return err
 	}
 
+	if err := configurator.CreateOrUpdateCRD(crds.ReleaseApproval); err != nil {
+		return err
+	}
+
 	cmd.Println("done")
 
 	return nil
@@ -351,36 +390,54 @@ func createValidatingWebhookSecret(cmd *cobra.Command, configurator *configurato
 
 	cmd.Println("Creating a secret for the validating webhook:")
 
+	privatekeyPEM, certificate, err := generateWebhookCertificate(cmd, configurator, validatingWebhookName)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("%sCreating the Secret using the private key and certificate in the %s namespace... ", level1Padding, shipperNamespace)
+	if err := configurator.CreateValidatingWebhookSecret(privatekeyPEM, certificate, shipperNamespace); err != nil {
+		return err
+	}
+	cmd.Println("done")
+
+	return nil
+}
+
+// generateWebhookCertificate returns a PEM-encoded private key and a
+// certificate for the named webhook Service in the Shipper namespace, signed
+// by the cluster through a CertificateSigningRequest of the same name.
+func generateWebhookCertificate(cmd *cobra.Command, configurator *configurator.Cluster, name string) ([]byte, []byte, error) {
 	cmd.Printf("%sGenerating a private key... ", level1Padding)
 	privatekey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	cmd.Println("done")
 
 	cmd.Printf("%sCreating a TLS certificate signing request... ", level1Padding)
-	csr, err := tls.GenerateCSRForServiceInNamespace(privatekey, validatingWebhookName, shipperNamespace)
+	csr, err := tls.GenerateCSRForServiceInNamespace(privatekey, name, shipperNamespace)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	cmd.Println("done")
 
 	cmd.Printf("%sCreating a Kubernetes CertificateSigningRequest... ", level1Padding)
-	if err := configurator.CreateCertificateSigningRequest(csr); err != nil {
-		return err
+	if err := configurator.CreateCertificateSigningRequest(name, csr); err != nil {
+		return nil, nil, err
 	}
 	cmd.Println("done")
 
 	cmd.Printf("%sApproving the CertificateSigningRequest... ", level1Padding)
-	if err := configurator.ApproveShipperCSR(); err != nil {
-		return err
+	if err := configurator.ApproveShipperCSR(name); err != nil {
+		return nil, nil, err
 	}
 	cmd.Println("done")
 
 	cmd.Printf("%sFetching the certificate from the CertificateSigningRequest object... ", level1Padding)
-	certificate, err := configurator.FetchCertificateFromCSR()
+	certificate, err := configurator.FetchCertificateFromCSR(name)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	cmd.Println("done")
 
@@ -389,13 +446,7 @@ func createValidatingWebhookSecret(cmd *cobra.Command, configurator *configurato
 	// PEM-encoded when we get it from Kubernetes above.
 	privatekeyPEM := tls.EncodePrivateKeyAsPEM(x509.MarshalPKCS1PrivateKey(privatekey))
 
-	cmd.Printf("%sCreating the Secret using the private key and certificate in the %s namespace... ", level1Padding, shipperNamespace)
-	if err := configurator.CreateValidatingWebhookSecret(privatekeyPEM, certificate, shipperNamespace); err != nil {
-		return err
-	}
-	cmd.Println("done")
-
-	return nil
+	return privatekeyPEM, certificate, nil
 }
 
 func createValidatingWebhookConfiguration(cmd *cobra.Command, configurator *configurator.Cluster) error {
@@ -503,6 +554,50 @@ func createManagementClusterRoleBinding(cmd *cobra.Command, configurator *config
 	return nil
 }
 
+func createManagementValuesRole(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Printf("Creating a Role called %s... ", managementValuesRoleName)
+	err := configurator.CreateValuesSecretsRole(
+		shipper.RBACManagementDomain,
+		shipperNamespace,
+		managementValuesRoleName,
+	)
+
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			cmd.Println("already exists. Skipping")
+			return nil
+		} else {
+			return err
+		}
+	}
+
+	cmd.Println("done")
+	return nil
+}
+
+func createManagementValuesRoleBinding(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Printf("Creating a RoleBinding called %s... ", managementValuesRoleBindingName)
+	err := configurator.CreateRoleBinding(
+		shipper.RBACManagementDomain,
+		shipperNamespace,
+		managementValuesRoleBindingName,
+		managementValuesRoleName,
+		managementClusterServiceAccount,
+	)
+
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			cmd.Println("already exists. Skipping")
+			return nil
+		} else {
+			return err
+		}
+	}
+
+	cmd.Println("done")
+	return nil
+}
+
 func createApplicationClusterRoleBinding(cmd *cobra.Command, configurator *configurator.Cluster) error {
 	cmd.Printf("Creating a ClusterRoleBinding called %s... ", applicationClusterRoleBindingName)
 	err := configurator.CreateClusterRoleBinding(
@@ -526,6 +621,91 @@ func createApplicationClusterRoleBinding(cmd *cobra.Command, configurator *confi
 	return nil
 }
 
+func createPodWebhookSecret(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Printf("Checking if a secret already exists for the pod webhook in the %s namespace... ", shipperNamespace)
+	exists, err := configurator.PodWebhookSecretExists(shipperNamespace)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		cmd.Println("yes. Skipping")
+		return nil
+	}
+	cmd.Println("no.")
+
+	cmd.Println("Creating a secret for the pod webhook:")
+
+	privatekeyPEM, certificate, err := generateWebhookCertificate(cmd, configurator, podWebhookName)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("%sCreating the Secret using the private key and certificate in the %s namespace... ", level1Padding, shipperNamespace)
+	if err := configurator.CreatePodWebhookSecret(privatekeyPEM, certificate, shipperNamespace); err != nil {
+		return err
+	}
+	cmd.Println("done")
+
+	return nil
+}
+
+func createPodWebhookService(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Print("Creating a Service object for the pod webhook... ")
+	if err := configurator.CreateOrUpdatePodWebhookService(shipperNamespace); err != nil {
+		return err
+	}
+	cmd.Println("done")
+
+	return nil
+}
+
+func createPodWebhookRBAC(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Print("Creating a service account for the pod webhook... ")
+	if err := configurator.CreatePodWebhookRBAC(shipper.RBACApplicationDomain, shipperNamespace); err != nil {
+		return err
+	}
+	cmd.Println("done")
+
+	return nil
+}
+
+func createPodWebhookDeployment(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Printf("Deploying the pod webhook from %s... ", podWebhookImage)
+	if err := configurator.CreateOrUpdatePodWebhookDeployment(podWebhookImage, shipperNamespace); err != nil {
+		return err
+	}
+	cmd.Println("done")
+
+	return nil
+}
+
+func createPodWebhookConfiguration(cmd *cobra.Command, configurator *configurator.Cluster) error {
+	cmd.Printf("Creating the MutatingWebhookConfiguration in %s namespace... ", shipperNamespace)
+	caBundle, err := configurator.FetchKubernetesCABundle()
+	if err != nil {
+		return err
+	}
+
+	if err := configurator.CreateOrUpdatePodWebhookConfiguration(caBundle, shipperNamespace); err != nil {
+		return err
+	}
+	cmd.Println("done")
+
+	return nil
+}
+
+// defaultPodWebhookImage returns the pod webhook image matching the version
+// of shipperctl, or the latest one for development builds.
+func defaultPodWebhookImage() string {
+	tag := version.Version
+	if tag == "" {
+		tag = "latest"
+	}
+
+	return fmt.Sprintf("bookingcom/shipper-pod-webhook:%s", tag)
+}
+
 func installAppClusterSecrets(
 	cmd *cobra.Command,
 	appConfigurator, mgmtConfigurator *configurator.Cluster,
@@ -16,10 +16,14 @@ import (
 	"github.com/bookingcom/shipper/cmd/shipperctl/configurator"
 	"github.com/bookingcom/shipper/cmd/shipperctl/release"
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
 )
 
 const (
 	clustersFlagName = "clusters"
+
+	trafficManaged       = "managed"
+	trafficNotApplicable = "not applicable"
 )
 
 var (
@@ -56,6 +60,7 @@ type outputRelease struct {
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
 	Clusters  string `json:"clusters"`
+	Traffic   string `json:"traffic"`
 }
 
 func init() {
@@ -116,6 +121,7 @@ func runCountContenderCommand(cmd *cobra.Command, args []string) error {
 						Namespace: contender.Namespace,
 						Name:      contender.Name,
 						Clusters:  clustersAnnotation,
+						Traffic:   releaseTraffic(&app),
 					})
 			}
 		}
@@ -154,6 +160,15 @@ func runCountReleasesCommand(cmd *cobra.Command, args []string) error {
 			errList = append(errList, err.Error())
 			continue
 		}
+		applicationList, err := shipperClient.ShipperV1alpha1().Applications(ns.Name).List(metav1.ListOptions{})
+		if err != nil {
+			errList = append(errList, err.Error())
+			continue
+		}
+		apps := make(map[string]*shipper.Application)
+		for i, app := range applicationList.Items {
+			apps[app.Name] = &applicationList.Items[i]
+		}
 		for _, rel := range releaseList.Items {
 			clustersAnnotation := rel.Annotations[shipper.ReleaseClustersAnnotation]
 			trueClusters := release.FilterSelectedClusters(strings.Split(clustersAnnotation, ","), clusters)
@@ -165,6 +180,7 @@ func runCountReleasesCommand(cmd *cobra.Command, args []string) error {
 						Namespace: rel.Namespace,
 						Name:      rel.Name,
 						Clusters:  clustersAnnotation,
+						Traffic:   releaseTraffic(apps[rel.Labels[shipper.AppLabel]]),
 					})
 			}
 		}
@@ -192,12 +208,14 @@ func printCountedRelease(stdout io.Writer, outputReleases []outputRelease) {
 			"NAMESPACE",
 			"NAME",
 			"CLUSTERS ANNOTATION",
+			"TRAFFIC",
 		)
 		for _, release := range outputReleases {
 			tbl.AddRow(
 				release.Namespace,
 				release.Name,
 				release.Clusters,
+				release.Traffic,
 			)
 		}
 
@@ -214,3 +232,13 @@ func printCountedRelease(stdout io.Writer, outputReleases []outputRelease) {
 		panic(err)
 	}
 }
+
+// releaseTraffic tells whether Shipper manages traffic for releases of app,
+// which it doesn't for applications that get no traffic at all.
+func releaseTraffic(app *shipper.Application) string {
+	if app != nil && trafficutil.NotApplicable(app) {
+		return trafficNotApplicable
+	}
+
+	return trafficManaged
+}
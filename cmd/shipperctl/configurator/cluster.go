@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -20,17 +21,23 @@ import (
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	client "github.com/bookingcom/shipper/pkg/client"
 	shipperclientset "github.com/bookingcom/shipper/pkg/client/clientset/versioned"
+	"github.com/bookingcom/shipper/pkg/webhook"
 	"github.com/mitchellh/go-homedir"
 	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
-	shipperCSRName                      = "shipper-validating-webhook"
 	shipperValidatingWebhookSecretName  = "shipper-validating-webhook"
 	shipperValidatingWebhookName        = "shipper.booking.com"
 	shipperValidatingWebhookServiceName = "shipper-validating-webhook"
 	shipperValidatingWebhookServicePath = "/validate"
+	shipperPodWebhookSecretName         = "shipper-pod-webhook"
+	shipperPodWebhookName               = "pods.shipper.booking.com"
+	shipperPodWebhookServiceName        = "shipper-pod-webhook"
+	shipperPodWebhookDeploymentName     = "shipper-pod-webhook"
+	shipperPodWebhookServiceAccountName = "shipper-pod-webhook"
+	shipperPodWebhookClusterRoleName    = "shipper:pod-webhook"
 	MaximumRetries                      = 20
 	AgentName                           = "configurator"
 )
@@ -89,6 +96,11 @@ func (c *Cluster) CreateClusterRole(domain, name string) error {
 				APIGroups: []string{""},
 				Resources: []string{"secrets"},
 			},
+			rbacv1.PolicyRule{
+				Verbs:     []string{"get", "list", "watch"},
+				APIGroups: []string{""},
+				Resources: []string{"configmaps"},
+			},
 			rbacv1.PolicyRule{
 				Verbs:     []string{rbacv1.VerbAll},
 				APIGroups: []string{""},
@@ -102,6 +114,60 @@ func (c *Cluster) CreateClusterRole(domain, name string) error {
 	return err
 }
 
+// CreateValuesSecretsRole creates a Role letting Shipper manage the Secrets
+// holding the values it resolves for releases, which all live in its own
+// namespace.
+func (c *Cluster) CreateValuesSecretsRole(domain, namespace, name string) error {
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				shipper.RBACDomainLabel: domain,
+			},
+		},
+		Rules: []rbacv1.PolicyRule{
+			rbacv1.PolicyRule{
+				Verbs:     []string{"create", "delete"},
+				APIGroups: []string{""},
+				Resources: []string{"secrets"},
+			},
+		},
+	}
+
+	_, err := c.KubeClient.RbacV1().Roles(namespace).Create(role)
+
+	return err
+}
+
+func (c *Cluster) CreateRoleBinding(domain, namespace, roleBindingName, roleName, subjectName string) error {
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleBindingName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				shipper.RBACDomainLabel: domain,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "Role",
+			Name:     roleName,
+		},
+		Subjects: []rbacv1.Subject{
+			rbacv1.Subject{
+				Kind:      "ServiceAccount",
+				Name:      subjectName,
+				Namespace: namespace,
+			},
+		},
+	}
+
+	_, err := c.KubeClient.RbacV1().RoleBindings(namespace).Create(roleBinding)
+
+	return err
+}
+
 func (c *Cluster) CreateClusterRoleBinding(domain, clusterRoleBindingName, clusterRoleName, subjectName, subjectNamespace string) error {
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
@@ -286,10 +352,10 @@ func NewClusterConfigurator(restConfig *rest.Config) (*Cluster, error) {
 	return configurator, nil
 }
 
-func (c *Cluster) CreateCertificateSigningRequest(csr []byte) error {
+func (c *Cluster) CreateCertificateSigningRequest(name string, csr []byte) error {
 	certificateSigningRequest := &certificatesv1beta1.CertificateSigningRequest{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: shipperCSRName,
+			Name: name,
 		},
 		Spec: certificatesv1beta1.CertificateSigningRequestSpec{
 			Request: csr,
@@ -306,8 +372,8 @@ func (c *Cluster) CreateCertificateSigningRequest(csr []byte) error {
 	return err
 }
 
-func (c *Cluster) ApproveShipperCSR() error {
-	csr, err := c.KubeClient.CertificatesV1beta1().CertificateSigningRequests().Get(shipperCSRName, metav1.GetOptions{})
+func (c *Cluster) ApproveShipperCSR(name string) error {
+	csr, err := c.KubeClient.CertificatesV1beta1().CertificateSigningRequests().Get(name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -324,14 +390,14 @@ func (c *Cluster) ApproveShipperCSR() error {
 	return err
 }
 
-// FetchCertificateFromCSR continually fetches the Shipper CSR until
-// it is populated with a certificate and then returns the PEM-encoded
-// certificate from the Status. This is a blocking function.
+// FetchCertificateFromCSR continually fetches the named Shipper CSR
+// until it is populated with a certificate and then returns the
+// PEM-encoded certificate from the Status. This is a blocking function.
 //
 // Note that the returned certificate is already PEM-encoded.
-func (c *Cluster) FetchCertificateFromCSR() ([]byte, error) {
+func (c *Cluster) FetchCertificateFromCSR(name string) ([]byte, error) {
 	for retries := 0; retries < MaximumRetries; retries++ {
-		csr, err := c.KubeClient.CertificatesV1beta1().CertificateSigningRequests().Get(shipperCSRName, metav1.GetOptions{})
+		csr, err := c.KubeClient.CertificatesV1beta1().CertificateSigningRequests().Get(name, metav1.GetOptions{})
 		if err != nil {
 			return nil, err
 		}
@@ -502,6 +568,257 @@ func (c *Cluster) CreateOrUpdateValidatingWebhookService(namespace string) error
 	return err
 }
 
+func (c *Cluster) PodWebhookSecretExists(namespace string) (bool, error) {
+	_, err := c.KubeClient.CoreV1().Secrets(namespace).Get(shipperPodWebhookSecretName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (c *Cluster) CreatePodWebhookSecret(privateKey, certificate []byte, namespace string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: shipperPodWebhookSecretName,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSPrivateKeyKey: privateKey,
+			corev1.TLSCertKey:       certificate,
+		},
+	}
+
+	_, err := c.KubeClient.CoreV1().Secrets(namespace).Create(secret)
+	return err
+}
+
+// CreateOrUpdatePodWebhookConfiguration registers the pod webhook for pods
+// with a release label. Its failure policy is always Ignore: pods must never
+// fail to be created because the webhook is unavailable, as the traffic
+// controller labels them anyway.
+func (c *Cluster) CreateOrUpdatePodWebhookConfiguration(caBundle []byte, namespace string) error {
+	path := webhook.MutatePodsPath
+	sideEffectClassNone := admissionregistrationv1beta1.SideEffectClassNone
+	failurePolicy := admissionregistrationv1beta1.Ignore
+	mutatingWebhookConfiguration := &admissionregistrationv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: shipperPodWebhookName,
+		},
+		Webhooks: []admissionregistrationv1beta1.MutatingWebhook{
+			admissionregistrationv1beta1.MutatingWebhook{
+				Name: shipperPodWebhookName,
+				ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
+					CABundle: caBundle,
+					Service: &admissionregistrationv1beta1.ServiceReference{
+						Name:      shipperPodWebhookServiceName,
+						Namespace: namespace,
+						Path:      &path,
+					},
+				},
+				Rules: []admissionregistrationv1beta1.RuleWithOperations{
+					admissionregistrationv1beta1.RuleWithOperations{
+						Operations: []admissionregistrationv1beta1.OperationType{
+							admissionregistrationv1beta1.Create,
+						},
+						Rule: admissionregistrationv1beta1.Rule{
+							APIGroups:   []string{corev1.GroupName},
+							APIVersions: []string{corev1.SchemeGroupVersion.Version},
+							Resources:   []string{"pods"},
+						},
+					},
+				},
+				ObjectSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      shipper.ReleaseLabel,
+							Operator: metav1.LabelSelectorOpExists,
+						},
+					},
+				},
+				SideEffects:   &sideEffectClassNone,
+				FailurePolicy: &failurePolicy,
+			},
+		},
+	}
+
+	existingConfig, err := c.KubeClient.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Get(shipperPodWebhookName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			_, err = c.KubeClient.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Create(mutatingWebhookConfiguration)
+		}
+
+		return err
+	}
+
+	existingConfig.Webhooks = mutatingWebhookConfiguration.Webhooks
+	_, err = c.KubeClient.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Update(existingConfig)
+	return err
+}
+
+func (c *Cluster) CreateOrUpdatePodWebhookService(namespace string) error {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shipperPodWebhookServiceName,
+			Namespace: namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app": shipperPodWebhookDeploymentName,
+			},
+			Ports: []corev1.ServicePort{
+				corev1.ServicePort{
+					Port:       443,
+					TargetPort: intstr.FromInt(9443),
+				},
+			},
+		},
+	}
+
+	existingService, err := c.KubeClient.CoreV1().Services(namespace).Get(shipperPodWebhookServiceName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			_, err = c.KubeClient.CoreV1().Services(namespace).Create(service)
+		}
+
+		return err
+	}
+
+	existingService.Spec.Selector = service.Spec.Selector
+	existingService.Spec.Ports = service.Spec.Ports
+	_, err = c.KubeClient.CoreV1().Services(namespace).Update(existingService)
+	return err
+}
+
+// CreatePodWebhookRBAC creates the service account the pod webhook runs as,
+// and lets it read the ConfigMaps holding release anchors in every namespace,
+// which is all it needs.
+func (c *Cluster) CreatePodWebhookRBAC(domain, namespace string) error {
+	err := c.CreateServiceAccount(domain, namespace, shipperPodWebhookServiceAccountName)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: shipperPodWebhookClusterRoleName,
+			Labels: map[string]string{
+				shipper.RBACDomainLabel: domain,
+			},
+		},
+		Rules: []rbacv1.PolicyRule{
+			rbacv1.PolicyRule{
+				Verbs:     []string{"get", "list", "watch"},
+				APIGroups: []string{""},
+				Resources: []string{"configmaps"},
+			},
+		},
+	}
+
+	_, err = c.KubeClient.RbacV1().ClusterRoles().Create(clusterRole)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	err = c.CreateClusterRoleBinding(
+		domain,
+		shipperPodWebhookClusterRoleName,
+		shipperPodWebhookClusterRoleName,
+		shipperPodWebhookServiceAccountName,
+		namespace,
+	)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+// CreateOrUpdatePodWebhookDeployment runs the pod webhook from image, as the
+// service account CreatePodWebhookRBAC sets up, serving with the certificate
+// in the pod webhook Secret.
+func (c *Cluster) CreateOrUpdatePodWebhookDeployment(image, namespace string) error {
+	labels := map[string]string{
+		"app": shipperPodWebhookDeploymentName,
+	}
+	replicas := int32(2)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shipperPodWebhookDeploymentName,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  shipperPodWebhookDeploymentName,
+							Image: image,
+							Args: []string{
+								"-webhook-cert", "/etc/webhook/certs/tls.crt",
+								"-webhook-key", "/etc/webhook/certs/tls.key",
+								"-webhook-port", "9443",
+								"-v", "2",
+								"-logtostderr",
+							},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "webhook",
+									ContainerPort: 9443,
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "webhook-certs",
+									MountPath: "/etc/webhook/certs",
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					ServiceAccountName: shipperPodWebhookServiceAccountName,
+					Volumes: []corev1.Volume{
+						{
+							Name: "webhook-certs",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: shipperPodWebhookSecretName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	existingDeployment, err := c.KubeClient.AppsV1().Deployments(namespace).Get(shipperPodWebhookDeploymentName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			_, err = c.KubeClient.AppsV1().Deployments(namespace).Create(deployment)
+		}
+
+		return err
+	}
+
+	existingDeployment.Spec = deployment.Spec
+	_, err = c.KubeClient.AppsV1().Deployments(namespace).Update(existingDeployment)
+	return err
+}
+
 func loadKubeConfig(kubeConfig, context string) (*rest.Config, error) {
 	path, err := homedir.Expand(kubeConfig)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	fakeapiextensionclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -204,3 +205,53 @@ func (f *fixture) newValidatingWebhookService(appName, namespace string) *corev1
 		},
 	}
 }
+
+// TestPodWebhookRunsWithItsOwnServiceAccount verifies that the pod webhook
+// doesn't run as the service account Shipper uses in application clusters,
+// which is a cluster admin, but as its own one that can only read ConfigMaps.
+func TestPodWebhookRunsWithItsOwnServiceAccount(t *testing.T) {
+	f := newFixture(t)
+	if err := f.configurator.CreatePodWebhookRBAC(shipper.RBACApplicationDomain, shipperSystemNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.configurator.CreateOrUpdatePodWebhookDeployment("shipper-pod-webhook", shipperSystemNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	client := f.configurator.KubeClient
+	if _, err := client.CoreV1().ServiceAccounts(shipperSystemNamespace).Get(shipperPodWebhookServiceAccountName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected the pod webhook service account to exist: %s", err)
+	}
+
+	clusterRole, err := client.RbacV1().ClusterRoles().Get(shipperPodWebhookClusterRoleName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRules := []rbacv1.PolicyRule{
+		{Verbs: []string{"get", "list", "watch"}, APIGroups: []string{""}, Resources: []string{"configmaps"}},
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedRules, clusterRole.Rules); !eq {
+		t.Fatalf("pod webhook cluster role rules different from expected:\n%s", diff)
+	}
+
+	binding, err := client.RbacV1().ClusterRoleBindings().Get(shipperPodWebhookClusterRoleName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binding.Subjects) != 1 || binding.Subjects[0].Name != shipperPodWebhookServiceAccountName {
+		t.Fatalf("expected the pod webhook cluster role to be bound to %q, got %v", shipperPodWebhookServiceAccountName, binding.Subjects)
+	}
+
+	deployment, err := client.AppsV1().Deployments(shipperSystemNamespace).Get(shipperPodWebhookDeploymentName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sa := deployment.Spec.Template.Spec.ServiceAccountName; sa != shipperPodWebhookServiceAccountName {
+		t.Fatalf("expected the pod webhook to run as %q, got %q", shipperPodWebhookServiceAccountName, sa)
+	}
+
+	// Setting things up again leaves them as they are.
+	if err := f.configurator.CreatePodWebhookRBAC(shipper.RBACApplicationDomain, shipperSystemNamespace); err != nil {
+		t.Fatalf("expected setting up the pod webhook RBAC again to succeed, got %s", err)
+	}
+}
@@ -20,6 +20,7 @@ require (
 	github.com/satori/go.uuid v1.2.0 // indirect
 	github.com/spaolacci/murmur3 v1.1.0 // indirect
 	github.com/spf13/cobra v1.0.0
+	golang.org/x/crypto v0.0.0-20200220183623-bac4c82f6975
 	k8s.io/api v0.17.12
 	k8s.io/apiextensions-apiserver v0.17.12
 	k8s.io/apimachinery v0.17.12
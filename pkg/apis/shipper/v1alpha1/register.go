@@ -47,6 +47,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&TrafficTargetList{},
 		&RolloutBlock{},
 		&RolloutBlockList{},
+		&ReleaseApproval{},
+		&ReleaseApprovalList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -5,6 +5,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
@@ -19,6 +20,10 @@ const (
 	ReleaseEnvironmentHashLabel  = "shipper-release-hash"
 	PodTrafficStatusLabel        = "shipper-traffic-status"
 	InstallationTargetOwnerLabel = "shipper-owned-by"
+	ChartRepoCredentialsLabel    = "shipper-chart-repo-credentials"
+	ChartKeyringLabel            = "shipper-chart-keyring"
+	ValuesFromLabel              = "shipper-values-from"
+	ReleaseNamespaceLabel        = "shipper-release-namespace"
 
 	AppHighestObservedGenerationAnnotation = "shipper.booking.com/app.highestObservedGeneration"
 
@@ -26,16 +31,46 @@ const (
 	AppChartVersionResolvedAnnotation = "shipper.booking.com/app.chart.version.resolved"
 	AppChartVersionRawAnnotation      = "shipper.booking.com/app.chart.version.raw"
 
+	// AppValuesFromRestoredHashAnnotation holds the hash of the values
+	// an application's valuesFrom pointed at when it went back to an
+	// older release, so those don't count as a change on their own.
+	AppValuesFromRestoredHashAnnotation = "shipper.booking.com/app.values-from.restored-hash"
+
 	ReleaseGenerationAnnotation        = "shipper.booking.com/release.generation"
 	ReleaseTemplateIterationAnnotation = "shipper.booking.com/release.template.iteration"
 	ReleaseClustersAnnotation          = "shipper.booking.com/release.clusters"
+	ReleaseValuesFromHashAnnotation    = "shipper.booking.com/release.values-from.hash"
+
+	SecretClusterSkipTlsVerifyAnnotation   = "shipper.booking.com/cluster-secret.insecure-tls-skip-verify"
+	SecretChartRepoURLPrefixAnnotation     = "shipper.booking.com/chart-repo-secret.url-prefix"
+	SecretChartKeyringNamespacesAnnotation = "shipper.booking.com/chart-keyring-secret.namespaces"
 
-	SecretClusterSkipTlsVerifyAnnotation = "shipper.booking.com/cluster-secret.insecure-tls-skip-verify"
+	ValuesFromDefaultKey = "values.yaml"
 
 	RolloutBlocksOverrideAnnotation = "shipper.booking.com/rollout-block.override"
 
 	LBLabel         = "shipper-lb"
 	LBForProduction = "production"
+	LBForRelease    = "release"
+
+	PodLabelsTrafficBackend     = "pod-labels"
+	IstioTrafficBackend         = "istio"
+	SMITrafficBackend           = "smi"
+	IngressNginxTrafficBackend  = "ingress-nginx"
+	ReadinessGateTrafficBackend = "readiness-gate"
+	// NoTrafficBackend is for applications that get no traffic at all,
+	// like queue workers, and don't need a Service.
+	NoTrafficBackend = "none"
+
+	PodTrafficReadinessGate = "shipper.booking.com/traffic"
+
+	// ContenderFirstCapacitySequencing has the contender reach its full
+	// step capacity before the incumbent gives up any of its own.
+	ContenderFirstCapacitySequencing = "ContenderFirst"
+	// InterleavedCapacitySequencing moves capacity between the contender
+	// and the incumbent in small alternating increments that keep their
+	// pods within the strategy's surge budget.
+	InterleavedCapacitySequencing = "Interleaved"
 
 	Enabled  = "enabled"
 	Disabled = "disabled"
@@ -78,8 +113,36 @@ type ApplicationList struct {
 type ApplicationSpec struct {
 	RevisionHistoryLimit *int32             `json:"revisionHistoryLimit"`
 	Template             ReleaseEnvironment `json:"template"`
+	// TrafficBackend selects how traffic is shifted between the
+	// application's releases, taking precedence over the backend
+	// configured for each cluster.
+	TrafficBackend string `json:"trafficBackend,omitempty"`
+	// RollbackTo is the name of a release in the application's history, or
+	// RollbackToPrevious, to roll the application back to. It is cleared
+	// once the rollback has started.
+	RollbackTo string `json:"rollbackTo,omitempty"`
+	// DependsOn lists applications whose latest release has to be
+	// complete, or reach a given step, before a release of this one moves
+	// past its first step.
+	DependsOn []ApplicationDependency `json:"dependsOn,omitempty"`
+}
+
+type ApplicationDependency struct {
+	// Namespace defaults to the namespace of the application that depends
+	// on it.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Step is the strategy step the dependency's latest release has to
+	// reach. When it isn't set, the release has to be complete.
+	Step *int32 `json:"step,omitempty"`
 }
 
+const (
+	// RollbackToPrevious rolls an application back to the release before
+	// its latest one.
+	RollbackToPrevious = "previous"
+)
+
 type ApplicationStatus struct {
 	Conditions []ApplicationCondition `json:"conditions,omitempty"`
 	History    []string               `json:"history,omitempty"`
@@ -149,6 +212,9 @@ type ClusterSpec struct {
 	Region       string                   `json:"region"`
 	APIMaster    string                   `json:"apiMaster"`
 	Scheduler    ClusterSchedulerSettings `json:"scheduler"`
+	// TrafficBackend selects how traffic is shifted between releases of
+	// applications in this cluster, unless they pick one themselves.
+	TrafficBackend string `json:"trafficBackend,omitempty"`
 }
 
 type ClusterSchedulerSettings struct {
@@ -196,6 +262,16 @@ type ReleaseStatus struct {
 	AchievedStep *AchievedStep          `json:"achievedStep,omitempty"`
 	Strategy     *ReleaseStrategyStatus `json:"strategy,omitempty"`
 	Conditions   []ReleaseCondition     `json:"conditions,omitempty"`
+	Approvals    []StepApproval         `json:"approvals,omitempty"`
+}
+
+// StepApproval records who let a release move on to a step that requires
+// approval, and with which ReleaseApproval.
+type StepApproval struct {
+	Step     int32  `json:"step"`
+	Name     string `json:"name"`
+	Approver string `json:"approver"`
+	Approval string `json:"approval"`
 }
 
 type AchievedStep struct {
@@ -226,6 +302,14 @@ type ReleaseEnvironment struct {
 	// the inlined "values.yaml" to apply to the chart when rendering it
 	// XXX pointer here means it's null-able, do we want that?
 	Values *ChartValues `json:"values"`
+	// ConfigMaps and Secrets in the application's namespace holding more
+	// values, merged in order over the inlined ones. They are resolved when
+	// the release is created, so changing the data they hold results in a
+	// new release.
+	ValuesFrom []ValuesFromSource `json:"valuesFrom,omitempty"`
+	// Values merged over the base ones only for the clusters each override
+	// matches, in the order they are listed.
+	Overrides []ValuesOverride `json:"overrides,omitempty"`
 
 	// requirements for target clusters for the deployment
 	ClusterRequirements ClusterRequirements `json:"clusterRequirements"`
@@ -233,6 +317,31 @@ type ReleaseEnvironment struct {
 	Strategy *RolloutStrategy `json:"strategy,omitempty"`
 }
 
+// ValuesOverride holds values that apply to a subset of clusters only. A
+// cluster matches an override if it matches all of the criteria that are
+// set.
+type ValuesOverride struct {
+	Region          string                `json:"region,omitempty"`
+	Cluster         string                `json:"cluster,omitempty"`
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	Values          *ChartValues          `json:"values"`
+}
+
+// ValuesFromSource references either a ConfigMap or a Secret holding chart
+// values in YAML.
+type ValuesFromSource struct {
+	ConfigMapRef *ValuesFromReference `json:"configMapRef,omitempty"`
+	SecretRef    *ValuesFromReference `json:"secretRef,omitempty"`
+}
+
+type ValuesFromReference struct {
+	Name string `json:"name"`
+	// The key holding the values. Defaults to "values.yaml".
+	Key string `json:"key,omitempty"`
+	// Optional references are skipped if the object or key do not exist.
+	Optional bool `json:"optional,omitempty"`
+}
+
 type ClusterRequirements struct {
 	// it is an error to not specify any regions
 	Regions      []RegionRequirement `json:"regions"`
@@ -246,17 +355,38 @@ type RegionRequirement struct {
 
 type RolloutStrategy struct {
 	Steps []RolloutStrategyStep `json:"steps"`
+	// CapacitySequencing selects how capacity moves between the
+	// contender and the incumbent within a step, defaulting to
+	// ContenderFirstCapacitySequencing.
+	CapacitySequencing string `json:"capacitySequencing,omitempty"`
+	// MaxSurge is how many pods, or what percentage of the replica
+	// count, the contender and the incumbent may have in a cluster on
+	// top of the replica count while capacity is interleaved. It
+	// defaults to 25% and is never less than one pod.
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// MinAvailablePercent is the percentage of the replica count the
+	// application's releases must keep available between them in every
+	// cluster. The incumbent's traffic and capacity are not reduced
+	// while that would take them below it.
+	MinAvailablePercent *int32 `json:"minAvailablePercent,omitempty"`
 }
 
 type RolloutStrategyStep struct {
 	Name     string                   `json:"name"`
 	Capacity RolloutStrategyStepValue `json:"capacity"`
 	Traffic  RolloutStrategyStepValue `json:"traffic"`
+	// RequiresApproval holds releases before this step until there is a
+	// ReleaseApproval for it.
+	RequiresApproval bool `json:"requiresApproval,omitempty"`
 }
 
+// RolloutStrategyStepValue is what a step asks of the incumbent and the
+// contender. Numbers are percentages, as are strings like "5%", while strings
+// like "3" are a number of pods in each cluster, never more than the replica
+// count.
 type RolloutStrategyStepValue struct {
-	Incumbent int32 `json:"incumbent"`
-	Contender int32 `json:"contender"`
+	Incumbent intstr.IntOrString `json:"incumbent"`
+	Contender intstr.IntOrString `json:"contender"`
 }
 
 type TargetConditionType string
@@ -305,6 +435,12 @@ type InstallationTargetStatus struct {
 type ClusterInstallationStatus struct {
 	Name       string                         `json:"name"`
 	Conditions []ClusterInstallationCondition `json:"conditions,omitempty"`
+	// AppliedOverrides lists the overrides that matched this cluster, in
+	// the order they were applied.
+	AppliedOverrides []string `json:"appliedOverrides,omitempty"`
+	// OverrideValues are the values from all matching overrides merged
+	// together, as they were applied over the base values.
+	OverrideValues *ChartValues `json:"overrideValues,omitempty"`
 }
 
 type ClusterInstallationCondition struct {
@@ -321,6 +457,14 @@ type InstallationTargetSpec struct {
 	// XXX these are nullable because of migration
 	Chart  *Chart       `json:"chart"`
 	Values *ChartValues `json:"values,omitempty"`
+	// ValuesSecretRef points to a Secret in Shipper's namespace holding the
+	// values resolved from the release's valuesFrom, which are merged over
+	// Values when rendering the chart. They live in a Secret as they might
+	// have come from one.
+	ValuesSecretRef *corev1.LocalObjectReference `json:"valuesSecretRef,omitempty"`
+	// Overrides are merged over the values above for the clusters they
+	// match.
+	Overrides []ValuesOverride `json:"overrides,omitempty"`
 }
 
 // +genclient
@@ -400,6 +544,10 @@ type ClusterConditionType string
 const (
 	ClusterConditionTypeOperational ClusterConditionType = "Operational"
 	ClusterConditionTypeReady       ClusterConditionType = "Ready"
+	// ClusterConditionTypeQuotaSufficient tells whether the ResourceQuotas
+	// in the application cluster had room for the release at full
+	// capacity when it was installed.
+	ClusterConditionTypeQuotaSufficient ClusterConditionType = "QuotaSufficient"
 )
 
 type ClusterCapacityCondition struct {
@@ -415,6 +563,22 @@ type PodStatus struct {
 	Containers     []corev1.ContainerStatus `json:"containers"`
 	InitContainers []corev1.ContainerStatus `json:"initContainers"`
 	Condition      corev1.PodCondition      `json:"condition"`
+	Logs           []ContainerLog           `json:"logs,omitempty"`
+	Events         []PodEvent               `json:"events,omitempty"`
+}
+
+// ContainerLog is the end of the log a container left behind the last time it
+// terminated.
+type ContainerLog struct {
+	Container string `json:"container"`
+	Log       string `json:"log"`
+}
+
+// PodEvent is a warning the application cluster recorded about a pod.
+type PodEvent struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	Count   int32  `json:"count"`
 }
 
 // the capacity and traffic controllers need context to pick the right
@@ -430,6 +594,9 @@ type ClusterCapacityTarget struct {
 	Name              string `json:"name"`
 	Percent           int32  `json:"percent"`
 	TotalReplicaCount int32  `json:"totalReplicaCount"`
+	// Replicas, when set, is the number of pods to run instead of a
+	// percentage of TotalReplicaCount. Percent is then only indicative.
+	Replicas *int32 `json:"replicas,omitempty"`
 }
 
 // +genclient
@@ -463,9 +630,19 @@ type TrafficTargetStatus struct {
 }
 
 type ClusterTrafficStatus struct {
-	Name            string                    `json:"name"`
-	AchievedTraffic uint32                    `json:"achievedTraffic"`
-	Conditions      []ClusterTrafficCondition `json:"conditions"`
+	Name            string `json:"name"`
+	AchievedTraffic uint32 `json:"achievedTraffic"`
+	// Services holds the traffic achieved through each of the
+	// application's production Services when there are several of them,
+	// for backends that look at them one by one. AchievedTraffic is the
+	// lowest of them.
+	Services   []ServiceTrafficStatus    `json:"services,omitempty"`
+	Conditions []ClusterTrafficCondition `json:"conditions"`
+}
+
+type ServiceTrafficStatus struct {
+	Name            string `json:"name"`
+	AchievedTraffic uint32 `json:"achievedTraffic"`
 }
 
 type ClusterTrafficCondition struct {
@@ -549,6 +726,9 @@ type RolloutBlockList struct {
 
 type RolloutBlockStatus struct {
 	Overrides RolloutBlockOverrides `json:"overrides"`
+	// Active tells whether the block is in effect right now, as of the
+	// last time the rolloutblock controller looked at it.
+	Active bool `json:"active"`
 }
 
 type RolloutBlockOverrides struct {
@@ -559,6 +739,26 @@ type RolloutBlockOverrides struct {
 type RolloutBlockSpec struct {
 	Message string             `json:"message"`
 	Author  RolloutBlockAuthor `json:"author"`
+
+	// StartTime and EndTime bound when the block is in effect. A block
+	// without them is in effect for as long as it exists.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	EndTime   *metav1.Time `json:"endTime,omitempty"`
+
+	// Schedule limits the block to recurring windows, such as a freeze
+	// over every weekend.
+	Schedule *RolloutBlockSchedule `json:"schedule,omitempty"`
+}
+
+type RolloutBlockSchedule struct {
+	// Cron is a five field cron expression for when each window starts,
+	// like "0 16 * * FRI".
+	Cron string `json:"cron"`
+	// Duration is how long each window lasts, like "64h".
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone is the name of the time zone Cron is in, like
+	// "Europe/Amsterdam". It defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type RolloutBlockAuthor struct {
@@ -570,6 +770,44 @@ const (
 	RolloutBlockReason = "RolloutsBlocked"
 )
 
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// A ReleaseApproval lets a release move on to a step of its strategy that
+// requires approval. Who can create them is up to RBAC.
+type ReleaseApproval struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReleaseApprovalSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ReleaseApprovalList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ReleaseApproval `json:"items"`
+}
+
+type ReleaseApprovalSpec struct {
+	// Release is the name of the release being approved, in the same
+	// namespace as the ReleaseApproval.
+	Release string `json:"release"`
+	// Step is the index of the step in the release's strategy.
+	Step     int32  `json:"step"`
+	Approver string `json:"approver"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+const (
+	WaitingForApprovalReason   = "WaitingForApproval"
+	WaitingForDependencyReason = "WaitingForDependency"
+	DependencyCycleReason      = "DependencyCycle"
+)
+
 func (ss *StrategyState) UnmarshalJSON(b []byte) error {
 	var s string
 	if err := json.Unmarshal(b, &s); err != nil {
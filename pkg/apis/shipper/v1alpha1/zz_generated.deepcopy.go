@@ -6,7 +6,9 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -70,6 +72,27 @@ func (in *ApplicationCondition) DeepCopy() *ApplicationCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationDependency) DeepCopyInto(out *ApplicationDependency) {
+	*out = *in
+	if in.Step != nil {
+		in, out := &in.Step, &out.Step
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationDependency.
+func (in *ApplicationDependency) DeepCopy() *ApplicationDependency {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationDependency)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationList) DeepCopyInto(out *ApplicationList) {
 	*out = *in
@@ -112,6 +135,13 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 		**out = **in
 	}
 	in.Template.DeepCopyInto(&out.Template)
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ApplicationDependency, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -220,7 +250,9 @@ func (in *CapacityTargetSpec) DeepCopyInto(out *CapacityTargetSpec) {
 	if in.Clusters != nil {
 		in, out := &in.Clusters, &out.Clusters
 		*out = make([]ClusterCapacityTarget, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -500,6 +532,11 @@ func (in *ClusterCapacityStatus) DeepCopy() *ClusterCapacityStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterCapacityTarget) DeepCopyInto(out *ClusterCapacityTarget) {
 	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -540,6 +577,16 @@ func (in *ClusterInstallationStatus) DeepCopyInto(out *ClusterInstallationStatus
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AppliedOverrides != nil {
+		in, out := &in.AppliedOverrides, &out.AppliedOverrides
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OverrideValues != nil {
+		in, out := &in.OverrideValues, &out.OverrideValues
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	return
 }
 
@@ -698,6 +745,11 @@ func (in *ClusterTrafficCondition) DeepCopy() *ClusterTrafficCondition {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterTrafficStatus) DeepCopyInto(out *ClusterTrafficStatus) {
 	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]ServiceTrafficStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]ClusterTrafficCondition, len(*in))
@@ -734,6 +786,22 @@ func (in *ClusterTrafficTarget) DeepCopy() *ClusterTrafficTarget {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerLog) DeepCopyInto(out *ContainerLog) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerLog.
+func (in *ContainerLog) DeepCopy() *ContainerLog {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerLog)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstallationTarget) DeepCopyInto(out *InstallationTarget) {
 	*out = *in
@@ -813,6 +881,18 @@ func (in *InstallationTargetSpec) DeepCopyInto(out *InstallationTargetSpec) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.ValuesSecretRef != nil {
+		in, out := &in.ValuesSecretRef, &out.ValuesSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]ValuesOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -860,6 +940,22 @@ func (in *InstallationTargetStatus) DeepCopy() *InstallationTargetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodEvent) DeepCopyInto(out *PodEvent) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodEvent.
+func (in *PodEvent) DeepCopy() *PodEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(PodEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodStatus) DeepCopyInto(out *PodStatus) {
 	*out = *in
@@ -878,6 +974,16 @@ func (in *PodStatus) DeepCopyInto(out *PodStatus) {
 		}
 	}
 	in.Condition.DeepCopyInto(&out.Condition)
+	if in.Logs != nil {
+		in, out := &in.Logs, &out.Logs
+		*out = make([]ContainerLog, len(*in))
+		copy(*out, *in)
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]PodEvent, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -940,6 +1046,82 @@ func (in *Release) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseApproval) DeepCopyInto(out *ReleaseApproval) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseApproval.
+func (in *ReleaseApproval) DeepCopy() *ReleaseApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReleaseApproval) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseApprovalList) DeepCopyInto(out *ReleaseApprovalList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReleaseApproval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseApprovalList.
+func (in *ReleaseApprovalList) DeepCopy() *ReleaseApprovalList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseApprovalList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReleaseApprovalList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseApprovalSpec) DeepCopyInto(out *ReleaseApprovalSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseApprovalSpec.
+func (in *ReleaseApprovalSpec) DeepCopy() *ReleaseApprovalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseApprovalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReleaseCondition) DeepCopyInto(out *ReleaseCondition) {
 	*out = *in
@@ -966,6 +1148,20 @@ func (in *ReleaseEnvironment) DeepCopyInto(out *ReleaseEnvironment) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]ValuesOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.ClusterRequirements.DeepCopyInto(&out.ClusterRequirements)
 	if in.Strategy != nil {
 		in, out := &in.Strategy, &out.Strategy
@@ -1055,6 +1251,11 @@ func (in *ReleaseStatus) DeepCopyInto(out *ReleaseStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]StepApproval, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1130,7 +1331,7 @@ func (in *RolloutBlock) DeepCopyInto(out *RolloutBlock) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 	return
 }
@@ -1218,10 +1419,40 @@ func (in *RolloutBlockOverrides) DeepCopy() *RolloutBlockOverrides {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutBlockSchedule) DeepCopyInto(out *RolloutBlockSchedule) {
+	*out = *in
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutBlockSchedule.
+func (in *RolloutBlockSchedule) DeepCopy() *RolloutBlockSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutBlockSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RolloutBlockSpec) DeepCopyInto(out *RolloutBlockSpec) {
 	*out = *in
 	out.Author = in.Author
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(RolloutBlockSchedule)
+		**out = **in
+	}
 	return
 }
 
@@ -1260,6 +1491,16 @@ func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
 		*out = make([]RolloutStrategyStep, len(*in))
 		copy(*out, *in)
 	}
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MinAvailablePercent != nil {
+		in, out := &in.MinAvailablePercent, &out.MinAvailablePercent
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -1294,6 +1535,8 @@ func (in *RolloutStrategyStep) DeepCopy() *RolloutStrategyStep {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RolloutStrategyStepValue) DeepCopyInto(out *RolloutStrategyStepValue) {
 	*out = *in
+	out.Incumbent = in.Incumbent
+	out.Contender = in.Contender
 	return
 }
 
@@ -1307,6 +1550,38 @@ func (in *RolloutStrategyStepValue) DeepCopy() *RolloutStrategyStepValue {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceTrafficStatus) DeepCopyInto(out *ServiceTrafficStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceTrafficStatus.
+func (in *ServiceTrafficStatus) DeepCopy() *ServiceTrafficStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceTrafficStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepApproval) DeepCopyInto(out *StepApproval) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepApproval.
+func (in *StepApproval) DeepCopy() *StepApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(StepApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetCondition) DeepCopyInto(out *TargetCondition) {
 	*out = *in
@@ -1439,3 +1714,71 @@ func (in *TrafficTargetStatus) DeepCopy() *TrafficTargetStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesFromReference) DeepCopyInto(out *ValuesFromReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesFromReference.
+func (in *ValuesFromReference) DeepCopy() *ValuesFromReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesFromReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesFromSource) DeepCopyInto(out *ValuesFromSource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ValuesFromReference)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(ValuesFromReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesFromSource.
+func (in *ValuesFromSource) DeepCopy() *ValuesFromSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesFromSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesOverride) DeepCopyInto(out *ValuesOverride) {
+	*out = *in
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesOverride.
+func (in *ValuesOverride) DeepCopy() *ValuesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
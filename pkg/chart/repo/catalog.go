@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
 	"sync"
 
@@ -15,13 +16,29 @@ import (
 type RemoteFetcher func(url string) ([]byte, error)
 
 func DefaultRemoteFetcher(url string) ([]byte, error) {
-	resp, err := instrumentedclient.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetch(instrumentedclient.DefaultClient, req)
+}
+
+func fetch(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, shippererrors.NewChartRepoUnauthorizedError(
+			req.URL.String(),
+			fmt.Errorf("bad response code: %s (%d)", resp.Status, resp.StatusCode),
+		)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("bad response code: %s (%d)", resp.Status, resp.StatusCode)
 	}
@@ -31,29 +48,48 @@ func DefaultRemoteFetcher(url string) ([]byte, error) {
 
 type CacheFactory func(name string) (Cache, error)
 
-func DefaultFileCacheFactory(cacheDir string) CacheFactory {
+// DefaultFileCacheFactory returns a CacheFactory that creates filesystem
+// caches in subdirectories of cacheDir, all sharing a budget of limit bytes.
+// A limit of 0 means the caches can grow without bounds. Whatever is left in
+// cacheDir from previous runs is checked before returning, so corrupt charts
+// are discarded and the budget is enforced from the start.
+func DefaultFileCacheFactory(cacheDir string, limit int64) (CacheFactory, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+
+	index := newFsCacheIndex(limit)
+	if err := index.warmUp(cacheDir); err != nil {
+		return nil, err
+	}
+
 	return func(name string) (Cache, error) {
 		return NewFilesystemCache(
 			filepath.Join(cacheDir, name),
-			0, // TODO set limit
+			index,
 		)
-	}
+	}, nil
 }
 
 type Catalog struct {
-	factory CacheFactory
-	repos   map[string]*Repo
-	fetcher RemoteFetcher
-	stopCh  <-chan struct{}
+	factory     CacheFactory
+	repos       map[string]*Repo
+	fetcher     RemoteFetcher
+	credentials *CredentialStore
+	stopCh      <-chan struct{}
 	sync.Mutex
 }
 
-func NewCatalog(factory CacheFactory, fetcher RemoteFetcher, stopCh <-chan struct{}) *Catalog {
+// NewCatalog returns a Catalog that creates repos on demand. credentials can
+// be nil, in which case every repo uses fetcher. Otherwise, repos that have
+// matching credentials use them and all the others fall back to fetcher.
+func NewCatalog(factory CacheFactory, fetcher RemoteFetcher, credentials *CredentialStore, stopCh <-chan struct{}) *Catalog {
 	return &Catalog{
-		factory: factory,
-		repos:   make(map[string]*Repo),
-		fetcher: fetcher,
-		stopCh:  stopCh,
+		factory:     factory,
+		repos:       make(map[string]*Repo),
+		fetcher:     fetcher,
+		credentials: credentials,
+		stopCh:      stopCh,
 	}
 }
 
@@ -74,7 +110,7 @@ func (c *Catalog) CreateRepoIfNotExist(repoURL string) (*Repo, error) {
 				fmt.Errorf("failed to create cache: %v", err),
 			)
 		}
-		repo, err = NewRepo(repoURL, cache, c.fetcher)
+		repo, err = NewRepo(repoURL, cache, c.fetcherForRepo(repoURL))
 		if err != nil {
 			return nil, err
 		}
@@ -84,3 +120,11 @@ func (c *Catalog) CreateRepoIfNotExist(repoURL string) (*Repo, error) {
 
 	return repo, nil
 }
+
+func (c *Catalog) fetcherForRepo(repoURL string) RemoteFetcher {
+	if c.credentials == nil {
+		return c.fetcher
+	}
+
+	return c.credentials.Fetcher(repoURL, c.fetcher)
+}
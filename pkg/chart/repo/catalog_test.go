@@ -2,9 +2,16 @@ package repo
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
 )
 
 type TestCache struct {
@@ -86,7 +93,7 @@ func TestCreateRepoIfNotExist(t *testing.T) {
 			defer close(stopCh)
 			c := NewCatalog(testCase.factory, func(_ string) ([]byte, error) {
 				return []byte{}, nil
-			}, stopCh)
+			}, nil, stopCh)
 			_, err := c.CreateRepoIfNotExist(testCase.url)
 			if (err == nil && testCase.err != nil) ||
 				(err != nil && testCase.err == nil) ||
@@ -96,3 +103,57 @@ func TestCreateRepoIfNotExist(t *testing.T) {
 		})
 	}
 }
+
+func TestCatalogSurfacesUnauthorizedRepo(t *testing.T) {
+	var authorized int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&authorized) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(IndexYamlResp))
+	}))
+	defer srv.Close()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := NewCatalog(func(name string) (Cache, error) {
+		return NewTestCache(name), nil
+	}, DefaultRemoteFetcher, nil, stopCh)
+
+	repo, err := c.CreateRepoIfNotExist(srv.URL)
+	if err != nil {
+		t.Fatalf("failed to create repo: %s", err)
+	}
+
+	chartspec := &shipper.Chart{Name: "nginx", Version: "0.0.1", RepoURL: srv.URL}
+
+	// A repo that turns us away from the start shouldn't leave callers
+	// waiting for an index that's never coming.
+	start := time.Now()
+	_, err = repo.FetchChartVersions(chartspec)
+	if !shippererrors.IsChartRepoUnauthorizedError(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+	if waited := time.Since(start); waited >= RepoFetchIndexTimeout {
+		t.Fatalf("expected an answer before the %s timeout, waited %s", RepoFetchIndexTimeout, waited)
+	}
+
+	atomic.StoreInt32(&authorized, 1)
+	if err := repo.refreshIndex(); err != nil {
+		t.Fatalf("failed to refresh index: %s", err)
+	}
+	if _, err := repo.FetchChartVersions(chartspec); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Once credentials stop working, the index fetched with them isn't
+	// used anymore.
+	atomic.StoreInt32(&authorized, 0)
+	if err := repo.refreshIndex(); !shippererrors.IsChartRepoUnauthorizedError(err) {
+		t.Fatalf("expected refreshing the index to fail as unauthorized, got %v", err)
+	}
+	if _, err := repo.FetchChartVersions(chartspec); !shippererrors.IsChartRepoUnauthorizedError(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package repo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1informer "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	"github.com/bookingcom/shipper/pkg/metrics/instrumentedclient"
+)
+
+const (
+	CredentialsUsernameKey = "username"
+	CredentialsPasswordKey = "password"
+	CredentialsTokenKey    = "token"
+	CredentialsCAKey       = "ca.crt"
+	CredentialsCertKey     = "tls.crt"
+	CredentialsKeyKey      = "tls.key"
+)
+
+// CredentialStore keeps track of chart repository credentials stored as
+// Secrets in the Shipper namespace. Only Secrets labeled with
+// shipper.ChartRepoCredentialsLabel are considered, and each of them applies
+// to every repo whose URL starts with the prefix in its
+// shipper.SecretChartRepoURLPrefixAnnotation annotation. When more than one
+// Secret matches a repo, the one with the longest prefix wins.
+//
+// A credentials Secret can contain basic auth ("username" and "password"), a
+// bearer token ("token"), a custom CA to verify the repo's certificate
+// ("ca.crt") and a client certificate ("tls.crt" and "tls.key"), in any
+// combination.
+type CredentialStore struct {
+	ns            string
+	secretLister  corev1listers.SecretLister
+	secretsSynced cache.InformerSynced
+
+	mutex   sync.Mutex
+	clients map[string]*credentialedClient
+}
+
+type credentialedClient struct {
+	resourceVersion string
+	client          *http.Client
+}
+
+func NewCredentialStore(secretInformer corev1informer.SecretInformer, ns string) *CredentialStore {
+	return &CredentialStore{
+		ns:            ns,
+		secretLister:  secretInformer.Lister(),
+		secretsSynced: secretInformer.Informer().HasSynced,
+		clients:       make(map[string]*credentialedClient),
+	}
+}
+
+// Fetcher returns a RemoteFetcher for the repo at repoURL. Credentials are
+// looked up every time the fetcher is called, so rotated Secrets are used as
+// soon as the informer observes them. Repos without matching credentials, as
+// well as URLs outside of the credentials' prefix (such as charts hosted
+// elsewhere), are fetched with fallback.
+func (s *CredentialStore) Fetcher(repoURL string, fallback RemoteFetcher) RemoteFetcher {
+	return func(url string) ([]byte, error) {
+		if !s.secretsSynced() {
+			return nil, shippererrors.NewChartRepoInternalError(
+				fmt.Errorf("chart repo credentials have not been synced yet"),
+			)
+		}
+
+		secret, err := s.secretForURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if secret == nil || !urlHasPrefix(url, secret.Annotations[shipper.SecretChartRepoURLPrefixAnnotation]) {
+			return fallback(url)
+		}
+
+		client, err := s.clientForSecret(secret)
+		if err != nil {
+			return nil, shippererrors.NewChartRepoInternalError(
+				fmt.Errorf("failed to build client from secret %q: %v", secret.Name, err),
+			)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if token, ok := secret.Data[CredentialsTokenKey]; ok {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		} else if username, ok := secret.Data[CredentialsUsernameKey]; ok {
+			req.SetBasicAuth(string(username), string(secret.Data[CredentialsPasswordKey]))
+		}
+
+		return fetch(client, req)
+	}
+}
+
+// secretForURL returns the credentials Secret with the longest URL prefix
+// matching url, or nil if there is none.
+func (s *CredentialStore) secretForURL(url string) (*corev1.Secret, error) {
+	selector := labels.Set{shipper.ChartRepoCredentialsLabel: shipper.True}.AsSelector()
+	secrets, err := s.secretLister.Secrets(s.ns).List(selector)
+	if err != nil {
+		return nil, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Secret"),
+			s.ns, selector, err)
+	}
+
+	var match *corev1.Secret
+	for _, secret := range secrets {
+		prefix, ok := secret.Annotations[shipper.SecretChartRepoURLPrefixAnnotation]
+		if !ok || !urlHasPrefix(url, prefix) {
+			continue
+		}
+
+		if match == nil || len(prefix) > len(match.Annotations[shipper.SecretChartRepoURLPrefixAnnotation]) {
+			match = secret
+		}
+	}
+
+	return match, nil
+}
+
+// clientForSecret returns an http.Client configured with the TLS settings in
+// secret. Clients are cached, and only rebuilt when the Secret changes.
+func (s *CredentialStore) clientForSecret(secret *corev1.Secret) (*http.Client, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := fmt.Sprintf("%s/%s", secret.Namespace, secret.Name)
+	if cached, ok := s.clients[key]; ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.client, nil
+	}
+
+	client, err := buildClient(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	s.clients[key] = &credentialedClient{
+		resourceVersion: secret.ResourceVersion,
+		client:          client,
+	}
+
+	return client, nil
+}
+
+func buildClient(secret *corev1.Secret) (*http.Client, error) {
+	ca, hasCA := secret.Data[CredentialsCAKey]
+	crt, hasCrt := secret.Data[CredentialsCertKey]
+	key, hasKey := secret.Data[CredentialsKeyKey]
+
+	if !hasCA && !hasCrt && !hasKey {
+		return instrumentedclient.DefaultClient, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if hasCA {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificates found in %q", CredentialsCAKey)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if hasCrt != hasKey {
+		return nil, fmt.Errorf("both %q and %q are required for client certificates", CredentialsCertKey, CredentialsKeyKey)
+	}
+
+	if hasCrt {
+		cert, err := tls.X509KeyPair(crt, key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return instrumentedclient.NewClientWithTLSConfig(tlsConfig), nil
+}
+
+// urlHasPrefix checks if url is prefix itself or lives under it, taking care
+// not to match "https://charts.example.com.evil" against
+// "https://charts.example.com".
+func urlHasPrefix(url, prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	return url == prefix || strings.HasPrefix(url, prefix+"/")
+}
@@ -0,0 +1,216 @@
+package repo
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+const (
+	testCredentialsNamespace = shipper.ShipperNamespace
+	testUsername             = "shipper"
+	testPassword             = "hunter2"
+	testToken                = "s3cr3t"
+	testIndex                = "index contents"
+)
+
+func newCredentialsSecret(name, prefix string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       testCredentialsNamespace,
+			ResourceVersion: "1",
+			Labels: map[string]string{
+				shipper.ChartRepoCredentialsLabel: shipper.True,
+			},
+			Annotations: map[string]string{
+				shipper.SecretChartRepoURLPrefixAnnotation: prefix,
+			},
+		},
+		Data: data,
+	}
+}
+
+func newTestCredentialStore(t *testing.T, objects ...runtime.Object) (*CredentialStore, *kubefake.Clientset, func()) {
+	client := kubefake.NewSimpleClientset(objects...)
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	secretInformer := informerFactory.Core().V1().Secrets()
+	store := NewCredentialStore(secretInformer, testCredentialsNamespace)
+
+	stopCh := make(chan struct{})
+	informerFactory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, secretInformer.Informer().HasSynced) {
+		t.Fatal("failed to sync secret informer")
+	}
+
+	return store, client, func() { close(stopCh) }
+}
+
+func basicAuthHandler(username, password string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testIndex))
+	}
+}
+
+func bearerAuthHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(testIndex))
+	}
+}
+
+func TestCredentialStoreFetcher(t *testing.T) {
+	basicSrv := httptest.NewServer(basicAuthHandler(testUsername, testPassword))
+	defer basicSrv.Close()
+
+	bearerSrv := httptest.NewServer(bearerAuthHandler(testToken))
+	defer bearerSrv.Close()
+
+	tlsSrv := httptest.NewTLSServer(basicAuthHandler(testUsername, testPassword))
+	defer tlsSrv.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw})
+
+	tests := []struct {
+		name       string
+		repoURL    string
+		secrets    []runtime.Object
+		expectAuth bool
+	}{
+		{
+			name:    "basic auth",
+			repoURL: basicSrv.URL + "/charts",
+			secrets: []runtime.Object{
+				newCredentialsSecret("basic", basicSrv.URL, map[string][]byte{
+					CredentialsUsernameKey: []byte(testUsername),
+					CredentialsPasswordKey: []byte(testPassword),
+				}),
+			},
+		},
+		{
+			name:    "bearer token",
+			repoURL: bearerSrv.URL + "/charts",
+			secrets: []runtime.Object{
+				newCredentialsSecret("bearer", bearerSrv.URL, map[string][]byte{
+					CredentialsTokenKey: []byte(testToken),
+				}),
+			},
+		},
+		{
+			name:    "custom CA",
+			repoURL: tlsSrv.URL + "/charts",
+			secrets: []runtime.Object{
+				newCredentialsSecret("tls", tlsSrv.URL, map[string][]byte{
+					CredentialsUsernameKey: []byte(testUsername),
+					CredentialsPasswordKey: []byte(testPassword),
+					CredentialsCAKey:       caPEM,
+				}),
+			},
+		},
+		{
+			name:    "longest prefix wins",
+			repoURL: basicSrv.URL + "/charts/private",
+			secrets: []runtime.Object{
+				newCredentialsSecret("short", basicSrv.URL, map[string][]byte{
+					CredentialsUsernameKey: []byte(testUsername),
+					CredentialsPasswordKey: []byte("wrong"),
+				}),
+				newCredentialsSecret("long", basicSrv.URL+"/charts/private", map[string][]byte{
+					CredentialsUsernameKey: []byte(testUsername),
+					CredentialsPasswordKey: []byte(testPassword),
+				}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _, stop := newTestCredentialStore(t, tt.secrets...)
+			defer stop()
+
+			fetcher := store.Fetcher(tt.repoURL, DefaultRemoteFetcher)
+			data, err := fetcher(tt.repoURL + "/index.yaml")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if string(data) != testIndex {
+				t.Fatalf("expected to fetch %q, got %q", testIndex, string(data))
+			}
+		})
+	}
+}
+
+func TestCredentialStoreUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(basicAuthHandler(testUsername, testPassword))
+	defer srv.Close()
+
+	// This secret's prefix is a string prefix of the server URL, but not
+	// a URL prefix, so it should not be used.
+	secret := newCredentialsSecret("unrelated", srv.URL[:len(srv.URL)-1], map[string][]byte{
+		CredentialsUsernameKey: []byte(testUsername),
+		CredentialsPasswordKey: []byte(testPassword),
+	})
+
+	store, _, stop := newTestCredentialStore(t, secret)
+	defer stop()
+
+	fetcher := store.Fetcher(srv.URL, DefaultRemoteFetcher)
+	_, err := fetcher(srv.URL + "/index.yaml")
+	if !shippererrors.IsChartRepoUnauthorizedError(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+}
+
+func TestCredentialStoreRotation(t *testing.T) {
+	srv := httptest.NewServer(bearerAuthHandler(testToken))
+	defer srv.Close()
+
+	secret := newCredentialsSecret("rotating", srv.URL, map[string][]byte{
+		CredentialsTokenKey: []byte("expired"),
+	})
+
+	store, client, stop := newTestCredentialStore(t, secret)
+	defer stop()
+
+	fetcher := store.Fetcher(srv.URL, DefaultRemoteFetcher)
+	if _, err := fetcher(srv.URL + "/index.yaml"); !shippererrors.IsChartRepoUnauthorizedError(err) {
+		t.Fatalf("expected an unauthorized error before rotation, got %v", err)
+	}
+
+	rotated := secret.DeepCopy()
+	rotated.ResourceVersion = "2"
+	rotated.Data[CredentialsTokenKey] = []byte(testToken)
+	_, err := client.CoreV1().Secrets(testCredentialsNamespace).Update(rotated)
+	if err != nil {
+		t.Fatalf("failed to update secret: %s", err)
+	}
+
+	err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, err := fetcher(srv.URL + "/index.yaml")
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("rotated credentials were never picked up: %s", err)
+	}
+}
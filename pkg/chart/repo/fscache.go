@@ -6,33 +6,71 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"k8s.io/klog"
 )
 
 type fsCache struct {
 	dir   string
-	limit int
+	index *fsCacheIndex
 }
 
-func NewFilesystemCache(dir string, limit int) (*fsCache, error) {
+// NewFilesystemCache returns a Cache that stores files in dir. The size of
+// all files is accounted for in index, which might be shared with other
+// caches so they can all live within the same budget.
+func NewFilesystemCache(dir string, index *fsCacheIndex) (*fsCache, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
 
-	return &fsCache{dir: dir, limit: limit}, nil
+	index.addLive(dir)
+
+	return &fsCache{dir: dir, index: index}, nil
 }
 
 func (f *fsCache) Fetch(name string) ([]byte, error) {
 	name = clean(name)
 	path := filepath.Join(f.dir, name)
-	return ioutil.ReadFile(path)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			cacheMisses.Inc()
+			f.index.remove(path)
+		}
+		return nil, err
+	}
+
+	cacheHits.Inc()
+	f.index.touch(path, int64(len(data)))
+
+	// The modification time is what we use to rebuild the LRU list when
+	// shipper restarts, so we keep it up to date with accesses.
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		klog.Warningf("Failed to update modification time of cached %q: %s", path, err)
+	}
+
+	return data, nil
 }
 
 func (f *fsCache) Store(name string, data []byte) error {
-	if err := f.ensureLimits(len(data)); err != nil {
+	name = clean(name)
+	path := filepath.Join(f.dir, name)
+
+	if err := f.index.reserve(path, int64(len(data))); err != nil {
 		return err
 	}
 
-	name = clean(name)
+	if err := f.write(path, name, data); err != nil {
+		f.index.remove(path)
+		return err
+	}
+
+	return nil
+}
+
+func (f *fsCache) write(path, name string, data []byte) error {
 	tmp, err := ioutil.TempFile(f.dir, name)
 	if err != nil {
 		return fmt.Errorf("failed to create tmp file: %v", err)
@@ -49,7 +87,6 @@ func (f *fsCache) Store(name string, data []byte) error {
 		return fmt.Errorf("failed to write to tmp file: %v", err)
 	}
 
-	path := filepath.Join(f.dir, name)
 	if err := os.Rename(tmp.Name(), path); err != nil {
 		return fmt.Errorf("failed to rename %q to %q: %v", tmp.Name(), path, err)
 	}
@@ -58,14 +95,10 @@ func (f *fsCache) Store(name string, data []byte) error {
 }
 
 func (f *fsCache) Clean() error {
+	f.index.removeDir(f.dir)
 	return os.RemoveAll(f.dir)
 }
 
-func (f *fsCache) ensureLimits(toStore int) error {
-	// TODO
-	return nil
-}
-
 func clean(v string) string {
 	v = strings.Replace(v, ":", "-", -1)
 	v = strings.Replace(v, "/", "-", -1)
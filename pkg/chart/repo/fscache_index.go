@@ -0,0 +1,270 @@
+package repo
+
+import (
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/klog"
+)
+
+const (
+	repoIndexFile = "index.yaml"
+	chartFileExt  = ".tgz"
+)
+
+func isCacheableFile(name string) bool {
+	return name == repoIndexFile ||
+		strings.HasSuffix(name, chartFileExt) ||
+		strings.HasSuffix(name, chartFileExt+provenanceFileExt)
+}
+
+// fsCacheIndex keeps track of every file stored by the filesystem caches of
+// all repos, so they can share a single byte budget. Whenever a new file
+// would take the total size over the limit, the least recently used files
+// are evicted to make room for it. The index.yaml of repos that are live in
+// this process is never evicted, as losing it means we can't tell a repo with
+// no cached index from one that is just slow to respond.
+type fsCacheIndex struct {
+	limit int64
+
+	mutex   sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+	live    map[string]struct{}
+}
+
+type fsCacheEntry struct {
+	path string
+	size int64
+}
+
+// newFsCacheIndex returns an empty index with a budget of limit bytes. A
+// limit of 0 means the cache can grow without bounds.
+func newFsCacheIndex(limit int64) *fsCacheIndex {
+	return &fsCacheIndex{
+		limit:   limit,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		live:    make(map[string]struct{}),
+	}
+}
+
+// warmUp populates the index with whatever is already in dir from previous
+// runs, ordered by modification time. Chart tarballs that can't be loaded are
+// discarded right away, and the least recently used files are evicted if dir
+// is over budget. No repo has been marked as live this early on, so every
+// index.yaml found is kept regardless, as any of them might be in use soon.
+func (i *fsCacheIndex) warmUp(dir string) error {
+	type cachedFile struct {
+		path string
+		info os.FileInfo
+	}
+
+	var files []cachedFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := info.Name()
+		if !info.Mode().IsRegular() || !isCacheableFile(name) {
+			return nil
+		}
+
+		if strings.HasSuffix(name, chartFileExt) {
+			if err := checkCachedChart(path); err != nil {
+				klog.Warningf("Discarding corrupt cached chart %q: %s", path, err)
+				cacheEvictions.WithLabelValues(evictionReasonCorrupt).Inc()
+				return os.Remove(path)
+			}
+		}
+
+		files = append(files, cachedFile{path: path, info: info})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to warm up chart cache in %q: %v", dir, err)
+	}
+
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].info.ModTime().Before(files[b].info.ModTime())
+	})
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	var pinned []string
+	for _, f := range files {
+		i.touchLocked(f.path, f.info.Size())
+
+		repoDir := filepath.Dir(f.path)
+		if _, ok := i.live[repoDir]; !ok && f.info.Name() == repoIndexFile {
+			i.live[repoDir] = struct{}{}
+			pinned = append(pinned, repoDir)
+		}
+	}
+
+	klog.V(2).Infof("Chart cache in %q holds %d files (%d bytes)", dir, len(files), i.size)
+
+	err = i.evictLocked("", 0)
+
+	for _, repoDir := range pinned {
+		delete(i.live, repoDir)
+	}
+
+	return err
+}
+
+func checkCachedChart(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = loadChartData(data)
+	return err
+}
+
+// addLive marks dir as belonging to a repo that is in use, so its index is
+// never evicted.
+func (i *fsCacheIndex) addLive(dir string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.live[dir] = struct{}{}
+}
+
+// touch marks path as the most recently used file.
+func (i *fsCacheIndex) touch(path string, size int64) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.touchLocked(path, size)
+}
+
+// reserve makes room for size bytes to be stored in path, evicting other
+// files if necessary, and accounts for them right away.
+func (i *fsCacheIndex) reserve(path string, size int64) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.limit > 0 && size > i.limit {
+		return fmt.Errorf("%q is %d bytes, which exceeds the chart cache limit of %d bytes", path, size, i.limit)
+	}
+
+	if err := i.evictLocked(path, size); err != nil {
+		return err
+	}
+
+	i.touchLocked(path, size)
+
+	return nil
+}
+
+// remove stops accounting for path. It does not remove any files.
+func (i *fsCacheIndex) remove(path string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.removeLocked(path)
+}
+
+// removeDir stops accounting for all files in dir. It does not remove any
+// files.
+func (i *fsCacheIndex) removeDir(dir string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for path := range i.entries {
+		if filepath.Dir(path) == dir {
+			i.removeLocked(path)
+		}
+	}
+
+	delete(i.live, dir)
+}
+
+func (i *fsCacheIndex) touchLocked(path string, size int64) {
+	if el, ok := i.entries[path]; ok {
+		entry := el.Value.(*fsCacheEntry)
+		i.size += size - entry.size
+		entry.size = size
+		i.lru.MoveToFront(el)
+	} else {
+		i.entries[path] = i.lru.PushFront(&fsCacheEntry{path: path, size: size})
+		i.size += size
+	}
+
+	cacheBytes.Set(float64(i.size))
+}
+
+func (i *fsCacheIndex) removeLocked(path string) {
+	el, ok := i.entries[path]
+	if !ok {
+		return
+	}
+
+	i.size -= el.Value.(*fsCacheEntry).size
+	i.lru.Remove(el)
+	delete(i.entries, path)
+
+	cacheBytes.Set(float64(i.size))
+}
+
+// evictLocked removes the least recently used files until there's room for
+// size bytes in path. The current contents of path, if any, are considered
+// as already freed, as they are about to be overwritten.
+func (i *fsCacheIndex) evictLocked(path string, size int64) error {
+	if i.limit == 0 {
+		return nil
+	}
+
+	needed := i.size + size
+	if el, ok := i.entries[path]; ok {
+		needed -= el.Value.(*fsCacheEntry).size
+	}
+
+	for el := i.lru.Back(); el != nil && needed > i.limit; {
+		entry := el.Value.(*fsCacheEntry)
+		prev := el.Prev()
+
+		if entry.path == path || i.pinnedLocked(entry.path) {
+			el = prev
+			continue
+		}
+
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to evict %q from chart cache: %v", entry.path, err)
+		}
+
+		klog.V(4).Infof("Evicted %q (%d bytes) from chart cache", entry.path, entry.size)
+		cacheEvictions.WithLabelValues(evictionReasonSize).Inc()
+
+		needed -= entry.size
+		i.removeLocked(entry.path)
+		el = prev
+	}
+
+	if needed > i.limit {
+		return fmt.Errorf("failed to make room for %d bytes in chart cache: only pinned files left", size)
+	}
+
+	return nil
+}
+
+func (i *fsCacheIndex) pinnedLocked(path string) bool {
+	if filepath.Base(path) != repoIndexFile {
+		return false
+	}
+
+	_, ok := i.live[filepath.Dir(path)]
+	return ok
+}
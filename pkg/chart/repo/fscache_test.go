@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFsCache(t *testing.T, index *fsCacheIndex, dir string) *fsCache {
+	cache, err := NewFilesystemCache(dir, index)
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+
+	return cache
+}
+
+func assertCached(t *testing.T, cache Cache, name string, expected bool) {
+	_, err := cache.Fetch(name)
+	if expected && err != nil {
+		t.Errorf("expected %q to be cached, got error: %s", name, err)
+	} else if !expected && !os.IsNotExist(err) {
+		t.Errorf("expected %q to be evicted, got error: %v", name, err)
+	}
+}
+
+func TestFsCacheEvictsLeastRecentlyUsedAcrossRepos(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chart-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index := newFsCacheIndex(30)
+	foo := newTestFsCache(t, index, filepath.Join(dir, "foo"))
+	bar := newTestFsCache(t, index, filepath.Join(dir, "bar"))
+
+	chunk := make([]byte, 10)
+
+	if err := foo.Store("a.tgz", chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := bar.Store("b.tgz", chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := foo.Store("c.tgz", chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	// Touching a.tgz makes b.tgz the least recently used file.
+	assertCached(t, foo, "a.tgz", true)
+
+	if err := foo.Store("d.tgz", chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	assertCached(t, bar, "b.tgz", false)
+	assertCached(t, foo, "a.tgz", true)
+	assertCached(t, foo, "c.tgz", true)
+	assertCached(t, foo, "d.tgz", true)
+
+	if index.size != 30 {
+		t.Errorf("expected cache to hold 30 bytes, got %d", index.size)
+	}
+}
+
+func TestFsCacheNeverEvictsLiveIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chart-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index := newFsCacheIndex(20)
+	foo := newTestFsCache(t, index, filepath.Join(dir, "foo"))
+
+	chunk := make([]byte, 10)
+
+	if err := foo.Store(repoIndexFile, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := foo.Store("a.tgz", chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := foo.Store("b.tgz", chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	assertCached(t, foo, repoIndexFile, true)
+	assertCached(t, foo, "a.tgz", false)
+	assertCached(t, foo, "b.tgz", true)
+
+	if err := foo.Store("c.tgz", make([]byte, 15)); err == nil {
+		t.Errorf("expected an error when only the live index is left to evict")
+	}
+
+	if err := foo.Store("d.tgz", make([]byte, 25)); err == nil {
+		t.Errorf("expected an error when storing a file bigger than the limit")
+	}
+}
+
+func TestFsCacheWarmUp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chart-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chart, err := ioutil.ReadFile(filepath.Join("testdata", "nginx-0.0.1.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoDir := filepath.Join(dir, "repo")
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repoIndex := []byte("apiVersion: v1\nentries: {}\n")
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{repoIndexFile, repoIndex},
+		{"old-0.0.1.tgz", chart},
+		{"corrupt-0.0.1.tgz", []byte("definitely not a tarball")},
+		{"new-0.0.1.tgz", chart},
+	}
+
+	past := time.Now().Add(-time.Hour)
+	for i, f := range files {
+		path := filepath.Join(repoDir, f.name)
+		if err := ioutil.WriteFile(path, f.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := past.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// There's only room for one chart, so the oldest one needs to go as
+	// well as the corrupt one. The repo index is even older, but it's
+	// kept as the repo might still be in use.
+	factory, err := DefaultFileCacheFactory(dir, int64(len(chart)+len(repoIndex)))
+	if err != nil {
+		t.Fatalf("failed to warm up cache: %s", err)
+	}
+
+	cache, err := factory("repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertCached(t, cache, "old-0.0.1.tgz", false)
+	assertCached(t, cache, "corrupt-0.0.1.tgz", false)
+	assertCached(t, cache, "new-0.0.1.tgz", true)
+	assertCached(t, cache, repoIndexFile, true)
+}
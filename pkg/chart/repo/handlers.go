@@ -33,3 +33,52 @@ func FetchChartFunc(c *Catalog) ChartFetcher {
 		return repo.Fetch(chartspec)
 	}
 }
+
+// ChartVerifier checks that a chart to be installed for an application in
+// namespace has been signed by a trusted key.
+type ChartVerifier func(chartspec *shipper.Chart, namespace string) error
+
+func VerifyChartFunc(c *Catalog, keyrings *KeyringStore) ChartVerifier {
+	return func(chartspec *shipper.Chart, namespace string) error {
+		keyring, err := keyrings.Keyring(chartspec.RepoURL, namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(keyring) == 0 {
+			return nil
+		}
+
+		repo, err := c.CreateRepoIfNotExist(chartspec.RepoURL)
+		if err != nil {
+			return err
+		}
+
+		_, err = repo.Verify(chartspec, keyring)
+		return err
+	}
+}
+
+// VerifiedChartFetcher fetches a chart to be installed for an application in
+// namespace, making sure it has been signed by a trusted key if it needs to.
+type VerifiedChartFetcher func(chartspec *shipper.Chart, namespace string) (*helmchart.Chart, error)
+
+func FetchVerifiedChartFunc(c *Catalog, keyrings *KeyringStore) VerifiedChartFetcher {
+	return func(chartspec *shipper.Chart, namespace string) (*helmchart.Chart, error) {
+		keyring, err := keyrings.Keyring(chartspec.RepoURL, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		repo, err := c.CreateRepoIfNotExist(chartspec.RepoURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(keyring) == 0 {
+			return repo.Fetch(chartspec)
+		}
+
+		return repo.FetchVerified(chartspec, keyring)
+	}
+}
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/openpgp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1informer "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+const KeyringKey = "keyring"
+
+// KeyringStore keeps track of the public keys that charts need to be signed
+// with, stored as Secrets in the Shipper namespace. Only Secrets labeled with
+// shipper.ChartKeyringLabel are considered, and each of them holds an OpenPGP
+// keyring (either binary or ASCII armored) under the "keyring" key.
+//
+// By default a keyring applies to every chart. It can be scoped down to the
+// repos whose URL starts with the prefix in its
+// shipper.SecretChartRepoURLPrefixAnnotation annotation, and to the
+// applications living in the comma-separated namespaces in its
+// shipper.SecretChartKeyringNamespacesAnnotation annotation. When more than
+// one keyring applies to a chart, a signature from any of their keys is
+// accepted. Charts with no keyring applying to them are not verified at all.
+type KeyringStore struct {
+	ns            string
+	secretLister  corev1listers.SecretLister
+	secretsSynced cache.InformerSynced
+
+	mutex    sync.Mutex
+	keyrings map[string]*parsedKeyring
+}
+
+type parsedKeyring struct {
+	resourceVersion string
+	keyring         openpgp.EntityList
+}
+
+func NewKeyringStore(secretInformer corev1informer.SecretInformer, ns string) *KeyringStore {
+	return &KeyringStore{
+		ns:            ns,
+		secretLister:  secretInformer.Lister(),
+		secretsSynced: secretInformer.Informer().HasSynced,
+		keyrings:      make(map[string]*parsedKeyring),
+	}
+}
+
+// Keyring returns all the keys that a chart from repoURL, installed in
+// namespace, can be signed with. An empty keyring means that the chart does
+// not need to be verified.
+func (s *KeyringStore) Keyring(repoURL, namespace string) (openpgp.EntityList, error) {
+	if !s.secretsSynced() {
+		return nil, shippererrors.NewChartRepoInternalError(
+			fmt.Errorf("chart keyrings have not been synced yet"),
+		)
+	}
+
+	selector := labels.Set{shipper.ChartKeyringLabel: shipper.True}.AsSelector()
+	secrets, err := s.secretLister.Secrets(s.ns).List(selector)
+	if err != nil {
+		return nil, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Secret"),
+			s.ns, selector, err)
+	}
+
+	var keyring openpgp.EntityList
+	for _, secret := range secrets {
+		if !keyringApplies(secret, repoURL, namespace) {
+			continue
+		}
+
+		entities, err := s.keyringForSecret(secret)
+		if err != nil {
+			return nil, shippererrors.NewChartRepoInternalError(
+				fmt.Errorf("failed to read keyring from secret %q: %v", secret.Name, err),
+			)
+		}
+
+		keyring = append(keyring, entities...)
+	}
+
+	return keyring, nil
+}
+
+func keyringApplies(secret *corev1.Secret, repoURL, namespace string) bool {
+	if prefix, ok := secret.Annotations[shipper.SecretChartRepoURLPrefixAnnotation]; ok {
+		if !urlHasPrefix(repoURL, prefix) {
+			return false
+		}
+	}
+
+	if namespaces, ok := secret.Annotations[shipper.SecretChartKeyringNamespacesAnnotation]; ok {
+		for _, ns := range strings.Split(namespaces, ",") {
+			if strings.TrimSpace(ns) == namespace {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// keyringForSecret returns the keyring stored in secret. Keyrings are cached,
+// and only parsed again when the Secret changes.
+func (s *KeyringStore) keyringForSecret(secret *corev1.Secret) (openpgp.EntityList, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := fmt.Sprintf("%s/%s", secret.Namespace, secret.Name)
+	if cached, ok := s.keyrings[key]; ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.keyring, nil
+	}
+
+	keyring, err := parseKeyring(secret.Data[KeyringKey])
+	if err != nil {
+		return nil, err
+	}
+
+	s.keyrings[key] = &parsedKeyring{
+		resourceVersion: secret.ResourceVersion,
+		keyring:         keyring,
+	}
+
+	return keyring, nil
+}
+
+func parseKeyring(data []byte) (openpgp.EntityList, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no keyring found in %q", KeyringKey)
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
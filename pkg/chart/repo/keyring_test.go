@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func newKeyringSecret(name string, keyring []byte, annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       testCredentialsNamespace,
+			ResourceVersion: "1",
+			Labels: map[string]string{
+				shipper.ChartKeyringLabel: shipper.True,
+			},
+			Annotations: annotations,
+		},
+		Data: map[string][]byte{
+			KeyringKey: keyring,
+		},
+	}
+}
+
+func newTestKeyringStore(t *testing.T, objects ...runtime.Object) (*KeyringStore, func()) {
+	client := kubefake.NewSimpleClientset(objects...)
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	secretInformer := informerFactory.Core().V1().Secrets()
+	store := NewKeyringStore(secretInformer, testCredentialsNamespace)
+
+	stopCh := make(chan struct{})
+	informerFactory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, secretInformer.Informer().HasSynced) {
+		t.Fatal("failed to sync secret informer")
+	}
+
+	return store, func() { close(stopCh) }
+}
+
+func TestKeyringStore(t *testing.T) {
+	global := newTestEntity(t, "global")
+	scoped := newTestEntity(t, "scoped")
+
+	secrets := []runtime.Object{
+		newKeyringSecret("global", armoredPublicKey(t, global), nil),
+		newKeyringSecret("scoped", armoredPublicKey(t, scoped), map[string]string{
+			shipper.SecretChartRepoURLPrefixAnnotation:     "https://charts.example.com/private",
+			shipper.SecretChartKeyringNamespacesAnnotation: "foo, bar",
+		}),
+	}
+
+	store, stop := newTestKeyringStore(t, secrets...)
+	defer stop()
+
+	tests := []struct {
+		name      string
+		repoURL   string
+		namespace string
+		expected  []*openpgp.Entity
+	}{
+		{
+			name:      "public repo",
+			repoURL:   "https://charts.example.com/public",
+			namespace: "foo",
+			expected:  []*openpgp.Entity{global},
+		},
+		{
+			name:      "private repo in a scoped namespace",
+			repoURL:   "https://charts.example.com/private",
+			namespace: "bar",
+			expected:  []*openpgp.Entity{global, scoped},
+		},
+		{
+			name:      "private repo in another namespace",
+			repoURL:   "https://charts.example.com/private",
+			namespace: "baz",
+			expected:  []*openpgp.Entity{global},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := store.Keyring(tt.repoURL, tt.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(keyring) != len(tt.expected) {
+				t.Fatalf("expected %d keys, got %d", len(tt.expected), len(keyring))
+			}
+
+			for _, expected := range tt.expected {
+				if len(keyring.KeysById(expected.PrimaryKey.KeyId)) == 0 {
+					t.Errorf("expected keyring to contain key for %v", expected.Identities)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyringStoreNoKeyrings(t *testing.T) {
+	store, stop := newTestKeyringStore(t)
+	defer stop()
+
+	keyring, err := store.Keyring("https://charts.example.com", "foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(keyring) != 0 {
+		t.Fatalf("expected no keys, got %d", len(keyring))
+	}
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "shipper"
+	subsys           = "chart_cache"
+
+	evictionReasonSize    = "size"
+	evictionReasonCorrupt = "corrupt"
+)
+
+var (
+	cacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: subsys,
+			Name:      "hits_total",
+			Help:      "How many files were found in the local chart cache",
+		},
+	)
+	cacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: subsys,
+			Name:      "misses_total",
+			Help:      "How many files were not found in the local chart cache",
+		},
+	)
+	cacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: subsys,
+			Name:      "evictions_total",
+			Help:      "How many files were evicted from the local chart cache",
+		},
+		[]string{"reason"},
+	)
+	cacheBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: subsys,
+			Name:      "bytes",
+			Help:      "How many bytes are currently stored in the local chart cache",
+		},
+	)
+)
+
+// GetMetrics returns all Prometheus variables that track metrics for the
+// chart cache. Used for registering with an HTTP handler.
+func GetMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		cacheHits,
+		cacheMisses,
+		cacheEvictions,
+		cacheBytes,
+	}
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"k8s.io/helm/pkg/provenance"
+	"sigs.k8s.io/yaml"
+)
+
+const provenanceFileExt = ".prov"
+
+// verifyProvenance does the same checks as helm's provenance.Signatory.Verify,
+// but works with data in memory instead of files on disk: prov needs to be
+// signed by a key in keyring, and it needs to contain the digest of archive
+// under filename.
+func verifyProvenance(keyring openpgp.EntityList, filename string, archive, prov []byte) (*provenance.Verification, error) {
+	block, _ := clearsign.Decode(prov)
+	if block == nil {
+		return nil, fmt.Errorf("signature block not found in provenance file")
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(
+		keyring,
+		bytes.NewBuffer(block.Bytes),
+		block.ArmoredSignature.Body,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify signature: %v", err)
+	}
+
+	// Helm separates the chart metadata from the file digests with a YAML
+	// document end marker, as "---" is not allowed in a clearsign block.
+	parts := bytes.Split(block.Plaintext, []byte("\n...\n"))
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("message block must have at least two parts")
+	}
+
+	sums := &provenance.SumCollection{}
+	if err := yaml.Unmarshal(parts[1], sums); err != nil {
+		return nil, fmt.Errorf("failed to parse file digests: %v", err)
+	}
+
+	digest, err := provenance.Digest(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := "sha256:" + digest
+	if expected, ok := sums.Files[filename]; !ok {
+		return nil, fmt.Errorf("provenance does not contain a digest for a file named %q", filename)
+	} else if expected != sum {
+		return nil, fmt.Errorf("sha256 sum does not match for %s: %q != %q", filename, expected, sum)
+	}
+
+	return &provenance.Verification{
+		SignedBy: signer,
+		FileHash: sum,
+		FileName: filename,
+	}, nil
+}
@@ -0,0 +1,229 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"k8s.io/helm/pkg/provenance"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+func newTestEntity(t *testing.T, name string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	return entity
+}
+
+func armoredPublicKey(t *testing.T, entity *openpgp.Entity) []byte {
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func signChart(t *testing.T, entity *openpgp.Entity, chartpath string) []byte {
+	signatory := &provenance.Signatory{
+		Entity:  entity,
+		KeyRing: openpgp.EntityList{entity},
+	}
+
+	prov, err := signatory.ClearSign(chartpath)
+	if err != nil {
+		t.Fatalf("failed to sign %q: %s", chartpath, err)
+	}
+
+	return []byte(prov)
+}
+
+// provenanceFetch serves charts from testdata like localFetch, and the
+// provenance files in provs by name.
+func provenanceFetch(t *testing.T, provs map[string][]byte) func(string) ([]byte, error) {
+	fetch := localFetch(t)
+	return func(requrl string) ([]byte, error) {
+		if !strings.HasSuffix(requrl, provenanceFileExt) {
+			return fetch(requrl)
+		}
+
+		u, err := url.Parse(requrl)
+		if err != nil {
+			return nil, err
+		}
+
+		prov, ok := provs[path.Base(u.Path)]
+		if !ok {
+			return nil, fmt.Errorf("not found")
+		}
+
+		return prov, nil
+	}
+}
+
+func TestVerify(t *testing.T) {
+	signer := newTestEntity(t, "signer")
+	stranger := newTestEntity(t, "stranger")
+
+	signed := signChart(t, signer, "testdata/nginx-0.0.1.tgz")
+	tampered := signChart(t, signer, "testdata/nginx-0.0.2.tgz")
+	tampered = bytes.Replace(tampered, []byte("nginx-0.0.2.tgz"), []byte("nginx-0.0.1.tgz"), -1)
+
+	tests := []struct {
+		name    string
+		keyring openpgp.EntityList
+		prov    []byte
+		wantErr bool
+	}{
+		{
+			name:    "signed by a trusted key",
+			keyring: openpgp.EntityList{signer},
+			prov:    signed,
+		},
+		{
+			name:    "signed by an unknown key",
+			keyring: openpgp.EntityList{stranger},
+			prov:    signed,
+			wantErr: true,
+		},
+		{
+			name:    "digest mismatch",
+			keyring: openpgp.EntityList{signer},
+			prov:    tampered,
+			wantErr: true,
+		},
+		{
+			name:    "missing provenance file",
+			keyring: openpgp.EntityList{signer},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provs := map[string][]byte{}
+			if tt.prov != nil {
+				provs["nginx-0.0.1.tgz.prov"] = tt.prov
+			}
+
+			repo, err := NewRepo(repoURL, NewTestCache("test-cache"), provenanceFetch(t, provs))
+			if err != nil {
+				t.Fatalf("failed to initialize repo: %s", err)
+			}
+			if err := repo.refreshIndex(); err != nil {
+				t.Fatal(err)
+			}
+
+			chartspec := &shipper.Chart{
+				Name:    "nginx",
+				Version: "0.0.1",
+				RepoURL: repoURL,
+			}
+
+			verification, err := repo.Verify(chartspec, tt.keyring)
+			if tt.wantErr {
+				if !shippererrors.IsChartVerificationError(err) {
+					t.Fatalf("expected a chart verification error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if verification.SignedBy != signer {
+				t.Fatalf("expected chart to be signed by %v, got %v", signer.Identities, verification.SignedBy.Identities)
+			}
+		})
+	}
+}
+
+func TestVerifyCachesProvenance(t *testing.T) {
+	signer := newTestEntity(t, "signer")
+	provs := map[string][]byte{
+		"nginx-0.0.1.tgz.prov": signChart(t, signer, "testdata/nginx-0.0.1.tgz"),
+	}
+
+	cache := NewTestCache("test-cache")
+	repo, err := NewRepo(repoURL, cache, provenanceFetch(t, provs))
+	if err != nil {
+		t.Fatalf("failed to initialize repo: %s", err)
+	}
+	if err := repo.refreshIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	chartspec := &shipper.Chart{Name: "nginx", Version: "0.0.1", RepoURL: repoURL}
+	if _, err := repo.Verify(chartspec, openpgp.EntityList{signer}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := cache.Fetch("nginx-0.0.1.tgz.prov"); err != nil {
+		t.Fatalf("expected provenance file to be cached: %s", err)
+	}
+
+	// The provenance file is gone from the repo, but we already have it.
+	delete(provs, "nginx-0.0.1.tgz.prov")
+	if _, err := repo.Verify(chartspec, openpgp.EntityList{signer}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestFetchVerifiedAfterEviction(t *testing.T) {
+	signer := newTestEntity(t, "signer")
+	provs := map[string][]byte{
+		"nginx-0.0.1.tgz.prov": signChart(t, signer, "testdata/nginx-0.0.1.tgz"),
+	}
+
+	tampered := false
+	fetch := provenanceFetch(t, provs)
+	cache := NewTestCache("test-cache")
+	repo, err := NewRepo(repoURL, cache, func(requrl string) ([]byte, error) {
+		if tampered && strings.HasSuffix(requrl, "nginx-0.0.1.tgz") {
+			return fetch(strings.Replace(requrl, "nginx-0.0.1.tgz", "nginx-0.0.2.tgz", 1))
+		}
+		return fetch(requrl)
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize repo: %s", err)
+	}
+	if err := repo.refreshIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	chartspec := &shipper.Chart{Name: "nginx", Version: "0.0.1", RepoURL: repoURL}
+	chart, err := repo.FetchVerified(chartspec, openpgp.EntityList{signer})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if chart.Metadata.Version != "0.0.1" {
+		t.Fatalf("expected chart version 0.0.1, got %q", chart.Metadata.Version)
+	}
+
+	// The archive is evicted from the cache, and what the repo serves
+	// now is not what was signed.
+	cache.Clean()
+	tampered = true
+
+	if _, err := repo.FetchVerified(chartspec, openpgp.EntityList{signer}); !shippererrors.IsChartVerificationError(err) {
+		t.Fatalf("expected a chart verification error, got %v", err)
+	}
+}
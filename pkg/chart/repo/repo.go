@@ -20,9 +20,11 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/Masterminds/semver"
+	"golang.org/x/crypto/openpgp"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/provenance"
 	"k8s.io/helm/pkg/repo"
 	"k8s.io/klog"
 
@@ -85,7 +87,13 @@ func (r *Repo) refreshIndex() error {
 
 	data, err = r.fetcher(r.indexURL)
 	if err != nil {
-		_, cacheErr := r.cache.Fetch("index.yaml")
+		// Authorization failures are kept as they are so they can be
+		// told apart from any other fetch failure up the stack.
+		if shippererrors.IsChartRepoUnauthorizedError(err) {
+			goto AtomicSave
+		}
+
+		_, cacheErr := r.cache.Fetch(repoIndexFile)
 		if cacheErr != nil {
 			multiError := shippererrors.NewMultiError()
 			multiError.Append(
@@ -122,10 +130,13 @@ func (r *Repo) refreshIndex() error {
 		}
 	}
 
-	// marking the repo index as at-least-once-resolved
-	r.once.Do(func() {
-		close(r.resolved)
-	})
+	// Most refreshes bring back the same index, which is left alone in
+	// the cache rather than rewritten every time.
+	if cached, cacheErr := r.cache.Fetch(repoIndexFile); cacheErr != nil || !bytes.Equal(cached, data) {
+		if cacheErr := r.cache.Store(repoIndexFile, data); cacheErr != nil {
+			klog.Warningf("failed to cache repo %q index: %s", r.repoURL, cacheErr)
+		}
+	}
 
 AtomicSave:
 	r.mutex.Lock()
@@ -135,6 +146,15 @@ AtomicSave:
 		r.index = index
 	}
 
+	// marking the repo index as at-least-once-resolved. A repo turning
+	// us away is as much of an answer as an index, and waiting for it
+	// won't get a better one.
+	if err == nil || shippererrors.IsChartRepoUnauthorizedError(err) {
+		r.once.Do(func() {
+			close(r.resolved)
+		})
+	}
+
 	return err
 }
 
@@ -163,7 +183,10 @@ func (r *Repo) FetchChartVersions(chartspec *shipper.Chart) (repo.ChartVersions,
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	if r.index == nil {
+	// An index fetched before the repo started turning us away might
+	// be stale for good, so it's not used until the credentials are
+	// sorted out.
+	if r.index == nil || shippererrors.IsChartRepoUnauthorizedError(r.lastErr) {
 		return nil, r.lastErr
 	}
 
@@ -223,6 +246,49 @@ func (r *Repo) LoadCached(cv *repo.ChartVersion) (*chart.Chart, error) {
 }
 
 func (r *Repo) FetchRemote(cv *repo.ChartVersion) (*chart.Chart, error) {
+	data, _, err := r.fetchRemoteData(cv)
+	if err != nil {
+		return nil, err
+	}
+
+	chart, err := loadChartData(data)
+	if err != nil {
+		return nil, shippererrors.NewChartDataCorruptionError(cv, err)
+	}
+
+	filename := chart2file(cv)
+	if err := r.cache.Store(filename, data); err != nil {
+		return nil, shippererrors.NewChartRepoInternalError(err)
+	}
+
+	return chart, nil
+}
+
+// fetchRemoteData downloads the archive for cv, and returns it along with the
+// URL it was downloaded from.
+func (r *Repo) fetchRemoteData(cv *repo.ChartVersion) ([]byte, *url.URL, error) {
+	chartURL, err := r.chartURL(cv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := r.fetcher(chartURL.String())
+	if err != nil {
+		if shippererrors.IsChartRepoUnauthorizedError(err) {
+			return nil, nil, err
+		}
+
+		chart, convErr := newChart(cv)
+		if convErr != nil {
+			return nil, nil, shippererrors.NewChartRepoInternalError(convErr)
+		}
+		return nil, nil, shippererrors.NewChartFetchFailureError(chart, err)
+	}
+
+	return data, chartURL, nil
+}
+
+func (r *Repo) chartURL(cv *repo.ChartVersion) (*url.URL, error) {
 	if cv == nil {
 		return nil, shippererrors.NewBrokenChartVersionError(
 			cv,
@@ -259,30 +325,112 @@ func (r *Repo) FetchRemote(cv *repo.ChartVersion) (*chart.Chart, error) {
 		chartURL.RawQuery = query.Encode()
 	}
 
-	url := chartURL.String()
-	data, err := r.fetcher(url)
+	return chartURL, nil
+}
+
+func (r *Repo) Fetch(chartspec *shipper.Chart) (*chart.Chart, error) {
+	chartver, err := r.chartVersion(chartspec)
 	if err != nil {
-		chart, convErr := newChart(cv)
-		if convErr != nil {
-			return nil, shippererrors.NewChartRepoInternalError(convErr)
-		}
-		return nil, shippererrors.NewChartFetchFailureError(chart, err)
+		return nil, err
 	}
 
-	chart, err := loadChartData(data)
+	if chart, err := r.LoadCached(chartver); err == nil {
+		return chart, nil
+	}
+
+	return r.FetchRemote(chartver)
+}
+
+// Verify checks the provenance file of the chart in chartspec against
+// keyring. Both the chart archive and its provenance file are fetched from
+// the cache if possible, and stored there otherwise. The provenance file is
+// expected to live right next to the chart archive, as "helm package --sign"
+// and "helm repo index" would leave it.
+func (r *Repo) Verify(chartspec *shipper.Chart, keyring openpgp.EntityList) (*provenance.Verification, error) {
+	_, verification, err := r.verify(chartspec, keyring)
+	return verification, err
+}
+
+// FetchVerified returns the chart in chartspec after checking its provenance
+// file against keyring. The chart is loaded from the very archive that was
+// verified, so it doesn't matter whether it was evicted from the cache since
+// it was last verified.
+func (r *Repo) FetchVerified(chartspec *shipper.Chart, keyring openpgp.EntityList) (*chart.Chart, error) {
+	archive, _, err := r.verify(chartspec, keyring)
 	if err != nil {
-		return nil, shippererrors.NewChartDataCorruptionError(cv, err)
+		return nil, err
 	}
 
-	filename := chart2file(cv)
-	if err := r.cache.Store(filename, data); err != nil {
-		return nil, shippererrors.NewChartRepoInternalError(err)
+	chart, err := loadChartData(archive)
+	if err != nil {
+		return nil, shippererrors.NewChartVerificationError(chartspec, err)
 	}
 
 	return chart, nil
 }
 
-func (r *Repo) Fetch(chartspec *shipper.Chart) (*chart.Chart, error) {
+// verify checks the provenance of the chart in chartspec like Verify does,
+// and returns the chart archive that got verified along with the result.
+func (r *Repo) verify(chartspec *shipper.Chart, keyring openpgp.EntityList) ([]byte, *provenance.Verification, error) {
+	chartver, err := r.chartVersion(chartspec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chartURL, err := r.chartURL(chartver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filename := chart2file(chartver)
+	archive, err := r.cache.Fetch(filename)
+	if err != nil {
+		archive, _, err = r.fetchRemoteData(chartver)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, err := loadChartData(archive); err != nil {
+			return nil, nil, shippererrors.NewChartDataCorruptionError(chartver, err)
+		}
+
+		if err := r.cache.Store(filename, archive); err != nil {
+			return nil, nil, shippererrors.NewChartRepoInternalError(err)
+		}
+	}
+
+	provFilename := filename + provenanceFileExt
+	prov, err := r.cache.Fetch(provFilename)
+	if err != nil {
+		provURL := chartURL.String() + provenanceFileExt
+		prov, err = r.fetcher(provURL)
+		if err != nil {
+			if shippererrors.IsChartRepoUnauthorizedError(err) {
+				return nil, nil, err
+			}
+
+			return nil, nil, shippererrors.NewChartVerificationError(
+				chartspec,
+				fmt.Errorf("failed to fetch provenance file %q: %v", provURL, err),
+			)
+		}
+
+		if err := r.cache.Store(provFilename, prov); err != nil {
+			return nil, nil, shippererrors.NewChartRepoInternalError(err)
+		}
+	}
+
+	verification, err := verifyProvenance(keyring, path.Base(chartURL.Path), archive, prov)
+	if err != nil {
+		return nil, nil, shippererrors.NewChartVerificationError(chartspec, err)
+	}
+
+	return archive, verification, nil
+}
+
+// chartVersion picks the chart version that matches chartspec from the
+// repo's index.
+func (r *Repo) chartVersion(chartspec *shipper.Chart) (*repo.ChartVersion, error) {
 	versions, err := r.FetchChartVersions(chartspec)
 	if err != nil {
 		return nil, err
@@ -297,13 +445,7 @@ func (r *Repo) Fetch(chartspec *shipper.Chart) (*chart.Chart, error) {
 		return nil, shippererrors.NewChartVersionResolveError(chartspec, repo.ErrNoChartVersion)
 	}
 
-	chartver := versions[ix]
-
-	if chart, err := r.LoadCached(chartver); err == nil {
-		return chart, nil
-	}
-
-	return r.FetchRemote(chartver)
+	return versions[ix], nil
 }
 
 func loadIndexData(data []byte) (*repo.IndexFile, error) {
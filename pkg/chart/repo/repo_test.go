@@ -183,6 +183,57 @@ func TestRefreshIndex(t *testing.T) {
 	}
 }
 
+// storeCountingCache counts how many times each file is stored.
+type storeCountingCache struct {
+	*TestCache
+	stores map[string]int
+}
+
+func (c *storeCountingCache) Store(name string, data []byte) error {
+	c.stores[name]++
+	return c.TestCache.Store(name, data)
+}
+
+// TestRefreshIndexStoresOnlyChanges verifies that the index is only written
+// to the cache when it differs from the one already there.
+func TestRefreshIndexStoresOnlyChanges(t *testing.T) {
+	cache := &storeCountingCache{
+		TestCache: NewTestCache("test-cache"),
+		stores:    make(map[string]int),
+	}
+
+	body := IndexYamlResp
+	repo, err := NewRepo(
+		repoURL,
+		cache,
+		func(string) ([]byte, error) {
+			return []byte(body), nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to initialize repo: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.refreshIndex(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if stores := cache.stores[repoIndexFile]; stores != 1 {
+		t.Fatalf("expected an unchanged index to be stored once, got %d stores", stores)
+	}
+
+	body = IndexYamlResp + "\n"
+	if err := repo.refreshIndex(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if stores := cache.stores[repoIndexFile]; stores != 2 {
+		t.Fatalf("expected a changed index to be stored again, got %d stores", stores)
+	}
+}
+
 func TestResolveVersion(t *testing.T) {
 	tests := []struct {
 		name      string
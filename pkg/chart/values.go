@@ -0,0 +1,51 @@
+package chart
+
+import (
+	"sigs.k8s.io/yaml"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+// ParseValues parses a values.yaml document into ChartValues.
+func ParseValues(data []byte) (shipper.ChartValues, error) {
+	values := shipper.ChartValues{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// MergeValues deep-merges overlays, in order, over base. Maps are merged key
+// by key, while any other value (lists included) in an overlay replaces the
+// one it is merged over. Neither base nor overlays are modified.
+func MergeValues(base *shipper.ChartValues, overlays ...shipper.ChartValues) *shipper.ChartValues {
+	if base == nil && len(overlays) == 0 {
+		return nil
+	}
+
+	merged := shipper.ChartValues{}
+	if base != nil {
+		merged = base.DeepCopy()
+	}
+
+	for _, overlay := range overlays {
+		overlay = overlay.DeepCopy()
+		mergeMaps(merged, overlay)
+	}
+
+	return &merged
+}
+
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
@@ -0,0 +1,62 @@
+package chart
+
+import (
+	"reflect"
+	"testing"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func TestMergeValues(t *testing.T) {
+	base := &shipper.ChartValues{
+		"replicaCount": float64(3),
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.17",
+		},
+		"hosts": []interface{}{"a.example.com"},
+	}
+
+	first, err := ParseValues([]byte("image:\n  tag: \"1.19\"\nhosts:\n- b.example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := ParseValues([]byte("replicaCount: 5\nimage:\n  pullPolicy: Always\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &shipper.ChartValues{
+		"replicaCount": float64(5),
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.19",
+			"pullPolicy": "Always",
+		},
+		"hosts": []interface{}{"b.example.com"},
+	}
+
+	baseCopy := base.DeepCopy()
+	merged := MergeValues(base, first, second)
+
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected merged values to be %v, got %v", *expected, *merged)
+	}
+
+	if !reflect.DeepEqual(*base, baseCopy) {
+		t.Errorf("expected base values not to be modified, got %v", *base)
+	}
+}
+
+func TestMergeValuesNilBase(t *testing.T) {
+	if merged := MergeValues(nil); merged != nil {
+		t.Errorf("expected nil values, got %v", *merged)
+	}
+
+	overlay := shipper.ChartValues{"foo": "bar"}
+	merged := MergeValues(nil, overlay)
+	if !reflect.DeepEqual(*merged, overlay) {
+		t.Errorf("expected merged values to be %v, got %v", overlay, *merged)
+	}
+}
@@ -0,0 +1,112 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeReleaseApprovals implements ReleaseApprovalInterface
+type FakeReleaseApprovals struct {
+	Fake *FakeShipperV1alpha1
+	ns   string
+}
+
+var releaseapprovalsResource = schema.GroupVersionResource{Group: "shipper.booking.com", Version: "v1alpha1", Resource: "releaseapprovals"}
+
+var releaseapprovalsKind = schema.GroupVersionKind{Group: "shipper.booking.com", Version: "v1alpha1", Kind: "ReleaseApproval"}
+
+// Get takes name of the releaseApproval, and returns the corresponding releaseApproval object, and an error if there is any.
+func (c *FakeReleaseApprovals) Get(name string, options v1.GetOptions) (result *v1alpha1.ReleaseApproval, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(releaseapprovalsResource, c.ns, name), &v1alpha1.ReleaseApproval{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReleaseApproval), err
+}
+
+// List takes label and field selectors, and returns the list of ReleaseApprovals that match those selectors.
+func (c *FakeReleaseApprovals) List(opts v1.ListOptions) (result *v1alpha1.ReleaseApprovalList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(releaseapprovalsResource, releaseapprovalsKind, c.ns, opts), &v1alpha1.ReleaseApprovalList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.ReleaseApprovalList{ListMeta: obj.(*v1alpha1.ReleaseApprovalList).ListMeta}
+	for _, item := range obj.(*v1alpha1.ReleaseApprovalList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested releaseApprovals.
+func (c *FakeReleaseApprovals) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(releaseapprovalsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a releaseApproval and creates it.  Returns the server's representation of the releaseApproval, and an error, if there is any.
+func (c *FakeReleaseApprovals) Create(releaseApproval *v1alpha1.ReleaseApproval) (result *v1alpha1.ReleaseApproval, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(releaseapprovalsResource, c.ns, releaseApproval), &v1alpha1.ReleaseApproval{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReleaseApproval), err
+}
+
+// Update takes the representation of a releaseApproval and updates it. Returns the server's representation of the releaseApproval, and an error, if there is any.
+func (c *FakeReleaseApprovals) Update(releaseApproval *v1alpha1.ReleaseApproval) (result *v1alpha1.ReleaseApproval, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(releaseapprovalsResource, c.ns, releaseApproval), &v1alpha1.ReleaseApproval{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReleaseApproval), err
+}
+
+// Delete takes name of the releaseApproval and deletes it. Returns an error if one occurs.
+func (c *FakeReleaseApprovals) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(releaseapprovalsResource, c.ns, name), &v1alpha1.ReleaseApproval{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeReleaseApprovals) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(releaseapprovalsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.ReleaseApprovalList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched releaseApproval.
+func (c *FakeReleaseApprovals) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReleaseApproval, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(releaseapprovalsResource, c.ns, name, pt, data, subresources...), &v1alpha1.ReleaseApproval{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReleaseApproval), err
+}
@@ -12,6 +12,8 @@ type InstallationTargetExpansion interface{}
 
 type ReleaseExpansion interface{}
 
+type ReleaseApprovalExpansion interface{}
+
 type RolloutBlockExpansion interface{}
 
 type TrafficTargetExpansion interface{}
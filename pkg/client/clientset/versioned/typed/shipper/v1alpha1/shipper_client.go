@@ -15,6 +15,7 @@ type ShipperV1alpha1Interface interface {
 	ClustersGetter
 	InstallationTargetsGetter
 	ReleasesGetter
+	ReleaseApprovalsGetter
 	RolloutBlocksGetter
 	TrafficTargetsGetter
 }
@@ -44,6 +45,10 @@ func (c *ShipperV1alpha1Client) Releases(namespace string) ReleaseInterface {
 	return newReleases(c, namespace)
 }
 
+func (c *ShipperV1alpha1Client) ReleaseApprovals(namespace string) ReleaseApprovalInterface {
+	return newReleaseApprovals(c, namespace)
+}
+
 func (c *ShipperV1alpha1Client) RolloutBlocks(namespace string) RolloutBlockInterface {
 	return newRolloutBlocks(c, namespace)
 }
@@ -47,6 +47,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Shipper().V1alpha1().InstallationTargets().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("releases"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Shipper().V1alpha1().Releases().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("releaseapprovals"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Shipper().V1alpha1().ReleaseApprovals().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("rolloutblocks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Shipper().V1alpha1().RolloutBlocks().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("traffictargets"):
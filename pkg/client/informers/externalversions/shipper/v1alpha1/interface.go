@@ -18,6 +18,8 @@ type Interface interface {
 	InstallationTargets() InstallationTargetInformer
 	// Releases returns a ReleaseInformer.
 	Releases() ReleaseInformer
+	// ReleaseApprovals returns a ReleaseApprovalInformer.
+	ReleaseApprovals() ReleaseApprovalInformer
 	// RolloutBlocks returns a RolloutBlockInformer.
 	RolloutBlocks() RolloutBlockInformer
 	// TrafficTargets returns a TrafficTargetInformer.
@@ -60,6 +62,11 @@ func (v *version) Releases() ReleaseInformer {
 	return &releaseInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// ReleaseApprovals returns a ReleaseApprovalInformer.
+func (v *version) ReleaseApprovals() ReleaseApprovalInformer {
+	return &releaseApprovalInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // RolloutBlocks returns a RolloutBlockInformer.
 func (v *version) RolloutBlocks() RolloutBlockInformer {
 	return &rolloutBlockInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
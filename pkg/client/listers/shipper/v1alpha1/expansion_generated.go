@@ -30,6 +30,14 @@ type InstallationTargetListerExpansion interface{}
 // InstallationTargetNamespaceLister.
 type InstallationTargetNamespaceListerExpansion interface{}
 
+// ReleaseApprovalListerExpansion allows custom methods to be added to
+// ReleaseApprovalLister.
+type ReleaseApprovalListerExpansion interface{}
+
+// ReleaseApprovalNamespaceListerExpansion allows custom methods to be added to
+// ReleaseApprovalNamespaceLister.
+type ReleaseApprovalNamespaceListerExpansion interface{}
+
 // RolloutBlockListerExpansion allows custom methods to be added to
 // RolloutBlockLister.
 type RolloutBlockListerExpansion interface{}
@@ -113,6 +113,21 @@ func (c *cluster) GetInformerFactory() (kubeinformers.SharedInformerFactory, err
 	return c.informerFactory, nil
 }
 
+// StartInformers starts the informers first asked for from the cluster's
+// informer factory after its cache synced. They are not waited on to sync.
+func (c *cluster) StartInformers() error {
+	c.stateMut.RLock()
+	defer c.stateMut.RUnlock()
+
+	if c.state != StateReady {
+		return shippererrors.NewClusterNotReadyError(c.name)
+	}
+
+	c.informerFactory.Start(c.stopCh)
+
+	return nil
+}
+
 // This will block until the cache syncs. If the cache is never going to sync
 // (because you gave it an invalid hostname, for instance) it will hang around
 // until this cluster is Shutdown() and replaced by a new one.
@@ -150,6 +165,11 @@ func (c *cluster) WaitForInformerCache() {
 		c.stateMut.Unlock()
 
 		c.cacheSyncCb()
+
+		// Event handler callbacks may have set up informers for
+		// resources they only found out about once the cluster was
+		// reachable. Starting the factory again starts just those.
+		c.informerFactory.Start(c.stopCh)
 	}
 }
 
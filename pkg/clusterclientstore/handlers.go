@@ -15,4 +15,7 @@ type SubscriptionRegisterFunc func(kubeinformers.SharedInformerFactory)
 // been built, and provides a hook for a controller to register its event
 // handlers. These will be event handlers for changes to the resources that the
 // controller has subscribed to in the `SubscriptionRegisterFunc` callback.
+// Informers first asked for in here get started afterwards, which is meant
+// for resources a controller can only tell a cluster serves once it's
+// reachable; they are not waited on to sync.
 type EventHandlerRegisterFunc func(kubeinformers.SharedInformerFactory, string)
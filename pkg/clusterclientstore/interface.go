@@ -12,4 +12,5 @@ type Interface interface {
 	GetClient(clusterName string, ua string) (kubernetes.Interface, error)
 	GetConfig(clusterName string) (*rest.Config, error)
 	GetInformerFactory(string) (kubeinformers.SharedInformerFactory, error)
+	StartInformers(clusterName string) error
 }
@@ -152,6 +152,18 @@ func (s *Store) GetInformerFactory(clusterName string) (kubeinformers.SharedInfo
 	return cluster.GetInformerFactory()
 }
 
+// StartInformers starts the informers asked for from the informer factory of
+// the specified cluster name since its cache synced, for resources that are
+// only needed in some clusters. They are not waited on to sync.
+func (s *Store) StartInformers(clusterName string) error {
+	cluster, ok := s.cache.Fetch(clusterName)
+	if !ok {
+		return shippererrors.NewClusterNotInStoreError(clusterName)
+	}
+
+	return cluster.StartInformers()
+}
+
 func (s *Store) syncCluster(name string) error {
 	// No splitting here because clusters are not namespaced.
 	clusterObj, err := s.clusterInformer.Lister().Get(name)
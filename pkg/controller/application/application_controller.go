@@ -11,6 +11,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -41,6 +44,7 @@ const (
 // Applications.
 type Controller struct {
 	shipperClientset clientset.Interface
+	kubeClientset    kubernetes.Interface
 
 	appLister listers.ApplicationLister
 	appSynced cache.InformerSynced
@@ -52,24 +56,47 @@ type Controller struct {
 	rbLister listers.RolloutBlockLister
 	rbSynced cache.InformerSynced
 
+	// configMapLister and secretLister only see the ConfigMaps and
+	// Secrets labelled as sources of values.
+	configMapLister corev1listers.ConfigMapLister
+	configMapSynced cache.InformerSynced
+	secretLister    corev1listers.SecretLister
+	secretSynced    cache.InformerSynced
+
+	// valuesSecretLister sees the Secrets in shipperNamespace, where the
+	// values resolved for each release are stored.
+	valuesSecretLister corev1listers.SecretLister
+	valuesSecretSynced cache.InformerSynced
+	shipperNamespace   string
+
 	versionResolver shipperrepo.ChartVersionResolver
 
 	recorder record.EventRecorder
 }
 
-// NewController returns a new Application controller.
+// NewController returns a new Application controller. kubeInformerFactory
+// only needs to watch shipperNamespace, and valuesFromInformerFactory only
+// the ConfigMaps and Secrets labelled with shipper.ValuesFromLabel.
 func NewController(
 	shipperClientset clientset.Interface,
+	kubeClientset kubernetes.Interface,
 	shipperInformerFactory informers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	valuesFromInformerFactory kubeinformers.SharedInformerFactory,
+	shipperNamespace string,
 	versionResolver shipperrepo.ChartVersionResolver,
 	recorder record.EventRecorder,
 ) *Controller {
 	appInformer := shipperInformerFactory.Shipper().V1alpha1().Applications()
 	relInformer := shipperInformerFactory.Shipper().V1alpha1().Releases()
 	rbInformer := shipperInformerFactory.Shipper().V1alpha1().RolloutBlocks()
+	configMapInformer := valuesFromInformerFactory.Core().V1().ConfigMaps()
+	secretInformer := valuesFromInformerFactory.Core().V1().Secrets()
+	valuesSecretInformer := kubeInformerFactory.Core().V1().Secrets()
 
 	c := &Controller{
 		shipperClientset: shipperClientset,
+		kubeClientset:    kubeClientset,
 
 		appLister: appInformer.Lister(),
 		appSynced: appInformer.Informer().HasSynced,
@@ -81,6 +108,15 @@ func NewController(
 		rbLister: rbInformer.Lister(),
 		rbSynced: rbInformer.Informer().HasSynced,
 
+		configMapLister: configMapInformer.Lister(),
+		configMapSynced: configMapInformer.Informer().HasSynced,
+		secretLister:    secretInformer.Lister(),
+		secretSynced:    secretInformer.Informer().HasSynced,
+
+		valuesSecretLister: valuesSecretInformer.Lister(),
+		valuesSecretSynced: valuesSecretInformer.Informer().HasSynced,
+		shipperNamespace:   shipperNamespace,
+
 		versionResolver: versionResolver,
 		recorder:        recorder,
 	}
@@ -102,9 +138,23 @@ func NewController(
 	})
 
 	rbInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			if rolloutblock.ActivityChanged(old, new) {
+				c.enqueueAppFromRolloutBlock(new)
+			}
+		},
 		DeleteFunc: c.enqueueAppFromRolloutBlock,
 	})
 
+	valuesSourceHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueAppsFromValuesSource,
+		UpdateFunc: func(_, new interface{}) {
+			c.enqueueAppsFromValuesSource(new)
+		},
+	}
+	configMapInformer.Informer().AddEventHandler(valuesSourceHandler)
+	secretInformer.Informer().AddEventHandler(valuesSourceHandler)
+
 	return c
 }
 
@@ -117,7 +167,7 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 	klog.V(2).Info("Starting Application controller")
 	defer klog.V(2).Info("Shutting down Application controller")
 
-	if !cache.WaitForCacheSync(stopCh, c.appSynced, c.relSynced, c.rbSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.appSynced, c.relSynced, c.rbSynced, c.configMapSynced, c.secretSynced, c.valuesSecretSynced) {
 		runtime.HandleError(fmt.Errorf("failed to sync caches for the Application controller"))
 		return
 	}
@@ -237,13 +287,17 @@ func (c *Controller) syncApplication(key string) error {
 	if err != nil {
 		if kerrors.IsNotFound(err) {
 			klog.V(3).Infof("Application %q has been deleted", key)
-			return nil
+			return c.cleanUpValuesSecrets(ns, name)
 		}
 
 		return shippererrors.NewKubeclientGetError(ns, name, err).
 			WithShipperKind("Application")
 	}
 
+	if err := c.cleanUpValuesSecrets(ns, name); err != nil {
+		return err
+	}
+
 	app = app.DeepCopy()
 
 	// Initialize annotations
@@ -395,10 +449,15 @@ func (c *Controller) processApplication(app *shipper.Application) error {
 	// If a semver constraint is found, it would be resolved in-place.
 	if !apputil.ChartVersionResolved(app) {
 		if _, err := apputil.ResolveChartVersion(app, c.versionResolver); err != nil {
+			reason := conditions.ChartVersionResolutionFailed
+			if shippererrors.IsChartRepoUnauthorizedError(err) {
+				reason = conditions.ChartRepoUnauthorized
+			}
+
 			cond := apputil.NewApplicationCondition(
 				shipper.ApplicationConditionTypeRollingOut,
 				corev1.ConditionFalse,
-				conditions.ChartVersionResolutionFailed,
+				reason,
 				err.Error(),
 			)
 
@@ -440,6 +499,26 @@ func (c *Controller) processApplication(app *shipper.Application) error {
 	)
 	diff.Append(apputil.SetApplicationCondition(&app.Status, *condition))
 
+	if app.Spec.RollbackTo != "" {
+		return c.rollBackApplication(app, appReleases, diff)
+	}
+
+	resolvedValuesFrom, err := c.resolveValuesFrom(app)
+	if err != nil {
+		releaseSyncedCond := apputil.NewApplicationCondition(
+			shipper.ApplicationConditionTypeReleaseSynced,
+			corev1.ConditionFalse,
+			conditions.ValuesFromResolutionFailed,
+			err.Error(),
+		)
+		diff.Append(apputil.SetApplicationCondition(&app.Status, *releaseSyncedCond))
+
+		if _, updErr := c.shipperClientset.ShipperV1alpha1().Applications(app.Namespace).Update(app); updErr != nil {
+			return shippererrors.NewKubeclientUpdateError(app, updErr).WithShipperKind("Application")
+		}
+		return err
+	}
+
 	if contender, err = apputil.GetContender(app.Name, appReleases); err != nil {
 		// Anything else rather than not found err is an abort case
 		if !shippererrors.IsContenderNotFoundError(err) {
@@ -448,9 +527,9 @@ func (c *Controller) processApplication(app *shipper.Application) error {
 
 		// Contender doesn't exist, so we are covering the case where Shipper
 		// is creating the first release for this application.
-		if releaseName, iteration, err := c.releaseNameForApplication(app); err != nil {
+		if releaseName, iteration, err := c.releaseNameForApplication(app, resolvedValuesFrom); err != nil {
 			return err
-		} else if rel, err := c.createReleaseForApplication(app, releaseName, iteration, generation); err != nil {
+		} else if rel, err := c.createReleaseForApplication(app, releaseName, iteration, generation, resolvedValuesFrom); err != nil {
 			releaseSyncedCond := apputil.NewApplicationCondition(
 				shipper.ApplicationConditionTypeReleaseSynced,
 				corev1.ConditionFalse,
@@ -503,6 +582,7 @@ func (c *Controller) processApplication(app *shipper.Application) error {
 		// keeping app annotations consistent with the new "old" release
 		apputil.UpdateChartVersionResolvedAnnotation(app, contender.Spec.Environment.Chart.Version)
 		apputil.SetHighestObservedGeneration(app, generation)
+		c.markValuesFromRestored(app)
 
 		abortingCond := apputil.NewApplicationCondition(
 			shipper.ApplicationConditionTypeAborting,
@@ -531,14 +611,16 @@ func (c *Controller) processApplication(app *shipper.Application) error {
 		highestObserved = generation
 	}
 
-	if !identicalEnvironments(app.Spec.Template, contender.Spec.Environment) {
-		// The application's template has been modified and is different than
-		// the contender's environment. This means that a new release should
-		// be created with the new template.
+	if !identicalEnvironments(app.Spec.Template, contender.Spec.Environment) ||
+		valuesFromChanged(app, contender, resolvedValuesFrom) {
+		// The application's template, or the values it references, have
+		// been modified and are different than the contender's
+		// environment. This means that a new release should be created
+		// with the new template.
 		highestObserved = highestObserved + 1
-		if releaseName, iteration, err := c.releaseNameForApplication(app); err != nil {
+		if releaseName, iteration, err := c.releaseNameForApplication(app, resolvedValuesFrom); err != nil {
 			return err
-		} else if rel, err := c.createReleaseForApplication(app, releaseName, iteration, highestObserved); err != nil {
+		} else if rel, err := c.createReleaseForApplication(app, releaseName, iteration, highestObserved, resolvedValuesFrom); err != nil {
 			releaseSyncedCond := apputil.NewApplicationCondition(
 				shipper.ApplicationConditionTypeReleaseSynced,
 				corev1.ConditionFalse,
@@ -556,6 +638,8 @@ func (c *Controller) processApplication(app *shipper.Application) error {
 		} else {
 			appReleases = append(appReleases, rel)
 		}
+	} else if err := c.ensureValuesSecret(contender, resolvedValuesFrom); err != nil {
+		return err
 	}
 
 	apputil.SetHighestObservedGeneration(app, highestObserved)
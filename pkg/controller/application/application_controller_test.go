@@ -8,8 +8,12 @@ import (
 	"github.com/Masterminds/semver"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
 	helmchart "k8s.io/helm/pkg/proto/hapi/chart"
@@ -1083,6 +1087,152 @@ func TestDeletingAbortedReleases(t *testing.T) {
 	f.run()
 }
 
+func TestRollbackDeletesNewerReleases(t *testing.T) {
+	f := newFixture(t)
+	app := newApplication(testAppName)
+	app.Annotations[shipper.AppHighestObservedGenerationAnnotation] = "1"
+	app.Spec.RollbackTo = shipper.RollbackToPrevious
+	f.objects = append(f.objects, app)
+
+	releaseFoo := newRelease("foo", app)
+	releaseutil.SetGeneration(releaseFoo, 0)
+	releaseutil.SetReleaseCondition(&releaseFoo.Status, *releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeComplete, corev1.ConditionTrue, "", ""))
+
+	releaseBar := newRelease("bar", app)
+	releaseutil.SetGeneration(releaseBar, 1)
+
+	f.objects = append(f.objects, releaseFoo, releaseBar)
+
+	app.Status.History = []string{"foo", "bar"}
+
+	// The application is left alone until the newer releases are gone.
+	expectedApp := app.DeepCopy()
+	apputil.UpdateChartNameAnnotation(expectedApp, "simple")
+	apputil.UpdateChartVersionRawAnnotation(expectedApp, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(expectedApp, "0.0.1")
+
+	message := `rollback requested, deleting releases after "foo"`
+	expectedApp.Status.Conditions = []shipper.ApplicationCondition{
+		{
+			Type:    shipper.ApplicationConditionTypeAborting,
+			Status:  corev1.ConditionTrue,
+			Reason:  conditions.RollbackRequested,
+			Message: message,
+		},
+		{
+			Type:   shipper.ApplicationConditionTypeBlocked,
+			Status: corev1.ConditionFalse,
+		},
+	}
+
+	f.expectReleaseDelete(releaseBar)
+	f.expectApplicationUpdate(expectedApp)
+
+	f.expectedEvents = []string{
+		fmt.Sprintf(`Normal ApplicationConditionChanged [] -> [Blocked False], [] -> [Aborting True RollbackRequested %s]`, message),
+	}
+
+	f.run()
+}
+
+func TestRollbackToPrevious(t *testing.T) {
+	f := newFixture(t)
+	app := newApplication(testAppName)
+	app.Annotations[shipper.AppHighestObservedGenerationAnnotation] = "1"
+	app.Spec.RollbackTo = shipper.RollbackToPrevious
+	app.Spec.Template.ClusterRequirements = shipper.ClusterRequirements{
+		Regions: []shipper.RegionRequirement{{Name: "foo"}},
+	}
+	f.objects = append(f.objects, app)
+
+	// The newer release is gone already, but still in the history.
+	release := newRelease("foo", app)
+	releaseutil.SetGeneration(release, 0)
+	release.Spec.Environment.ClusterRequirements = shipper.ClusterRequirements{
+		Regions: []shipper.RegionRequirement{{Name: "bar"}},
+	}
+	f.objects = append(f.objects, release)
+
+	app.Status.History = []string{"foo", "bar"}
+
+	expectedApp := app.DeepCopy()
+	expectedApp.Annotations[shipper.AppHighestObservedGenerationAnnotation] = "0"
+	apputil.UpdateChartNameAnnotation(expectedApp, "simple")
+	apputil.UpdateChartVersionRawAnnotation(expectedApp, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(expectedApp, "0.0.1")
+	expectedApp.Spec.Template = release.Spec.Environment
+	expectedApp.Spec.RollbackTo = ""
+	expectedApp.Status.History = []string{"foo"}
+
+	message := `rollback in progress, returning state to release "foo"`
+	expectedApp.Status.Conditions = []shipper.ApplicationCondition{
+		{
+			Type:    shipper.ApplicationConditionTypeAborting,
+			Status:  corev1.ConditionTrue,
+			Reason:  conditions.RollbackRequested,
+			Message: message,
+		},
+		{
+			Type:   shipper.ApplicationConditionTypeBlocked,
+			Status: corev1.ConditionFalse,
+		},
+		{
+			Type:   shipper.ApplicationConditionTypeRollingOut,
+			Status: corev1.ConditionTrue,
+		},
+	}
+
+	f.expectApplicationUpdate(expectedApp)
+
+	f.expectedEvents = []string{
+		`Normal ApplicationRolledBack Rolled back to release "foo"`,
+		fmt.Sprintf(`Normal ApplicationConditionChanged [] -> [Blocked False], [] -> [Aborting True RollbackRequested %s], [] -> [RollingOut True]`, message),
+	}
+
+	f.run()
+}
+
+func TestRollbackToReleaseNotInHistory(t *testing.T) {
+	f := newFixture(t)
+	app := newApplication(testAppName)
+	app.Annotations[shipper.AppHighestObservedGenerationAnnotation] = "0"
+	app.Spec.RollbackTo = "bar"
+	f.objects = append(f.objects, app)
+
+	release := newRelease("foo", app)
+	releaseutil.SetGeneration(release, 0)
+	f.objects = append(f.objects, release)
+
+	app.Status.History = []string{"foo"}
+
+	expectedApp := app.DeepCopy()
+	apputil.UpdateChartNameAnnotation(expectedApp, "simple")
+	apputil.UpdateChartVersionRawAnnotation(expectedApp, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(expectedApp, "0.0.1")
+
+	message := fmt.Sprintf(`cannot roll application %q back to "bar": release "bar" is not in the application's history`, testAppName)
+	expectedApp.Status.Conditions = []shipper.ApplicationCondition{
+		{
+			Type:    shipper.ApplicationConditionTypeAborting,
+			Status:  corev1.ConditionFalse,
+			Reason:  conditions.RollbackFailed,
+			Message: message,
+		},
+		{
+			Type:   shipper.ApplicationConditionTypeBlocked,
+			Status: corev1.ConditionFalse,
+		},
+	}
+
+	f.expectApplicationUpdate(expectedApp)
+
+	f.expectedEvents = []string{
+		fmt.Sprintf(`Normal ApplicationConditionChanged [] -> [Blocked False], [] -> [Aborting False RollbackFailed %s]`, message),
+	}
+
+	f.run()
+}
+
 func TestHandleChartNotFound(t *testing.T) {
 	f := newFixture(t)
 	app := newApplication(testAppName)
@@ -1121,6 +1271,37 @@ func TestHandleChartNotFound(t *testing.T) {
 	f.run()
 }
 
+func TestHandleChartRepoUnauthorized(t *testing.T) {
+	f := newFixture(t)
+	app := newApplication(testAppName)
+	url := "https://charts.example.com/index.yaml"
+	f.resolveChartVersion = func(chartspec *shipper.Chart) (*repo.ChartVersion, error) {
+		return nil, errors.NewChartRepoUnauthorizedError(url, fmt.Errorf("bad response code: 401 Unauthorized (401)"))
+	}
+
+	f.objects = append(f.objects, app)
+	expectedApp := app.DeepCopy()
+
+	msg := fmt.Sprintf("unauthorized to fetch %q: bad response code: 401 Unauthorized (401)", url)
+	expectedApp.Status.Conditions = []shipper.ApplicationCondition{
+		{
+			Type:    shipper.ApplicationConditionTypeRollingOut,
+			Status:  corev1.ConditionFalse,
+			Reason:  conditions.ChartRepoUnauthorized,
+			Message: msg,
+		},
+	}
+	expectedApp.Status.History = []string{}
+
+	f.expectApplicationUpdate(expectedApp)
+
+	f.expectedEvents = []string{
+		fmt.Sprintf(`Normal ApplicationConditionChanged [] -> [RollingOut False ChartRepoUnauthorized %s]`, msg),
+	}
+
+	f.run()
+}
+
 func newRelease(releaseName string, app *shipper.Application) *shipper.Release {
 	return &shipper.Release{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1149,18 +1330,18 @@ var vanguard = shipper.RolloutStrategy{
 	Steps: []shipper.RolloutStrategyStep{
 		{
 			Name:     "staging",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 1},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 0},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(1)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(0)},
 		},
 		{
 			Name:     "50/50",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 50, Contender: 50},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 50, Contender: 50},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(50), Contender: intstr.FromInt(50)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(50), Contender: intstr.FromInt(50)},
 		},
 		{
 			Name:     "full on",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
 		},
 	},
 }
@@ -1208,11 +1389,14 @@ func newRolloutBlock(name string) *shipper.RolloutBlock {
 }
 
 type fixture struct {
-	t        *testing.T
-	client   *shipperfake.Clientset
-	actions  []kubetesting.Action
-	objects  []runtime.Object
-	recorder *record.FakeRecorder
+	t           *testing.T
+	client      *shipperfake.Clientset
+	kubeClient  *kubefake.Clientset
+	actions     []kubetesting.Action
+	kubeActions []kubetesting.Action
+	objects     []runtime.Object
+	kubeObjects []runtime.Object
+	recorder    *record.FakeRecorder
 
 	receivedEvents []string
 	expectedEvents []string
@@ -1231,26 +1415,46 @@ func newFixture(t *testing.T) *fixture {
 	}
 }
 
-func (f *fixture) newController() (*Controller, shipperinformers.SharedInformerFactory) {
+// newController returns a controller watching the fixture's objects, along
+// with a function starting its informers and waiting for them to sync. Its
+// informers are set up like in cmd/shipper.
+func (f *fixture) newController() (*Controller, func(stopCh <-chan struct{})) {
 	f.client = shipperfake.NewSimpleClientset(f.objects...)
+	f.kubeClient = kubefake.NewSimpleClientset(f.kubeObjects...)
 
 	const noResyncPeriod time.Duration = 0
 	shipperInformerFactory := shipperinformers.NewSharedInformerFactory(f.client, noResyncPeriod)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
+		f.kubeClient, noResyncPeriod, kubeinformers.WithNamespace(shipper.ShipperNamespace))
+	valuesFromInformerFactory := kubeinformers.NewFilteredSharedInformerFactory(
+		f.kubeClient, noResyncPeriod, metav1.NamespaceAll,
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = labels.Set{shipper.ValuesFromLabel: shipper.True}.String()
+		})
 
-	c := NewController(f.client, shipperInformerFactory, f.resolveChartVersion, f.recorder)
+	c := NewController(f.client, f.kubeClient, shipperInformerFactory, kubeInformerFactory,
+		valuesFromInformerFactory, shipper.ShipperNamespace, f.resolveChartVersion, f.recorder)
 
-	return c, shipperInformerFactory
+	start := func(stopCh <-chan struct{}) {
+		shipperInformerFactory.Start(stopCh)
+		kubeInformerFactory.Start(stopCh)
+		valuesFromInformerFactory.Start(stopCh)
+		shipperInformerFactory.WaitForCacheSync(stopCh)
+		kubeInformerFactory.WaitForCacheSync(stopCh)
+		valuesFromInformerFactory.WaitForCacheSync(stopCh)
+	}
+
+	return c, start
 }
 
 func (f *fixture) run() {
 	f.recorder = record.NewFakeRecorder(42)
-	c, i := f.newController()
+	c, start := f.newController()
 
 	stopCh := make(chan struct{})
 	defer close(stopCh)
 
-	i.Start(stopCh)
-	i.WaitForCacheSync(stopCh)
+	start(stopCh)
 
 	wait.PollUntil(
 		10*time.Millisecond,
@@ -1274,6 +1478,11 @@ func (f *fixture) run() {
 	actual := shippertesting.FilterActions(f.client.Actions())
 	shippertesting.CheckActions(f.actions, actual, f.t)
 
+	if f.kubeActions != nil {
+		kubeActual := shippertesting.FilterActions(f.kubeClient.Actions())
+		shippertesting.CheckActions(f.kubeActions, kubeActual, f.t)
+	}
+
 	shippertesting.CheckEvents(f.expectedEvents, f.receivedEvents, f.t)
 }
 
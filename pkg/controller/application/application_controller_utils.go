@@ -17,7 +17,7 @@ import (
 	shippererrors "github.com/bookingcom/shipper/pkg/errors"
 )
 
-func (c *Controller) createReleaseForApplication(app *shipper.Application, releaseName string, iteration, generation int) (*shipper.Release, error) {
+func (c *Controller) createReleaseForApplication(app *shipper.Application, releaseName string, iteration, generation int, valuesFrom *valuesFrom) (*shipper.Release, error) {
 	// Label releases with their hash; select by that label and increment if needed
 	// appname-hash-of-template-iteration.
 
@@ -30,7 +30,7 @@ func (c *Controller) createReleaseForApplication(app *shipper.Application, relea
 			Labels: map[string]string{
 				shipper.ReleaseLabel:                releaseName,
 				shipper.AppLabel:                    app.Name,
-				shipper.ReleaseEnvironmentHashLabel: hashReleaseEnvironment(app.Spec.Template),
+				shipper.ReleaseEnvironmentHashLabel: hashApplicationTemplate(app, valuesFrom),
 			},
 			Annotations: map[string]string{
 				shipper.ReleaseTemplateIterationAnnotation: strconv.Itoa(iteration),
@@ -51,6 +51,10 @@ func (c *Controller) createReleaseForApplication(app *shipper.Application, relea
 		newRelease.Labels[k] = v
 	}
 
+	if valuesFrom.hash != "" {
+		newRelease.Annotations[shipper.ReleaseValuesFromHashAnnotation] = valuesFrom.hash
+	}
+
 	// application may contain semver range, need to convert it into a specific version
 	cv, err := c.versionResolver(&newRelease.Spec.Environment.Chart)
 	if err != nil {
@@ -67,11 +71,15 @@ func (c *Controller) createReleaseForApplication(app *shipper.Application, relea
 			WithShipperKind("Release")
 	}
 
+	if err := c.ensureValuesSecret(rel, valuesFrom); err != nil {
+		return nil, err
+	}
+
 	return rel, nil
 }
 
-func (c *Controller) releaseNameForApplication(app *shipper.Application) (string, int, error) {
-	hash := hashReleaseEnvironment(app.Spec.Template)
+func (c *Controller) releaseNameForApplication(app *shipper.Application, valuesFrom *valuesFrom) (string, int, error) {
+	hash := hashApplicationTemplate(app, valuesFrom)
 	// TODO(asurikov): move the hash to annotations.
 	selector := labels.Set{
 		shipper.AppLabel:                    app.GetName(),
@@ -141,6 +149,20 @@ func hashReleaseEnvironment(env shipper.ReleaseEnvironment) string {
 	return fmt.Sprintf("%x", hash.Sum32())
 }
 
+// hashApplicationTemplate hashes the application's template along with the
+// contents of the values it references, if any.
+func hashApplicationTemplate(app *shipper.Application, valuesFrom *valuesFrom) string {
+	hash := hashReleaseEnvironment(app.Spec.Template)
+	if valuesFrom.hash == "" {
+		return hash
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(hash))
+	h.Write([]byte(valuesFrom.hash))
+	return fmt.Sprintf("%x", h.Sum32())
+}
+
 func createOwnerRefFromApplication(app *shipper.Application) metav1.OwnerReference {
 	// App's TypeMeta can be empty so can't use it to set APIVersion and Kind. See
 	// https://github.com/kubernetes/client-go/issues/60#issuecomment-281533822 and
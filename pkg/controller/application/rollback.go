@@ -0,0 +1,141 @@
+package application
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	apputil "github.com/bookingcom/shipper/pkg/util/application"
+	"github.com/bookingcom/shipper/pkg/util/conditions"
+	diffutil "github.com/bookingcom/shipper/pkg/util/diff"
+	releaseutil "github.com/bookingcom/shipper/pkg/util/release"
+)
+
+// rollBackApplication returns app to the release its spec.rollbackTo points
+// at, the same way an abort does when the releases after it are deleted. It
+// deletes those releases first, and only once they're gone does it copy the
+// release's environment back to app and clear spec.rollbackTo, so app never
+// gets synced against a release that is on its way out.
+func (c *Controller) rollBackApplication(
+	app *shipper.Application,
+	releases []*shipper.Release,
+	diff *diffutil.MultiDiff,
+) error {
+	target, generation, err := rollbackTarget(app, releases)
+	if err != nil {
+		err = shippererrors.NewRollbackError(app.Name, app.Spec.RollbackTo, err)
+		abortingCond := apputil.NewApplicationCondition(
+			shipper.ApplicationConditionTypeAborting,
+			corev1.ConditionFalse,
+			conditions.RollbackFailed,
+			err.Error())
+		diff.Append(apputil.SetApplicationCondition(&app.Status, *abortingCond))
+
+		if _, updErr := c.shipperClientset.ShipperV1alpha1().Applications(app.Namespace).Update(app); updErr != nil {
+			return shippererrors.NewKubeclientUpdateError(app, updErr).WithShipperKind("Application")
+		}
+		return err
+	}
+
+	var newer []*shipper.Release
+	for _, rel := range releases {
+		relGeneration, err := releaseutil.GetGeneration(rel)
+		if err != nil {
+			return err
+		}
+
+		if relGeneration > generation {
+			newer = append(newer, rel)
+		}
+	}
+
+	if len(newer) > 0 {
+		for _, rel := range newer {
+			err := c.shipperClientset.ShipperV1alpha1().Releases(app.Namespace).Delete(rel.Name, &metav1.DeleteOptions{})
+			if err != nil && !kerrors.IsNotFound(err) {
+				return shippererrors.NewKubeclientDeleteError(app.Namespace, rel.Name, err).
+					WithShipperKind("Release")
+			}
+		}
+
+		abortingCond := apputil.NewApplicationCondition(
+			shipper.ApplicationConditionTypeAborting,
+			corev1.ConditionTrue,
+			conditions.RollbackRequested,
+			fmt.Sprintf("rollback requested, deleting releases after %q", target.Name))
+		diff.Append(apputil.SetApplicationCondition(&app.Status, *abortingCond))
+
+		return nil
+	}
+
+	apputil.CopyEnvironment(app, target)
+	apputil.UpdateChartVersionResolvedAnnotation(app, target.Spec.Environment.Chart.Version)
+	apputil.SetHighestObservedGeneration(app, generation)
+	c.markValuesFromRestored(app)
+	app.Spec.RollbackTo = ""
+
+	abortingCond := apputil.NewApplicationCondition(
+		shipper.ApplicationConditionTypeAborting,
+		corev1.ConditionTrue,
+		conditions.RollbackRequested,
+		fmt.Sprintf("rollback in progress, returning state to release %q", target.Name))
+	diff.Append(apputil.SetApplicationCondition(&app.Status, *abortingCond))
+
+	rollingOutCond := apputil.NewApplicationCondition(
+		shipper.ApplicationConditionTypeRollingOut,
+		corev1.ConditionTrue,
+		"", "")
+	diff.Append(apputil.SetApplicationCondition(&app.Status, *rollingOutCond))
+
+	c.recorder.Eventf(app, corev1.EventTypeNormal, "ApplicationRolledBack",
+		"Rolled back to release %q", target.Name)
+
+	app.Status.History = apputil.ReleasesToApplicationHistory(releases)
+
+	return nil
+}
+
+// rollbackTarget resolves app's spec.rollbackTo against its history, and
+// returns the release it points at along with its generation.
+func rollbackTarget(app *shipper.Application, releases []*shipper.Release) (*shipper.Release, int, error) {
+	history := app.Status.History
+	name := app.Spec.RollbackTo
+
+	if name == shipper.RollbackToPrevious {
+		if len(history) < 2 {
+			return nil, 0, fmt.Errorf("there is no release before the latest one in the application's history")
+		}
+		name = history[len(history)-2]
+	} else {
+		found := false
+		for _, relName := range history {
+			if relName == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, 0, fmt.Errorf("release %q is not in the application's history", name)
+		}
+	}
+
+	for _, rel := range releases {
+		if rel.Name != name {
+			continue
+		}
+
+		generation, err := releaseutil.GetGeneration(rel)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return rel, generation, nil
+	}
+
+	return nil, 0, fmt.Errorf("release %q does not exist anymore", name)
+}
@@ -0,0 +1,327 @@
+package application
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shipperchart "github.com/bookingcom/shipper/pkg/chart"
+	"github.com/bookingcom/shipper/pkg/controller"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	releaseutil "github.com/bookingcom/shipper/pkg/util/release"
+)
+
+// valuesFrom holds the values read from the ConfigMaps and Secrets referenced
+// in an application's template, in order, along with a hash of their
+// contents. The hash is kept whole, as it's what tells whether they changed;
+// release names only get a shortened hash of it along with the template.
+type valuesFrom struct {
+	values []shipper.ChartValues
+	hash   string
+}
+
+// resolveValuesFrom reads all the values referenced in the application's
+// valuesFrom. Applications without valuesFrom get an empty hash, so their
+// releases are named exactly as they were before valuesFrom existed.
+func (c *Controller) resolveValuesFrom(app *shipper.Application) (*valuesFrom, error) {
+	resolved := &valuesFrom{}
+	if len(app.Spec.Template.ValuesFrom) == 0 {
+		return resolved, nil
+	}
+
+	hash := sha256.New()
+	for _, source := range app.Spec.Template.ValuesFrom {
+		kind, ref, data, err := c.readValuesFromSource(app.Namespace, source)
+		if err != nil {
+			return nil, err
+		}
+
+		// Missing optional references are hashed as well, so creating
+		// them later on results in a new release.
+		fmt.Fprintf(hash, "%s/%s/%s:%d:", kind, ref.Name, valuesFromKey(ref), len(data))
+		hash.Write(data)
+
+		if data == nil {
+			continue
+		}
+
+		values, err := shipperchart.ParseValues(data)
+		if err != nil {
+			return nil, shippererrors.NewValuesFromError(kind, app.Namespace, ref.Name, valuesFromKey(ref), err)
+		}
+
+		resolved.values = append(resolved.values, values)
+	}
+
+	resolved.hash = fmt.Sprintf("%x", hash.Sum(nil))
+
+	return resolved, nil
+}
+
+// markValuesFromRestored records the hash of the values app's valuesFrom
+// points at right now, after its environment was copied back from an older
+// release. That release keeps the values it was created with, and only
+// changes to them from here on result in a new release, so going back to it
+// isn't undone right away.
+func (c *Controller) markValuesFromRestored(app *shipper.Application) {
+	delete(app.Annotations, shipper.AppValuesFromRestoredHashAnnotation)
+
+	if len(app.Spec.Template.ValuesFrom) == 0 {
+		return
+	}
+
+	// If the values can't be resolved now, no release will be
+	// created until they can, so there's nothing to hold back.
+	resolved, err := c.resolveValuesFrom(app)
+	if err != nil {
+		return
+	}
+
+	if app.Annotations == nil {
+		app.Annotations = map[string]string{}
+	}
+	app.Annotations[shipper.AppValuesFromRestoredHashAnnotation] = resolved.hash
+}
+
+// valuesFromChanged tells whether the values app's valuesFrom points at
+// are different from the ones contender was created with, and should
+// result in a new release. Values that were already there when app went
+// back to contender don't.
+func valuesFromChanged(app *shipper.Application, contender *shipper.Release, resolved *valuesFrom) bool {
+	if contender.Annotations[shipper.ReleaseValuesFromHashAnnotation] == resolved.hash {
+		delete(app.Annotations, shipper.AppValuesFromRestoredHashAnnotation)
+		return false
+	}
+
+	restored, ok := app.Annotations[shipper.AppValuesFromRestoredHashAnnotation]
+	if ok && restored == resolved.hash {
+		return false
+	}
+
+	delete(app.Annotations, shipper.AppValuesFromRestoredHashAnnotation)
+	return true
+}
+
+// readValuesFromSource returns the raw values source points to, or nil if
+// it's optional and does not exist.
+func (c *Controller) readValuesFromSource(namespace string, source shipper.ValuesFromSource) (string, *shipper.ValuesFromReference, []byte, error) {
+	var (
+		kind string
+		ref  *shipper.ValuesFromReference
+		data map[string][]byte
+		err  error
+	)
+
+	switch {
+	case source.ConfigMapRef != nil && source.SecretRef != nil:
+		return "", nil, nil, shippererrors.NewValuesFromError("", namespace, source.ConfigMapRef.Name, "",
+			fmt.Errorf("configMapRef and secretRef are mutually exclusive"))
+	case source.ConfigMapRef != nil:
+		kind, ref = "ConfigMap", source.ConfigMapRef
+
+		var configMap *corev1.ConfigMap
+		if configMap, err = c.configMapLister.ConfigMaps(namespace).Get(ref.Name); err == nil {
+			data = make(map[string][]byte, len(configMap.Data))
+			for k, v := range configMap.Data {
+				data[k] = []byte(v)
+			}
+		}
+	case source.SecretRef != nil:
+		kind, ref = "Secret", source.SecretRef
+
+		var secret *corev1.Secret
+		if secret, err = c.secretLister.Secrets(namespace).Get(ref.Name); err == nil {
+			data = secret.Data
+		}
+	default:
+		return "", nil, nil, shippererrors.NewValuesFromError("", namespace, "", "",
+			fmt.Errorf("one of configMapRef or secretRef is required"))
+	}
+
+	key := valuesFromKey(ref)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return "", nil, nil, shippererrors.NewKubeclientGetError(namespace, ref.Name, err).
+				WithCoreV1Kind(kind)
+		} else if ref.Optional {
+			return kind, ref, nil, nil
+		} else if c.existsUnlabelled(kind, namespace, ref.Name) {
+			err = fmt.Errorf("it needs the %s=%s label for Shipper to see it",
+				shipper.ValuesFromLabel, shipper.True)
+		}
+
+		return "", nil, nil, shippererrors.NewValuesFromError(kind, namespace, ref.Name, key, err)
+	}
+
+	values, ok := data[key]
+	if !ok {
+		if ref.Optional {
+			return kind, ref, nil, nil
+		}
+
+		return "", nil, nil, shippererrors.NewValuesFromError(kind, namespace, ref.Name, key,
+			fmt.Errorf("key not found"))
+	}
+
+	return kind, ref, values, nil
+}
+
+// existsUnlabelled tells whether a values source the listers can't see
+// exists anyway, which means it's missing shipper.ValuesFromLabel.
+func (c *Controller) existsUnlabelled(kind, namespace, name string) bool {
+	var err error
+	if kind == "ConfigMap" {
+		_, err = c.kubeClientset.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	} else {
+		_, err = c.kubeClientset.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	}
+
+	return err == nil
+}
+
+func valuesFromKey(ref *shipper.ValuesFromReference) string {
+	if ref.Key == "" {
+		return shipper.ValuesFromDefaultKey
+	}
+
+	return ref.Key
+}
+
+// ensureValuesSecret makes sure that rel's resolved values are stored in the
+// Secret its installation target will read them from. The Secret lives in
+// Shipper's namespace, so Shipper only needs to create Secrets there.
+func (c *Controller) ensureValuesSecret(rel *shipper.Release, resolved *valuesFrom) error {
+	if len(rel.Spec.Environment.ValuesFrom) == 0 {
+		return nil
+	}
+
+	name := releaseutil.ValuesSecretName(rel.Namespace, rel.Name)
+	if _, err := c.valuesSecretLister.Secrets(c.shipperNamespace).Get(name); err == nil {
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		return shippererrors.NewKubeclientGetError(c.shipperNamespace, name, err).
+			WithCoreV1Kind("Secret")
+	}
+
+	data, err := yaml.Marshal(shipperchart.MergeValues(nil, resolved.values...))
+	if err != nil {
+		return shippererrors.NewUnrecoverableError(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.shipperNamespace,
+			Labels: map[string]string{
+				shipper.ReleaseLabel:          rel.Name,
+				shipper.AppLabel:              rel.Labels[shipper.AppLabel],
+				shipper.ReleaseNamespaceLabel: rel.Namespace,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			shipper.ValuesFromDefaultKey: data,
+		},
+	}
+
+	_, err = c.kubeClientset.CoreV1().Secrets(c.shipperNamespace).Create(secret)
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return shippererrors.NewKubeclientCreateError(secret, err).
+			WithCoreV1Kind("Secret")
+	}
+
+	klog.V(4).Infof("Stored resolved values for Release %q in Secret %q", controller.MetaKey(rel), name)
+
+	return nil
+}
+
+// cleanUpValuesSecrets deletes the values Secrets of the releases of the
+// application named appName in namespace that are gone. Releases can't own
+// them across namespaces, so they aren't garbage collected with them.
+func (c *Controller) cleanUpValuesSecrets(namespace, appName string) error {
+	selector := labels.Set{
+		shipper.AppLabel:              appName,
+		shipper.ReleaseNamespaceLabel: namespace,
+	}.AsSelector()
+
+	secrets, err := c.valuesSecretLister.Secrets(c.shipperNamespace).List(selector)
+	if err != nil {
+		return shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Secret"),
+			c.shipperNamespace, selector, err)
+	}
+
+	for _, secret := range secrets {
+		relName := secret.Labels[shipper.ReleaseLabel]
+		if _, err := c.relLister.Releases(namespace).Get(relName); err == nil {
+			continue
+		}
+
+		// Secrets are created right after their releases, which
+		// might not have made it to the lister yet.
+		_, err := c.shipperClientset.ShipperV1alpha1().Releases(namespace).Get(relName, metav1.GetOptions{})
+		if err == nil {
+			continue
+		} else if !kerrors.IsNotFound(err) {
+			return shippererrors.NewKubeclientGetError(namespace, relName, err).
+				WithShipperKind("Release")
+		}
+
+		err = c.kubeClientset.CoreV1().Secrets(c.shipperNamespace).Delete(secret.Name, &metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return shippererrors.NewKubeclientDeleteError(c.shipperNamespace, secret.Name, err).
+				WithCoreV1Kind("Secret")
+		}
+
+		klog.V(4).Infof("Deleted Secret %q holding the values of gone Release %s/%s", secret.Name, namespace, relName)
+	}
+
+	return nil
+}
+
+// enqueueAppsFromValuesSource enqueues every application in obj's namespace
+// that takes values from it, so changes to it result in new releases.
+func (c *Controller) enqueueAppsFromValuesSource(obj interface{}) {
+	var (
+		kind string
+		meta metav1.Object
+	)
+
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		kind, meta = "ConfigMap", o
+	case *corev1.Secret:
+		kind, meta = "Secret", o
+	default:
+		runtime.HandleError(fmt.Errorf("not a ConfigMap or Secret: %#v", obj))
+		return
+	}
+
+	apps, err := c.appLister.Applications(meta.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("error fetching applications: %s", err))
+		return
+	}
+
+	for _, app := range apps {
+		for _, source := range app.Spec.Template.ValuesFrom {
+			ref := source.ConfigMapRef
+			if kind == "Secret" {
+				ref = source.SecretRef
+			}
+
+			if ref != nil && ref.Name == meta.GetName() {
+				c.enqueueApp(app)
+				break
+			}
+		}
+	}
+}
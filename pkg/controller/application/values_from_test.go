@@ -0,0 +1,374 @@
+package application
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+	apputil "github.com/bookingcom/shipper/pkg/util/application"
+	releaseutil "github.com/bookingcom/shipper/pkg/util/release"
+)
+
+func newValuesConfigMap(name, values string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: shippertesting.TestNamespace,
+			Labels:    map[string]string{shipper.ValuesFromLabel: shipper.True},
+		},
+		Data: map[string]string{
+			shipper.ValuesFromDefaultKey: values,
+		},
+	}
+}
+
+func newValuesSecret(name, key, values string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: shippertesting.TestNamespace,
+			Labels:    map[string]string{shipper.ValuesFromLabel: shipper.True},
+		},
+		Data: map[string][]byte{
+			key: []byte(values),
+		},
+	}
+}
+
+// newReleaseValuesSecret returns the Secret holding the resolved values of
+// the test application's release named relName.
+func newReleaseValuesSecret(relName, values string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      releaseutil.ValuesSecretName(shippertesting.TestNamespace, relName),
+			Namespace: shipper.ShipperNamespace,
+			Labels: map[string]string{
+				shipper.ReleaseLabel:          relName,
+				shipper.AppLabel:              testAppName,
+				shipper.ReleaseNamespaceLabel: shippertesting.TestNamespace,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			shipper.ValuesFromDefaultKey: []byte(values),
+		},
+	}
+}
+
+func newApplicationWithValuesFrom(name string) *shipper.Application {
+	app := newApplication(name)
+	app.Spec.Template.ValuesFrom = []shipper.ValuesFromSource{
+		{ConfigMapRef: &shipper.ValuesFromReference{Name: "common"}},
+		{SecretRef: &shipper.ValuesFromReference{Name: "credentials", Key: "secrets.yaml"}},
+		{ConfigMapRef: &shipper.ValuesFromReference{Name: "missing", Optional: true}},
+	}
+
+	return app
+}
+
+// resolveValuesFrom resolves app's valuesFrom against kubeObjects with a
+// throwaway controller.
+func resolveValuesFrom(t *testing.T, app *shipper.Application, kubeObjects ...runtime.Object) (*valuesFrom, error) {
+	f := newFixture(t)
+	f.kubeObjects = kubeObjects
+	c, start := f.newController()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	start(stopCh)
+
+	return c.resolveValuesFrom(app)
+}
+
+func TestResolveValuesFrom(t *testing.T) {
+	app := newApplicationWithValuesFrom(testAppName)
+	common := newValuesConfigMap("common", "replicaCount: 3\nimage:\n  tag: \"1.0\"\n")
+	credentials := newValuesSecret("credentials", "secrets.yaml", "password: hunter2\n")
+
+	resolved, err := resolveValuesFrom(t, app, common, credentials)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(resolved.values) != 2 {
+		t.Fatalf("expected values from 2 sources, got %d", len(resolved.values))
+	}
+
+	if resolved.values[1]["password"] != "hunter2" {
+		t.Errorf("expected values to be resolved in order, got %v", resolved.values)
+	}
+
+	changed := common.DeepCopy()
+	changed.Data[shipper.ValuesFromDefaultKey] = "replicaCount: 5\n"
+	resolvedChanged, err := resolveValuesFrom(t, app, changed, credentials)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if resolved.hash == resolvedChanged.hash {
+		t.Errorf("expected hash to change along with the referenced data")
+	}
+
+	if len(resolved.hash) != 2*sha256.Size {
+		t.Errorf("expected a whole sha256 hash, got %q", resolved.hash)
+	}
+
+	_, err = resolveValuesFrom(t, app, common)
+	if !shippererrors.IsValuesFromError(err) {
+		t.Errorf("expected a values from error for a missing secret, got %v", err)
+	}
+
+	unlabelled := credentials.DeepCopy()
+	unlabelled.Labels = nil
+	_, err = resolveValuesFrom(t, app, common, unlabelled)
+	if !shippererrors.IsValuesFromError(err) || !strings.Contains(err.Error(), shipper.ValuesFromLabel) {
+		t.Errorf("expected a values from error asking for the %s label, got %v", shipper.ValuesFromLabel, err)
+	}
+}
+
+func TestCreateFirstReleaseWithValuesFrom(t *testing.T) {
+	f := newFixture(t)
+	app := newApplicationWithValuesFrom(testAppName)
+	common := newValuesConfigMap("common", "replicaCount: 3\n")
+	credentials := newValuesSecret("credentials", "secrets.yaml", "password: hunter2\n")
+
+	f.objects = append(f.objects, app)
+	f.kubeObjects = append(f.kubeObjects, common, credentials)
+
+	resolved, err := resolveValuesFrom(t, app, common, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedApp := app.DeepCopy()
+	expectedApp.Annotations[shipper.AppHighestObservedGenerationAnnotation] = "0"
+	apputil.UpdateChartNameAnnotation(expectedApp, "simple")
+	apputil.UpdateChartVersionRawAnnotation(expectedApp, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(expectedApp, "0.0.1")
+
+	envHash := hashApplicationTemplate(expectedApp, resolved)
+	if envHash == hashReleaseEnvironment(expectedApp.Spec.Template) {
+		t.Fatalf("expected the environment hash to include the values from hash")
+	}
+
+	expectedRelName := fmt.Sprintf("%s-%s-0", testAppName, envHash)
+	expectedApp.Status.Conditions = []shipper.ApplicationCondition{
+		{Type: shipper.ApplicationConditionTypeAborting, Status: corev1.ConditionFalse},
+		{Type: shipper.ApplicationConditionTypeBlocked, Status: corev1.ConditionFalse},
+		{Type: shipper.ApplicationConditionTypeReleaseSynced, Status: corev1.ConditionTrue},
+		{
+			Type:    shipper.ApplicationConditionTypeRollingOut,
+			Status:  corev1.ConditionTrue,
+			Message: fmt.Sprintf(InitialReleaseMessageFormat, expectedRelName),
+		},
+		{Type: shipper.ApplicationConditionTypeValidHistory, Status: corev1.ConditionTrue},
+	}
+	expectedApp.Status.History = []string{expectedRelName}
+
+	expectedRelease := newRelease(expectedRelName, expectedApp)
+	expectedRelease.Labels[shipper.ReleaseEnvironmentHashLabel] = envHash
+	expectedRelease.Annotations[shipper.ReleaseTemplateIterationAnnotation] = "0"
+	expectedRelease.Annotations[shipper.ReleaseGenerationAnnotation] = "0"
+	expectedRelease.Annotations[shipper.RolloutBlocksOverrideAnnotation] = ""
+	expectedRelease.Annotations[shipper.ReleaseValuesFromHashAnnotation] = resolved.hash
+
+	expectedSecret := newReleaseValuesSecret(expectedRelName, "password: hunter2\nreplicaCount: 3\n")
+
+	f.expectReleaseCreate(expectedRelease)
+	f.expectApplicationUpdate(expectedApp)
+	f.kubeActions = []kubetesting.Action{
+		kubetesting.NewCreateAction(corev1.SchemeGroupVersion.WithResource("secrets"), shipper.ShipperNamespace, expectedSecret),
+	}
+
+	f.expectedEvents = []string{
+		fmt.Sprintf(`Normal ApplicationConditionChanged [] -> [Aborting False], [] -> [ValidHistory True], [] -> [ReleaseSynced True], [] -> [RollingOut True Rolling out initial release "%s"]`, expectedRelName),
+		"Normal ApplicationConditionChanged [] -> [Blocked False]",
+	}
+
+	f.run()
+}
+
+func TestCreateReleaseWhenValuesFromChange(t *testing.T) {
+	app := newApplicationWithValuesFrom(testAppName)
+	apputil.SetHighestObservedGeneration(app, 0)
+	apputil.UpdateChartNameAnnotation(app, "simple")
+	apputil.UpdateChartVersionRawAnnotation(app, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(app, "0.0.1")
+
+	common := newValuesConfigMap("common", "replicaCount: 3\n")
+	credentials := newValuesSecret("credentials", "secrets.yaml", "password: hunter2\n")
+
+	// The incumbent was created before the ConfigMap was changed.
+	incumbentRelName := fmt.Sprintf("%s-%s-0", testAppName, "old")
+	incumbentRel := newRelease(incumbentRelName, app)
+	incumbentRel.Annotations[shipper.ReleaseValuesFromHashAnnotation] = "old"
+	releaseutil.SetGeneration(incumbentRel, 0)
+	releaseutil.SetIteration(incumbentRel, 0)
+	incumbentSecret := newReleaseValuesSecret(incumbentRelName, "replicaCount: 1\n")
+
+	f := newFixture(t)
+	f.objects = append(f.objects, app, incumbentRel)
+	f.kubeObjects = append(f.kubeObjects, common, credentials, incumbentSecret)
+	f.recorder = record.NewFakeRecorder(42)
+
+	c, start := f.newController()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	start(stopCh)
+
+	app = app.DeepCopy()
+	if err := c.processApplication(app); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var created *shipper.Release
+	for _, action := range f.client.Actions() {
+		if action.Matches("create", "releases") {
+			created = action.(kubetesting.CreateAction).GetObject().(*shipper.Release)
+		}
+	}
+
+	if created == nil {
+		t.Fatalf("expected a new release to be created")
+	}
+
+	if created.Annotations[shipper.ReleaseValuesFromHashAnnotation] == "old" {
+		t.Errorf("expected new release to carry the new values from hash")
+	}
+
+	if generation, _ := releaseutil.GetGeneration(created); generation != 1 {
+		t.Errorf("expected new release to have generation 1, got %d", generation)
+	}
+}
+
+// TestCleanUpValuesSecrets verifies that the values Secrets of releases that
+// are gone are deleted, as nothing else would garbage collect them.
+func TestCleanUpValuesSecrets(t *testing.T) {
+	app := newApplicationWithValuesFrom(testAppName)
+	rel := newRelease(fmt.Sprintf("%s-%s-0", testAppName, "current"), app)
+	goneRelName := fmt.Sprintf("%s-%s-0", testAppName, "gone")
+
+	f := newFixture(t)
+	f.objects = append(f.objects, app, rel)
+	f.kubeObjects = append(f.kubeObjects,
+		newReleaseValuesSecret(rel.Name, "replicaCount: 1\n"),
+		newReleaseValuesSecret(goneRelName, "replicaCount: 1\n"))
+
+	c, start := f.newController()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	start(stopCh)
+
+	if err := c.cleanUpValuesSecrets(shippertesting.TestNamespace, testAppName); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []kubetesting.Action{
+		kubetesting.NewDeleteAction(
+			corev1.SchemeGroupVersion.WithResource("secrets"),
+			shipper.ShipperNamespace,
+			releaseutil.ValuesSecretName(shippertesting.TestNamespace, goneRelName)),
+	}
+	shippertesting.CheckActions(expected, shippertesting.FilterActions(f.kubeClient.Actions()), t)
+}
+
+// assertStaysOnRestoredRelease syncs app, which is on its way back to an
+// older release whose values have changed since, twice: first to go back to
+// it, and then as it would be synced right after. No release is expected to
+// be created, as values that were already there don't count as a change.
+func assertStaysOnRestoredRelease(t *testing.T, app *shipper.Application, restoredRel *shipper.Release) {
+	common := newValuesConfigMap("common", "replicaCount: 3\n")
+	credentials := newValuesSecret("credentials", "secrets.yaml", "password: hunter2\n")
+
+	f := newFixture(t)
+	f.objects = append(f.objects, app, restoredRel)
+	f.kubeObjects = append(f.kubeObjects, common, credentials,
+		newReleaseValuesSecret(restoredRel.Name, "replicaCount: 1\n"))
+	f.recorder = record.NewFakeRecorder(42)
+
+	c, start := f.newController()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	start(stopCh)
+
+	app = app.DeepCopy()
+	for i := 0; i < 2; i++ {
+		if err := c.processApplication(app); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for _, action := range f.client.Actions() {
+		if action.Matches("create", "releases") {
+			t.Fatalf("expected to stay on release %q, but a new release was created", restoredRel.Name)
+		}
+	}
+
+	resolved, err := c.resolveValuesFrom(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash := app.Annotations[shipper.AppValuesFromRestoredHashAnnotation]; hash != resolved.hash {
+		t.Fatalf("expected app to record values from hash %q, got %q", resolved.hash, hash)
+	}
+
+	// Changing the values once more results in a new release.
+	resolved.hash = "changed"
+	if !valuesFromChanged(app, restoredRel, resolved) {
+		t.Fatalf("expected values changed after going back to release %q to count as a change", restoredRel.Name)
+	}
+}
+
+func newReleaseWithOldValuesFrom(app *shipper.Application, generation int) *shipper.Release {
+	rel := newRelease(fmt.Sprintf("%s-%s-0", testAppName, "old"), app)
+	rel.Annotations[shipper.ReleaseValuesFromHashAnnotation] = "old"
+	releaseutil.SetGeneration(rel, generation)
+	releaseutil.SetIteration(rel, 0)
+
+	return rel
+}
+
+// TestAbortWithChangedValuesFrom verifies that aborting back to a release
+// created before a referenced ConfigMap changed isn't undone by a new release
+// with the changed values.
+func TestAbortWithChangedValuesFrom(t *testing.T) {
+	app := newApplicationWithValuesFrom(testAppName)
+	apputil.SetHighestObservedGeneration(app, 1)
+	apputil.UpdateChartNameAnnotation(app, "simple")
+	apputil.UpdateChartVersionRawAnnotation(app, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(app, "0.0.1")
+
+	rel := newReleaseWithOldValuesFrom(app, 0)
+	app.Status.History = []string{rel.Name, "aborted"}
+
+	assertStaysOnRestoredRelease(t, app, rel)
+}
+
+// TestRollbackWithChangedValuesFrom verifies that rolling back to a release
+// created before a referenced ConfigMap changed isn't undone by a new release
+// with the changed values once spec.rollbackTo is cleared.
+func TestRollbackWithChangedValuesFrom(t *testing.T) {
+	app := newApplicationWithValuesFrom(testAppName)
+	apputil.SetHighestObservedGeneration(app, 1)
+	apputil.UpdateChartNameAnnotation(app, "simple")
+	apputil.UpdateChartVersionRawAnnotation(app, "0.0.1")
+	apputil.UpdateChartVersionResolvedAnnotation(app, "0.0.1")
+	app.Spec.RollbackTo = shipper.RollbackToPrevious
+
+	rel := newReleaseWithOldValuesFrom(app, 0)
+	app.Status.History = []string{rel.Name, "rolled-back"}
+
+	assertStaysOnRestoredRelease(t, app, rel)
+}
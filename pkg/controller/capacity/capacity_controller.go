@@ -32,7 +32,7 @@ import (
 	clusterstatusutil "github.com/bookingcom/shipper/pkg/util/clusterstatus"
 	diffutil "github.com/bookingcom/shipper/pkg/util/diff"
 	"github.com/bookingcom/shipper/pkg/util/filters"
-	"github.com/bookingcom/shipper/pkg/util/replicas"
+	podutil "github.com/bookingcom/shipper/pkg/util/pod"
 	targetutil "github.com/bookingcom/shipper/pkg/util/target"
 	shipperworkqueue "github.com/bookingcom/shipper/pkg/workqueue"
 )
@@ -47,6 +47,8 @@ const (
 	PodsNotReady     = "PodsNotReady"
 	DeploymentStuck  = "DeploymentStuck"
 
+	WaitingForDisruptionBudget = "WaitingForDisruptionBudget"
+
 	CapacityTargetConditionChanged  = "CapacityTargetConditionChanged"
 	ClusterCapacityConditionChanged = "ClusterCapacityConditionChanged"
 )
@@ -61,6 +63,7 @@ type Controller struct {
 	releasesListerSynced  cache.InformerSynced
 	workqueue             workqueue.RateLimitingInterface
 	recorder              record.EventRecorder
+	sadPodDiagnoses       *sadPodDiagnoses
 }
 
 // NewController returns a new CapacityTarget controller.
@@ -84,6 +87,7 @@ func NewController(
 		workqueue:             workqueue.NewNamedRateLimitingQueue(shipperworkqueue.NewDefaultControllerRateLimiter(), "capacity_controller_capacitytargets"),
 		recorder:              recorder,
 		clusterClientStore:    store,
+		sadPodDiagnoses:       newSadPodDiagnoses(),
 	}
 
 	klog.Info("Setting up event handlers")
@@ -227,12 +231,18 @@ func (c *Controller) processCapacityTargetOnCluster(
 
 	// availableReplicas and reports will be used by the defer at the top
 	// of this func
-	availableReplicas = deployment.Status.AvailableReplicas
+	availableReplicas = availableReplicaCount(deployment, pods)
 	reports = []shipper.ClusterCapacityReport{*report}
 
-	desiredReplicas := int32(replicas.CalculateDesiredReplicaCount(uint(spec.TotalReplicaCount), float64(spec.Percent)))
-	if deployment.Spec.Replicas == nil || desiredReplicas != *deployment.Spec.Replicas {
-		_, err = c.patchDeploymentWithReplicaCount(deployment, spec.Name, desiredReplicas)
+	desiredReplicas := capacityutil.DesiredReplicaCount(*spec)
+
+	// Scaling down takes pods away from the application, so we only go as
+	// far as its PodDisruptionBudgets allow, and get the rest of the way
+	// in later syncs as pods elsewhere become ready.
+	replicaCount := desiredReplicas
+	var limitedBy *disruptionBudget
+	if deployment.Spec.Replicas != nil && desiredReplicas < *deployment.Spec.Replicas {
+		replicaCount, limitedBy, err = c.boundScaleDown(spec.Name, deployment, pods, desiredReplicas)
 		if err != nil {
 			readyCond = capacityutil.NewClusterCapacityCondition(
 				shipper.ClusterConditionTypeReady,
@@ -241,7 +251,20 @@ func (c *Controller) processCapacityTargetOnCluster(
 				err.Error(),
 			)
 			return err
-		} else {
+		}
+	}
+
+	if deployment.Spec.Replicas == nil || replicaCount != *deployment.Spec.Replicas {
+		_, err = c.patchDeploymentWithReplicaCount(deployment, spec.Name, replicaCount)
+		if err != nil {
+			readyCond = capacityutil.NewClusterCapacityCondition(
+				shipper.ClusterConditionTypeReady,
+				corev1.ConditionFalse,
+				InternalError,
+				err.Error(),
+			)
+			return err
+		} else if limitedBy == nil {
 			readyCond = capacityutil.NewClusterCapacityCondition(
 				shipper.ClusterConditionTypeReady,
 				corev1.ConditionFalse,
@@ -252,6 +275,19 @@ func (c *Controller) processCapacityTargetOnCluster(
 		}
 	}
 
+	if limitedBy != nil {
+		readyCond = capacityutil.NewClusterCapacityCondition(
+			shipper.ClusterConditionTypeReady,
+			corev1.ConditionFalse,
+			WaitingForDisruptionBudget,
+			fmt.Sprintf(
+				"scaling down to %d replicas instead of %d: PodDisruptionBudget %q needs %d healthy pods, and there are %d",
+				replicaCount, desiredReplicas, limitedBy.name,
+				limitedBy.desiredHealthy, limitedBy.currentHealthy),
+		)
+		return shippererrors.NewCapacityInProgressError(ct.Name)
+	}
+
 	// Deployment was successfully updated, but the update hasn't been
 	// observed by the deployment controller yet, so our change is still in
 	// flight, and we can't trust the status yet.
@@ -268,7 +304,7 @@ func (c *Controller) processCapacityTargetOnCluster(
 
 	// If the number of available replicas matches what we want, the
 	// CapacityTarget is Ready and there's nothing left to check.
-	if replicas.AchievedDesiredReplicaPercentage(spec.TotalReplicaCount, availableReplicas, spec.Percent) {
+	if availableReplicas == desiredReplicas {
 		readyCond = capacityutil.NewClusterCapacityCondition(
 			shipper.ClusterConditionTypeReady,
 			corev1.ConditionTrue,
@@ -288,6 +324,7 @@ func (c *Controller) processCapacityTargetOnCluster(
 	if len(sadPods) > SadPodLimit {
 		sadPods = sadPods[:SadPodLimit]
 	}
+	c.diagnoseSadPods(spec.Name, deployment.Namespace, pods, sadPods)
 
 	replicaFailureCond := getDeploymentCondition(deployment.Status, appsv1.DeploymentReplicaFailure)
 	progressingCond := getDeploymentCondition(deployment.Status, appsv1.DeploymentProgressing)
@@ -453,6 +490,26 @@ func (c *Controller) registerDeploymentEventHandlers(informerFactory kubeinforme
 func (c *Controller) subscribeToDeployments(informerFactory kubeinformers.SharedInformerFactory) {
 	informerFactory.Apps().V1().Deployments().Informer()
 	informerFactory.Core().V1().Pods().Informer()
+	informerFactory.Policy().V1beta1().PodDisruptionBudgets().Informer()
+}
+
+// availableReplicaCount returns how many of deployment's pods are available.
+// Pods with the traffic readiness gate aren't available to the Deployment
+// until they get traffic, which they only do once they're counted here, so
+// for those we count the pods that are ready to serve instead.
+func availableReplicaCount(deployment *appsv1.Deployment, pods []*corev1.Pod) int32 {
+	if !podutil.HasTrafficReadinessGate(&deployment.Spec.Template.Spec) {
+		return deployment.Status.AvailableReplicas
+	}
+
+	var available int32
+	for _, pod := range pods {
+		if podutil.IsReady(pod) {
+			available++
+		}
+	}
+
+	return available
 }
 
 func (c Controller) getClusterObjects(cluster, ns, appName, release string) (*appsv1.Deployment, []*corev1.Pod, error) {
@@ -496,6 +553,20 @@ func (c Controller) getClusterObjects(cluster, ns, appName, release string) (*ap
 	return deployment, pods, nil
 }
 
+func (c *Controller) boundScaleDown(
+	cluster string,
+	deployment *appsv1.Deployment,
+	pods []*corev1.Pod,
+	desiredReplicas int32,
+) (int32, *disruptionBudget, error) {
+	informerFactory, err := c.clusterClientStore.GetInformerFactory(cluster)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return boundScaleDown(informerFactory, deployment, pods, desiredReplicas)
+}
+
 func (c *Controller) patchDeploymentWithReplicaCount(deployment *appsv1.Deployment, clusterName string, replicaCount int32) (*appsv1.Deployment, error) {
 	targetClusterClient, err := c.clusterClientStore.GetClient(clusterName, AgentName)
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	shippertesting "github.com/bookingcom/shipper/pkg/testing"
@@ -66,6 +67,36 @@ func TestSingleCluster(t *testing.T) {
 	)
 }
 
+// TestSingleClusterReplicas checks that an absolute number of replicas takes
+// precedence over percent, which is only there for people to read.
+func TestSingleClusterReplicas(t *testing.T) {
+	replicas := int32(1)
+	totalReplicaCount := int32(10)
+	ct := buildCapacityTarget(shippertesting.TestApp, ctName, []shipper.ClusterCapacityTarget{
+		{
+			Name:              clusterA,
+			Percent:           10,
+			TotalReplicaCount: totalReplicaCount,
+			Replicas:          &replicas,
+		},
+	})
+
+	runCapacityControllerTest(t,
+		map[string][]runtime.Object{
+			clusterA: []runtime.Object{buildDeployment(shippertesting.TestApp, ctName, 0, replicas)},
+		},
+		[]capacityTargetTestExpectation{
+			{
+				capacityTarget: ct,
+				status:         buildSuccessStatus(ctName, ct.Spec.Clusters),
+				replicasByCluster: map[string]int32{
+					clusterA: replicas,
+				},
+			},
+		},
+	)
+}
+
 // TestMultipleClusters does the same thing as TestSingleCluster, but does so
 // for multiple clusters.
 func TestMultipleClusters(t *testing.T) {
@@ -363,3 +394,123 @@ func runController(f *shippertesting.ControllerTestFixture) {
 		}
 	}
 }
+
+// TestCapacityScaleDownHeldByDisruptionBudget verifies that the capacity
+// controller doesn't scale a Deployment down further than the
+// PodDisruptionBudgets covering the application's pods allow, and says so.
+func TestCapacityScaleDownHeldByDisruptionBudget(t *testing.T) {
+	totalReplicaCount := int32(10)
+	ct := buildCapacityTarget(shippertesting.TestApp, ctName, []shipper.ClusterCapacityTarget{
+		{
+			Name:              clusterA,
+			Percent:           0,
+			TotalReplicaCount: totalReplicaCount,
+		},
+	})
+
+	incumbent := buildDeployment(shippertesting.TestApp, ctName, totalReplicaCount, totalReplicaCount)
+	contender := buildDeployment(shippertesting.TestApp, "contender", totalReplicaCount, totalReplicaCount)
+	minAvailable := intstr.FromInt(18)
+	objects := []runtime.Object{
+		incumbent,
+		contender,
+		buildPodDisruptionBudget(shippertesting.TestApp, &minAvailable, nil),
+	}
+	for _, pod := range buildReadyPodsForDeployment(incumbent, int(totalReplicaCount)) {
+		objects = append(objects, pod)
+	}
+	for _, pod := range buildReadyPodsForDeployment(contender, int(totalReplicaCount)) {
+		objects = append(objects, pod)
+	}
+
+	f := shippertesting.NewControllerTestFixture()
+	cluster := f.AddNamedCluster(clusterA)
+	cluster.AddMany(objects)
+	f.ShipperClient.Tracker().Add(ct)
+
+	runController(f)
+
+	ctGVR := shipper.SchemeGroupVersion.WithResource("capacitytargets")
+	object, err := f.ShipperClient.Tracker().Get(ctGVR, ct.Namespace, ct.Name)
+	if err != nil {
+		t.Fatalf("could not Get CapacityTarget %q: %s", ct.Name, err)
+	}
+
+	ct = object.(*shipper.CapacityTarget)
+	if len(ct.Status.Clusters) != 1 {
+		t.Fatalf("expected status for exactly one cluster, got %d", len(ct.Status.Clusters))
+	}
+
+	// 20 healthy pods with 18 of them needed leaves room for the
+	// incumbent to give up only 2, and it keeps the rest even though
+	// the 2 it gave up are still around. Those don't count as healthy
+	// anymore, as they're on their way out.
+	expectedCond := shipper.ClusterCapacityCondition{
+		Type:    shipper.ClusterConditionTypeReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  WaitingForDisruptionBudget,
+		Message: `scaling down to 8 replicas instead of 0: PodDisruptionBudget "pdb" needs 18 healthy pods, and there are 18`,
+	}
+	readyCond := capacityutil.GetClusterCapacityCondition(ct.Status.Clusters[0], shipper.ClusterConditionTypeReady)
+	if readyCond == nil {
+		t.Fatalf("expected cluster %q to have a Ready condition", clusterA)
+	}
+
+	eq, diff := shippertesting.DeepEqualDiff(expectedCond, *readyCond)
+	if !eq {
+		t.Fatalf("Ready condition different from expected:\n%s", diff)
+	}
+
+	assertDeploymentReplicas(t, ct, f.Clusters[clusterA], 8)
+}
+
+// TestCapacityCountsPodsWaitingForTraffic verifies that pods held back only by
+// the traffic readiness gate count as available, as they only get traffic once
+// their release has achieved capacity.
+func TestCapacityCountsPodsWaitingForTraffic(t *testing.T) {
+	totalReplicaCount := int32(10)
+	ct := buildCapacityTarget(shippertesting.TestApp, ctName, []shipper.ClusterCapacityTarget{
+		{
+			Name:              clusterA,
+			Percent:           10,
+			TotalReplicaCount: totalReplicaCount,
+		},
+	})
+
+	deployment := buildDeployment(shippertesting.TestApp, ctName, 1, 0)
+	deployment.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{
+		{ConditionType: shipper.PodTrafficReadinessGate},
+	}
+	deployment.Status.Replicas = 1
+
+	pod := buildReadyPodsForDeployment(deployment, 1)[0]
+	pod.Spec.ReadinessGates = deployment.Spec.Template.Spec.ReadinessGates
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.ContainersReady, Status: corev1.ConditionTrue},
+		{Type: shipper.PodTrafficReadinessGate, Status: corev1.ConditionFalse},
+		{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+	}
+
+	status := buildSuccessStatus(ctName, ct.Spec.Clusters)
+	noContainers := []shipper.ClusterCapacityReportContainerBreakdown{}
+	status.Clusters[0].Reports[0].Breakdown = []shipper.ClusterCapacityReportBreakdown{
+		{Type: string(corev1.ContainersReady), Status: string(corev1.ConditionTrue), Count: 1, Containers: noContainers},
+		{Type: string(corev1.PodReady), Status: string(corev1.ConditionFalse), Count: 1, Containers: noContainers},
+		{Type: shipper.PodTrafficReadinessGate, Status: string(corev1.ConditionFalse), Count: 1, Containers: noContainers},
+	}
+
+	runCapacityControllerTest(t,
+		map[string][]runtime.Object{
+			clusterA: []runtime.Object{deployment, pod},
+		},
+		[]capacityTargetTestExpectation{
+			{
+				capacityTarget: ct,
+				status:         status,
+				replicasByCluster: map[string]int32{
+					clusterA: 1,
+				},
+			},
+		},
+	)
+}
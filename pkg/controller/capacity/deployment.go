@@ -13,6 +13,7 @@ import (
 
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	podutil "github.com/bookingcom/shipper/pkg/util/pod"
 )
 
 func (c *Controller) enqueueCapacityTargetFromDeployment(obj interface{}) {
@@ -59,6 +60,11 @@ func (c Controller) getCapacityTargetForReleaseAndNamespace(release, namespace s
 func (c Controller) getSadPods(pods []*corev1.Pod) []shipper.PodStatus {
 	var sadPods []shipper.PodStatus
 	for _, pod := range pods {
+		// Pods waiting on nothing but traffic are doing just fine.
+		if podutil.HasTrafficReadinessGate(&pod.Spec) && podutil.IsReady(pod) {
+			continue
+		}
+
 		if condition, ok := c.getFalsePodCondition(pod); ok {
 			sadPod := shipper.PodStatus{
 				Name:           pod.Name,
@@ -0,0 +1,283 @@
+package capacity
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+const (
+	// SadPodLogLines and SadPodLogBytes bound how much of a crashed
+	// container's log ends up in a sad pod's status.
+	SadPodLogLines = 20
+	SadPodLogBytes = 2048
+
+	// SadPodEventLimit and SadPodEventMessageBytes bound how many of a
+	// pod's warning events end up in a sad pod's status, and how much of
+	// each of them.
+	SadPodEventLimit        = 5
+	SadPodEventMessageBytes = 256
+
+	// SadPodEventRefreshInterval is how often the events of a sad pod are
+	// looked at again, and SadPodDiagnosisExpiry how long the diagnosis
+	// of a pod that isn't sad anymore is kept around.
+	SadPodEventRefreshInterval = time.Minute
+	SadPodDiagnosisExpiry      = 10 * time.Minute
+)
+
+// sadPodDiagnosis is what was found out about a sad pod the last time it was
+// diagnosed.
+type sadPodDiagnosis struct {
+	restarts        int32
+	logs            []shipper.ContainerLog
+	events          []shipper.PodEvent
+	eventsFetchedAt time.Time
+	usedAt          time.Time
+}
+
+// sadPodDiagnoses remembers the diagnoses of sad pods by UID, so they aren't
+// fetched from application clusters all over again on every sync. Logs only
+// change when containers restart, so they're only fetched again then, and
+// events are refreshed every SadPodEventRefreshInterval.
+type sadPodDiagnoses struct {
+	mu        sync.Mutex
+	diagnoses map[types.UID]sadPodDiagnosis
+
+	getPreviousLog func(client kubernetes.Interface, namespace, pod, container string) (string, error)
+}
+
+func newSadPodDiagnoses() *sadPodDiagnoses {
+	return &sadPodDiagnoses{
+		diagnoses:      make(map[types.UID]sadPodDiagnosis),
+		getPreviousLog: getPreviousLog,
+	}
+}
+
+// diagnoseSadPods attaches to each of sadPods what the application cluster
+// can tell about why it's sad: the end of the log of one of its containers
+// that crashed, if any did, and its most recent warning events. Diagnostics
+// are a best effort, and failing to get them doesn't fail the sync.
+func (c *Controller) diagnoseSadPods(clusterName, namespace string, pods []*corev1.Pod, sadPods []shipper.PodStatus) {
+	client, err := c.clusterClientStore.GetClient(clusterName, AgentName)
+	if err != nil {
+		klog.V(4).Infof("Not diagnosing sad pods in cluster %q: %s", clusterName, err)
+		return
+	}
+
+	uids := make(map[string]types.UID, len(pods))
+	for _, pod := range pods {
+		uids[pod.Name] = pod.UID
+	}
+
+	now := time.Now()
+	for i := range sadPods {
+		c.sadPodDiagnoses.diagnose(client, namespace, uids[sadPods[i].Name], &sadPods[i], now)
+	}
+
+	c.sadPodDiagnoses.forgetUnusedSince(now.Add(-SadPodDiagnosisExpiry))
+}
+
+// diagnose attaches its diagnosis to sadPod, whose UID is uid, fetching
+// whatever is out of date in it.
+func (d *sadPodDiagnoses) diagnose(
+	client kubernetes.Interface,
+	namespace string,
+	uid types.UID,
+	sadPod *shipper.PodStatus,
+	now time.Time,
+) {
+	d.mu.Lock()
+	diagnosis, ok := d.diagnoses[uid]
+	d.mu.Unlock()
+
+	if restarts := podRestarts(sadPod); !ok || diagnosis.restarts != restarts {
+		diagnosis = sadPodDiagnosis{
+			restarts: restarts,
+			logs:     d.getCrashLogs(client, namespace, sadPod),
+		}
+	}
+
+	if now.Sub(diagnosis.eventsFetchedAt) >= SadPodEventRefreshInterval {
+		events, err := getPodEvents(client, namespace, sadPod.Name)
+		if err != nil {
+			klog.V(4).Infof("Failed to list events of pod %s/%s: %s", namespace, sadPod.Name, err)
+		} else {
+			diagnosis.events = events
+		}
+
+		diagnosis.eventsFetchedAt = now
+	}
+
+	diagnosis.usedAt = now
+
+	d.mu.Lock()
+	d.diagnoses[uid] = diagnosis
+	d.mu.Unlock()
+
+	sadPod.Logs = diagnosis.logs
+	sadPod.Events = diagnosis.events
+}
+
+// forgetUnusedSince drops the diagnoses of pods that haven't been sad since
+// t.
+func (d *sadPodDiagnoses) forgetUnusedSince(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for uid, diagnosis := range d.diagnoses {
+		if diagnosis.usedAt.Before(t) {
+			delete(d.diagnoses, uid)
+		}
+	}
+}
+
+// podRestarts returns how many times the containers of sadPod restarted.
+func podRestarts(sadPod *shipper.PodStatus) int32 {
+	var restarts int32
+	for _, statuses := range [][]corev1.ContainerStatus{sadPod.InitContainers, sadPod.Containers} {
+		for _, status := range statuses {
+			restarts += status.RestartCount
+		}
+	}
+
+	return restarts
+}
+
+// getCrashLogs returns the end of the previous log of a container in sadPod
+// that crashed, if any did.
+func (d *sadPodDiagnoses) getCrashLogs(client kubernetes.Interface, namespace string, sadPod *shipper.PodStatus) []shipper.ContainerLog {
+	container, ok := crashedContainer(sadPod)
+	if !ok {
+		return nil
+	}
+
+	log, err := d.getPreviousLog(client, namespace, sadPod.Name, container)
+	if err != nil {
+		klog.V(4).Infof("Failed to get log of container %q in pod %s/%s: %s",
+			container, namespace, sadPod.Name, err)
+		return nil
+	} else if log == "" {
+		return nil
+	}
+
+	return []shipper.ContainerLog{{Container: container, Log: log}}
+}
+
+// getPodEvents returns the most recent warning events about pod.
+func getPodEvents(client kubernetes.Interface, namespace, pod string) ([]shipper.PodEvent, error) {
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": pod,
+	}.AsSelector()
+	events, err := client.CoreV1().Events(namespace).List(metav1.ListOptions{
+		FieldSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summarizePodEvents(pod, events.Items), nil
+}
+
+// crashedContainer returns the name of a container in sadPod that isn't ready
+// and has terminated before, so there is a previous log to look at. Init
+// containers come first, as the others don't start until those are done.
+func crashedContainer(sadPod *shipper.PodStatus) (string, bool) {
+	for _, statuses := range [][]corev1.ContainerStatus{sadPod.InitContainers, sadPod.Containers} {
+		for _, status := range statuses {
+			if !status.Ready && status.RestartCount > 0 {
+				return status.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func getPreviousLog(client kubernetes.Interface, namespace, pod, container string) (string, error) {
+	tailLines := int64(SadPodLogLines)
+	limitBytes := int64(SadPodLogBytes)
+	raw, err := client.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   true,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}).Do().Raw()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(raw), "\n"), nil
+}
+
+// summarizePodEvents returns the most recent warning events about pod, up to
+// SadPodEventLimit of them, latest first.
+func summarizePodEvents(pod string, events []corev1.Event) []shipper.PodEvent {
+	warnings := make([]corev1.Event, 0, len(events))
+	for _, event := range events {
+		if event.Type == corev1.EventTypeWarning && event.InvolvedObject.Name == pod {
+			warnings = append(warnings, event)
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return eventTime(warnings[j]).Before(eventTime(warnings[i]))
+	})
+
+	if len(warnings) > SadPodEventLimit {
+		warnings = warnings[:SadPodEventLimit]
+	}
+
+	var podEvents []shipper.PodEvent
+	for _, event := range warnings {
+		count := event.Count
+		if event.Series != nil {
+			count = event.Series.Count
+		}
+
+		podEvents = append(podEvents, shipper.PodEvent{
+			Reason:  event.Reason,
+			Message: truncate(event.Message, SadPodEventMessageBytes),
+			Count:   count,
+		})
+	}
+
+	return podEvents
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if event.Series != nil {
+		return event.Series.LastObservedTime.Time
+	} else if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+
+	return event.EventTime.Time
+}
+
+// truncate cuts s down to at most n bytes, without splitting any of its
+// characters.
+func truncate(s string, n int) string {
+	const ellipsis = "..."
+	if len(s) <= n {
+		return s
+	}
+
+	end := n - len(ellipsis)
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	return s[:end] + ellipsis
+}
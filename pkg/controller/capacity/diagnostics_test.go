@@ -0,0 +1,234 @@
+package capacity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+func TestCrashedContainer(t *testing.T) {
+	tests := []struct {
+		name     string
+		sadPod   shipper.PodStatus
+		expected string
+	}{
+		{
+			"never restarted",
+			shipper.PodStatus{
+				Containers: []corev1.ContainerStatus{{Name: "app"}},
+			},
+			"",
+		},
+		{
+			"restarted but ready",
+			shipper.PodStatus{
+				Containers: []corev1.ContainerStatus{{Name: "app", Ready: true, RestartCount: 3}},
+			},
+			"",
+		},
+		{
+			"crashing container",
+			shipper.PodStatus{
+				Containers: []corev1.ContainerStatus{
+					{Name: "sidecar", Ready: true},
+					{Name: "app", RestartCount: 3},
+				},
+			},
+			"app",
+		},
+		{
+			"init containers first",
+			shipper.PodStatus{
+				InitContainers: []corev1.ContainerStatus{{Name: "init", RestartCount: 1}},
+				Containers:     []corev1.ContainerStatus{{Name: "app", RestartCount: 3}},
+			},
+			"init",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container, ok := crashedContainer(&tt.sadPod)
+			if ok != (tt.expected != "") || container != tt.expected {
+				t.Fatalf("expected crashed container %q, got %q", tt.expected, container)
+			}
+		})
+	}
+}
+
+func TestSummarizePodEvents(t *testing.T) {
+	const pod = "foobar-deadbeef"
+	now := time.Now()
+
+	events := []corev1.Event{
+		buildPodEvent(pod, corev1.EventTypeNormal, "Pulled", now),
+		buildPodEvent("someone-else", corev1.EventTypeWarning, "FailedMount", now),
+	}
+	for i := 0; i < SadPodEventLimit+2; i++ {
+		events = append(events, buildPodEvent(
+			pod, corev1.EventTypeWarning, fmt.Sprintf("BackOff%d", i),
+			now.Add(time.Duration(i)*time.Minute)))
+	}
+	events[len(events)-1].Message = strings.Repeat("x", SadPodEventMessageBytes*2)
+
+	podEvents := summarizePodEvents(pod, events)
+
+	if len(podEvents) != SadPodEventLimit {
+		t.Fatalf("expected %d events, got %d: %v", SadPodEventLimit, len(podEvents), podEvents)
+	}
+
+	for i, event := range podEvents {
+		expected := fmt.Sprintf("BackOff%d", SadPodEventLimit+1-i)
+		if event.Reason != expected {
+			t.Fatalf("expected event %d to be %q, got %q", i, expected, event.Reason)
+		}
+	}
+
+	if l := len(podEvents[0].Message); l != SadPodEventMessageBytes {
+		t.Fatalf("expected message to be cut down to %d bytes, got %d", SadPodEventMessageBytes, l)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"much too long", 10, "much to..."},
+		{"ünïcödé", 8, "ünï..."},
+	}
+
+	for _, tt := range tests {
+		if actual := truncate(tt.s, tt.n); actual != tt.expected {
+			t.Errorf("expected %q cut down to %d bytes to be %q, got %q", tt.s, tt.n, tt.expected, actual)
+		}
+	}
+}
+
+func TestDiagnoseSadPodEvents(t *testing.T) {
+	const pod = "foobar-deadbeef"
+	event := buildPodEvent(pod, corev1.EventTypeWarning, "FailedScheduling", time.Now())
+	event.Message = "0/3 nodes are available: 3 Insufficient cpu."
+	event.Count = 4
+	client := kubefake.NewSimpleClientset(&event)
+
+	sadPod := shipper.PodStatus{
+		Name:       pod,
+		Containers: []corev1.ContainerStatus{{Name: "app"}},
+	}
+	newSadPodDiagnoses().diagnose(client, shippertesting.TestNamespace, "uid", &sadPod, time.Now())
+
+	expected := shipper.PodStatus{
+		Name:       pod,
+		Containers: sadPod.Containers,
+		Events: []shipper.PodEvent{
+			{
+				Reason:  "FailedScheduling",
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+				Count:   4,
+			},
+		},
+	}
+
+	eq, diff := shippertesting.DeepEqualDiff(expected, sadPod)
+	if !eq {
+		t.Fatalf("sad pod different from expected:\n%s", diff)
+	}
+}
+
+// TestSadPodDiagnosesAreCached verifies that a sad pod's log is only fetched
+// again once its containers restart, and its events once they're old enough.
+func TestSadPodDiagnosesAreCached(t *testing.T) {
+	const pod = "foobar-deadbeef"
+	event := buildPodEvent(pod, corev1.EventTypeWarning, "BackOff", time.Now())
+	client := kubefake.NewSimpleClientset(&event)
+
+	buildSadPod := func(restarts int32) shipper.PodStatus {
+		return shipper.PodStatus{
+			Name:       pod,
+			Containers: []corev1.ContainerStatus{{Name: "app", RestartCount: restarts}},
+		}
+	}
+
+	// The fake clientset can't get logs, so fetching them is faked as well.
+	diagnoses := newSadPodDiagnoses()
+	logFetches := 0
+	diagnoses.getPreviousLog = func(kubernetes.Interface, string, string, string) (string, error) {
+		logFetches++
+		return "panic: oops", nil
+	}
+	now := time.Now()
+
+	steps := []struct {
+		name     string
+		restarts int32
+		after    time.Duration
+		fetches  []string
+	}{
+		{"first diagnosis", 1, 0, []string{"get log", "list events"}},
+		{"nothing changed", 1, time.Second, nil},
+		{"events are out of date", 1, SadPodEventRefreshInterval, []string{"list events"}},
+		{"container restarted", 2, time.Second, []string{"get log", "list events"}},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.after)
+		client.ClearActions()
+		logFetches = 0
+
+		sadPod := buildSadPod(step.restarts)
+		diagnoses.diagnose(client, shippertesting.TestNamespace, "uid", &sadPod, now)
+
+		var fetches []string
+		for i := 0; i < logFetches; i++ {
+			fetches = append(fetches, "get log")
+		}
+		for _, action := range client.Actions() {
+			fetches = append(fetches, fmt.Sprintf("%s %s", action.GetVerb(), action.GetResource().Resource))
+		}
+
+		if eq, diff := shippertesting.DeepEqualDiff(step.fetches, fetches); !eq {
+			t.Errorf("%s: fetches differ from expected:\n%s", step.name, diff)
+		}
+
+		if len(sadPod.Logs) != 1 || len(sadPod.Events) != 1 {
+			t.Errorf("%s: expected the sad pod to be diagnosed, got %+v", step.name, sadPod)
+		}
+	}
+
+	diagnoses.forgetUnusedSince(now.Add(time.Second))
+	if len(diagnoses.diagnoses) != 0 {
+		t.Errorf("expected diagnoses to be forgotten, got %d", len(diagnoses.diagnoses))
+	}
+}
+
+func buildPodEvent(pod, eventType, reason string, timestamp time.Time) corev1.Event {
+	return corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: shippertesting.TestNamespace,
+			Name:      fmt.Sprintf("%s.%s", pod, reason),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: shippertesting.TestNamespace,
+			Name:      pod,
+		},
+		Type:          eventType,
+		Reason:        reason,
+		Message:       reason,
+		Count:         1,
+		LastTimestamp: metav1.NewTime(timestamp),
+	}
+}
@@ -0,0 +1,202 @@
+package capacity
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubeinformers "k8s.io/client-go/informers"
+
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	podutil "github.com/bookingcom/shipper/pkg/util/pod"
+)
+
+// disruptionBudget is what a PodDisruptionBudget covering an application's
+// pods asks of them, and how they're doing.
+type disruptionBudget struct {
+	name           string
+	currentHealthy int32
+	desiredHealthy int32
+}
+
+// allowed is how many healthy pods can go without breaking the budget.
+func (b disruptionBudget) allowed() int32 {
+	if allowed := b.currentHealthy - b.desiredHealthy; allowed > 0 {
+		return allowed
+	}
+
+	return 0
+}
+
+// boundScaleDown returns how many replicas deployment, with pods, can be
+// scaled down to on the way to desiredReplicas without breaking any
+// PodDisruptionBudget covering its pods. Budgets count pods across all of the
+// application's releases, so scaling the incumbent down usually goes in
+// batches as the contender's pods become ready. When the result isn't
+// desiredReplicas, the budget that held it back is returned along with it.
+func boundScaleDown(
+	informerFactory kubeinformers.SharedInformerFactory,
+	deployment *appsv1.Deployment,
+	pods []*corev1.Pod,
+	desiredReplicas int32,
+) (int32, *disruptionBudget, error) {
+	budgets, err := getDisruptionBudgets(informerFactory, deployment)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Deployments get rid of pods that aren't ready first, so only
+	// healthy ones are taken out of the budget. Healthy pods over the
+	// current replica count are on their way out already, from an
+	// earlier batch, and have been taken out of it before.
+	current := *deployment.Spec.Replicas
+	healthy := countHealthyPods(pods)
+	var leaving int32
+	if healthy > current {
+		leaving = healthy - current
+		healthy = current
+	}
+
+	replicas := desiredReplicas
+	var limitedBy *disruptionBudget
+	for i := range budgets {
+		budget := &budgets[i]
+		budget.currentHealthy -= leaving
+		if floor := healthy - budget.allowed(); floor > replicas {
+			replicas = floor
+			limitedBy = budget
+		}
+	}
+
+	return replicas, limitedBy, nil
+}
+
+// getDisruptionBudgets returns the PodDisruptionBudgets in deployment's
+// namespace that cover its pods.
+func getDisruptionBudgets(
+	informerFactory kubeinformers.SharedInformerFactory,
+	deployment *appsv1.Deployment,
+) ([]disruptionBudget, error) {
+	namespace := deployment.Namespace
+	pdbs, err := informerFactory.Policy().V1beta1().PodDisruptionBudgets().Lister().
+		PodDisruptionBudgets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, shippererrors.NewKubeclientListError(
+			policyv1beta1.SchemeGroupVersion.WithKind("PodDisruptionBudget"),
+			namespace, labels.Everything(), err)
+	}
+
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+	budgets := make([]disruptionBudget, 0)
+	for _, pdb := range pdbs {
+		selector, err := pdbSelector(pdb)
+		if err != nil {
+			return nil, err
+		} else if !selector.Matches(podLabels) {
+			continue
+		}
+
+		deployments, err := informerFactory.Apps().V1().Deployments().Lister().
+			Deployments(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, shippererrors.NewKubeclientListError(
+				appsv1.SchemeGroupVersion.WithKind("Deployment"),
+				namespace, labels.Everything(), err)
+		}
+
+		pods, err := informerFactory.Core().V1().Pods().Lister().
+			Pods(namespace).List(selector)
+		if err != nil {
+			return nil, shippererrors.NewKubeclientListError(
+				corev1.SchemeGroupVersion.WithKind("Pod"),
+				namespace, selector, err)
+		}
+
+		budget, err := buildDisruptionBudget(pdb, selector, deployments, pods)
+		if err != nil {
+			return nil, err
+		}
+
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, nil
+}
+
+// pdbSelector returns the selector of a PodDisruptionBudget. An empty one
+// selects nothing, as it does for the disruption controller in
+// policy/v1beta1.
+func pdbSelector(pdb *policyv1beta1.PodDisruptionBudget) (labels.Selector, error) {
+	if pdb.Spec.Selector == nil {
+		return labels.Nothing(), nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return nil, shippererrors.NewUnrecoverableError(fmt.Errorf(
+			"failed to transform label selector of PodDisruptionBudget %q into a selector: %s",
+			pdb.Name, err))
+	} else if selector.Empty() {
+		return labels.Nothing(), nil
+	}
+
+	return selector, nil
+}
+
+// buildDisruptionBudget works out what pdb asks of the pods it selects, the
+// same way the disruption controller does: the pods it expects are those
+// the Deployments it covers are scaled to, and healthy ones are those ready
+// and not on their way out.
+func buildDisruptionBudget(
+	pdb *policyv1beta1.PodDisruptionBudget,
+	selector labels.Selector,
+	deployments []*appsv1.Deployment,
+	pods []*corev1.Pod,
+) (disruptionBudget, error) {
+	var expected int32
+	for _, deployment := range deployments {
+		if deployment.Spec.Replicas != nil && selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			expected += *deployment.Spec.Replicas
+		}
+	}
+
+	healthy := countHealthyPods(pods)
+
+	desired := expected
+	if pdb.Spec.MinAvailable != nil {
+		minAvailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MinAvailable, int(expected), true)
+		if err != nil {
+			return disruptionBudget{}, shippererrors.NewUnrecoverableError(fmt.Errorf(
+				"invalid minAvailable in PodDisruptionBudget %q: %s", pdb.Name, err))
+		}
+		desired = int32(minAvailable)
+	} else if pdb.Spec.MaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(expected), true)
+		if err != nil {
+			return disruptionBudget{}, shippererrors.NewUnrecoverableError(fmt.Errorf(
+				"invalid maxUnavailable in PodDisruptionBudget %q: %s", pdb.Name, err))
+		}
+		desired = expected - int32(maxUnavailable)
+	}
+
+	return disruptionBudget{
+		name:           pdb.Name,
+		currentHealthy: healthy,
+		desiredHealthy: desired,
+	}, nil
+}
+
+func countHealthyPods(pods []*corev1.Pod) int32 {
+	var healthy int32
+	for _, pod := range pods {
+		if podutil.IsReady(pod) {
+			healthy++
+		}
+	}
+
+	return healthy
+}
@@ -0,0 +1,168 @@
+package capacity
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+func TestBuildDisruptionBudget(t *testing.T) {
+	three := intstr.FromInt(3)
+	ninetyPercent := intstr.FromString("90%")
+	tenPercent := intstr.FromString("10%")
+	garbage := intstr.FromString("lots")
+
+	incumbent := buildDeployment(shippertesting.TestApp, "incumbent", 10, 10)
+	contender := buildDeployment(shippertesting.TestApp, "contender", 5, 2)
+	other := buildDeployment("other-app", "other", 10, 10)
+	deployments := []*appsv1.Deployment{incumbent, contender, other}
+
+	var pods []*corev1.Pod
+	pods = append(pods, buildReadyPodsForDeployment(incumbent, 10)...)
+	pods = append(pods, buildReadyPodsForDeployment(contender, 2)...)
+	pods = append(pods, buildSadPodForDeployment(contender))
+
+	terminating := buildReadyPodsForDeployment(contender, 1)[0]
+	terminating.Name = "terminating"
+	terminating.DeletionTimestamp = &metav1.Time{}
+	pods = append(pods, terminating)
+
+	tests := []struct {
+		name           string
+		minAvailable   *intstr.IntOrString
+		maxUnavailable *intstr.IntOrString
+		expected       disruptionBudget
+		expectErr      bool
+	}{
+		{"absolute minAvailable", &three, nil, disruptionBudget{"pdb", 12, 3}, false},
+		{"percentage minAvailable rounds up", &ninetyPercent, nil, disruptionBudget{"pdb", 12, 14}, false},
+		{"percentage maxUnavailable rounds up", nil, &tenPercent, disruptionBudget{"pdb", 12, 13}, false},
+		{"no disruptions without either", nil, nil, disruptionBudget{"pdb", 12, 15}, false},
+		{"invalid minAvailable", &garbage, nil, disruptionBudget{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdb := buildPodDisruptionBudget(shippertesting.TestApp, tt.minAvailable, tt.maxUnavailable)
+			selector, err := pdbSelector(pdb)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			budget, err := buildDisruptionBudget(pdb, selector, deployments, pods)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got budget %+v", budget)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if budget != tt.expected {
+				t.Fatalf("expected budget %+v, got %+v", tt.expected, budget)
+			}
+		})
+	}
+}
+
+func TestEmptyPDBSelectorSelectsNothing(t *testing.T) {
+	pdb := buildPodDisruptionBudget(shippertesting.TestApp, nil, nil)
+	pdb.Spec.Selector = &metav1.LabelSelector{}
+
+	selector, err := pdbSelector(pdb)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if selector.Matches(labels.Set{shipper.AppLabel: shippertesting.TestApp}) {
+		t.Fatalf("expected an empty selector to select nothing")
+	}
+}
+
+func TestBoundScaleDownWithPodsLeaving(t *testing.T) {
+	// The incumbent was already scaled down to 8 replicas in an
+	// earlier batch, but its 2 extra pods are still around.
+	incumbent := buildDeployment(shippertesting.TestApp, "incumbent", 8, 8)
+	contender := buildDeployment(shippertesting.TestApp, "contender", 10, 10)
+	incumbentPods := buildReadyPodsForDeployment(incumbent, 10)
+	minAvailable := intstr.FromInt(18)
+
+	informerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+	informerFactory.Policy().V1beta1().PodDisruptionBudgets().Informer().GetIndexer().
+		Add(buildPodDisruptionBudget(shippertesting.TestApp, &minAvailable, nil))
+	for _, deployment := range []*appsv1.Deployment{incumbent, contender} {
+		informerFactory.Apps().V1().Deployments().Informer().GetIndexer().Add(deployment)
+	}
+	podIndexer := informerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for _, pod := range append(incumbentPods, buildReadyPodsForDeployment(contender, 10)...) {
+		podIndexer.Add(pod)
+	}
+
+	replicas, limitedBy, err := boundScaleDown(informerFactory, incumbent, incumbentPods, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The 2 pods on their way out have been taken out of the budget
+	// already, so there's no room left for any more.
+	if replicas != 8 {
+		t.Fatalf("expected to scale down to 8 replicas, got %d", replicas)
+	}
+
+	expected := disruptionBudget{"pdb", 18, 18}
+	if limitedBy == nil || *limitedBy != expected {
+		t.Fatalf("expected to be limited by budget %+v, got %+v", expected, limitedBy)
+	}
+}
+
+func buildPodDisruptionBudget(app string, minAvailable, maxUnavailable *intstr.IntOrString) *policyv1beta1.PodDisruptionBudget {
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdb",
+			Namespace: shippertesting.TestNamespace,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable:   minAvailable,
+			MaxUnavailable: maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{shipper.AppLabel: app},
+			},
+		},
+	}
+}
+
+func buildReadyPodsForDeployment(deployment *appsv1.Deployment, count int) []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, count)
+	for i := 0; i < count; i++ {
+		pods = append(pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: deployment.Namespace,
+				Name:      fmt.Sprintf("%s-%d", deployment.Name, i),
+				Labels:    deployment.Spec.Template.Labels,
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		})
+	}
+
+	return pods
+}
@@ -100,6 +100,14 @@ func buildDeployment(app, release string, replicas int32, availableReplicas int3
 					shipper.ReleaseLabel: release,
 				},
 			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						shipper.AppLabel:     app,
+						shipper.ReleaseLabel: release,
+					},
+				},
+			},
 		},
 		Status: appsv1.DeploymentStatus{
 			AvailableReplicas: availableReplicas,
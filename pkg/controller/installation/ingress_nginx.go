@@ -0,0 +1,203 @@
+package installation
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+// ingressNginxObjects adapts the objects rendered from a chart to the
+// ingress-nginx traffic backend. The release gets a backend Service selecting
+// only its own pods, and every Ingress in the chart is pointed at it instead
+// of the production Service. When withCanaries is true, every Ingress also
+// gets a release-specific canary copy that starts out with no traffic, and
+// whose weight is then managed by the traffic controller.
+//
+// The objects returned are copies: the ones passed in are left untouched, as
+// they are shared between clusters.
+func ingressNginxObjects(
+	it *shipper.InstallationTarget,
+	objects []runtime.Object,
+	withCanaries bool,
+) ([]runtime.Object, error) {
+	var productionServices []*corev1.Service
+	for _, obj := range objects {
+		svc, ok := obj.(*corev1.Service)
+		if ok && svc.Labels[shipper.LBLabel] == shipper.LBForProduction {
+			productionServices = append(productionServices, svc)
+		}
+	}
+
+	// Ingresses are pointed at a backend Service generated from the
+	// production one, so there can't be several of them to pick from.
+	if len(productionServices) != 1 {
+		return nil, shippererrors.NewInvalidChartError(
+			fmt.Sprintf("ingress-nginx traffic backend requires exactly one production Service, but %d found",
+				len(productionServices)))
+	}
+
+	productionService := productionServices[0]
+
+	backendService := buildReleaseBackendService(it, productionService)
+
+	adapted := make([]runtime.Object, 0, len(objects)+1)
+	var canaries []runtime.Object
+	for _, obj := range objects {
+		gvks, _, err := kubescheme.Scheme.ObjectKinds(obj)
+		if err != nil || len(gvks) == 0 || gvks[0].Kind != "Ingress" {
+			adapted = append(adapted, obj)
+			continue
+		}
+
+		ingress := &unstructured.Unstructured{}
+		if err := kubescheme.Scheme.Convert(obj, ingress, nil); err != nil {
+			return nil, shippererrors.NewConvertUnstructuredError("error converting object to unstructured: %s", err)
+		}
+
+		if err := rewriteIngressBackends(ingress, productionService.Name, backendService.Name); err != nil {
+			return nil, shippererrors.NewConvertUnstructuredError("error rewriting Ingress %q backends: %s", ingress.GetName(), err)
+		}
+
+		adapted = append(adapted, ingress)
+
+		if withCanaries {
+			canaries = append(canaries, buildCanaryIngress(it, ingress))
+		}
+	}
+
+	adapted = append(adapted, backendService)
+
+	return append(adapted, canaries...), nil
+}
+
+// buildReleaseBackendService returns a ClusterIP Service with the same ports
+// as the production Service, but selecting only the pods of the release.
+func buildReleaseBackendService(it *shipper.InstallationTarget, productionService *corev1.Service) *corev1.Service {
+	labels := make(map[string]string, len(productionService.Labels))
+	for key, value := range productionService.Labels {
+		labels[key] = value
+	}
+	labels[shipper.LBLabel] = shipper.LBForRelease
+
+	selector := make(map[string]string, len(productionService.Spec.Selector)+1)
+	for key, value := range productionService.Spec.Selector {
+		selector[key] = value
+	}
+	selector[shipper.ReleaseLabel] = it.Name
+
+	var ports []corev1.ServicePort
+	for _, port := range productionService.Spec.Ports {
+		port.NodePort = 0
+		ports = append(ports, port)
+	}
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      trafficutil.ReleaseScopedName(productionService.Name, it.Name),
+			Namespace: productionService.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports:    ports,
+		},
+	}
+}
+
+// buildCanaryIngress returns a release-specific copy of ingress, marked as an
+// ingress-nginx canary with no traffic.
+func buildCanaryIngress(it *shipper.InstallationTarget, ingress *unstructured.Unstructured) *unstructured.Unstructured {
+	canary := ingress.DeepCopy()
+	canary.SetName(trafficutil.ReleaseScopedName(ingress.GetName(), it.Name))
+
+	labels := canary.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[shipper.LBLabel] = shipper.LBForRelease
+	canary.SetLabels(labels)
+
+	annotations := canary.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[trafficutil.IngressNginxCanaryAnnotation] = "true"
+	annotations[trafficutil.IngressNginxCanaryWeightAnnotation] = "0"
+	canary.SetAnnotations(annotations)
+
+	return canary
+}
+
+// isPrimaryIngress returns whether obj is an Ingress rendered from a chart,
+// as opposed to a release-specific canary.
+func isPrimaryIngress(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "Ingress" &&
+		obj.GetLabels()[shipper.LBLabel] != shipper.LBForRelease
+}
+
+// rewriteIngressBackends points every backend in ingress using the Service
+// from to the Service to.
+func rewriteIngressBackends(ingress *unstructured.Unstructured, from, to string) error {
+	rewrite := func(backend map[string]interface{}) {
+		if name, ok := backend["serviceName"].(string); ok && name == from {
+			backend["serviceName"] = to
+		}
+	}
+
+	if backend, ok, err := unstructured.NestedMap(ingress.Object, "spec", "backend"); err != nil {
+		return err
+	} else if ok {
+		rewrite(backend)
+		if err := unstructured.SetNestedMap(ingress.Object, backend, "spec", "backend"); err != nil {
+			return err
+		}
+	}
+
+	rules, ok, err := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		paths, ok, err := unstructured.NestedSlice(rule, "http", "paths")
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		for _, p := range paths {
+			path, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				rewrite(backend)
+			}
+		}
+
+		if err := unstructured.SetNestedSlice(rule, paths, "http", "paths"); err != nil {
+			return err
+		}
+	}
+
+	return unstructured.SetNestedSlice(ingress.Object, rules, "spec", "rules")
+}
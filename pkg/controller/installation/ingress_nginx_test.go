@@ -0,0 +1,163 @@
+package installation
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+func TestIngressNginxObjects(t *testing.T) {
+	it := buildInstallationTarget(shippertesting.TestNamespace, shippertesting.TestApp, nil, nil)
+
+	svc := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-prod",
+			Namespace: shippertesting.TestNamespace,
+			Labels: map[string]string{
+				shipper.AppLabel: shippertesting.TestApp,
+				shipper.LBLabel:  shipper.LBForProduction,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeNodePort,
+			Selector: map[string]string{
+				shipper.AppLabel:              shippertesting.TestApp,
+				shipper.PodTrafficStatusLabel: shipper.Enabled,
+			},
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+		},
+	}
+
+	backend := func(serviceName string) networkingv1beta1.IngressBackend {
+		return networkingv1beta1.IngressBackend{
+			ServiceName: serviceName,
+			ServicePort: intstr.FromInt(80),
+		}
+	}
+
+	ingress := &networkingv1beta1.Ingress{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: shippertesting.TestNamespace,
+			Labels:    map[string]string{shipper.AppLabel: shippertesting.TestApp},
+		},
+		Spec: networkingv1beta1.IngressSpec{
+			Rules: []networkingv1beta1.IngressRule{
+				{
+					Host: "app.example.com",
+					IngressRuleValue: networkingv1beta1.IngressRuleValue{
+						HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+							Paths: []networkingv1beta1.HTTPIngressPath{
+								{Path: "/", Backend: backend(svc.Name)},
+								{Path: "/static", Backend: backend("static")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	objects := []runtime.Object{svc, ingress}
+	backendName := trafficutil.ReleaseScopedName(svc.Name, it.Name)
+
+	serviceNames := func(obj runtime.Object) []string {
+		u := obj.(*unstructured.Unstructured)
+		rules, _, _ := unstructured.NestedSlice(u.Object, "spec", "rules")
+		paths, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "http", "paths")
+
+		var names []string
+		for _, p := range paths {
+			name, _, _ := unstructured.NestedString(p.(map[string]interface{}), "backend", "serviceName")
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	adapted, err := ingressNginxObjects(it, objects, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(adapted) != 3 {
+		t.Fatalf("expected 3 objects without canaries, got %d", len(adapted))
+	}
+
+	if adapted[0] != svc {
+		t.Errorf("expected production Service to be left untouched")
+	}
+
+	expectedNames := []string{backendName, "static"}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedNames, serviceNames(adapted[1])); !eq {
+		t.Errorf("primary Ingress backends differ from expected:\n%s", diff)
+	}
+
+	if ingress.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName != svc.Name {
+		t.Errorf("expected rendered Ingress to be left untouched")
+	}
+
+	expectedBackend := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backendName,
+			Namespace: shippertesting.TestNamespace,
+			Labels: map[string]string{
+				shipper.AppLabel: shippertesting.TestApp,
+				shipper.LBLabel:  shipper.LBForRelease,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				shipper.AppLabel:              shippertesting.TestApp,
+				shipper.PodTrafficStatusLabel: shipper.Enabled,
+				shipper.ReleaseLabel:          it.Name,
+			},
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedBackend, adapted[2]); !eq {
+		t.Errorf("release backend Service differs from expected:\n%s", diff)
+	}
+
+	adapted, err = ingressNginxObjects(it, objects, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(adapted) != 4 {
+		t.Fatalf("expected 4 objects with canaries, got %d", len(adapted))
+	}
+
+	canary := adapted[3].(*unstructured.Unstructured)
+	if name := trafficutil.ReleaseScopedName(ingress.Name, it.Name); canary.GetName() != name {
+		t.Errorf("expected canary Ingress to be called %q, got %q", name, canary.GetName())
+	}
+
+	if isPrimaryIngress(canary) {
+		t.Errorf("expected canary Ingress not to be considered a primary one")
+	}
+
+	expectedAnnotations := map[string]string{
+		trafficutil.IngressNginxCanaryAnnotation:       "true",
+		trafficutil.IngressNginxCanaryWeightAnnotation: "0",
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedAnnotations, canary.GetAnnotations()); !eq {
+		t.Errorf("canary Ingress annotations differ from expected:\n%s", diff)
+	}
+
+	if eq, diff := shippertesting.DeepEqualDiff(expectedNames, serviceNames(canary)); !eq {
+		t.Errorf("canary Ingress backends differ from expected:\n%s", diff)
+	}
+}
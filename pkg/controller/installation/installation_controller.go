@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -23,6 +24,7 @@ import (
 	"k8s.io/klog"
 
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shipperchart "github.com/bookingcom/shipper/pkg/chart"
 	shipperrepo "github.com/bookingcom/shipper/pkg/chart/repo"
 	shipperclient "github.com/bookingcom/shipper/pkg/client/clientset/versioned"
 	shipperinformers "github.com/bookingcom/shipper/pkg/client/informers/externalversions"
@@ -35,6 +37,7 @@ import (
 	"github.com/bookingcom/shipper/pkg/util/filters"
 	installationutil "github.com/bookingcom/shipper/pkg/util/installation"
 	targetutil "github.com/bookingcom/shipper/pkg/util/target"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
 	shipperworkqueue "github.com/bookingcom/shipper/pkg/workqueue"
 )
 
@@ -45,6 +48,7 @@ const (
 
 	ChartError               = "ChartError"
 	ClustersNotReady         = "ClustersNotReady"
+	InsufficientQuota        = "InsufficientQuota"
 	InternalError            = "InternalError"
 	TargetClusterClientError = "TargetClusterClientError"
 	UnknownError             = "UnknownError"
@@ -65,31 +69,47 @@ type Controller struct {
 	appSynced                 cache.InformerSynced
 	installationTargetsLister shipperlisters.InstallationTargetLister
 	installationTargetsSynced cache.InformerSynced
+	capacityTargetsLister     shipperlisters.CapacityTargetLister
+	capacityTargetsSynced     cache.InformerSynced
 	clusterLister             shipperlisters.ClusterLister
 	clusterSynced             cache.InformerSynced
 	releaseLister             shipperlisters.ReleaseLister
 	releaseSynced             cache.InformerSynced
+	secretLister              corev1listers.SecretLister
+	secretSynced              cache.InformerSynced
 	dynamicClientBuilderFunc  DynamicClientBuilderFunc
 
-	chartFetcher shipperrepo.ChartFetcher
+	// shipperNamespace is where the Secrets holding values resolved
+	// from valuesFrom are.
+	shipperNamespace string
+
+	// chartFetcher checks the provenance of the charts it fetches
+	// whenever they need to be signed, so that we never render a chart
+	// that hasn't been verified.
+	chartFetcher shipperrepo.VerifiedChartFetcher
 
 	recorder record.EventRecorder
 }
 
-// NewController returns a new Installation controller.
+// NewController returns a new Installation controller. kubeInformerFactory
+// only needs to watch shipperNamespace.
 func NewController(
 	shipperclientset shipperclient.Interface,
 	shipperInformerFactory shipperinformers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	shipperNamespace string,
 	store clusterclientstore.Interface,
 	dynamicClientBuilderFunc DynamicClientBuilderFunc,
-	chartFetcher shipperrepo.ChartFetcher,
+	chartFetcher shipperrepo.VerifiedChartFetcher,
 	recorder record.EventRecorder,
 ) *Controller {
 
 	installationTargetInformer := shipperInformerFactory.Shipper().V1alpha1().InstallationTargets()
+	capacityTargetInformer := shipperInformerFactory.Shipper().V1alpha1().CapacityTargets()
 	clusterInformer := shipperInformerFactory.Shipper().V1alpha1().Clusters()
 	releaseInformer := shipperInformerFactory.Shipper().V1alpha1().Releases()
 	applicationInformer := shipperInformerFactory.Shipper().V1alpha1().Applications()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
 
 	controller := &Controller{
 		appLister:                 applicationInformer.Lister(),
@@ -100,9 +120,14 @@ func NewController(
 		clusterSynced:             clusterInformer.Informer().HasSynced,
 		releaseLister:             releaseInformer.Lister(),
 		releaseSynced:             releaseInformer.Informer().HasSynced,
+		secretLister:              secretInformer.Lister(),
+		secretSynced:              secretInformer.Informer().HasSynced,
 		installationTargetsLister: installationTargetInformer.Lister(),
 		installationTargetsSynced: installationTargetInformer.Informer().HasSynced,
+		capacityTargetsLister:     capacityTargetInformer.Lister(),
+		capacityTargetsSynced:     capacityTargetInformer.Informer().HasSynced,
 		dynamicClientBuilderFunc:  dynamicClientBuilderFunc,
+		shipperNamespace:          shipperNamespace,
 		workqueue:                 workqueue.NewNamedRateLimitingQueue(shipperworkqueue.NewDefaultControllerRateLimiter(), "installation_controller_installationtargets"),
 		chartFetcher:              chartFetcher,
 		recorder:                  recorder,
@@ -139,6 +164,7 @@ func (c *Controller) registerAppClusterEventHandlers(informerFactory kubeinforme
 func (c *Controller) subscribeToAppClusterEvents(informerFactory kubeinformers.SharedInformerFactory) {
 	informerFactory.Apps().V1().Deployments().Informer()
 	informerFactory.Core().V1().Services().Informer()
+	informerFactory.Core().V1().ResourceQuotas().Informer()
 }
 
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
@@ -148,7 +174,7 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 	klog.V(2).Info("Starting Installation controller")
 	defer klog.V(2).Info("Shutting down Installation controller")
 
-	if !cache.WaitForCacheSync(stopCh, c.installationTargetsSynced, c.releaseSynced, c.appSynced, c.clusterSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.installationTargetsSynced, c.capacityTargetsSynced, c.releaseSynced, c.appSynced, c.clusterSynced, c.secretSynced) {
 		runtime.HandleError(fmt.Errorf("failed to wait for caches to sync"))
 		return
 	}
@@ -302,7 +328,15 @@ func (c *Controller) processInstallationTarget(it *shipper.InstallationTarget) (
 	diff := diffutil.NewMultiDiff()
 	defer c.reportConditionChange(it, InstallationTargetConditionChanged, diff)
 
-	objects, err := FetchAndRenderChart(c.chartFetcher, it)
+	values, err := c.valuesForInstallationTarget(it)
+	if err != nil {
+		it.Status.Conditions = targetutil.TransitionToNotOperational(
+			diff, it.Status.Conditions,
+			ChartError, err.Error())
+		return it, err
+	}
+
+	objects, err := FetchAndRenderChart(c.chartFetcher, it, values)
 	if err != nil {
 		it.Status.Conditions = targetutil.TransitionToNotOperational(
 			diff, it.Status.Conditions,
@@ -329,7 +363,7 @@ func (c *Controller) processInstallationTarget(it *shipper.InstallationTarget) (
 			}
 		}
 
-		err := c.processInstallationTargetOnCluster(it, clusterName, clusterStatus, installer)
+		err := c.processInstallationTargetOnCluster(it, clusterName, clusterStatus, values, installer)
 		if err != nil {
 			clusterErrors.Append(err)
 		}
@@ -364,10 +398,35 @@ func (c *Controller) processInstallationTarget(it *shipper.InstallationTarget) (
 	return it, clusterErrors.Flatten()
 }
 
+// valuesForInstallationTarget returns the values to render the chart with,
+// which are the IT's own values with the ones from its values Secret, if any,
+// merged over them.
+func (c *Controller) valuesForInstallationTarget(it *shipper.InstallationTarget) (*shipper.ChartValues, error) {
+	if it.Spec.ValuesSecretRef == nil {
+		return it.Spec.Values, nil
+	}
+
+	name := it.Spec.ValuesSecretRef.Name
+	secret, err := c.secretLister.Secrets(c.shipperNamespace).Get(name)
+	if err != nil {
+		return nil, shippererrors.NewKubeclientGetError(c.shipperNamespace, name, err).
+			WithCoreV1Kind("Secret")
+	}
+
+	values, err := shipperchart.ParseValues(secret.Data[shipper.ValuesFromDefaultKey])
+	if err != nil {
+		return nil, shippererrors.NewRenderManifestError(
+			fmt.Errorf("failed to parse values from Secret %q: %s", name, err))
+	}
+
+	return shipperchart.MergeValues(it.Spec.Values, values), nil
+}
+
 func (c *Controller) processInstallationTargetOnCluster(
 	it *shipper.InstallationTarget,
 	clusterName string,
 	status *shipper.ClusterInstallationStatus,
+	values *shipper.ChartValues,
 	installer *Installer,
 ) error {
 	diff := diffutil.NewMultiDiff()
@@ -403,6 +462,34 @@ func (c *Controller) processInstallationTargetOnCluster(
 		return err
 	}
 
+	// Clusters matching any overrides get the chart rendered again with
+	// their own values. The rest share the installer rendered with the
+	// base values.
+	applied, overrideValues, err := overridesForCluster(it.Spec.Overrides, cluster)
+	status.AppliedOverrides = applied
+	status.OverrideValues = overrideValues
+	if err == nil && overrideValues != nil {
+		installer, err = c.installerWithValues(it,
+			shipperchart.MergeValues(values, *overrideValues))
+	}
+
+	if err != nil {
+		operationalCond = installationutil.NewClusterInstallationCondition(
+			shipper.ClusterConditionTypeOperational,
+			corev1.ConditionTrue,
+			"",
+			"",
+		)
+		readyCond = installationutil.NewClusterInstallationCondition(
+			shipper.ClusterConditionTypeReady,
+			corev1.ConditionFalse,
+			reasonForReadyCondition(err),
+			err.Error(),
+		)
+
+		return err
+	}
+
 	client, restConfig, err := c.GetClusterAndConfig(clusterName)
 	if err != nil {
 		operationalCond = installationutil.NewClusterInstallationCondition(
@@ -422,7 +509,26 @@ func (c *Controller) processInstallationTargetOnCluster(
 		"",
 	)
 
-	err = installer.install(cluster, client, restConfig, c.dynamicClientBuilderFunc)
+	// Quotas are only checked until the release is installed, as
+	// from then on its own pods are part of what they count as used.
+	if !installationReady(status) {
+		if quotaCond := c.quotaConditionForCluster(it, clusterName, installer); quotaCond != nil {
+			quotaDiff := installationutil.SetClusterInstallationCondition(status, *quotaCond)
+			diff.Append(quotaDiff)
+			if !quotaDiff.IsEmpty() && quotaCond.Status == corev1.ConditionFalse {
+				c.warnApplication(it, InsufficientQuota, fmt.Sprintf(
+					"release %q might not reach full capacity in cluster %q: %s",
+					it.Name, clusterName, quotaCond.Message))
+			}
+		}
+	}
+
+	trafficBackend, err := c.trafficBackendForCluster(it, cluster)
+	if err == nil {
+		err = installer.withTrafficBackend(trafficBackend).
+			install(cluster, client, restConfig, c.dynamicClientBuilderFunc)
+	}
+
 	if err != nil {
 		readyCond = installationutil.NewClusterInstallationCondition(
 			shipper.ClusterConditionTypeReady,
@@ -444,6 +550,137 @@ func (c *Controller) processInstallationTargetOnCluster(
 	return nil
 }
 
+// quotaConditionForCluster returns the QuotaSufficient condition for the
+// installation target in cluster, or nil if there are no ResourceQuotas in
+// its namespace there. Either way, the release is still installed: the
+// incumbent might free up enough quota as it scales down.
+func (c *Controller) quotaConditionForCluster(
+	it *shipper.InstallationTarget,
+	clusterName string,
+	installer *Installer,
+) *shipper.ClusterInstallationCondition {
+	informerFactory, err := c.clusterClientStore.GetInformerFactory(clusterName)
+	if err != nil {
+		return installationutil.NewClusterInstallationCondition(
+			shipper.ClusterConditionTypeQuotaSufficient,
+			corev1.ConditionUnknown,
+			TargetClusterClientError,
+			err.Error(),
+		)
+	}
+
+	quotas, err := informerFactory.Core().V1().ResourceQuotas().Lister().
+		ResourceQuotas(it.Namespace).List(labels.Everything())
+	if err != nil {
+		err = shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("ResourceQuota"),
+			it.Namespace, labels.Everything(), err)
+		return installationutil.NewClusterInstallationCondition(
+			shipper.ClusterConditionTypeQuotaSufficient,
+			corev1.ConditionUnknown,
+			InternalError,
+			err.Error(),
+		)
+	} else if len(quotas) == 0 {
+		return nil
+	}
+
+	totalReplicaCount, err := c.totalReplicaCountForCluster(it, clusterName)
+	if err != nil {
+		return installationutil.NewClusterInstallationCondition(
+			shipper.ClusterConditionTypeQuotaSufficient,
+			corev1.ConditionUnknown,
+			InternalError,
+			err.Error(),
+		)
+	}
+
+	if ok, msg := checkResourceQuotas(quotas, installer.objects, totalReplicaCount); !ok {
+		return installationutil.NewClusterInstallationCondition(
+			shipper.ClusterConditionTypeQuotaSufficient,
+			corev1.ConditionFalse,
+			InsufficientQuota,
+			msg,
+		)
+	}
+
+	return installationutil.NewClusterInstallationCondition(
+		shipper.ClusterConditionTypeQuotaSufficient,
+		corev1.ConditionTrue,
+		"",
+		"",
+	)
+}
+
+// totalReplicaCountForCluster returns how many replicas the release behind it
+// has at full capacity in cluster, according to its capacity target.
+func (c *Controller) totalReplicaCountForCluster(it *shipper.InstallationTarget, clusterName string) (int32, error) {
+	ct, err := c.capacityTargetsLister.CapacityTargets(it.Namespace).Get(it.Name)
+	if err != nil {
+		return 0, shippererrors.NewKubeclientGetError(it.Namespace, it.Name, err).
+			WithShipperKind("CapacityTarget")
+	}
+
+	for _, spec := range ct.Spec.Clusters {
+		if spec.Name == clusterName {
+			return spec.TotalReplicaCount, nil
+		}
+	}
+
+	return 0, fmt.Errorf("CapacityTarget %q has no capacity for cluster %q", shippercontroller.MetaKey(ct), clusterName)
+}
+
+// warnApplication records a warning event on the application the
+// installation target belongs to, where users are more likely to look.
+func (c *Controller) warnApplication(it *shipper.InstallationTarget, reason, msg string) {
+	appName, ok := it.Labels[shipper.AppLabel]
+	if !ok {
+		return
+	}
+
+	app, err := c.appLister.Applications(it.Namespace).Get(appName)
+	if err != nil {
+		klog.Warningf("Failed to get Application %s/%s to warn: %s", it.Namespace, appName, err)
+		return
+	}
+
+	c.recorder.Event(app, corev1.EventTypeWarning, reason, msg)
+}
+
+func installationReady(status *shipper.ClusterInstallationStatus) bool {
+	ready := installationutil.GetClusterInstallationCondition(*status, shipper.ClusterConditionTypeReady)
+	return ready != nil && ready.Status == corev1.ConditionTrue
+}
+
+// trafficBackendForCluster returns the traffic backend the installation
+// target's application uses in cluster, as the traffic controller will shift
+// traffic with it.
+func (c *Controller) trafficBackendForCluster(it *shipper.InstallationTarget, cluster *shipper.Cluster) (string, error) {
+	appName, ok := it.Labels[shipper.AppLabel]
+	if !ok {
+		return trafficutil.BackendName(nil, cluster), nil
+	}
+
+	app, err := c.appLister.Applications(it.Namespace).Get(appName)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return "", shippererrors.NewKubeclientGetError(it.Namespace, appName, err).
+			WithShipperKind("Application")
+	} else if err != nil {
+		app = nil
+	}
+
+	return trafficutil.BackendName(app, cluster), nil
+}
+
+func (c *Controller) installerWithValues(it *shipper.InstallationTarget, values *shipper.ChartValues) (*Installer, error) {
+	objects, err := FetchAndRenderChart(c.chartFetcher, it, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewInstaller(it, objects), nil
+}
+
 func (c *Controller) GetClusterAndConfig(clusterName string) (kubernetes.Interface, *rest.Config, error) {
 	client, err := c.clusterClientStore.GetClient(clusterName, AgentName)
 	if err != nil {
@@ -467,7 +704,8 @@ func reasonForReadyCondition(err error) string {
 		return InternalError
 	}
 
-	if shippererrors.IsDecodeManifestError(err) || shippererrors.IsConvertUnstructuredError(err) || shippererrors.IsInvalidChartError(err) {
+	if shippererrors.IsDecodeManifestError(err) || shippererrors.IsConvertUnstructuredError(err) ||
+		shippererrors.IsInvalidChartError(err) || shippererrors.IsRenderManifestError(err) {
 		return ChartError
 	}
 
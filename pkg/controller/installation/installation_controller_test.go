@@ -8,6 +8,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -118,6 +119,83 @@ func TestInvalidChart(t *testing.T) {
 	)
 }
 
+// TestInsufficientQuota verifies that the installation controller warns
+// about ResourceQuotas that can't fit the release at full capacity, but
+// installs it anyway.
+func TestInsufficientQuota(t *testing.T) {
+	clusters := []string{clusterA}
+	chart := buildChart(chartName, version, repoUrl)
+	it := buildInstallationTarget(shippertesting.TestNamespace, shippertesting.TestApp, clusters, &chart)
+
+	quota := buildResourceQuota("pods", corev1.ResourceList{
+		corev1.ResourcePods: resource.MustParse("5"),
+	}, corev1.ResourceList{
+		corev1.ResourcePods: resource.MustParse("3"),
+	})
+	quota.Namespace = shippertesting.TestNamespace
+
+	f := newFixture(objectsPerClusterMap{clusterA: []runtime.Object{quota}})
+	f.ShipperClient.Tracker().Add(buildCluster(clusterA))
+	f.ShipperClient.Tracker().Add(it)
+	f.ShipperClient.Tracker().Add(&shipper.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shippertesting.TestApp,
+			Namespace: shippertesting.TestNamespace,
+		},
+	})
+	f.ShipperClient.Tracker().Add(&shipper.CapacityTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      it.Name,
+			Namespace: it.Namespace,
+		},
+		Spec: shipper.CapacityTargetSpec{
+			Clusters: []shipper.ClusterCapacityTarget{
+				{Name: clusterA, TotalReplicaCount: 3},
+			},
+		},
+	})
+
+	runController(f)
+
+	itGVR := shipper.SchemeGroupVersion.WithResource("installationtargets")
+	object, err := f.ShipperClient.Tracker().Get(itGVR, it.Namespace, it.Name)
+	if err != nil {
+		t.Fatalf("could not Get InstallationTarget: %s", err)
+	}
+
+	msg := `ResourceQuota "pods" has 2 of pods left, but the release needs 3`
+	expected := buildSuccessStatus(clusters)
+	expected.Clusters[0].Conditions = []shipper.ClusterInstallationCondition{
+		ClusterInstallationOperational,
+		{
+			Type:    shipper.ClusterConditionTypeQuotaSufficient,
+			Status:  corev1.ConditionFalse,
+			Reason:  InsufficientQuota,
+			Message: msg,
+		},
+		ClusterInstallationReady,
+	}
+
+	actual := object.(*shipper.InstallationTarget).Status
+	if eq, diff := shippertesting.DeepEqualDiff(expected, actual); !eq {
+		t.Fatalf("InstallationTarget has Status different from expected:\n%s", diff)
+	}
+
+	assertClusterObjects(t, it, f.Clusters[clusterA], buildExpectedObjects(it))
+
+	warning := fmt.Sprintf(
+		"Warning %s release %q might not reach full capacity in cluster %q: %s",
+		InsufficientQuota, it.Name, clusterA, msg)
+	close(f.Recorder.Events)
+	for event := range f.Recorder.Events {
+		if event == warning {
+			return
+		}
+	}
+
+	t.Fatalf("expected event %q", warning)
+}
+
 // buildExpectedObjects returns a list of the objects we expect from
 // `chartName`. This can be hardcoded for as long as we depend on that one chart.
 func buildExpectedObjects(it *shipper.InstallationTarget) []object {
@@ -131,6 +209,186 @@ func buildExpectedObjects(it *shipper.InstallationTarget) []object {
 	}
 }
 
+// TestValuesFromSecret verifies that values stored in the installation
+// target's values Secret are merged over its own values.
+func TestValuesFromSecret(t *testing.T) {
+	chart := buildChart(chartName, version, repoUrl)
+	it := buildInstallationTarget(shippertesting.TestNamespace, shippertesting.TestApp, []string{}, &chart)
+	it.Spec.Values = &shipper.ChartValues{
+		"replicaCount": float64(1),
+		"image":        map[string]interface{}{"repository": "nginx"},
+	}
+	it.Spec.ValuesSecretRef = &corev1.LocalObjectReference{Name: "values"}
+
+	f := newFixture(nil)
+	f.KubeClient.Tracker().Add(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "values",
+			Namespace: shipper.ShipperNamespace,
+		},
+		Data: map[string][]byte{
+			shipper.ValuesFromDefaultKey: []byte("image:\n  tag: \"1.19\"\n"),
+		},
+	})
+
+	c := NewController(
+		f.ShipperClient,
+		f.ShipperInformerFactory,
+		f.KubeInformerFactory,
+		shipper.ShipperNamespace,
+		f.ClusterClientStore,
+		f.DynamicClientBuilder,
+		localFetchChart,
+		f.Recorder,
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.Run(stopCh)
+
+	values, err := c.valuesForInstallationTarget(it)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &shipper.ChartValues{
+		"replicaCount": float64(1),
+		"image":        map[string]interface{}{"repository": "nginx", "tag": "1.19"},
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expected, values); !eq {
+		t.Errorf("values differ from expected:\n%s", diff)
+	}
+
+	it.Spec.ValuesSecretRef.Name = "missing"
+	if _, err := c.valuesForInstallationTarget(it); err == nil {
+		t.Errorf("expected an error for a missing values Secret")
+	}
+}
+
+// TestValuesOverrides verifies that the installation controller reports the
+// overrides applied to each cluster in its status.
+func TestValuesOverrides(t *testing.T) {
+	clusters := []string{clusterA, clusterB}
+	chart := buildChart(chartName, version, repoUrl)
+	it := buildInstallationTarget(shippertesting.TestNamespace, shippertesting.TestApp, clusters, &chart)
+	it.Spec.Overrides = []shipper.ValuesOverride{
+		{
+			Cluster: clusterA,
+			Values: &shipper.ChartValues{
+				"image": map[string]interface{}{"tag": "1.19"},
+			},
+		},
+	}
+
+	status := buildSuccessStatus(clusters)
+	status.Clusters[0].AppliedOverrides = []string{"0: cluster=cluster-a"}
+	status.Clusters[0].OverrideValues = &shipper.ChartValues{
+		"image": map[string]interface{}{"tag": "1.19"},
+	}
+
+	runInstallationControllerTest(t,
+		clusters,
+		[]installationTargetTestExpectation{
+			{
+				installationTarget: it,
+				status:             status,
+				objectsByCluster: map[string][]object{
+					clusterA: buildExpectedObjects(it),
+					clusterB: buildExpectedObjects(it),
+				},
+			},
+		},
+	)
+}
+
+func TestOverridesForCluster(t *testing.T) {
+	cluster := buildCluster(clusterA)
+	cluster.Spec.Region = shippertesting.TestRegion
+	cluster.Labels = map[string]string{"tier": "canary"}
+
+	canary := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"tier": "canary"},
+	}
+
+	tests := []struct {
+		name            string
+		overrides       []shipper.ValuesOverride
+		expectedApplied []string
+		expectedValues  *shipper.ChartValues
+	}{
+		{
+			name: "no matching overrides",
+			overrides: []shipper.ValuesOverride{
+				{Region: "elsewhere", Values: &shipper.ChartValues{"a": "region"}},
+				{Cluster: clusterB, Values: &shipper.ChartValues{"a": "cluster"}},
+			},
+		},
+		{
+			name: "overrides merged in order",
+			overrides: []shipper.ValuesOverride{
+				{
+					Region: shippertesting.TestRegion,
+					Values: &shipper.ChartValues{
+						"a": "region",
+						"b": map[string]interface{}{"c": "region", "d": "region"},
+					},
+				},
+				{
+					ClusterSelector: canary,
+					Values: &shipper.ChartValues{
+						"b": map[string]interface{}{"c": "selector"},
+					},
+				},
+				{
+					Cluster: clusterA,
+					Values:  &shipper.ChartValues{"a": "cluster"},
+				},
+			},
+			expectedApplied: []string{
+				fmt.Sprintf("0: region=%s", shippertesting.TestRegion),
+				"1: clusterSelector=tier=canary",
+				"2: cluster=cluster-a",
+			},
+			expectedValues: &shipper.ChartValues{
+				"a": "cluster",
+				"b": map[string]interface{}{"c": "selector", "d": "region"},
+			},
+		},
+		{
+			name: "all criteria must match",
+			overrides: []shipper.ValuesOverride{
+				{
+					Region:  shippertesting.TestRegion,
+					Cluster: clusterB,
+					Values:  &shipper.ChartValues{"a": "b"},
+				},
+				{
+					Values: &shipper.ChartValues{"a": "all"},
+				},
+			},
+			expectedApplied: []string{"1: all clusters"},
+			expectedValues:  &shipper.ChartValues{"a": "all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, values, err := overridesForCluster(tt.overrides, cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if eq, diff := shippertesting.DeepEqualDiff(tt.expectedApplied, applied); !eq {
+				t.Errorf("applied overrides differ from expected:\n%s", diff)
+			}
+
+			if eq, diff := shippertesting.DeepEqualDiff(tt.expectedValues, values); !eq {
+				t.Errorf("override values differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
+
 func runInstallationControllerTest(
 	t *testing.T,
 	clusterNames []string,
@@ -219,6 +477,8 @@ func runController(f *shippertesting.ControllerTestFixture) {
 	controller := NewController(
 		f.ShipperClient,
 		f.ShipperInformerFactory,
+		f.KubeInformerFactory,
+		shipper.ShipperNamespace,
 		f.ClusterClientStore,
 		f.DynamicClientBuilder,
 		localFetchChart,
@@ -28,6 +28,7 @@ type DynamicClientBuilderFunc func(gvk *schema.GroupVersionKind, restConfig *res
 type Installer struct {
 	installationTarget *shipper.InstallationTarget
 	objects            []runtime.Object
+	trafficBackend     string
 }
 
 // NewInstaller returns a new Installer.
@@ -41,6 +42,14 @@ func NewInstaller(
 	}
 }
 
+// withTrafficBackend returns a copy of the installer that adapts the objects
+// it installs to backend.
+func (i *Installer) withTrafficBackend(backend string) *Installer {
+	installer := *i
+	installer.trafficBackend = backend
+	return &installer
+}
+
 // buildResourceClient returns a ResourceClient suitable to manipulate the kind
 // of resource represented by the given GroupVersionKind at the given Cluster.
 func (i *Installer) buildResourceClient(
@@ -98,7 +107,14 @@ func (i *Installer) install(
 ) error {
 	it := i.installationTarget
 
+	if i.trafficBackend != shipper.NoTrafficBackend {
+		if err := checkProductionService(i.objects, i.trafficBackend); err != nil {
+			return err
+		}
+	}
+
 	var createdConfigMap *corev1.ConfigMap
+	anchorCreated := false
 
 	configMap := anchor.CreateConfigMapAnchor(it)
 	// TODO(jgreff): use a lister insted of a bare client
@@ -112,6 +128,7 @@ func (i *Installer) install(
 			return shippererrors.NewKubeclientCreateError(configMap, err).
 				WithCoreV1Kind("ConfigMap")
 		}
+		anchorCreated = true
 	} else {
 		createdConfigMap = existingConfigMap
 	}
@@ -119,7 +136,22 @@ func (i *Installer) install(
 	ownerReference := anchor.ConfigMapAnchorToOwnerReference(createdConfigMap)
 	resourceClients := make(map[string]dynamic.ResourceInterface)
 
-	for _, preparedObj := range i.objects {
+	objects := i.objects
+	if i.trafficBackend == shipper.IngressNginxTrafficBackend {
+		// Canary Ingresses are only created on the first installation
+		// of a release. From then on they belong to the traffic
+		// controller, which deletes them once they're not needed.
+		objects, err = ingressNginxObjects(it, objects, anchorCreated)
+		if err != nil {
+			return err
+		}
+	} else if i.trafficBackend == shipper.ReadinessGateTrafficBackend {
+		objects = readinessGateObjects(objects)
+	} else if i.trafficBackend == shipper.NoTrafficBackend {
+		objects = noTrafficObjects(objects)
+	}
+
+	for _, preparedObj := range objects {
 		obj := &unstructured.Unstructured{}
 		err = kubescheme.Scheme.Convert(preparedObj, obj, nil)
 		if err != nil {
@@ -204,6 +236,23 @@ func (i *Installer) install(
 			existingObj.SetOwnerReferences(ownerReferences)
 		}
 
+		// With ingress-nginx, the traffic controller points the
+		// primary Ingress at the release that should get most
+		// traffic, so all we do is make sure the release's anchor
+		// keeps it around.
+		if i.trafficBackend == shipper.IngressNginxTrafficBackend && isPrimaryIngress(existingObj) {
+			if ownerReferenceFound {
+				continue
+			}
+
+			if _, err := resourceClient.Update(existingObj, metav1.UpdateOptions{}); err != nil {
+				return shippererrors.NewKubeclientUpdateError(obj, err).
+					WithKind(gvk)
+			}
+
+			continue
+		}
+
 		existingObj.SetLabels(obj.GetLabels())
 		existingObj.SetAnnotations(obj.GetAnnotations())
 		existingUnstructuredObj := existingObj.UnstructuredContent()
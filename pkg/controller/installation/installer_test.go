@@ -1,6 +1,7 @@
 package installation
 
 import (
+	"fmt"
 	"regexp"
 	"testing"
 
@@ -22,7 +23,7 @@ import (
 var restConfig *rest.Config
 
 func newInstaller(it *shipper.InstallationTarget) (*Installer, error) {
-	objects, err := FetchAndRenderChart(localFetchChart, it)
+	objects, err := FetchAndRenderChart(localFetchChart, it, it.Spec.Values)
 	if err != nil {
 		return nil, err
 	}
@@ -280,6 +281,7 @@ func TestInstallerChartTarballInvalidDeploymentName(t *testing.T) {
 	chart := buildChart(appName, "invalid-deployment-name", repoUrl)
 
 	it := buildInstallationTarget(testNs, appName, []string{cluster.Name}, &chart)
+	it.Name = "reviews-api-deadbeef-0"
 	_, err := newInstaller(it)
 	if err == nil {
 		t.Fatal("NewInstaller should fail, invalid deployment name")
@@ -290,6 +292,45 @@ func TestInstallerChartTarballInvalidDeploymentName(t *testing.T) {
 	}
 }
 
+// TestInstallerNoService tests that charts without a Service can only be
+// installed for applications that get no traffic.
+func TestInstallerNoService(t *testing.T) {
+	cluster := buildCluster("minikube-a")
+	appName := "reviews-api"
+	testNs := "reviews-api"
+
+	// The chart with an invalid deployment name has no Service, and its
+	// Deployment name is only invalid for installation targets with
+	// other names.
+	chart := buildChart(appName, "invalid-deployment-name", repoUrl)
+	it := buildInstallationTarget(testNs, appName, []string{cluster.Name}, &chart)
+	installer, err := newInstaller(it)
+	if err != nil {
+		t.Fatalf("could not initialize the installer: %s", err)
+	}
+
+	f := newFixture(objectsPerClusterMap{cluster.Name: nil})
+	fakeCluster := f.Clusters[cluster.Name]
+
+	err = installer.install(cluster, fakeCluster.Client, restConfig, f.DynamicClientBuilder)
+	if _, ok := err.(shippererrors.InvalidChartError); !ok {
+		t.Fatalf("expected installing without a Service to fail with InvalidChartError, got %v instead", err)
+	}
+
+	if len(fakeCluster.Client.Actions()) != 0 {
+		t.Errorf("expected nothing to be done in the cluster, got %d actions", len(fakeCluster.Client.Actions()))
+	}
+
+	err = installer.withTrafficBackend(shipper.NoTrafficBackend).
+		install(cluster, fakeCluster.Client, restConfig, f.DynamicClientBuilder)
+	if err != nil {
+		t.Fatalf("expected installing an application without traffic to succeed, got %s", err)
+	}
+
+	filteredActions := filterActions(fakeCluster.DynamicClient.Actions(), "create")
+	validateAction(t, filteredActions[0], "Deployment")
+}
+
 // TestInstallerBrokenChartContents tests if the installation process fails when the
 // release contains a valid chart tarball with invalid K8s object templates.
 func TestInstallerBrokenChartContents(t *testing.T) {
@@ -416,7 +457,7 @@ func TestInstallerMultiServiceNoLB(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected an error, none raised")
 	}
-	if matched, err := regexp.MatchString("one and only one .* object .* is required", err.Error()); err != nil {
+	if matched, err := regexp.MatchString("at least one .* object .* is required", err.Error()); err != nil {
 		t.Fatalf("Failed to test error against the regex: %s", err)
 	} else if !matched {
 		t.Fatalf("Unexpected error raised: %s", err)
@@ -473,6 +514,39 @@ func TestInstallerMultiServiceWithLB(t *testing.T) {
 	validateDeploymentCreateAction(t, validateAction(t, filteredActions[3], "Deployment"), map[string]string{"app": "reviews-api"})
 }
 
+// TestPrepareObjectsMultipleProductionServices tests that every Service
+// labeled as a production load balancer gets traffic shifted through it.
+func TestPrepareObjectsMultipleProductionServices(t *testing.T) {
+	it := buildInstallationTarget("reviews-api", "reviews-api", []string{"minikube-a"}, nil)
+
+	service := `apiVersion: v1
+kind: Service
+metadata:
+  name: %s
+  labels:
+    shipper-lb: production
+spec:
+  selector:
+    app: reviews-api
+`
+	manifests := []string{
+		fmt.Sprintf(service, "reviews-api-http"),
+		fmt.Sprintf(service, "reviews-api-grpc"),
+	}
+
+	objects, err := prepareObjects(it, manifests)
+	if err != nil {
+		t.Fatalf("unexpected error preparing objects: %s", err)
+	}
+
+	for _, obj := range objects {
+		svc := obj.(*corev1.Service)
+		if v := svc.Spec.Selector[shipper.PodTrafficStatusLabel]; v != shipper.Enabled {
+			t.Errorf("expected Service %q to select pods by traffic status, got %q", svc.Name, v)
+		}
+	}
+}
+
 func TestInstallerMultiServiceWithLBOffTheShelf(t *testing.T) {
 	cluster := buildCluster("minikube-a")
 	appName := "nginx"
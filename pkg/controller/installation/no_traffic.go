@@ -0,0 +1,76 @@
+package installation
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+// noTrafficObjects adapts the objects rendered from a chart to applications
+// that get no traffic. Charts for those usually have no Service at all, but
+// if they do, the production Service stops selecting pods by their traffic
+// label, as nothing will ever set it.
+func noTrafficObjects(objects []runtime.Object) []runtime.Object {
+	adapted := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		if svc, ok := obj.(*corev1.Service); ok {
+			obj = withoutTrafficSelector(svc)
+		}
+
+		adapted = append(adapted, obj)
+	}
+
+	return adapted
+}
+
+// withoutTrafficSelector returns a copy of svc that selects pods regardless
+// of their traffic label if it's the production Service, and svc itself
+// otherwise.
+func withoutTrafficSelector(svc *corev1.Service) *corev1.Service {
+	if svc.Labels[shipper.LBLabel] != shipper.LBForProduction {
+		return svc
+	}
+
+	svc = svc.DeepCopy()
+	delete(svc.Spec.Selector, shipper.PodTrafficStatusLabel)
+
+	return svc
+}
+
+// checkProductionService makes sure there is a production Service among
+// objects for traffic to go through. Charts without any Service are only fine
+// for applications that get no traffic. The traffic backends that route
+// traffic to the production Service through a mesh or an Ingress need it to
+// be the only one, as they'd have no way to pick between several.
+func checkProductionService(objects []runtime.Object, trafficBackend string) error {
+	productionServices := 0
+	for _, obj := range objects {
+		svc, ok := obj.(*corev1.Service)
+		if ok && svc.Labels[shipper.LBLabel] == shipper.LBForProduction {
+			productionServices++
+		}
+	}
+
+	if productionServices == 0 {
+		return shippererrors.NewInvalidChartError(
+			fmt.Sprintf(
+				"the chart has no v1.Service object to shift traffic to; "+
+					"set the Application's trafficBackend to %q if it gets no traffic",
+				shipper.NoTrafficBackend))
+	}
+
+	switch trafficBackend {
+	case shipper.IstioTrafficBackend, shipper.SMITrafficBackend, shipper.IngressNginxTrafficBackend:
+		if productionServices > 1 {
+			return shippererrors.NewInvalidChartError(
+				fmt.Sprintf("%s traffic backend requires exactly one production Service, but %d found",
+					trafficBackend, productionServices))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package installation
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+func TestNoTrafficObjects(t *testing.T) {
+	production := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "app-prod",
+			Labels: map[string]string{shipper.LBLabel: shipper.LBForProduction},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				shipper.AppLabel:              shippertesting.TestApp,
+				shipper.PodTrafficStatusLabel: shipper.Enabled,
+			},
+		},
+	}
+
+	adapted := noTrafficObjects([]runtime.Object{production})
+
+	expectedSelector := map[string]string{shipper.AppLabel: shippertesting.TestApp}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedSelector, adapted[0].(*corev1.Service).Spec.Selector); !eq {
+		t.Errorf("production Service selector differs from expected:\n%s", diff)
+	}
+
+	if len(production.Spec.Selector) != 2 {
+		t.Errorf("expected rendered objects to be left untouched")
+	}
+
+	if err := checkProductionService(adapted, shipper.PodLabelsTrafficBackend); err != nil {
+		t.Errorf("expected production Service to be found, got %s", err)
+	}
+
+	if err := checkProductionService(nil, shipper.PodLabelsTrafficBackend); err == nil {
+		t.Errorf("expected an error for objects without a production Service")
+	}
+}
+
+func TestCheckProductionServiceCount(t *testing.T) {
+	buildProductionService := func(name string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{shipper.LBLabel: shipper.LBForProduction},
+			},
+		}
+	}
+
+	objects := []runtime.Object{buildProductionService("app-http"), buildProductionService("app-grpc")}
+
+	tests := []struct {
+		trafficBackend string
+		valid          bool
+	}{
+		{shipper.PodLabelsTrafficBackend, true},
+		{shipper.ReadinessGateTrafficBackend, true},
+		{shipper.IstioTrafficBackend, false},
+		{shipper.SMITrafficBackend, false},
+		{shipper.IngressNginxTrafficBackend, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.trafficBackend, func(t *testing.T) {
+			err := checkProductionService(objects, tt.trafficBackend)
+			if tt.valid && err != nil {
+				t.Errorf("expected several production Services to be fine, got %s", err)
+			} else if !tt.valid && !shippererrors.IsInvalidChartError(err) {
+				t.Errorf("expected an invalid chart error for several production Services, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package installation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// quotaTrackedResources are the compute resources a pod's containers are
+// counted against in a ResourceQuota, by the name of the resource in the
+// containers' requests.
+var quotaTrackedResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+	corev1.ResourceEphemeralStorage,
+}
+
+// checkResourceQuotas tells whether quotas have room for the Deployments in
+// objects at totalReplicaCount replicas each, on top of what is already used.
+// The message lists every resource that doesn't fit. Quotas that only apply
+// to some pods, through scopes, are not checked, as they may well not apply to
+// the release's.
+func checkResourceQuotas(
+	quotas []*corev1.ResourceQuota,
+	objects []runtime.Object,
+	totalReplicaCount int32,
+) (bool, string) {
+	usage := releaseQuotaUsage(objects, totalReplicaCount)
+
+	var shortfalls []string
+	for _, quota := range quotas {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+
+		for name, hard := range quota.Status.Hard {
+			needed, ok := usage[name]
+			if !ok {
+				continue
+			}
+
+			left := hard.DeepCopy()
+			if used, ok := quota.Status.Used[name]; ok {
+				left.Sub(used)
+			}
+
+			if needed.Cmp(left) > 0 {
+				shortfalls = append(shortfalls, fmt.Sprintf(
+					"ResourceQuota %q has %s of %s left, but the release needs %s",
+					quota.Name, left.String(), name, needed.String()))
+			}
+		}
+	}
+
+	if len(shortfalls) > 0 {
+		// Quotas and their resources come out of maps, so we sort
+		// them to keep the message stable across syncs.
+		sort.Strings(shortfalls)
+		return false, strings.Join(shortfalls, "; ")
+	}
+
+	return true, ""
+}
+
+// releaseQuotaUsage adds up what the pods of every Deployment in objects count
+// against a ResourceQuota at totalReplicaCount replicas each.
+func releaseQuotaUsage(objects []runtime.Object, totalReplicaCount int32) corev1.ResourceList {
+	usage := corev1.ResourceList{}
+	for _, obj := range objects {
+		deployment, ok := obj.(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
+
+		podUsage := podQuotaUsage(&deployment.Spec.Template.Spec)
+		for i := int32(0); i < totalReplicaCount; i++ {
+			addResourceList(usage, podUsage)
+		}
+	}
+
+	return usage
+}
+
+// podQuotaUsage returns what a pod counts against a ResourceQuota: one pod,
+// plus its effective requests and limits, which are the largest of the sum
+// of its containers' and of any of its init containers', as the scheduler
+// sees it.
+func podQuotaUsage(spec *corev1.PodSpec) corev1.ResourceList {
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		addResourceList(requests, container.Resources.Requests)
+		addResourceList(limits, container.Resources.Limits)
+	}
+	for _, container := range spec.InitContainers {
+		maxResourceList(requests, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+
+	usage := corev1.ResourceList{
+		corev1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	for _, name := range quotaTrackedResources {
+		if request, ok := requests[name]; ok {
+			usage[name] = request.DeepCopy()
+			usage[corev1.ResourceName("requests."+name)] = request.DeepCopy()
+		}
+		if limit, ok := limits[name]; ok {
+			usage[corev1.ResourceName("limits."+name)] = limit.DeepCopy()
+		}
+	}
+
+	return usage
+}
+
+func addResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if current, ok := list[name]; ok {
+			current.Add(quantity)
+			list[name] = current
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if current, ok := list[name]; !ok || quantity.Cmp(current) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package installation
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPodQuotaUsage(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			buildContainer("100m", "128Mi", "200m", ""),
+			buildContainer("50m", "64Mi", "", ""),
+		},
+		InitContainers: []corev1.Container{
+			buildContainer("500m", "64Mi", "", ""),
+		},
+	}
+
+	expected := corev1.ResourceList{
+		corev1.ResourcePods:           resource.MustParse("1"),
+		corev1.ResourceCPU:            resource.MustParse("500m"),
+		corev1.ResourceRequestsCPU:    resource.MustParse("500m"),
+		corev1.ResourceLimitsCPU:      resource.MustParse("200m"),
+		corev1.ResourceMemory:         resource.MustParse("192Mi"),
+		corev1.ResourceRequestsMemory: resource.MustParse("192Mi"),
+	}
+
+	usage := podQuotaUsage(spec)
+	if len(usage) != len(expected) {
+		t.Fatalf("expected usage of %v, got %v", expected, usage)
+	}
+	for name, quantity := range expected {
+		if actual, ok := usage[name]; !ok || actual.Cmp(quantity) != 0 {
+			t.Errorf("expected %s of %s, got %s", quantity.String(), name, actual.String())
+		}
+	}
+}
+
+func TestCheckResourceQuotas(t *testing.T) {
+	deployment := &appsv1.Deployment{}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		buildContainer("250m", "", "", ""),
+	}
+	objects := []runtime.Object{deployment, loadService("baseline")}
+
+	tests := []struct {
+		name        string
+		quotas      []*corev1.ResourceQuota
+		expectOK    bool
+		expectedMsg string
+	}{
+		{
+			"no quotas",
+			nil,
+			true,
+			"",
+		},
+		{
+			"enough room",
+			[]*corev1.ResourceQuota{
+				buildResourceQuota("compute", corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("2"),
+					corev1.ResourcePods:        resource.MustParse("10"),
+				}, corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("1"),
+				}),
+			},
+			true,
+			"",
+		},
+		{
+			"not enough room left",
+			[]*corev1.ResourceQuota{
+				buildResourceQuota("compute", corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("2"),
+					corev1.ResourcePods:        resource.MustParse("10"),
+				}, corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("1500m"),
+					corev1.ResourcePods:        resource.MustParse("8"),
+				}),
+			},
+			false,
+			`ResourceQuota "compute" has 2 of pods left, but the release needs 4; ` +
+				`ResourceQuota "compute" has 500m of requests.cpu left, but the release needs 1`,
+		},
+		{
+			"scoped quotas are ignored",
+			[]*corev1.ResourceQuota{
+				func() *corev1.ResourceQuota {
+					quota := buildResourceQuota("best-effort", corev1.ResourceList{
+						corev1.ResourcePods: resource.MustParse("1"),
+					}, nil)
+					quota.Spec.Scopes = []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}
+					return quota
+				}(),
+			},
+			true,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, msg := checkResourceQuotas(tt.quotas, objects, 4)
+			if ok != tt.expectOK || msg != tt.expectedMsg {
+				t.Fatalf("expected (%t, %q), got (%t, %q)", tt.expectOK, tt.expectedMsg, ok, msg)
+			}
+		})
+	}
+}
+
+func buildContainer(cpuRequest, memoryRequest, cpuLimit, memoryLimit string) corev1.Container {
+	parse := func(quantities map[corev1.ResourceName]string) corev1.ResourceList {
+		list := corev1.ResourceList{}
+		for name, quantity := range quantities {
+			if quantity != "" {
+				list[name] = resource.MustParse(quantity)
+			}
+		}
+		return list
+	}
+
+	return corev1.Container{
+		Resources: corev1.ResourceRequirements{
+			Requests: parse(map[corev1.ResourceName]string{
+				corev1.ResourceCPU:    cpuRequest,
+				corev1.ResourceMemory: memoryRequest,
+			}),
+			Limits: parse(map[corev1.ResourceName]string{
+				corev1.ResourceCPU:    cpuLimit,
+				corev1.ResourceMemory: memoryLimit,
+			}),
+		},
+	}
+}
+
+func buildResourceQuota(name string, hard, used corev1.ResourceList) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: hard,
+			Used: used,
+		},
+	}
+}
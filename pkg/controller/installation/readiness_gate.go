@@ -0,0 +1,51 @@
+package installation
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	podutil "github.com/bookingcom/shipper/pkg/util/pod"
+)
+
+// readinessGateObjects adapts the objects rendered from a chart to the
+// readiness-gate traffic backend. Pods of every Deployment get a readiness
+// gate on the condition the traffic controller sets, and the production
+// Service stops selecting pods by their traffic label, as the readiness gate
+// already keeps pods without traffic out of its Endpoints.
+//
+// The objects returned are copies: the ones passed in are left untouched, as
+// they are shared between clusters.
+func readinessGateObjects(objects []runtime.Object) []runtime.Object {
+	adapted := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			adapted = append(adapted, injectTrafficReadinessGate(o))
+		case *corev1.Service:
+			adapted = append(adapted, withoutTrafficSelector(o))
+		default:
+			adapted = append(adapted, obj)
+		}
+	}
+
+	return adapted
+}
+
+// injectTrafficReadinessGate returns a copy of deployment whose pods have a
+// readiness gate on shipper.PodTrafficReadinessGate.
+func injectTrafficReadinessGate(deployment *appsv1.Deployment) *appsv1.Deployment {
+	deployment = deployment.DeepCopy()
+
+	podSpec := &deployment.Spec.Template.Spec
+	if podutil.HasTrafficReadinessGate(podSpec) {
+		return deployment
+	}
+
+	podSpec.ReadinessGates = append(podSpec.ReadinessGates, corev1.PodReadinessGate{
+		ConditionType: shipper.PodTrafficReadinessGate,
+	})
+
+	return deployment
+}
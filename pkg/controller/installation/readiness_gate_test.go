@@ -0,0 +1,78 @@
+package installation
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+func TestReadinessGateObjects(t *testing.T) {
+	selector := map[string]string{
+		shipper.AppLabel:              shippertesting.TestApp,
+		shipper.PodTrafficStatusLabel: shipper.Enabled,
+	}
+
+	production := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "app-prod",
+			Labels: map[string]string{shipper.LBLabel: shipper.LBForProduction},
+		},
+		Spec: corev1.ServiceSpec{Selector: selector},
+	}
+	other := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-other"},
+		Spec:       corev1.ServiceSpec{Selector: selector},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					ReadinessGates: []corev1.PodReadinessGate{
+						{ConditionType: "example.com/other"},
+					},
+				},
+			},
+		},
+	}
+
+	adapted := readinessGateObjects([]runtime.Object{production, other, deployment})
+	if len(adapted) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(adapted))
+	}
+
+	expectedSelector := map[string]string{shipper.AppLabel: shippertesting.TestApp}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedSelector, adapted[0].(*corev1.Service).Spec.Selector); !eq {
+		t.Errorf("production Service selector differs from expected:\n%s", diff)
+	}
+
+	if adapted[1] != other {
+		t.Errorf("expected non-production Service to be left untouched")
+	}
+
+	expectedGates := []corev1.PodReadinessGate{
+		{ConditionType: "example.com/other"},
+		{ConditionType: shipper.PodTrafficReadinessGate},
+	}
+	gates := adapted[2].(*appsv1.Deployment).Spec.Template.Spec.ReadinessGates
+	if eq, diff := shippertesting.DeepEqualDiff(expectedGates, gates); !eq {
+		t.Errorf("Deployment readiness gates differ from expected:\n%s", diff)
+	}
+
+	if len(production.Spec.Selector) != 2 || len(deployment.Spec.Template.Spec.ReadinessGates) != 1 {
+		t.Errorf("expected rendered objects to be left untouched")
+	}
+
+	// Adapting objects twice must not pile up readiness gates.
+	again := readinessGateObjects(adapted)
+	gates = again[2].(*appsv1.Deployment).Spec.Template.Spec.ReadinessGates
+	if eq, diff := shippertesting.DeepEqualDiff(expectedGates, gates); !eq {
+		t.Errorf("Deployment readiness gates differ from expected after adapting twice:\n%s", diff)
+	}
+}
@@ -23,10 +23,11 @@ type kubeobj interface {
 }
 
 func FetchAndRenderChart(
-	chartFetcher shipperrepo.ChartFetcher,
+	chartFetcher shipperrepo.VerifiedChartFetcher,
 	it *shipper.InstallationTarget,
+	values *shipper.ChartValues,
 ) ([]runtime.Object, error) {
-	chart, err := chartFetcher(it.Spec.Chart)
+	chart, err := chartFetcher(it.Spec.Chart, it.Namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +36,7 @@ func FetchAndRenderChart(
 		chart,
 		it.GetName(),
 		it.GetNamespace(),
-		it.Spec.Values,
+		values,
 	)
 
 	if err != nil {
@@ -45,6 +46,80 @@ func FetchAndRenderChart(
 	return prepareObjects(it, manifests)
 }
 
+// overridesForCluster returns a description of every override that matches
+// cluster, along with their values merged together in order. It returns nil
+// values if no override matches.
+func overridesForCluster(
+	overrides []shipper.ValuesOverride,
+	cluster *shipper.Cluster,
+) ([]string, *shipper.ChartValues, error) {
+	var (
+		applied []string
+		values  []shipper.ChartValues
+	)
+
+	for i, override := range overrides {
+		if override.Region != "" && override.Region != cluster.Spec.Region {
+			continue
+		}
+
+		if override.Cluster != "" && override.Cluster != cluster.Name {
+			continue
+		}
+
+		if override.ClusterSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(override.ClusterSelector)
+			if err != nil {
+				return nil, nil, shippererrors.NewRenderManifestError(
+					fmt.Errorf("invalid clusterSelector in override %d: %s", i, err))
+			}
+
+			if !selector.Matches(labels.Set(cluster.Labels)) {
+				continue
+			}
+		}
+
+		applied = append(applied, describeOverride(i, override))
+		if override.Values != nil {
+			values = append(values, *override.Values)
+		}
+	}
+
+	if len(applied) == 0 {
+		return nil, nil, nil
+	}
+
+	merged := shipperchart.MergeValues(nil, values...)
+	if merged == nil {
+		merged = &shipper.ChartValues{}
+	}
+
+	return applied, merged, nil
+}
+
+func describeOverride(i int, override shipper.ValuesOverride) string {
+	var criteria []string
+
+	if override.Region != "" {
+		criteria = append(criteria, fmt.Sprintf("region=%s", override.Region))
+	}
+
+	if override.Cluster != "" {
+		criteria = append(criteria, fmt.Sprintf("cluster=%s", override.Cluster))
+	}
+
+	if override.ClusterSelector != nil {
+		criteria = append(criteria, fmt.Sprintf("clusterSelector=%s",
+			metav1.FormatLabelSelector(override.ClusterSelector)))
+	}
+
+	if len(criteria) == 0 {
+		criteria = append(criteria, "all clusters")
+	}
+
+	return fmt.Sprintf("%d: %s", i, strings.Join(criteria, ", "))
+}
+
 func prepareObjects(it *shipper.InstallationTarget, manifests []string) ([]runtime.Object, error) {
 	shipperLabels := labels.Merge(labels.Set(it.Labels), labels.Set{
 		shipper.InstallationTargetOwnerLabel: it.Name,
@@ -104,25 +179,35 @@ func prepareObjects(it *shipper.InstallationTarget, manifests []string) ([]runti
 		preparedObjects = append(preparedObjects, obj)
 	}
 
+	// Charts without any Service are fine for applications that get no
+	// traffic. Whether that's the case depends on the traffic backend, so
+	// it's up to the installer to tell.
+	if len(allServices) == 0 {
+		return preparedObjects, nil
+	}
+
 	// If we have observed only 1 Service object and it was not marked with
 	// shipper-lb=production label, we can do it ourselves.
 	if len(productionLBServices) == 0 && len(allServices) == 1 {
 		productionLBServices = allServices
 	}
 
-	// If, after all, we still can not identify a single Service which will
-	// be the production LB, there is nothing else to do rather than bail
-	// out
-	if len(productionLBServices) != 1 {
+	// If, after all, we still can not identify any Service which will be
+	// a production LB, there is nothing else to do rather than bail out.
+	// Charts can have as many of them as they need, like one for HTTP and
+	// another one for gRPC, and traffic is shifted through all of them.
+	if len(productionLBServices) == 0 {
 		return nil, shippererrors.NewInvalidChartError(
 			fmt.Sprintf(
-				"one and only one v1.Service object with label %q is required, but %d found instead",
-				shipper.LBLabel, len(productionLBServices)))
+				"at least one v1.Service object with label %q is required when there are several, but none of the %d found has it",
+				shipper.LBLabel, len(allServices)))
 	}
 
-	err := patchService(it, productionLBServices[0])
-	if err != nil {
-		return nil, err
+	for _, svc := range productionLBServices {
+		err := patchService(it, svc)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return preparedObjects, nil
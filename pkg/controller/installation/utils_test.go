@@ -67,7 +67,7 @@ var (
 	}
 )
 
-var localFetchChart = func(chartspec *shipper.Chart) (*chart.Chart, error) {
+var localFetchChart = func(chartspec *shipper.Chart, namespace string) (*chart.Chart, error) {
 	re := regexp.MustCompile(`[^a-zA-Z0-9]+`)
 	pathurl := re.ReplaceAllString(chartspec.RepoURL, "_")
 	data, err := ioutil.ReadFile(
@@ -0,0 +1,72 @@
+package release
+
+import (
+	"sort"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+// approveSteps returns how far up to targetStep rel, the release whose
+// strategy is being executed, can go. Moving on to a step that requires
+// approval takes a ReleaseApproval for rel and that step, unless rel has
+// recorded one in its status already. It also returns all the approvals rel
+// should have recorded, and whether it is being held back at an earlier step.
+func approveSteps(
+	rel *shipper.Release,
+	strategy *shipper.RolloutStrategy,
+	targetStep int32,
+	releaseApprovals []*shipper.ReleaseApproval,
+) ([]shipper.StepApproval, int32, bool) {
+	var approvals []shipper.StepApproval
+	approvals = append(approvals, rel.Status.Approvals...)
+
+	recorded := make(map[int32]bool)
+	for _, approval := range approvals {
+		recorded[approval.Step] = true
+	}
+
+	// The oldest approval for a step is the one that counts, so the
+	// approver doesn't change if more of them come along.
+	releaseApprovals = append([]*shipper.ReleaseApproval(nil), releaseApprovals...)
+	sort.Slice(releaseApprovals, func(i, j int) bool {
+		a, b := releaseApprovals[i], releaseApprovals[j]
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Name < b.Name
+	})
+
+	for step := int32(1); step <= targetStep; step++ {
+		if !strategy.Steps[step].RequiresApproval || recorded[step] {
+			continue
+		}
+
+		approval, ok := findReleaseApproval(releaseApprovals, rel.Name, step)
+		if !ok {
+			return approvals, step - 1, true
+		}
+
+		approvals = append(approvals, shipper.StepApproval{
+			Step:     step,
+			Name:     strategy.Steps[step].Name,
+			Approver: approval.Spec.Approver,
+			Approval: approval.Name,
+		})
+	}
+
+	return approvals, targetStep, false
+}
+
+func findReleaseApproval(
+	releaseApprovals []*shipper.ReleaseApproval,
+	releaseName string,
+	step int32,
+) (*shipper.ReleaseApproval, bool) {
+	for _, approval := range releaseApprovals {
+		if approval.Spec.Release == releaseName && approval.Spec.Step == step {
+			return approval, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,119 @@
+package release
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func TestApproveSteps(t *testing.T) {
+	strategy := &shipper.RolloutStrategy{
+		Steps: []shipper.RolloutStrategyStep{
+			{Name: "staging"},
+			{Name: "canary", RequiresApproval: true},
+			{Name: "full on", RequiresApproval: true},
+		},
+	}
+
+	now := time.Now()
+	buildApproval := func(name, release string, step int32, approver string, age time.Duration) *shipper.ReleaseApproval {
+		return &shipper.ReleaseApproval{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Spec: shipper.ReleaseApprovalSpec{
+				Release:  release,
+				Step:     step,
+				Approver: approver,
+			},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		recorded        []shipper.StepApproval
+		approvals       []*shipper.ReleaseApproval
+		targetStep      int32
+		expectStep      int32
+		expectWaiting   bool
+		expectApprovers []string
+	}{
+		{
+			name:          "no approval needed before the first gate",
+			targetStep:    0,
+			expectStep:    0,
+			expectWaiting: false,
+		},
+		{
+			name:          "held before an unapproved step",
+			targetStep:    2,
+			expectStep:    0,
+			expectWaiting: true,
+		},
+		{
+			name:          "approvals for other releases don't count",
+			approvals:     []*shipper.ReleaseApproval{buildApproval("a", "other", 1, "jane", 0)},
+			targetStep:    1,
+			expectStep:    0,
+			expectWaiting: true,
+		},
+		{
+			name:            "held at an approved step",
+			approvals:       []*shipper.ReleaseApproval{buildApproval("a", "test", 1, "jane", 0)},
+			targetStep:      2,
+			expectStep:      1,
+			expectWaiting:   true,
+			expectApprovers: []string{"jane"},
+		},
+		{
+			name: "oldest approval counts",
+			approvals: []*shipper.ReleaseApproval{
+				buildApproval("a", "test", 1, "jane", time.Minute),
+				buildApproval("b", "test", 1, "john", time.Hour),
+				buildApproval("c", "test", 2, "jane", 0),
+			},
+			targetStep:      2,
+			expectStep:      2,
+			expectWaiting:   false,
+			expectApprovers: []string{"john", "jane"},
+		},
+		{
+			name: "recorded approvals stay",
+			recorded: []shipper.StepApproval{
+				{Step: 1, Name: "canary", Approver: "jane", Approval: "deleted"},
+			},
+			targetStep:      1,
+			expectStep:      1,
+			expectWaiting:   false,
+			expectApprovers: []string{"jane"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel := &shipper.Release{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Status:     shipper.ReleaseStatus{Approvals: tt.recorded},
+			}
+
+			approvals, step, waiting := approveSteps(rel, strategy, tt.targetStep, tt.approvals)
+			if step != tt.expectStep || waiting != tt.expectWaiting {
+				t.Fatalf("expected step %d (waiting: %t), got step %d (waiting: %t)",
+					tt.expectStep, tt.expectWaiting, step, waiting)
+			}
+
+			if len(approvals) != len(tt.expectApprovers) {
+				t.Fatalf("expected %d approvals, got %+v", len(tt.expectApprovers), approvals)
+			}
+			for i, approver := range tt.expectApprovers {
+				if approvals[i].Approver != approver {
+					t.Fatalf("expected approval %d by %q, got %+v", i, approver, approvals[i])
+				}
+			}
+		})
+	}
+}
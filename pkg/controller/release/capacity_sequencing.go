@@ -0,0 +1,220 @@
+package release
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	capacityutil "github.com/bookingcom/shipper/pkg/util/capacity"
+	"github.com/bookingcom/shipper/pkg/util/replicas"
+	targetutil "github.com/bookingcom/shipper/pkg/util/target"
+)
+
+var defaultMaxSurge = intstr.FromString("25%")
+
+// surgePods resolves maxSurge into a number of pods out of
+// totalReplicaCount. The budget is never less than one pod, so capacity can
+// always move.
+func surgePods(maxSurge *intstr.IntOrString, totalReplicaCount int32) (int32, error) {
+	if maxSurge == nil {
+		maxSurge = &defaultMaxSurge
+	}
+
+	surge, err := intstr.GetValueFromIntOrPercent(maxSurge, int(totalReplicaCount), true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maxSurge %q: %s", maxSurge.String(), err)
+	} else if surge < 0 {
+		return 0, fmt.Errorf("invalid maxSurge %q: must not be negative", maxSurge.String())
+	} else if surge < 1 {
+		surge = 1
+	}
+
+	return int32(surge), nil
+}
+
+// interleaveCapacity returns the next capacity targets for the contender and
+// the incumbent on their way to goal, moving one of them per cluster at a
+// time so their pods stay within maxSurge over the replica count. A spec that
+// needs no change comes back nil.
+//
+// The incumbent never shrinks below the pods it needs for the share of
+// traffic it currently gets. When that's holding it back, the returned flag is
+// true: traffic has to move away from the incumbent before capacity can move
+// any further.
+func interleaveCapacity(
+	contender, incumbent *shipper.CapacityTarget,
+	contenderTraffic, incumbentTraffic *shipper.TrafficTarget,
+	goal shipper.RolloutStrategyStepValue,
+	maxSurge *intstr.IntOrString,
+) (*shipper.CapacityTargetSpec, *shipper.CapacityTargetSpec, bool, error) {
+	incumbentSpecs := make(map[string]shipper.ClusterCapacityTarget)
+	for _, spec := range incumbent.Spec.Clusters {
+		incumbentSpecs[spec.Name] = spec
+	}
+
+	contenderWeights := clusterWeights(contenderTraffic)
+	incumbentWeights := clusterWeights(incumbentTraffic)
+	waitingForTraffic := false
+
+	contenderSpec := &shipper.CapacityTargetSpec{}
+	contenderChanged := false
+	nextIncumbentSpecs := make(map[string]shipper.ClusterCapacityTarget)
+	for _, spec := range contender.Spec.Clusters {
+		t := clusterCapacityForStep(spec, goal.Contender)
+
+		// Clusters the incumbent isn't on have nothing to interleave
+		// with.
+		if incumbentSpec, ok := incumbentSpecs[spec.Name]; ok {
+			surge, err := surgePods(maxSurge, spec.TotalReplicaCount)
+			if err != nil {
+				return nil, nil, false, err
+			}
+
+			incumbentGoal := clusterCapacityForStep(incumbentSpec, goal.Incumbent)
+			incumbentCurrent := capacityutil.DesiredReplicaCount(incumbentSpec)
+			contenderPods, incumbentPods := interleaveClusterCapacity(
+				capacityutil.DesiredReplicaCount(spec),
+				incumbentCurrent,
+				capacityutil.DesiredReplicaCount(t),
+				capacityutil.DesiredReplicaCount(incumbentGoal),
+				spec.TotalReplicaCount, surge)
+
+			incumbentWeight := incumbentWeights[spec.Name]
+			trafficFloor := trafficPods(incumbentWeight, incumbentWeight+contenderWeights[spec.Name], incumbentSpec.TotalReplicaCount)
+			if incumbentPods < incumbentCurrent && incumbentPods < trafficFloor {
+				incumbentPods = minInt32(incumbentCurrent, trafficFloor)
+				waitingForTraffic = true
+			}
+
+			t = clusterCapacityTowards(spec, t, contenderPods)
+			nextIncumbentSpecs[spec.Name] = clusterCapacityTowards(incumbentSpec, incumbentGoal, incumbentPods)
+		}
+
+		contenderChanged = contenderChanged || !sameClusterCapacity(t, spec)
+		contenderSpec.Clusters = append(contenderSpec.Clusters, t)
+	}
+
+	incumbentSpec := &shipper.CapacityTargetSpec{}
+	incumbentChanged := false
+	for _, spec := range incumbent.Spec.Clusters {
+		t, ok := nextIncumbentSpecs[spec.Name]
+		if !ok {
+			t = clusterCapacityForStep(spec, goal.Incumbent)
+		}
+
+		incumbentChanged = incumbentChanged || !sameClusterCapacity(t, spec)
+		incumbentSpec.Clusters = append(incumbentSpec.Clusters, t)
+	}
+
+	if !contenderChanged {
+		contenderSpec = nil
+	}
+	if !incumbentChanged {
+		incumbentSpec = nil
+	}
+
+	return contenderSpec, incumbentSpec, waitingForTraffic, nil
+}
+
+// clusterWeights returns the traffic weight tt asks for in each cluster.
+func clusterWeights(tt *shipper.TrafficTarget) map[string]uint32 {
+	weights := make(map[string]uint32)
+	for _, spec := range tt.Spec.Clusters {
+		weights[spec.Name] = spec.Weight
+	}
+
+	return weights
+}
+
+// trafficPods is how many out of totalReplicaCount pods a release needs to
+// serve weight out of totalWeight.
+func trafficPods(weight, totalWeight uint32, totalReplicaCount int32) int32 {
+	if weight == 0 {
+		return 0
+	}
+
+	pods := (uint64(weight)*uint64(totalReplicaCount) + uint64(totalWeight) - 1) / uint64(totalWeight)
+	return int32(pods)
+}
+
+// clusterCapacityTowards returns the spec asking for pods on the way from
+// spec to goal. Specs ask for the exact number of pods in between, so they
+// don't depend on how percentages round.
+func clusterCapacityTowards(spec, goal shipper.ClusterCapacityTarget, pods int32) shipper.ClusterCapacityTarget {
+	switch pods {
+	case capacityutil.DesiredReplicaCount(goal):
+		return goal
+	case capacityutil.DesiredReplicaCount(spec):
+		return spec
+	}
+
+	return clusterCapacityForPods(spec, pods)
+}
+
+// interleaveClusterCapacity takes a single increment from the contender's and
+// the incumbent's pods in a cluster towards their goals, keeping them within
+// surge over totalReplicaCount. Whichever has to grow does so first, as far as
+// the budget allows. Whichever has to shrink then gives up as many pods as the
+// other one has made up for, or all of them once the other one is done
+// growing.
+func interleaveClusterCapacity(
+	contender, incumbent, contenderGoal, incumbentGoal, totalReplicaCount, surge int32,
+) (int32, int32) {
+	room := totalReplicaCount + surge - contender - incumbent
+
+	switch {
+	case contender < contenderGoal && room > 0:
+		return minInt32(contenderGoal, contender+room), incumbent
+	case incumbent < incumbentGoal && room > 0:
+		return contender, minInt32(incumbentGoal, incumbent+room)
+	case incumbent > incumbentGoal:
+		return contender, shrinkPods(incumbentGoal, contender, contenderGoal, totalReplicaCount)
+	case contender > contenderGoal:
+		return shrinkPods(contenderGoal, incumbent, incumbentGoal, totalReplicaCount), incumbent
+	}
+
+	// Either both are there already, or the step itself asks for more
+	// than the budget allows and there is nothing to interleave.
+	return contenderGoal, incumbentGoal
+}
+
+// shrinkPods is how many pods a release with a goal of goal pods can shrink
+// to while the other release is on its way to otherGoal pods, without the two
+// having fewer than totalReplicaCount between them.
+func shrinkPods(goal, other, otherGoal, totalReplicaCount int32) int32 {
+	if other >= otherGoal {
+		return goal
+	}
+
+	return maxInt32(goal, totalReplicaCount-other)
+}
+
+func podsForPercent(percent, totalReplicaCount int32) int32 {
+	return int32(replicas.CalculateDesiredReplicaCount(uint(totalReplicaCount), float64(percent)))
+}
+
+// capacityTargetReady tells whether ct has caught up with its current spec,
+// whatever that is.
+func capacityTargetReady(ct *shipper.CapacityTarget) bool {
+	if ct.Status.ObservedGeneration < ct.Generation {
+		return false
+	}
+
+	ready, _ := targetutil.IsReady(ct.Status.Conditions)
+	return ready
+}
+
+func minInt32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,203 @@
+package release
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	capacityutil "github.com/bookingcom/shipper/pkg/util/capacity"
+)
+
+func TestSurgePods(t *testing.T) {
+	threePods := intstr.FromInt(3)
+	tenPercent := intstr.FromString("10%")
+	zeroPercent := intstr.FromString("0%")
+	negative := intstr.FromInt(-1)
+	garbage := intstr.FromString("lots")
+
+	tests := []struct {
+		name              string
+		maxSurge          *intstr.IntOrString
+		totalReplicaCount int32
+		expected          int32
+		expectErr         bool
+	}{
+		{"defaults to 25%", nil, 10, 3, false},
+		{"absolute pods", &threePods, 10, 3, false},
+		{"percentage rounds up", &tenPercent, 15, 2, false},
+		{"never less than one pod", &zeroPercent, 10, 1, false},
+		{"negative", &negative, 10, 0, true},
+		{"not a number", &garbage, 10, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			surge, err := surgePods(tt.maxSurge, tt.totalReplicaCount)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got surge of %d pods", surge)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if surge != tt.expected {
+				t.Fatalf("expected surge of %d pods, got %d", tt.expected, surge)
+			}
+		})
+	}
+}
+
+func TestInterleaveClusterCapacity(t *testing.T) {
+	tests := []struct {
+		name                             string
+		contender, incumbent             int32
+		contenderGoal, incumbentGoal     int32
+		totalReplicaCount, surge         int32
+		expectContender, expectIncumbent int32
+	}{
+		{"contender grows within budget", 1, 10, 5, 5, 10, 2, 2, 10},
+		{"incumbent shrinks to make room", 2, 10, 5, 5, 10, 2, 2, 8},
+		{"incumbent shrinks to goal once contender is there", 5, 8, 5, 5, 10, 2, 5, 5},
+		{"incumbent grows back first on the way back", 5, 5, 1, 10, 10, 2, 5, 7},
+		{"contender shrinks to make room on the way back", 5, 7, 1, 10, 10, 2, 3, 7},
+		{"odd replica count", 1, 7, 4, 4, 7, 1, 1, 6},
+		{"step larger than budget", 2, 10, 5, 10, 10, 1, 5, 10},
+		{"nothing to do", 5, 5, 5, 5, 10, 2, 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contender, incumbent := interleaveClusterCapacity(
+				tt.contender, tt.incumbent, tt.contenderGoal, tt.incumbentGoal,
+				tt.totalReplicaCount, tt.surge)
+			if contender != tt.expectContender || incumbent != tt.expectIncumbent {
+				t.Fatalf("expected contender at %d pods and incumbent at %d, got %d and %d",
+					tt.expectContender, tt.expectIncumbent, contender, incumbent)
+			}
+		})
+	}
+}
+
+func TestInterleaveCapacityStaysWithinBudget(t *testing.T) {
+	const totalReplicaCount = 7
+	maxSurge := intstr.FromInt(1)
+
+	steps := []shipper.RolloutStrategyStepValue{
+		{Incumbent: intstr.FromInt(100), Contender: intstr.FromString("1")},
+		{Incumbent: intstr.FromInt(50), Contender: intstr.FromInt(50)},
+		{Incumbent: intstr.FromInt(0), Contender: intstr.FromString("100%")},
+		{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(1)},
+	}
+
+	contender := buildCapacityTarget("contender", 0, totalReplicaCount)
+	incumbent := buildCapacityTarget("incumbent", 100, totalReplicaCount)
+	// Neither gets any traffic, so capacity is free to move.
+	contenderTraffic := buildTrafficTarget("contender", 0)
+	incumbentTraffic := buildTrafficTarget("incumbent", 0)
+
+	for _, step := range steps {
+		for i := 0; ; i++ {
+			if i > 100 {
+				t.Fatalf("capacity did not converge on step %v", step)
+			}
+
+			contenderSpec, incumbentSpec, _, err := interleaveCapacity(
+				contender, incumbent, contenderTraffic, incumbentTraffic, step, &maxSurge)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if contenderSpec == nil && incumbentSpec == nil {
+				break
+			}
+			if contenderSpec != nil {
+				contender.Spec = *contenderSpec
+			}
+			if incumbentSpec != nil {
+				incumbent.Spec = *incumbentSpec
+			}
+
+			pods := capacityutil.DesiredReplicaCount(contender.Spec.Clusters[0]) +
+				capacityutil.DesiredReplicaCount(incumbent.Spec.Clusters[0])
+			if pods > totalReplicaCount+1 {
+				t.Fatalf("expected at most %d pods, got %d on step %v", totalReplicaCount+1, pods, step)
+			}
+		}
+
+		expectedContender := clusterCapacityForStep(contender.Spec.Clusters[0], step.Contender)
+		expectedIncumbent := clusterCapacityForStep(incumbent.Spec.Clusters[0], step.Incumbent)
+		if !sameClusterCapacity(contender.Spec.Clusters[0], expectedContender) ||
+			!sameClusterCapacity(incumbent.Spec.Clusters[0], expectedIncumbent) {
+			t.Fatalf("expected capacity to converge on step %v, got contender at %+v and incumbent at %+v",
+				step, contender.Spec.Clusters[0], incumbent.Spec.Clusters[0])
+		}
+	}
+}
+
+func TestInterleaveCapacityKeepsIncumbentPodsForItsTraffic(t *testing.T) {
+	const totalReplicaCount = 10
+	maxSurge := intstr.FromInt(3)
+	step := shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)}
+
+	contender := buildCapacityTarget("contender", 80, totalReplicaCount)
+	incumbent := buildCapacityTarget("incumbent", 50, totalReplicaCount)
+	contenderTraffic := buildTrafficTarget("contender", 50)
+	incumbentTraffic := buildTrafficTarget("incumbent", 50)
+
+	// The contender takes up the whole surge budget, and the incumbent
+	// needs all of its pods for half of the traffic.
+	contenderSpec, incumbentSpec, waitingForTraffic, err := interleaveCapacity(
+		contender, incumbent, contenderTraffic, incumbentTraffic, step, &maxSurge)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if contenderSpec != nil || incumbentSpec != nil {
+		t.Fatalf("expected capacity to stay put, got contender at %+v and incumbent at %+v",
+			contenderSpec, incumbentSpec)
+	}
+	if !waitingForTraffic {
+		t.Fatalf("expected capacity to be waiting for traffic")
+	}
+
+	// Once traffic has moved away from the incumbent, it can shrink.
+	incumbentTraffic.Spec.Clusters[0].Weight = 0
+	_, incumbentSpec, waitingForTraffic, err = interleaveCapacity(
+		contender, incumbent, contenderTraffic, incumbentTraffic, step, &maxSurge)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if waitingForTraffic {
+		t.Fatalf("expected capacity not to be waiting for traffic")
+	}
+	if incumbentSpec == nil {
+		t.Fatalf("expected the incumbent to shrink")
+	}
+	if pods := capacityutil.DesiredReplicaCount(incumbentSpec.Clusters[0]); pods != 2 {
+		t.Fatalf("expected the incumbent to shrink to 2 pods, got %d", pods)
+	}
+}
+
+func buildCapacityTarget(name string, percent, totalReplicaCount int32) *shipper.CapacityTarget {
+	ct := &shipper.CapacityTarget{}
+	ct.Name = name
+	ct.Spec.Clusters = []shipper.ClusterCapacityTarget{
+		{Name: "minikube", Percent: percent, TotalReplicaCount: totalReplicaCount},
+	}
+
+	return ct
+}
+
+func buildTrafficTarget(name string, weight uint32) *shipper.TrafficTarget {
+	tt := &shipper.TrafficTarget{}
+	tt.Name = name
+	tt.Spec.Clusters = []shipper.ClusterTrafficTarget{
+		{Name: "minikube", Weight: weight},
+	}
+
+	return tt
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"sort"
 
+	"k8s.io/apimachinery/pkg/util/intstr"
+
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	capacityutil "github.com/bookingcom/shipper/pkg/util/capacity"
 	targetutil "github.com/bookingcom/shipper/pkg/util/target"
 )
 
@@ -14,7 +17,7 @@ func checkInstallation(it *shipper.InstallationTarget) (bool, string) {
 
 func checkCapacity(
 	ct *shipper.CapacityTarget,
-	stepCapacity int32,
+	stepCapacity intstr.IntOrString,
 ) (
 	bool,
 	*shipper.CapacityTargetSpec,
@@ -27,12 +30,8 @@ func checkCapacity(
 	clustersNotReadyMap := make(map[string]struct{})
 	for _, spec := range ct.Spec.Clusters {
 		t := spec
-		if spec.Percent != stepCapacity {
-			t = shipper.ClusterCapacityTarget{
-				Name:              spec.Name,
-				Percent:           stepCapacity,
-				TotalReplicaCount: spec.TotalReplicaCount,
-			}
+		if goal := clusterCapacityForStep(spec, stepCapacity); !sameClusterCapacity(spec, goal) {
+			t = goal
 
 			clustersNotReadyMap[spec.Name] = struct{}{}
 			canProceed = false
@@ -79,7 +78,7 @@ func checkCapacity(
 
 func checkTraffic(
 	tt *shipper.TrafficTarget,
-	stepTrafficWeight uint32,
+	stepTrafficWeights map[string]uint32,
 ) (
 	bool,
 	*shipper.TrafficTargetSpec,
@@ -92,10 +91,10 @@ func checkTraffic(
 	clustersNotReadyMap := make(map[string]struct{})
 	for _, spec := range tt.Spec.Clusters {
 		t := spec
-		if spec.Weight != stepTrafficWeight {
+		if weight := stepTrafficWeights[spec.Name]; spec.Weight != weight {
 			t = shipper.ClusterTrafficTarget{
 				Name:   spec.Name,
-				Weight: stepTrafficWeight,
+				Weight: weight,
 			}
 
 			clustersNotReadyMap[spec.Name] = struct{}{}
@@ -140,3 +139,96 @@ func checkTraffic(
 
 	return canProceed, newSpec, reason
 }
+
+// checkMinAvailability tells whether reducing the incumbent's traffic and
+// capacity to stepTrafficWeights and podGoals leaves it and the contender with
+// at least minAvailablePercent of the replica count available between them
+// in every cluster it is reduced in. Otherwise, the reason says which cluster
+// would fall short. Clusters missing from podGoals keep their capacity.
+func checkMinAvailability(
+	contender, incumbent *shipper.CapacityTarget,
+	incumbentTraffic *shipper.TrafficTarget,
+	podGoals map[string]int32,
+	stepTrafficWeights map[string]uint32,
+	minAvailablePercent int32,
+) (bool, string) {
+	contenderAvailable := make(map[string]int32)
+	for _, status := range contender.Status.Clusters {
+		contenderAvailable[status.Name] = status.AvailableReplicas
+	}
+
+	incumbentAvailable := make(map[string]int32)
+	for _, status := range incumbent.Status.Clusters {
+		incumbentAvailable[status.Name] = status.AvailableReplicas
+	}
+
+	trafficReduced := make(map[string]bool)
+	for _, spec := range incumbentTraffic.Spec.Clusters {
+		trafficReduced[spec.Name] = spec.Weight > stepTrafficWeights[spec.Name]
+	}
+
+	clusters := make([]shipper.ClusterCapacityTarget, len(incumbent.Spec.Clusters))
+	copy(clusters, incumbent.Spec.Clusters)
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+
+	for _, spec := range clusters {
+		current := capacityutil.DesiredReplicaCount(spec)
+		goal, ok := podGoals[spec.Name]
+		if !ok {
+			goal = current
+		}
+
+		capacityReduced := current > goal
+		if !capacityReduced && !trafficReduced[spec.Name] {
+			continue
+		}
+
+		available := incumbentAvailable[spec.Name]
+		if capacityReduced {
+			available = minInt32(available, goal)
+		}
+		available += contenderAvailable[spec.Name]
+
+		if needed := podsForPercent(minAvailablePercent, spec.TotalReplicaCount); available < needed {
+			return false, fmt.Sprintf("cluster %q would be left with %d available pods out of the %d needed",
+				spec.Name, available, needed)
+		}
+	}
+
+	return true, ""
+}
+
+// reducesTraffic tells whether moving tt to stepTrafficWeights takes any
+// traffic away from it.
+func reducesTraffic(tt *shipper.TrafficTarget, stepTrafficWeights map[string]uint32) bool {
+	for _, spec := range tt.Spec.Clusters {
+		if spec.Weight > stepTrafficWeights[spec.Name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stepPodGoals returns how many pods ct asks for in each cluster at
+// stepCapacity.
+func stepPodGoals(ct *shipper.CapacityTarget, stepCapacity intstr.IntOrString) map[string]int32 {
+	goals := make(map[string]int32)
+	for _, spec := range ct.Spec.Clusters {
+		goals[spec.Name] = capacityutil.DesiredReplicaCount(clusterCapacityForStep(spec, stepCapacity))
+	}
+
+	return goals
+}
+
+// specPodGoals returns how many pods spec asks for in each cluster.
+func specPodGoals(spec *shipper.CapacityTargetSpec) map[string]int32 {
+	goals := make(map[string]int32)
+	for _, cluster := range spec.Clusters {
+		goals[cluster.Name] = capacityutil.DesiredReplicaCount(cluster)
+	}
+
+	return goals
+}
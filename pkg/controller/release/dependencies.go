@@ -0,0 +1,248 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shipperlisters "github.com/bookingcom/shipper/pkg/client/listers/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	apputil "github.com/bookingcom/shipper/pkg/util/application"
+	releaseutil "github.com/bookingcom/shipper/pkg/util/release"
+)
+
+// checkDependencies returns a Blocked condition for rel if the applications
+// its own one depends on aren't where they need to be for rel to move past
+// its first step, or if they end up depending on rel's application in turn.
+// It returns nil if rel can move on.
+func (c *Controller) checkDependencies(rel *shipper.Release) (*shipper.ReleaseCondition, error) {
+	appName, err := releaseutil.ApplicationNameForRelease(rel)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := c.applicationLister.Applications(rel.Namespace).Get(appName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, shippererrors.NewKubeclientGetError(rel.Namespace, appName, err).
+			WithShipperKind("Application")
+	}
+
+	if len(app.Spec.DependsOn) == 0 {
+		return nil, nil
+	}
+
+	if cycle := findDependencyCycle(c.applicationLister, app); cycle != nil {
+		return releaseutil.NewReleaseCondition(
+			shipper.ReleaseConditionTypeBlocked,
+			corev1.ConditionTrue,
+			shipper.DependencyCycleReason,
+			fmt.Sprintf("application %q ends up depending on itself: %s",
+				cycle[0], strings.Join(cycle, " -> ")),
+		), nil
+	}
+
+	for _, dep := range app.Spec.DependsOn {
+		msg, err := c.checkDependency(app, dep)
+		if err != nil {
+			return nil, err
+		} else if msg != "" {
+			return releaseutil.NewReleaseCondition(
+				shipper.ReleaseConditionTypeBlocked,
+				corev1.ConditionTrue,
+				shipper.WaitingForDependencyReason,
+				msg,
+			), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// checkDependency returns what app is waiting for from dep, or an empty
+// string if nothing.
+func (c *Controller) checkDependency(app *shipper.Application, dep shipper.ApplicationDependency) (string, error) {
+	namespace := dependencyNamespace(app, dep)
+	key := fmt.Sprintf("%s/%s", namespace, dep.Name)
+
+	if _, err := c.applicationLister.Applications(namespace).Get(dep.Name); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("waiting for application %q, which does not exist", key), nil
+		}
+
+		return "", shippererrors.NewKubeclientGetError(namespace, dep.Name, err).
+			WithShipperKind("Application")
+	}
+
+	releases, err := c.releaseLister.Releases(namespace).ReleasesForApplication(dep.Name)
+	if err != nil {
+		return "", err
+	}
+
+	contender, err := apputil.GetContender(dep.Name, releaseutil.SortByGenerationDescending(releases))
+	if err != nil {
+		return fmt.Sprintf("waiting for application %q to have a release", key), nil
+	}
+
+	if dep.Step == nil {
+		if !releaseutil.ReleaseComplete(contender) {
+			return fmt.Sprintf("waiting for release %q of application %q to complete",
+				contender.Name, key), nil
+		}
+	} else if achieved := contender.Status.AchievedStep; achieved == nil || achieved.Step < *dep.Step {
+		return fmt.Sprintf("waiting for release %q of application %q to reach step %d",
+			contender.Name, key, *dep.Step), nil
+	}
+
+	return "", nil
+}
+
+// findDependencyCycle follows the dependencies of app, and returns the keys
+// of the applications on the way back to app if they lead there.
+func findDependencyCycle(lister shipperlisters.ApplicationLister, app *shipper.Application) []string {
+	start := fmt.Sprintf("%s/%s", app.Namespace, app.Name)
+	visited := make(map[string]bool)
+
+	var visit func(app *shipper.Application, path []string) []string
+	visit = func(app *shipper.Application, path []string) []string {
+		for _, dep := range app.Spec.DependsOn {
+			namespace := dependencyNamespace(app, dep)
+			key := fmt.Sprintf("%s/%s", namespace, dep.Name)
+			next := append(append([]string(nil), path...), key)
+
+			if key == start {
+				return next
+			} else if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			depApp, err := lister.Applications(namespace).Get(dep.Name)
+			if err != nil {
+				continue
+			}
+
+			if cycle := visit(depApp, next); cycle != nil {
+				return cycle
+			}
+		}
+
+		return nil
+	}
+
+	return visit(app, []string{start})
+}
+
+func dependencyNamespace(app *shipper.Application, dep shipper.ApplicationDependency) string {
+	if dep.Namespace == "" {
+		return app.Namespace
+	}
+
+	return dep.Namespace
+}
+
+// applicationDependencyIndex indexes applications by the namespace/name keys
+// of the applications they depend on.
+const applicationDependencyIndex = "shipper-application-dependency"
+
+// addApplicationDependencyIndex adds applicationDependencyIndex to informer,
+// unless another controller sharing it has already done so.
+func addApplicationDependencyIndex(informer cache.SharedIndexInformer) {
+	if _, ok := informer.GetIndexer().GetIndexers()[applicationDependencyIndex]; ok {
+		return
+	}
+
+	err := informer.AddIndexers(cache.Indexers{
+		applicationDependencyIndex: applicationDependencyIndexFunc,
+	})
+	if err != nil {
+		klog.Fatalf("Failed to index applications by their dependencies: %s", err)
+	}
+}
+
+func applicationDependencyIndexFunc(obj interface{}) ([]string, error) {
+	app, ok := obj.(*shipper.Application)
+	if !ok {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(app.Spec.DependsOn))
+	for _, dep := range app.Spec.DependsOn {
+		keys = append(keys, fmt.Sprintf("%s/%s", dependencyNamespace(app, dep), dep.Name))
+	}
+
+	return keys, nil
+}
+
+// dependencyProgressChanged tells whether rel went through a change that
+// applications depending on its own one might be waiting for: either it
+// completed, or it achieved another step.
+func dependencyProgressChanged(old, new *shipper.Release) bool {
+	if releaseutil.ReleaseComplete(old) != releaseutil.ReleaseComplete(new) {
+		return true
+	}
+
+	oldStep, newStep := old.Status.AchievedStep, new.Status.AchievedStep
+	if oldStep == nil || newStep == nil {
+		return oldStep != newStep
+	}
+
+	return oldStep.Step != newStep.Step
+}
+
+// enqueueDependentReleases enqueues the latest release of every application
+// that depends on the application rel belongs to, as it might be waiting
+// for rel.
+func (c *Controller) enqueueDependentReleases(rel *shipper.Release) {
+	appName, err := releaseutil.ApplicationNameForRelease(rel)
+	if err != nil {
+		return
+	}
+
+	key := fmt.Sprintf("%s/%s", rel.Namespace, appName)
+	objs, err := c.applicationIndexer.ByIndex(applicationDependencyIndex, key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("error fetching applications depending on %q: %s", key, err))
+		return
+	}
+
+	for _, obj := range objs {
+		if app, ok := obj.(*shipper.Application); ok {
+			c.enqueueApplicationContender(app)
+		}
+	}
+}
+
+// dependenciesChanged tells whether the applications app depends on changed,
+// which is the only change to an application its latest release might be
+// waiting for.
+func dependenciesChanged(old, new *shipper.Application) bool {
+	return !equality.Semantic.DeepEqual(old.Spec.DependsOn, new.Spec.DependsOn)
+}
+
+// enqueueApplicationContender enqueues the latest release of app, which is
+// the only one its dependencies hold back.
+func (c *Controller) enqueueApplicationContender(app *shipper.Application) {
+	releases, err := c.releaseLister.Releases(app.Namespace).ReleasesForApplication(app.Name)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("error fetching releases for application %q: %s", app.Name, err))
+		return
+	}
+
+	contender, err := apputil.GetContender(app.Name, releaseutil.SortByGenerationDescending(releases))
+	if err != nil {
+		return
+	}
+
+	c.enqueueRelease(contender)
+}
@@ -0,0 +1,209 @@
+package release
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shipperlisters "github.com/bookingcom/shipper/pkg/client/listers/shipper/v1alpha1"
+	releaseutil "github.com/bookingcom/shipper/pkg/util/release"
+)
+
+func TestFindDependencyCycle(t *testing.T) {
+	app := func(namespace, name string, deps ...shipper.ApplicationDependency) *shipper.Application {
+		return &shipper.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       shipper.ApplicationSpec{DependsOn: deps},
+		}
+	}
+	dep := func(namespace, name string) shipper.ApplicationDependency {
+		return shipper.ApplicationDependency{Namespace: namespace, Name: name}
+	}
+
+	tests := []struct {
+		name   string
+		apps   []*shipper.Application
+		expect []string
+	}{
+		{
+			"no cycle",
+			[]*shipper.Application{
+				app("web", "frontend", dep("", "api")),
+				app("web", "api", dep("db", "schema")),
+				app("db", "schema"),
+			},
+			nil,
+		},
+		{
+			"missing dependency",
+			[]*shipper.Application{
+				app("web", "frontend", dep("", "api")),
+			},
+			nil,
+		},
+		{
+			"depends on itself",
+			[]*shipper.Application{
+				app("web", "frontend", dep("", "frontend")),
+			},
+			[]string{"web/frontend", "web/frontend"},
+		},
+		{
+			"across namespaces",
+			[]*shipper.Application{
+				app("web", "frontend", dep("", "api")),
+				app("web", "api", dep("db", "schema")),
+				app("db", "schema", dep("web", "frontend")),
+			},
+			[]string{"web/frontend", "web/api", "db/schema", "web/frontend"},
+		},
+		{
+			"cycle elsewhere",
+			[]*shipper.Application{
+				app("web", "frontend", dep("", "api")),
+				app("web", "api", dep("", "auth")),
+				app("web", "auth", dep("", "api")),
+			},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+				cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+			})
+			for _, app := range tt.apps {
+				indexer.Add(app)
+			}
+			lister := shipperlisters.NewApplicationLister(indexer)
+
+			cycle := findDependencyCycle(lister, tt.apps[0])
+			if !reflect.DeepEqual(cycle, tt.expect) {
+				t.Fatalf("expected cycle %v, got %v", tt.expect, cycle)
+			}
+		})
+	}
+}
+
+func TestApplicationDependencyIndex(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		applicationDependencyIndex: applicationDependencyIndexFunc,
+	})
+	for _, app := range []*shipper.Application{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "frontend"},
+			Spec: shipper.ApplicationSpec{DependsOn: []shipper.ApplicationDependency{
+				{Name: "api"},
+				{Namespace: "db", Name: "schema"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "api"},
+			Spec: shipper.ApplicationSpec{DependsOn: []shipper.ApplicationDependency{
+				{Name: "schema"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"},
+		},
+	} {
+		indexer.Add(app)
+	}
+
+	tests := []struct {
+		key    string
+		expect []string
+	}{
+		{"web/api", []string{"web/frontend"}},
+		{"db/schema", []string{"db/api", "web/frontend"}},
+		{"db/api", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			keys, err := indexer.IndexKeys(applicationDependencyIndex, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.expect) {
+				t.Fatalf("expected applications %v to depend on %q, got %v", tt.expect, tt.key, keys)
+			}
+		})
+	}
+}
+
+func TestDependencyProgressChanged(t *testing.T) {
+	release := func(step *int32, complete bool) *shipper.Release {
+		rel := &shipper.Release{}
+		if step != nil {
+			rel.Status.AchievedStep = &shipper.AchievedStep{Step: *step}
+		}
+		if complete {
+			releaseutil.SetReleaseCondition(&rel.Status, *releaseutil.NewReleaseCondition(
+				shipper.ReleaseConditionTypeComplete, corev1.ConditionTrue, "", ""))
+		}
+		return rel
+	}
+	step := func(n int32) *int32 { return &n }
+
+	tests := []struct {
+		name   string
+		old    *shipper.Release
+		new    *shipper.Release
+		expect bool
+	}{
+		{"nothing changed", release(step(1), false), release(step(1), false), false},
+		{"first step achieved", release(nil, false), release(step(0), false), true},
+		{"next step achieved", release(step(0), false), release(step(1), false), true},
+		{"completed", release(step(2), false), release(step(2), true), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := dependencyProgressChanged(tt.old, tt.new); changed != tt.expect {
+				t.Fatalf("expected progress changed to be %t, got %t", tt.expect, changed)
+			}
+		})
+	}
+}
+
+func TestDependenciesChanged(t *testing.T) {
+	app := func(deps ...string) *shipper.Application {
+		app := &shipper.Application{}
+		for _, dep := range deps {
+			app.Spec.DependsOn = append(app.Spec.DependsOn, shipper.ApplicationDependency{Name: dep})
+		}
+		return app
+	}
+
+	withStatus := app("database")
+	withStatus.Status.History = []string{"release-0"}
+
+	tests := []struct {
+		name   string
+		old    *shipper.Application
+		new    *shipper.Application
+		expect bool
+	}{
+		{"no dependencies", app(), app(), false},
+		{"only status changed", app("database"), withStatus, false},
+		{"dependency added", app("database"), app("database", "cache"), true},
+		{"dependencies removed", app("database"), app(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := dependenciesChanged(tt.old, tt.new); changed != tt.expect {
+				t.Fatalf("expected dependencies changed to be %t, got %t", tt.expect, changed)
+			}
+		})
+	}
+}
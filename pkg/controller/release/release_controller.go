@@ -38,7 +38,9 @@ const (
 )
 
 const (
-	ClustersNotReady = "ClustersNotReady"
+	ClustersNotReady          = "ClustersNotReady"
+	InvalidMaxSurge           = "InvalidMaxSurge"
+	WaitingForMinAvailability = "WaitingForMinAvailability"
 )
 
 // Controller is a Kubernetes controller whose role is to pick up a newly created
@@ -49,6 +51,7 @@ type Controller struct {
 	clientset shipperclient.Interface
 
 	applicationLister  shipperlisters.ApplicationLister
+	applicationIndexer cache.Indexer
 	applicationsSynced cache.InformerSynced
 
 	releaseLister  shipperlisters.ReleaseLister
@@ -69,9 +72,13 @@ type Controller struct {
 	rolloutBlockLister shipperlisters.RolloutBlockLister
 	rolloutBlockSynced cache.InformerSynced
 
+	releaseApprovalLister  shipperlisters.ReleaseApprovalLister
+	releaseApprovalsSynced cache.InformerSynced
+
 	releaseWorkqueue workqueue.RateLimitingInterface
 
-	chartFetcher shipperrepo.ChartFetcher
+	chartFetcher  shipperrepo.ChartFetcher
+	chartVerifier shipperrepo.ChartVerifier
 
 	recorder record.EventRecorder
 }
@@ -93,6 +100,7 @@ func NewController(
 	clientset shipperclient.Interface,
 	informerFactory shipperinformers.SharedInformerFactory,
 	chartFetcher shipperrepo.ChartFetcher,
+	chartVerifier shipperrepo.ChartVerifier,
 	recorder record.EventRecorder,
 ) *Controller {
 
@@ -103,13 +111,17 @@ func NewController(
 	trafficTargetInformer := informerFactory.Shipper().V1alpha1().TrafficTargets()
 	capacityTargetInformer := informerFactory.Shipper().V1alpha1().CapacityTargets()
 	rolloutBlockInformer := informerFactory.Shipper().V1alpha1().RolloutBlocks()
+	releaseApprovalInformer := informerFactory.Shipper().V1alpha1().ReleaseApprovals()
 
 	klog.Info("Building a release controller")
 
+	addApplicationDependencyIndex(applicationInformer.Informer())
+
 	controller := &Controller{
 		clientset: clientset,
 
 		applicationLister:  applicationInformer.Lister(),
+		applicationIndexer: applicationInformer.Informer().GetIndexer(),
 		applicationsSynced: applicationInformer.Informer().HasSynced,
 
 		releaseLister:  releaseInformer.Lister(),
@@ -130,12 +142,16 @@ func NewController(
 		rolloutBlockLister: rolloutBlockInformer.Lister(),
 		rolloutBlockSynced: rolloutBlockInformer.Informer().HasSynced,
 
+		releaseApprovalLister:  releaseApprovalInformer.Lister(),
+		releaseApprovalsSynced: releaseApprovalInformer.Informer().HasSynced,
+
 		releaseWorkqueue: workqueue.NewNamedRateLimitingQueue(
 			shipperworkqueue.NewDefaultControllerRateLimiter(),
 			"release_controller_releases",
 		),
 
-		chartFetcher: chartFetcher,
+		chartFetcher:  chartFetcher,
+		chartVerifier: chartVerifier,
 
 		recorder: recorder,
 	}
@@ -151,11 +167,46 @@ func NewController(
 			DeleteFunc: controller.enqueueReleaseAndNeighbours,
 		})
 
+	releaseInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldRel, oldOk := oldObj.(*shipper.Release)
+				newRel, newOk := newObj.(*shipper.Release)
+				if oldOk && newOk && dependencyProgressChanged(oldRel, newRel) {
+					controller.enqueueDependentReleases(newRel)
+				}
+			},
+		})
+
+	applicationInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldApp, oldOk := oldObj.(*shipper.Application)
+				newApp, newOk := newObj.(*shipper.Application)
+				if oldOk && newOk && dependenciesChanged(oldApp, newApp) {
+					controller.enqueueApplicationContender(newApp)
+				}
+			},
+		})
+
 	rolloutBlockInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if rolloutblock.ActivityChanged(oldObj, newObj) {
+					controller.enqueueReleaseFromRolloutBlock(newObj)
+				}
+			},
 			DeleteFunc: controller.enqueueReleaseFromRolloutBlock,
 		})
 
+	releaseApprovalInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: controller.enqueueReleaseFromReleaseApproval,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				controller.enqueueReleaseFromReleaseApproval(newObj)
+			},
+		})
+
 	eventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: controller.enqueueReleaseFromAssociatedObject,
 		UpdateFunc: func(oldObj, newObj interface{}) {
@@ -188,6 +239,7 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 		c.trafficTargetsSynced,
 		c.capacityTargetsSynced,
 		c.rolloutBlockSynced,
+		c.releaseApprovalsSynced,
 	); !ok {
 		runtime.HandleError(fmt.Errorf("failed to wait for caches to sync"))
 		return
@@ -298,6 +350,7 @@ func (c *Controller) syncOneReleaseHandler(key string) error {
 		c.trafficTargetLister,
 		c.rolloutBlockLister,
 		c.chartFetcher,
+		c.chartVerifier,
 		c.recorder,
 	)
 
@@ -323,13 +376,18 @@ func (c *Controller) syncOneReleaseHandler(key string) error {
 		goto ApplyChanges
 	}
 
-	condition = releaseutil.NewReleaseCondition(
-		shipper.ReleaseConditionTypeBlocked,
-		corev1.ConditionFalse,
-		"",
-		"",
-	)
-	diff.Append(releaseutil.SetReleaseCondition(&rel.Status, *condition))
+	// Releases held back by their strategy or their dependencies stay
+	// blocked until the strategy is executed and finds out whether they
+	// still are.
+	if !isHeldBack(rel) {
+		condition = releaseutil.NewReleaseCondition(
+			shipper.ReleaseConditionTypeBlocked,
+			corev1.ConditionFalse,
+			"",
+			"",
+		)
+		diff.Append(releaseutil.SetReleaseCondition(&rel.Status, *condition))
+	}
 
 	relinfo, err = scheduler.ScheduleRelease(rel.DeepCopy())
 	if err != nil {
@@ -460,6 +518,72 @@ func (c *Controller) executeReleaseStrategy(relinfo *releaseInfo, diff *diffutil
 		return nil, nil, shippererrors.NewUnrecoverableError(err)
 	}
 
+	if err := validateStrategy(strategy); err != nil {
+		err = fmt.Errorf("invalid strategy for Release %q: %s",
+			controller.MetaKey(rel), err)
+		return nil, nil, shippererrors.NewUnrecoverableError(err)
+	}
+
+	head := rel
+	if !isHead {
+		head = succ
+	}
+	releaseApprovals, err := c.releaseApprovalLister.ReleaseApprovals(head.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, nil, shippererrors.NewKubeclientListError(
+			shipper.SchemeGroupVersion.WithKind("ReleaseApproval"),
+			head.Namespace, labels.Everything(), err)
+	}
+
+	// Only the head release is held back, any other release just
+	// follows it.
+	blockedCond := releaseutil.NewReleaseCondition(
+		shipper.ReleaseConditionTypeBlocked,
+		corev1.ConditionFalse,
+		"",
+		"",
+	)
+
+	approvals, approvedStep, waiting := approveSteps(head, strategy, targetStep, releaseApprovals)
+	if isHead {
+		rel.Status.Approvals = approvals
+	}
+	if waiting {
+		targetStep = approvedStep
+		blockedCond = releaseutil.NewReleaseCondition(
+			shipper.ReleaseConditionTypeBlocked,
+			corev1.ConditionTrue,
+			shipper.WaitingForApprovalReason,
+			fmt.Sprintf(
+				"step %d (%q) requires approval: waiting for a ReleaseApproval for release %q and step %d",
+				approvedStep+1, strategy.Steps[approvedStep+1].Name, head.Name, approvedStep+1),
+		)
+	}
+
+	// Dependencies only hold releases back before they get going, so a
+	// dependency starting a rollout of its own doesn't undo one that is
+	// already under way.
+	if achieved := head.Status.AchievedStep; achieved == nil || achieved.Step == 0 {
+		dependencyCond, err := c.checkDependencies(head)
+		if err != nil {
+			return nil, nil, err
+		} else if dependencyCond != nil {
+			targetStep = 0
+			blockedCond = dependencyCond
+		}
+	}
+
+	if isHead {
+		diff.Append(releaseutil.SetReleaseCondition(&rel.Status, *blockedCond))
+	} else if isHeldBack(rel) {
+		diff.Append(releaseutil.SetReleaseCondition(&rel.Status, *releaseutil.NewReleaseCondition(
+			shipper.ReleaseConditionTypeBlocked,
+			corev1.ConditionFalse,
+			"",
+			"",
+		)))
+	}
+
 	executor := NewStrategyExecutor(strategy, targetStep)
 
 	complete, patches, trans := executor.Execute(relinfoPrev, relinfo, relinfoSucc)
@@ -693,6 +817,42 @@ func (c *Controller) enqueueReleaseFromRolloutBlock(obj interface{}) {
 	}
 }
 
+// isHeldBack tells whether rel is blocked by something other than a rollout
+// block: either a step that requires approval, or its dependencies.
+func isHeldBack(rel *shipper.Release) bool {
+	cond := releaseutil.GetReleaseCondition(rel.Status, shipper.ReleaseConditionTypeBlocked)
+	if cond == nil || cond.Status != corev1.ConditionTrue {
+		return false
+	}
+
+	switch cond.Reason {
+	case shipper.WaitingForApprovalReason,
+		shipper.WaitingForDependencyReason,
+		shipper.DependencyCycleReason:
+		return true
+	}
+
+	return false
+}
+
+func (c *Controller) enqueueReleaseFromReleaseApproval(obj interface{}) {
+	approval, ok := obj.(*shipper.ReleaseApproval)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("not a shipper.ReleaseApproval: %#v", obj))
+		return
+	}
+
+	rel, err := c.releaseLister.Releases(approval.Namespace).Get(approval.Spec.Release)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			runtime.HandleError(err)
+		}
+		return
+	}
+
+	c.enqueueReleaseAndNeighbours(rel)
+}
+
 func (c *Controller) enqueueReleaseFromAssociatedObject(obj interface{}) {
 	kubeobj, ok := obj.(metav1.Object)
 	if !ok {
@@ -738,6 +898,10 @@ func reasonForReleaseCondition(err error) string {
 		return "RolloutBlock"
 	case shippererrors.ChartRepoInternalError:
 		return "ChartRepoInternal"
+	case shippererrors.ChartVerificationError:
+		return "UnverifiedChart"
+	case shippererrors.ChartRepoUnauthorizedError:
+		return conditions.ChartRepoUnauthorized
 	case shippererrors.NoCachedChartRepoIndexError:
 		return "NoCachedChartRepoIndex"
 	}
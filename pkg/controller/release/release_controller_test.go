@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
@@ -39,18 +40,18 @@ var vanguard = shipper.RolloutStrategy{
 	Steps: []shipper.RolloutStrategyStep{
 		{
 			Name:     "staging",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 1},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 0},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(1)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(0)},
 		},
 		{
 			Name:     "50/50",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 50, Contender: 50},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 50, Contender: 50},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(50), Contender: intstr.FromInt(50)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(50), Contender: intstr.FromInt(50)},
 		},
 		{
 			Name:     "full on",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
 		},
 	},
 }
@@ -59,8 +60,8 @@ var fullon = shipper.RolloutStrategy{
 	Steps: []shipper.RolloutStrategyStep{
 		{
 			Name:     "full on",
-			Capacity: shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
-			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
+			Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+			Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
 		},
 	},
 }
@@ -226,6 +227,7 @@ func (f *fixture) newController() *Controller {
 		f.clientset,
 		f.informerFactory,
 		localFetchChart,
+		localVerifyChart,
 		f.recorder,
 	)
 }
@@ -1720,6 +1722,189 @@ func TestContenderCapacityShouldIncrease(t *testing.T) {
 	f.run()
 }
 
+func TestContenderCapacityShouldIncreaseToReplicas(t *testing.T) {
+	namespace := "test-namespace"
+	incumbentName, contenderName := "test-incumbent", "test-contender"
+	app := buildApplication(namespace, "test-app")
+	cluster := buildCluster("minikube")
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(200)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+	incumbent := f.buildIncumbent(namespace, incumbentName, totalReplicaCount)
+
+	strategy := vanguard.DeepCopy()
+	strategy.Steps[0].Capacity.Contender = intstr.FromString("1")
+	contender.release.Spec.Environment.Strategy = strategy
+
+	f.addObjects(
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+
+		incumbent.release.DeepCopy(),
+		incumbent.installationTarget.DeepCopy(),
+		incumbent.capacityTarget.DeepCopy(),
+		incumbent.trafficTarget.DeepCopy(),
+	)
+
+	f.filter = f.filter.Extend(actionfilter{
+		[]string{"patch"},
+		[]string{"releases", "capacitytargets"},
+	})
+
+	// Exactly one canary pod, where 1% of 200 would have been 2.
+	ct := contender.capacityTarget
+	replicas := int32(1)
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": shipper.CapacityTargetSpec{
+			Clusters: []shipper.ClusterCapacityTarget{
+				{Name: "minikube", Percent: 1, TotalReplicaCount: totalReplicaCount, Replicas: &replicas},
+			},
+		},
+	})
+	f.actions = append(f.actions, kubetesting.NewPatchAction(
+		shipper.SchemeGroupVersion.WithResource("capacitytargets"),
+		ct.GetNamespace(), ct.GetName(), types.MergePatchType, patch))
+
+	step := contender.release.Spec.TargetStep
+	strategyConditions := conditions.NewStrategyConditions(
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedInstallation,
+			Status: corev1.ConditionTrue,
+			Step:   step,
+		},
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedCapacity,
+			Status: corev1.ConditionFalse,
+			Step:   step,
+			Reason: ClustersNotReady,
+			Message: fmt.Sprintf(
+				"release %q hasn't achieved capacity in clusters: [minikube]. for more details try `kubectl describe ct %s`",
+				contenderName, contenderName),
+		},
+	)
+	patch, _ = json.Marshal(map[string]interface{}{
+		"status": shipper.ReleaseStatus{
+			Strategy: &shipper.ReleaseStrategyStatus{
+				Conditions: strategyConditions.AsReleaseStrategyConditions(),
+				State:      strategyConditions.AsReleaseStrategyState(step, true, false, true),
+			},
+		},
+	})
+	f.actions = append(f.actions, kubetesting.NewPatchAction(
+		shipper.SchemeGroupVersion.WithResource("releases"),
+		namespace, contenderName, types.MergePatchType, patch))
+
+	f.expectedEvents = []string{
+		"Normal ReleaseConditionChanged [] -> [Scheduled True], [] -> [StrategyExecuted True]",
+	}
+
+	f.run()
+}
+
+func TestContenderCapacityShouldInterleaveWithIncumbent(t *testing.T) {
+	namespace := "test-namespace"
+	incumbentName, contenderName := "test-incumbent", "test-contender"
+	app := buildApplication(namespace, "test-app")
+	cluster := buildCluster("minikube")
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(10)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+	incumbent := f.buildIncumbent(namespace, incumbentName, totalReplicaCount)
+
+	maxSurge := intstr.FromInt(2)
+	strategy := vanguard.DeepCopy()
+	strategy.CapacitySequencing = shipper.InterleavedCapacitySequencing
+	strategy.MaxSurge = &maxSurge
+	contender.release.Spec.Environment.Strategy = strategy
+	contender.release.Spec.TargetStep = 1
+	contender.capacityTarget.Spec.Clusters[0].Percent = 1
+
+	f.addObjects(
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+
+		incumbent.release.DeepCopy(),
+		incumbent.installationTarget.DeepCopy(),
+		incumbent.capacityTarget.DeepCopy(),
+		incumbent.trafficTarget.DeepCopy(),
+	)
+
+	f.filter = f.filter.Extend(actionfilter{
+		[]string{"patch"},
+		[]string{"releases", "capacitytargets"},
+	})
+
+	// With room for 2 more pods than the 11 already running, the
+	// contender only gets to grow to 2 of its 5 and the incumbent is
+	// left alone until then.
+	ct := contender.capacityTarget
+	contenderReplicas := int32(2)
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": shipper.CapacityTargetSpec{
+			Clusters: []shipper.ClusterCapacityTarget{
+				{Name: "minikube", Percent: 20, TotalReplicaCount: totalReplicaCount, Replicas: &contenderReplicas},
+			},
+		},
+	})
+	f.actions = append(f.actions, kubetesting.NewPatchAction(
+		shipper.SchemeGroupVersion.WithResource("capacitytargets"),
+		ct.GetNamespace(), ct.GetName(), types.MergePatchType, patch))
+
+	step := contender.release.Spec.TargetStep
+	strategyConditions := conditions.NewStrategyConditions(
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedInstallation,
+			Status: corev1.ConditionTrue,
+			Step:   step,
+		},
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedCapacity,
+			Status: corev1.ConditionFalse,
+			Step:   step,
+			Reason: ClustersNotReady,
+			Message: fmt.Sprintf(
+				"release %q hasn't achieved capacity in clusters: [minikube]. for more details try `kubectl describe ct %s`",
+				contenderName, contenderName),
+		},
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionIncumbentAchievedCapacity,
+			Status: corev1.ConditionFalse,
+			Step:   step,
+			Reason: ClustersNotReady,
+			Message: fmt.Sprintf(
+				"release %q hasn't achieved capacity in clusters: [minikube]. for more details try `kubectl describe ct %s`",
+				incumbentName, incumbentName),
+		},
+	)
+	patch, _ = json.Marshal(map[string]interface{}{
+		"status": shipper.ReleaseStatus{
+			Strategy: &shipper.ReleaseStrategyStatus{
+				Conditions: strategyConditions.AsReleaseStrategyConditions(),
+				State:      strategyConditions.AsReleaseStrategyState(step, true, false, true),
+			},
+		},
+	})
+	f.actions = append(f.actions, kubetesting.NewPatchAction(
+		shipper.SchemeGroupVersion.WithResource("releases"),
+		namespace, contenderName, types.MergePatchType, patch))
+
+	f.expectedEvents = []string{
+		"Normal ReleaseConditionChanged [] -> [Scheduled True], [] -> [StrategyExecuted True]",
+	}
+
+	f.run()
+}
+
 func TestContenderCapacityShouldIncreaseWithRolloutBlockOverride(t *testing.T) {
 	namespace := "test-namespace"
 	incumbentName, contenderName := "test-incumbent", "test-contender"
@@ -1809,6 +1994,201 @@ func TestContenderCapacityShouldNotIncreaseWithRolloutBlock(t *testing.T) {
 	f.run()
 }
 
+func TestContenderCapacityShouldNotIncreaseWithoutApproval(t *testing.T) {
+	namespace := "test-namespace"
+	contenderName := "test-contender"
+	app := buildApplication(namespace, "test-app")
+	cluster := buildCluster("minikube")
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(3)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+
+	strategy := vanguard.DeepCopy()
+	strategy.Steps[1].RequiresApproval = true
+	contender.release.Spec.Environment.Strategy = strategy
+	contender.release.Spec.TargetStep = 1
+
+	f.addObjects(
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+	)
+
+	f.filter = f.filter.Extend(actionfilter{
+		[]string{"update", "patch"},
+		[]string{"releases", "capacitytargets"},
+	})
+
+	expectedContender := contender.release.DeepCopy()
+	message := fmt.Sprintf(
+		"step 1 (%q) requires approval: waiting for a ReleaseApproval for release %q and step 1",
+		strategy.Steps[1].Name, contenderName)
+	for _, cond := range []*shipper.ReleaseCondition{
+		releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeScheduled, corev1.ConditionTrue, "", ""),
+		releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeStrategyExecuted, corev1.ConditionTrue, "", ""),
+		releaseutil.NewReleaseCondition(
+			shipper.ReleaseConditionTypeBlocked,
+			corev1.ConditionTrue,
+			shipper.WaitingForApprovalReason,
+			message),
+	} {
+		releaseutil.SetReleaseCondition(&expectedContender.Status, *cond)
+	}
+
+	f.actions = append(f.actions, kubetesting.NewUpdateAction(
+		shipper.SchemeGroupVersion.WithResource("releases"),
+		namespace,
+		expectedContender))
+
+	// The contender is held at step 0 rather than moving on to step 1.
+	ct := contender.capacityTarget.DeepCopy()
+	r := contender.release.DeepCopy()
+	r.Spec.TargetStep = 0
+	f.expectCapacityStatusPatch(0, ct, r, 1, uint(totalReplicaCount), Contender)
+
+	f.expectedEvents = []string{
+		fmt.Sprintf("Normal ReleaseConditionChanged [] -> [Scheduled True], [Blocked False] -> [Blocked True WaitingForApproval %s], [] -> [StrategyExecuted True]", message),
+	}
+	f.run()
+}
+
+func TestContenderCapacityShouldIncreaseWithApproval(t *testing.T) {
+	namespace := "test-namespace"
+	contenderName := "test-contender"
+	app := buildApplication(namespace, "test-app")
+	cluster := buildCluster("minikube")
+
+	approval := &shipper.ReleaseApproval{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-approval",
+			Namespace: namespace,
+		},
+		Spec: shipper.ReleaseApprovalSpec{
+			Release:  contenderName,
+			Step:     1,
+			Approver: "jane",
+		},
+	}
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy(), approval.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(10)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+
+	strategy := vanguard.DeepCopy()
+	strategy.Steps[1].RequiresApproval = true
+	contender.release.Spec.Environment.Strategy = strategy
+	contender.release.Spec.TargetStep = 1
+
+	f.addObjects(
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+	)
+
+	f.filter = f.filter.Extend(actionfilter{
+		[]string{"update", "patch"},
+		[]string{"releases", "capacitytargets"},
+	})
+
+	expectedContender := contender.release.DeepCopy()
+	expectedContender.Status.Approvals = []shipper.StepApproval{
+		{
+			Step:     1,
+			Name:     strategy.Steps[1].Name,
+			Approver: "jane",
+			Approval: "test-approval",
+		},
+	}
+	for _, cond := range []*shipper.ReleaseCondition{
+		releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeScheduled, corev1.ConditionTrue, "", ""),
+		releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeStrategyExecuted, corev1.ConditionTrue, "", ""),
+	} {
+		releaseutil.SetReleaseCondition(&expectedContender.Status, *cond)
+	}
+
+	f.actions = append(f.actions, kubetesting.NewUpdateAction(
+		shipper.SchemeGroupVersion.WithResource("releases"),
+		namespace,
+		expectedContender))
+
+	ct := contender.capacityTarget.DeepCopy()
+	r := contender.release.DeepCopy()
+	f.expectCapacityStatusPatch(1, ct, r, 50, uint(totalReplicaCount), Contender)
+
+	f.expectedEvents = []string{
+		"Normal ReleaseConditionChanged [] -> [Scheduled True], [] -> [StrategyExecuted True]",
+	}
+	f.run()
+}
+
+func TestContenderCapacityShouldNotIncreaseBeforeDependency(t *testing.T) {
+	namespace := "test-namespace"
+	contenderName := "test-contender"
+	app := buildApplication(namespace, "test-app")
+	app.Spec.DependsOn = []shipper.ApplicationDependency{{Name: "test-api"}}
+	api := buildApplication(namespace, "test-api")
+	cluster := buildCluster("minikube")
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(3)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+	contender.release.Spec.TargetStep = 1
+
+	f.addObjects(
+		api.DeepCopy(),
+
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+	)
+
+	f.filter = f.filter.Extend(actionfilter{
+		[]string{"update", "patch"},
+		[]string{"releases", "capacitytargets"},
+	})
+
+	expectedContender := contender.release.DeepCopy()
+	message := fmt.Sprintf("waiting for application \"%s/test-api\" to have a release", namespace)
+	for _, cond := range []*shipper.ReleaseCondition{
+		releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeScheduled, corev1.ConditionTrue, "", ""),
+		releaseutil.NewReleaseCondition(shipper.ReleaseConditionTypeStrategyExecuted, corev1.ConditionTrue, "", ""),
+		releaseutil.NewReleaseCondition(
+			shipper.ReleaseConditionTypeBlocked,
+			corev1.ConditionTrue,
+			shipper.WaitingForDependencyReason,
+			message),
+	} {
+		releaseutil.SetReleaseCondition(&expectedContender.Status, *cond)
+	}
+
+	f.actions = append(f.actions, kubetesting.NewUpdateAction(
+		shipper.SchemeGroupVersion.WithResource("releases"),
+		namespace,
+		expectedContender))
+
+	// The contender is held at step 0 until its dependency has a
+	// complete release.
+	ct := contender.capacityTarget.DeepCopy()
+	r := contender.release.DeepCopy()
+	r.Spec.TargetStep = 0
+	f.expectCapacityStatusPatch(0, ct, r, 1, uint(totalReplicaCount), Contender)
+
+	f.expectedEvents = []string{
+		fmt.Sprintf("Normal ReleaseConditionChanged [] -> [Scheduled True], [Blocked False] -> [Blocked True WaitingForDependency %s], [] -> [StrategyExecuted True]", message),
+	}
+	f.run()
+}
+
 func TestContenderTrafficShouldIncrease(t *testing.T) {
 	namespace := "test-namespace"
 	incumbentName, contenderName := "test-incumbent", "test-contender"
@@ -1977,6 +2357,152 @@ func TestIncumbentTrafficShouldDecrease(t *testing.T) {
 	f.run()
 }
 
+func TestIncumbentTrafficShouldDecreaseWithinMinAvailability(t *testing.T) {
+	namespace := "test-namespace"
+	incumbentName, contenderName := "test-incumbent", "test-contender"
+	app := buildApplication(namespace, "test-app")
+	cluster := buildCluster("minikube")
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(10)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+	incumbent := f.buildIncumbent(namespace, incumbentName, totalReplicaCount)
+
+	minAvailablePercent := int32(80)
+	strategy := vanguard.DeepCopy()
+	strategy.MinAvailablePercent = &minAvailablePercent
+	contender.release.Spec.Environment.Strategy = strategy
+	contender.release.Spec.TargetStep = 1
+	contender.capacityTarget.Spec.Clusters[0].Percent = 50
+	contender.capacityTarget.Spec.Clusters[0].TotalReplicaCount = totalReplicaCount
+	contender.trafficTarget.Spec.Clusters[0].Weight = 50
+	contender.release.Status.AchievedStep = &shipper.AchievedStep{Step: 1}
+
+	// 5 pods from the contender and the 5 the incumbent is going down
+	// to are more than the 8 needed.
+	contender.capacityTarget.Status.Clusters = []shipper.ClusterCapacityStatus{
+		{Name: "minikube", AvailableReplicas: 5},
+	}
+	incumbent.capacityTarget.Status.Clusters = []shipper.ClusterCapacityStatus{
+		{Name: "minikube", AvailableReplicas: totalReplicaCount},
+	}
+
+	f.addObjects(
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+
+		incumbent.release.DeepCopy(),
+		incumbent.installationTarget.DeepCopy(),
+		incumbent.capacityTarget.DeepCopy(),
+		incumbent.trafficTarget.DeepCopy(),
+	)
+
+	tt := incumbent.trafficTarget.DeepCopy()
+	r := contender.release.DeepCopy()
+	f.expectTrafficStatusPatch(contender.release.Spec.TargetStep, tt, r, 50, Incumbent)
+	f.run()
+}
+
+func TestIncumbentShouldNotDecreaseBelowMinAvailability(t *testing.T) {
+	namespace := "test-namespace"
+	incumbentName, contenderName := "test-incumbent", "test-contender"
+	app := buildApplication(namespace, "test-app")
+	cluster := buildCluster("minikube")
+
+	f := newFixture(t, app.DeepCopy(), cluster.DeepCopy())
+	f.cycles = 1
+
+	totalReplicaCount := int32(10)
+	contender := f.buildContender(namespace, contenderName, totalReplicaCount)
+	incumbent := f.buildIncumbent(namespace, incumbentName, totalReplicaCount)
+
+	minAvailablePercent := int32(80)
+	strategy := vanguard.DeepCopy()
+	strategy.MinAvailablePercent = &minAvailablePercent
+	contender.release.Spec.Environment.Strategy = strategy
+	contender.release.Spec.TargetStep = 1
+	contender.capacityTarget.Spec.Clusters[0].Percent = 50
+	contender.capacityTarget.Spec.Clusters[0].TotalReplicaCount = totalReplicaCount
+	contender.trafficTarget.Spec.Clusters[0].Weight = 50
+	contender.release.Status.AchievedStep = &shipper.AchievedStep{Step: 1}
+
+	// The contender is flapping, and only 2 of its pods are available,
+	// which along with the 5 the incumbent is going down to falls
+	// short of the 8 needed.
+	contender.capacityTarget.Status.Clusters = []shipper.ClusterCapacityStatus{
+		{Name: "minikube", AvailableReplicas: 2},
+	}
+	incumbent.capacityTarget.Status.Clusters = []shipper.ClusterCapacityStatus{
+		{Name: "minikube", AvailableReplicas: totalReplicaCount},
+	}
+
+	f.addObjects(
+		contender.release.DeepCopy(),
+		contender.installationTarget.DeepCopy(),
+		contender.capacityTarget.DeepCopy(),
+		contender.trafficTarget.DeepCopy(),
+
+		incumbent.release.DeepCopy(),
+		incumbent.installationTarget.DeepCopy(),
+		incumbent.capacityTarget.DeepCopy(),
+		incumbent.trafficTarget.DeepCopy(),
+	)
+
+	f.filter = f.filter.Extend(actionfilter{
+		[]string{"patch"},
+		[]string{"releases", "capacitytargets", "traffictargets"},
+	})
+
+	step := contender.release.Spec.TargetStep
+	strategyConditions := conditions.NewStrategyConditions(
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedInstallation,
+			Status: corev1.ConditionTrue,
+			Step:   step,
+		},
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedCapacity,
+			Status: corev1.ConditionTrue,
+			Step:   step,
+		},
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionContenderAchievedTraffic,
+			Status: corev1.ConditionTrue,
+			Step:   step,
+		},
+		shipper.ReleaseStrategyCondition{
+			Type:   shipper.StrategyConditionIncumbentAchievedTraffic,
+			Status: corev1.ConditionFalse,
+			Step:   step,
+			Reason: WaitingForMinAvailability,
+			Message: fmt.Sprintf(
+				"release %q is not being reduced any further: cluster %q would be left with 7 available pods out of the 8 needed to keep 80%% of the replica count available",
+				incumbentName, "minikube"),
+		},
+	)
+	patch, _ := json.Marshal(map[string]interface{}{
+		"status": shipper.ReleaseStatus{
+			Strategy: &shipper.ReleaseStrategyStatus{
+				Conditions: strategyConditions.AsReleaseStrategyConditions(),
+				State:      strategyConditions.AsReleaseStrategyState(step, true, false, true),
+			},
+		},
+	})
+	f.actions = append(f.actions, kubetesting.NewPatchAction(
+		shipper.SchemeGroupVersion.WithResource("releases"),
+		namespace, contenderName, types.MergePatchType, patch))
+
+	f.expectedEvents = []string{
+		"Normal ReleaseConditionChanged [] -> [Scheduled True], [] -> [StrategyExecuted True]",
+	}
+
+	f.run()
+}
+
 func TestIncumbentTrafficShouldDecreaseWithRolloutBlockOverride(t *testing.T) {
 	namespace := "test-namespace"
 	incumbentName, contenderName := "test-incumbent", "test-contender"
@@ -2295,13 +2821,13 @@ func TestApplicationExposesStrategyFailureIndexOutOfBounds(t *testing.T) {
 		Steps: []shipper.RolloutStrategyStep{
 			{
 				Name:     "staging",
-				Capacity: shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 1},
-				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 0},
+				Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(1)},
+				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(0)},
 			},
 			{
 				Name:     "full on",
-				Capacity: shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
-				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
+				Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
 			},
 		},
 	}
@@ -2375,13 +2901,13 @@ func TestApplicationExposesStrategyFailureSuccessorIndexOutOfBounds(t *testing.T
 		Steps: []shipper.RolloutStrategyStep{
 			{
 				Name:     "staging",
-				Capacity: shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 1},
-				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 100, Contender: 0},
+				Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(1)},
+				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(0)},
 			},
 			{
 				Name:     "full on",
-				Capacity: shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
-				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: 0, Contender: 100},
+				Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
 			},
 		},
 	}
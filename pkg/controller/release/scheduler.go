@@ -32,7 +32,8 @@ type Scheduler struct {
 	capacityTargetLister     listers.CapacityTargetLister
 	rolloutBlockLister       listers.RolloutBlockLister
 
-	chartFetcher shipperrepo.ChartFetcher
+	chartFetcher  shipperrepo.ChartFetcher
+	chartVerifier shipperrepo.ChartVerifier
 
 	recorder record.EventRecorder
 }
@@ -45,6 +46,7 @@ func NewScheduler(
 	trafficTargetLister listers.TrafficTargetLister,
 	rolloutBlockLister listers.RolloutBlockLister,
 	chartFetcher shipperrepo.ChartFetcher,
+	chartVerifier shipperrepo.ChartVerifier,
 	recorder record.EventRecorder,
 ) *Scheduler {
 	return &Scheduler{
@@ -56,7 +58,8 @@ func NewScheduler(
 		capacityTargetLister:     capacityTargetLister,
 		rolloutBlockLister:       rolloutBlockLister,
 
-		chartFetcher: chartFetcher,
+		chartFetcher:  chartFetcher,
+		chartVerifier: chartVerifier,
 
 		recorder: recorder,
 	}
@@ -107,6 +110,10 @@ func (s *Scheduler) ScheduleRelease(rel *shipper.Release) (*releaseInfo, error)
 		)
 	}
 
+	if err := s.verifyChart(rel); err != nil {
+		return nil, err
+	}
+
 	replicaCount, err := s.fetchChartAndExtractReplicaCount(rel)
 	if err != nil {
 		return nil, err
@@ -141,6 +148,29 @@ func (s *Scheduler) ScheduleRelease(rel *shipper.Release) (*releaseInfo, error)
 	}, nil
 }
 
+// verifyChart checks the provenance of the release's chart before anything
+// gets created for it, so that releases with charts that can't be trusted
+// don't go any further. Once the installation target exists we don't go
+// through it again on every sync: the installation controller verifies the
+// chart it renders by itself.
+func (s *Scheduler) verifyChart(rel *shipper.Release) error {
+	_, err := s.installationTargetLister.InstallationTargets(rel.Namespace).Get(rel.Name)
+	if err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return shippererrors.NewKubeclientGetError(rel.Namespace, rel.Name, err).
+			WithShipperKind("InstallationTarget")
+	}
+
+	if err := s.chartVerifier(&rel.Spec.Environment.Chart, rel.Namespace); err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("Verified chart for release %q", controller.MetaKey(rel))
+
+	return nil
+}
+
 func releaseHasClusters(rel *shipper.Release) bool {
 	return len(rel.Annotations[shipper.ReleaseClustersAnnotation]) > 0
 }
@@ -263,9 +293,15 @@ func (s *Scheduler) CreateOrUpdateInstallationTarget(rel *shipper.Release) (*shi
 			Spec: shipper.InstallationTargetSpec{
 				Chart:       rel.Spec.Environment.Chart.DeepCopy(),
 				Values:      rel.Spec.Environment.Values,
+				Overrides:   rel.Spec.Environment.Overrides,
 				CanOverride: true,
 			},
 		}
+		if len(rel.Spec.Environment.ValuesFrom) > 0 {
+			it.Spec.ValuesSecretRef = &corev1.LocalObjectReference{
+				Name: releaseutil.ValuesSecretName(rel.Namespace, rel.Name),
+			}
+		}
 		setInstallationTargetClusters(it, clusters)
 
 		updIt, err := s.clientset.ShipperV1alpha1().InstallationTargets(rel.GetNamespace()).Create(it)
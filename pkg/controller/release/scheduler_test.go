@@ -44,6 +44,10 @@ var localFetchChart = func(chartspec *shipper.Chart) (*helmchart.Chart, error) {
 	return chartutil.LoadArchive(buf)
 }
 
+var localVerifyChart = func(chartspec *shipper.Chart, namespace string) error {
+	return nil
+}
+
 func buildRelease() *shipper.Release {
 	return &shipper.Release{
 		TypeMeta: metav1.TypeMeta{
@@ -210,6 +214,7 @@ func newScheduler(
 		trafficTargetLister,
 		rolloutBlockLister,
 		localFetchChart,
+		localVerifyChart,
 		record.NewFakeRecorder(42))
 
 	stopCh := make(chan struct{})
@@ -438,6 +443,54 @@ func TestCreateAssociatedObjects(t *testing.T) {
 	shippertesting.CheckActions(expectedActions, filteredActions, t)
 }
 
+// TestCreateAssociatedObjectsUnverifiedChart checks that nothing gets
+// installed for a release whose chart fails verification.
+func TestCreateAssociatedObjectsUnverifiedChart(t *testing.T) {
+	cluster := buildCluster("minikube-a")
+	release := buildRelease()
+	release.Annotations[shipper.ReleaseClustersAnnotation] = cluster.GetName()
+	fixtures := []runtime.Object{release, cluster}
+
+	c, clientset := newScheduler(fixtures)
+	c.chartVerifier = func(chartspec *shipper.Chart, namespace string) error {
+		return shippererrors.NewChartVerificationError(chartspec, fmt.Errorf("signed by an unknown key"))
+	}
+
+	_, err := c.ScheduleRelease(release.DeepCopy())
+	if !shippererrors.IsChartVerificationError(err) {
+		t.Fatalf("expected a chart verification error, got %v", err)
+	}
+
+	filteredActions := filterActions(
+		clientset.Actions(),
+		[]string{"update", "create"},
+		[]string{"installationtargets", "traffictargets", "capacitytargets"},
+	)
+	shippertesting.CheckActions([]kubetesting.Action{}, filteredActions, t)
+}
+
+// TestCreateAssociatedObjectsSkipsVerificationForExistingInstallationTarget
+// checks that charts are only verified before being installed for the first
+// time, so keyring changes don't affect releases that are already rolled out.
+func TestCreateAssociatedObjectsSkipsVerificationForExistingInstallationTarget(t *testing.T) {
+	cluster := buildCluster("minikube-a")
+	release := buildRelease()
+	release.Annotations[shipper.ReleaseClustersAnnotation] = cluster.GetName()
+
+	it, _, _ := buildAssociatedObjects(release, []*shipper.Cluster{cluster})
+	fixtures := []runtime.Object{release, cluster, it}
+
+	c, _ := newScheduler(fixtures)
+	c.chartVerifier = func(chartspec *shipper.Chart, namespace string) error {
+		t.Fatalf("did not expect chart to be verified again")
+		return nil
+	}
+
+	if _, err := c.ScheduleRelease(release.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestCreateAssociatedObjectsDuplicateInstallationTargetMismatchingClusters
 // tests a case when an installation target already exists but has a mismatching
 // set of clusters. The job of the scheduler is to correct the mismatch and
@@ -0,0 +1,163 @@
+package release
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+// parseStepValue reads a strategy step value. Numbers, and strings like "5%",
+// are percentages, while strings like "3" are a number of pods, in which case
+// it also returns true.
+func parseStepValue(value intstr.IntOrString) (int32, bool, error) {
+	if value.Type == intstr.Int {
+		if value.IntVal < 0 {
+			return 0, false, fmt.Errorf("%d is negative", value.IntVal)
+		}
+		return value.IntVal, false, nil
+	}
+
+	absolute := !strings.HasSuffix(value.StrVal, "%")
+	n, err := strconv.ParseInt(strings.TrimSuffix(value.StrVal, "%"), 10, 32)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("%q is neither a percentage, like \"5%%\", nor a number of pods, like \"3\"", value.StrVal)
+	}
+
+	return int32(n), absolute, nil
+}
+
+// stepValue is parseStepValue for strategies that have gone through
+// validateStrategy already.
+func stepValue(value intstr.IntOrString) (int32, bool) {
+	n, absolute, _ := parseStepValue(value)
+	return n, absolute
+}
+
+// validateStrategy checks that all of strategy's steps make sense, so they
+// can be used without checking any further.
+func validateStrategy(strategy *shipper.RolloutStrategy) error {
+	if len(strategy.Steps) > 0 && strategy.Steps[0].RequiresApproval {
+		return fmt.Errorf("step 0 (%q) can't require approval, as every release starts there",
+			strategy.Steps[0].Name)
+	}
+
+	for i, step := range strategy.Steps {
+		values := []struct {
+			name  string
+			value intstr.IntOrString
+			// Traffic weights have never been capped, unless
+			// they are explicitly percentages.
+			capped bool
+		}{
+			{"capacity.incumbent", step.Capacity.Incumbent, true},
+			{"capacity.contender", step.Capacity.Contender, true},
+			{"traffic.incumbent", step.Traffic.Incumbent, step.Traffic.Incumbent.Type == intstr.String},
+			{"traffic.contender", step.Traffic.Contender, step.Traffic.Contender.Type == intstr.String},
+		}
+
+		for _, v := range values {
+			n, absolute, err := parseStepValue(v.value)
+			if err != nil {
+				return fmt.Errorf("step %d (%q) has an invalid %s: %s", i, step.Name, v.name, err)
+			} else if v.capped && !absolute && n > 100 {
+				return fmt.Errorf("step %d (%q) has an invalid %s: %s is more than 100%%", i, step.Name, v.name, v.value.String())
+			}
+		}
+	}
+
+	return nil
+}
+
+// clusterCapacityForStep returns what spec needs to look like for a release
+// to have the capacity value asks for in its cluster. A number of pods is
+// capped at the release's replica count.
+func clusterCapacityForStep(spec shipper.ClusterCapacityTarget, value intstr.IntOrString) shipper.ClusterCapacityTarget {
+	n, absolute := stepValue(value)
+	if !absolute {
+		return shipper.ClusterCapacityTarget{
+			Name:              spec.Name,
+			Percent:           n,
+			TotalReplicaCount: spec.TotalReplicaCount,
+		}
+	}
+
+	return clusterCapacityForPods(spec, minInt32(n, spec.TotalReplicaCount))
+}
+
+// clusterCapacityForPods returns spec asking for exactly pods. Its percent is
+// what those make up of the replica count, rounded up, for people to read.
+func clusterCapacityForPods(spec shipper.ClusterCapacityTarget, pods int32) shipper.ClusterCapacityTarget {
+	var percent int32
+	if spec.TotalReplicaCount > 0 {
+		percent = int32(math.Ceil(float64(pods) * 100 / float64(spec.TotalReplicaCount)))
+	}
+
+	return shipper.ClusterCapacityTarget{
+		Name:              spec.Name,
+		Percent:           percent,
+		TotalReplicaCount: spec.TotalReplicaCount,
+		Replicas:          &pods,
+	}
+}
+
+// sameClusterCapacity tells whether a and b ask for the same thing in the
+// same way.
+func sameClusterCapacity(a, b shipper.ClusterCapacityTarget) bool {
+	if a.Name != b.Name || a.Percent != b.Percent || a.TotalReplicaCount != b.TotalReplicaCount {
+		return false
+	} else if a.Replicas == nil || b.Replicas == nil {
+		return a.Replicas == b.Replicas
+	}
+
+	return *a.Replicas == *b.Replicas
+}
+
+// trafficWeights returns the weight the contender, or the incumbent, should
+// have in each of tt's clusters for a step's traffic value. Steps that only
+// have percentages keep them as weights, as they've always been. Steps with a
+// number of pods in them have both values turned into a weight of 100 per
+// pod, so they compare, using the replica count in each cluster from ct.
+func trafficWeights(
+	tt *shipper.TrafficTarget,
+	ct *shipper.CapacityTarget,
+	step shipper.RolloutStrategyStepValue,
+	contender bool,
+) map[string]uint32 {
+	value := step.Incumbent
+	if contender {
+		value = step.Contender
+	}
+
+	n, absolute := stepValue(value)
+	_, contenderAbsolute := stepValue(step.Contender)
+	_, incumbentAbsolute := stepValue(step.Incumbent)
+
+	totalReplicaCount := make(map[string]int32)
+	for _, spec := range ct.Spec.Clusters {
+		totalReplicaCount[spec.Name] = spec.TotalReplicaCount
+	}
+
+	weights := make(map[string]uint32)
+	for _, spec := range tt.Spec.Clusters {
+		total, ok := totalReplicaCount[spec.Name]
+		switch {
+		case !contenderAbsolute && !incumbentAbsolute:
+			weights[spec.Name] = uint32(n)
+		case absolute:
+			pods := n
+			if ok {
+				pods = minInt32(n, total)
+			}
+			weights[spec.Name] = uint32(pods) * 100
+		default:
+			weights[spec.Name] = uint32(n) * uint32(total)
+		}
+	}
+
+	return weights
+}
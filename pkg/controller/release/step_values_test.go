@@ -0,0 +1,183 @@
+package release
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func TestParseStepValue(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          intstr.IntOrString
+		expected       int32
+		expectAbsolute bool
+		expectErr      bool
+	}{
+		{"number is a percentage", intstr.FromInt(50), 50, false, false},
+		{"explicit percentage", intstr.FromString("5%"), 5, false, false},
+		{"number of pods", intstr.FromString("3"), 3, true, false},
+		{"negative number", intstr.FromInt(-1), 0, false, true},
+		{"negative pods", intstr.FromString("-1"), 0, false, true},
+		{"not a number", intstr.FromString("lots"), 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, absolute, err := parseStepValue(tt.value)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d", n)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if n != tt.expected || absolute != tt.expectAbsolute {
+				t.Fatalf("expected %d (absolute: %t), got %d (absolute: %t)",
+					tt.expected, tt.expectAbsolute, n, absolute)
+			}
+		})
+	}
+}
+
+func TestValidateStrategy(t *testing.T) {
+	step := func(capacity, traffic intstr.IntOrString) *shipper.RolloutStrategy {
+		return &shipper.RolloutStrategy{
+			Steps: []shipper.RolloutStrategyStep{
+				{
+					Name:     "staging",
+					Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: capacity},
+					Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: traffic},
+				},
+			},
+		}
+	}
+
+	gated := step(intstr.FromInt(1), intstr.FromInt(1))
+	gated.Steps[0].RequiresApproval = true
+
+	tests := []struct {
+		name      string
+		strategy  *shipper.RolloutStrategy
+		expectErr bool
+	}{
+		{"percentages", step(intstr.FromInt(1), intstr.FromInt(1)), false},
+		{"number of pods", step(intstr.FromString("1"), intstr.FromString("1")), false},
+		{"more pods than replicas", step(intstr.FromString("1000"), intstr.FromString("1000")), false},
+		{"traffic weight over 100", step(intstr.FromInt(1), intstr.FromInt(1000)), false},
+		{"capacity over 100%", step(intstr.FromInt(101), intstr.FromInt(1)), true},
+		{"explicit traffic percentage over 100%", step(intstr.FromInt(1), intstr.FromString("101%")), true},
+		{"garbage", step(intstr.FromString("one"), intstr.FromInt(1)), true},
+		{"first step requires approval", gated, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStrategy(tt.strategy)
+			if tt.expectErr && err == nil {
+				t.Fatalf("expected an error, got none")
+			} else if !tt.expectErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestClusterCapacityForStep(t *testing.T) {
+	spec := shipper.ClusterCapacityTarget{Name: "minikube", TotalReplicaCount: 10}
+	one, ten := int32(1), int32(10)
+
+	tests := []struct {
+		name     string
+		value    intstr.IntOrString
+		expected shipper.ClusterCapacityTarget
+	}{
+		{
+			"percentage",
+			intstr.FromInt(50),
+			shipper.ClusterCapacityTarget{Name: "minikube", Percent: 50, TotalReplicaCount: 10},
+		},
+		{
+			"one pod",
+			intstr.FromString("1"),
+			shipper.ClusterCapacityTarget{Name: "minikube", Percent: 10, TotalReplicaCount: 10, Replicas: &one},
+		},
+		{
+			"capped at replica count",
+			intstr.FromString("30"),
+			shipper.ClusterCapacityTarget{Name: "minikube", Percent: 100, TotalReplicaCount: 10, Replicas: &ten},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterCapacityForStep(spec, tt.value)
+			if !sameClusterCapacity(got, tt.expected) {
+				t.Fatalf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTrafficWeights(t *testing.T) {
+	tt := &shipper.TrafficTarget{
+		Spec: shipper.TrafficTargetSpec{
+			Clusters: []shipper.ClusterTrafficTarget{{Name: "small"}, {Name: "large"}},
+		},
+	}
+	ct := &shipper.CapacityTarget{
+		Spec: shipper.CapacityTargetSpec{
+			Clusters: []shipper.ClusterCapacityTarget{
+				{Name: "small", TotalReplicaCount: 4},
+				{Name: "large", TotalReplicaCount: 20},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		step            shipper.RolloutStrategyStepValue
+		expectContender map[string]uint32
+		expectIncumbent map[string]uint32
+	}{
+		{
+			"percentages stay as they are",
+			shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(90), Contender: intstr.FromInt(10)},
+			map[string]uint32{"small": 10, "large": 10},
+			map[string]uint32{"small": 90, "large": 90},
+		},
+		{
+			"one pod against the rest",
+			shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromString("1")},
+			map[string]uint32{"small": 100, "large": 100},
+			map[string]uint32{"small": 400, "large": 2000},
+		},
+		{
+			"pods capped at replica count",
+			shipper.RolloutStrategyStepValue{Incumbent: intstr.FromString("0"), Contender: intstr.FromString("10")},
+			map[string]uint32{"small": 400, "large": 1000},
+			map[string]uint32{"small": 0, "large": 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for contender, expected := range map[bool]map[string]uint32{
+				true:  test.expectContender,
+				false: test.expectIncumbent,
+			} {
+				got := trafficWeights(tt, ct, test.step, contender)
+				for cluster, weight := range expected {
+					if got[cluster] != weight {
+						t.Fatalf("expected weight %d in cluster %q (contender: %t), got %d",
+							weight, cluster, contender, got[cluster])
+					}
+				}
+			}
+		})
+	}
+}
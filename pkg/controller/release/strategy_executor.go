@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog"
 
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	"github.com/bookingcom/shipper/pkg/controller"
 	"github.com/bookingcom/shipper/pkg/util/conditions"
 	releaseutil "github.com/bookingcom/shipper/pkg/util/release"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
 )
 
 type PipelineContinuation bool
@@ -148,7 +150,15 @@ func (e *StrategyExecutor) Execute(prev, curr, succ *releaseInfo) (bool, []Strat
 	pipeline.Enqueue(genInstallationEnforcer(ctx, curr, succ))
 
 	if isHead {
-		pipeline.Enqueue(genCapacityEnforcer(ctx, curr, succ))
+		// Interleaving moves the incumbent's capacity along with the
+		// contender's, so the incumbent's own capacity is only
+		// confirmed further down the pipeline.
+		interleaved := hasTail && e.strategy.CapacitySequencing == shipper.InterleavedCapacitySequencing
+		if interleaved {
+			pipeline.Enqueue(genInterleavedCapacityEnforcer(ctx, curr, prev, e.strategy.MaxSurge, e.strategy.MinAvailablePercent))
+		} else {
+			pipeline.Enqueue(genCapacityEnforcer(ctx, curr, succ))
+		}
 		pipeline.Enqueue(genTrafficEnforcer(ctx, curr, succ))
 		if hasTail {
 			// This is the moment where a contender is performing a look-behind.
@@ -156,8 +166,15 @@ func (e *StrategyExecutor) Execute(prev, curr, succ *releaseInfo) (bool, []Strat
 			// except that it's not the head of the chain anymore.
 			prevctx := ctx.Copy()
 			prevctx.isHead = false
+			if minAvailablePercent := e.strategy.MinAvailablePercent; minAvailablePercent != nil {
+				pipeline.Enqueue(genMinAvailabilityEnforcer(prevctx, prev, curr, *minAvailablePercent))
+			}
 			pipeline.Enqueue(genTrafficEnforcer(prevctx, prev, curr))
-			pipeline.Enqueue(genCapacityEnforcer(prevctx, prev, curr))
+			if interleaved {
+				pipeline.Enqueue(genInterleavedCapacityCheck(prevctx, prev))
+			} else {
+				pipeline.Enqueue(genCapacityEnforcer(prevctx, prev, curr))
+			}
 		}
 	} else {
 		pipeline.Enqueue(genTrafficEnforcer(ctx, curr, succ))
@@ -210,7 +227,7 @@ func genInstallationEnforcer(ctx *context, curr, succ *releaseInfo) PipelineStep
 func genCapacityEnforcer(ctx *context, curr, succ *releaseInfo) PipelineStep {
 	return func(strategyStep shipper.RolloutStrategyStep, cond conditions.StrategyConditionsMap) (PipelineContinuation, []StrategyPatch, []ReleaseStrategyStateTransition) {
 		var condType shipper.StrategyConditionType
-		var capacityWeight int32
+		var capacityWeight intstr.IntOrString
 		isHead := succ == nil
 		isInitiator := releasesIdentical(ctx.release, curr.release)
 
@@ -272,10 +289,208 @@ func genCapacityEnforcer(ctx *context, curr, succ *releaseInfo) PipelineStep {
 	}
 }
 
+// genInterleavedCapacityEnforcer moves the capacity of both the contender in
+// curr and the incumbent in prev towards the step's, one increment at a time,
+// waiting for both capacity targets to catch up with each increment before
+// taking the next one. With minAvailablePercent set, the incumbent is held
+// back like genMinAvailabilityEnforcer does.
+func genInterleavedCapacityEnforcer(ctx *context, curr, prev *releaseInfo, maxSurge *intstr.IntOrString, minAvailablePercent *int32) PipelineStep {
+	return func(strategyStep shipper.RolloutStrategyStep, cond conditions.StrategyConditionsMap) (PipelineContinuation, []StrategyPatch, []ReleaseStrategyStateTransition) {
+		contenderAchieved := setCapacityCondition(ctx, cond, curr,
+			shipper.StrategyConditionContenderAchievedCapacity, strategyStep.Capacity.Contender)
+		incumbentAchieved := setCapacityCondition(ctx, cond, prev,
+			shipper.StrategyConditionIncumbentAchievedCapacity, strategyStep.Capacity.Incumbent)
+
+		if contenderAchieved && incumbentAchieved {
+			klog.Infof("Release %q %s", controller.MetaKey(curr.release), "has achieved interleaved capacity")
+			return PipelineContinue, nil, nil
+		}
+
+		klog.Infof("Release %q %s", controller.MetaKey(curr.release), "hasn't achieved interleaved capacity yet")
+
+		patches := make([]StrategyPatch, 0, 3)
+
+		if capacityTargetReady(curr.capacityTarget) && capacityTargetReady(prev.capacityTarget) {
+			contenderSpec, incumbentSpec, waitingForTraffic, err := interleaveCapacity(
+				curr.capacityTarget, prev.capacityTarget,
+				curr.trafficTarget, prev.trafficTarget,
+				strategyStep.Capacity, maxSurge)
+			if err != nil {
+				cond.SetFalse(
+					shipper.StrategyConditionContenderAchievedCapacity,
+					conditions.StrategyConditionsUpdate{
+						Reason:             InvalidMaxSurge,
+						Message:            err.Error(),
+						Step:               ctx.step,
+						LastTransitionTime: time.Now(),
+					},
+				)
+			} else if waitingForTraffic && contenderSpec == nil && incumbentSpec == nil {
+				// The incumbent can't give up any more pods while
+				// it still gets traffic for them, so traffic has
+				// to move first. Capacity is left unachieved, so
+				// the step isn't over before it catches up.
+				klog.Infof("Release %q %s", controller.MetaKey(curr.release), "is waiting for traffic to move away from the incumbent")
+				return PipelineContinue, nil, nil
+			} else {
+				contenderPatch := &CapacityTargetSpecPatch{
+					NewSpec: contenderSpec,
+					Name:    curr.release.GetName(),
+				}
+				if contenderPatch.Alters(curr.capacityTarget) {
+					patches = append(patches, contenderPatch)
+				}
+
+				if incumbentSpec != nil && minAvailablePercent != nil {
+					available, reason := checkMinAvailability(
+						curr.capacityTarget, prev.capacityTarget, prev.trafficTarget,
+						specPodGoals(incumbentSpec), clusterWeights(prev.trafficTarget),
+						*minAvailablePercent)
+					if !available {
+						klog.Infof("Release %q %s", controller.MetaKey(prev.release), "is held back by minimum availability")
+						setWaitingForMinAvailability(ctx, cond, prev,
+							shipper.StrategyConditionIncumbentAchievedCapacity, reason, *minAvailablePercent)
+						incumbentSpec = nil
+					}
+				}
+
+				incumbentPatch := &CapacityTargetSpecPatch{
+					NewSpec: incumbentSpec,
+					Name:    prev.release.GetName(),
+				}
+				if incumbentPatch.Alters(prev.capacityTarget) {
+					patches = append(patches, incumbentPatch)
+				}
+			}
+		}
+
+		relPatch := buildContenderStrategyConditionsPatch(ctx, cond)
+		if relPatch.Alters(ctx.release) {
+			patches = append(patches, relPatch)
+		}
+
+		return PipelineBreak, patches, nil
+	}
+}
+
+// genInterleavedCapacityCheck holds the pipeline until the incumbent in curr
+// has achieved the step's capacity. Unlike genCapacityEnforcer, it leaves
+// moving it to genInterleavedCapacityEnforcer, so the contender's and the
+// incumbent's pods stay within maxSurge.
+func genInterleavedCapacityCheck(ctx *context, curr *releaseInfo) PipelineStep {
+	return func(strategyStep shipper.RolloutStrategyStep, cond conditions.StrategyConditionsMap) (PipelineContinuation, []StrategyPatch, []ReleaseStrategyStateTransition) {
+		if setCapacityCondition(ctx, cond, curr,
+			shipper.StrategyConditionIncumbentAchievedCapacity, strategyStep.Capacity.Incumbent) {
+			return PipelineContinue, nil, nil
+		}
+
+		patches := make([]StrategyPatch, 0, 1)
+		relPatch := buildContenderStrategyConditionsPatch(ctx, cond)
+		if relPatch.Alters(ctx.release) {
+			patches = append(patches, relPatch)
+		}
+
+		return PipelineBreak, patches, nil
+	}
+}
+
+// setCapacityCondition sets condType on whether the release in info has
+// achieved stepCapacity, and returns that.
+func setCapacityCondition(
+	ctx *context,
+	cond conditions.StrategyConditionsMap,
+	info *releaseInfo,
+	condType shipper.StrategyConditionType,
+	stepCapacity intstr.IntOrString,
+) bool {
+	achieved, _, clustersNotReady := checkCapacity(info.capacityTarget, stepCapacity)
+	if !achieved {
+		cond.SetFalse(
+			condType,
+			conditions.StrategyConditionsUpdate{
+				Reason:             ClustersNotReady,
+				Message:            fmt.Sprintf("release %q hasn't achieved capacity in clusters: %v. for more details try `kubectl describe ct %s`", info.release.GetName(), clustersNotReady, info.capacityTarget.GetName()),
+				Step:               ctx.step,
+				LastTransitionTime: time.Now(),
+			},
+		)
+
+		return false
+	}
+
+	cond.SetTrue(
+		condType,
+		conditions.StrategyConditionsUpdate{
+			Step:               ctx.step,
+			LastTransitionTime: time.Now(),
+			Message:            "",
+			Reason:             "",
+		},
+	)
+
+	return true
+}
+
+// genMinAvailabilityEnforcer holds off reducing the traffic and capacity of
+// the incumbent in curr while that would leave it and the contender in succ
+// with fewer than minAvailablePercent of the replica count available in any
+// cluster, as happens when the contender's pods come and go.
+func genMinAvailabilityEnforcer(ctx *context, curr, succ *releaseInfo, minAvailablePercent int32) PipelineStep {
+	return func(strategyStep shipper.RolloutStrategyStep, cond conditions.StrategyConditionsMap) (PipelineContinuation, []StrategyPatch, []ReleaseStrategyStateTransition) {
+		stepTrafficWeights := trafficWeights(curr.trafficTarget, curr.capacityTarget, strategyStep.Traffic, false)
+		available, reason := checkMinAvailability(
+			succ.capacityTarget, curr.capacityTarget, curr.trafficTarget,
+			stepPodGoals(curr.capacityTarget, strategyStep.Capacity.Incumbent),
+			stepTrafficWeights, minAvailablePercent)
+		if available {
+			return PipelineContinue, nil, nil
+		}
+
+		klog.Infof("Release %q %s", controller.MetaKey(curr.release), "is held back by minimum availability")
+
+		// Traffic is taken away from the incumbent before capacity,
+		// so that's what is being held back until it's done.
+		condType := shipper.StrategyConditionIncumbentAchievedCapacity
+		if reducesTraffic(curr.trafficTarget, stepTrafficWeights) {
+			condType = shipper.StrategyConditionIncumbentAchievedTraffic
+		}
+
+		setWaitingForMinAvailability(ctx, cond, curr, condType, reason, minAvailablePercent)
+
+		patches := make([]StrategyPatch, 0, 1)
+		relPatch := buildContenderStrategyConditionsPatch(ctx, cond)
+		if relPatch.Alters(ctx.release) {
+			patches = append(patches, relPatch)
+		}
+
+		return PipelineBreak, patches, nil
+	}
+}
+
+// setWaitingForMinAvailability sets condType to False on the incumbent in
+// info being held back by minimum availability for reason.
+func setWaitingForMinAvailability(
+	ctx *context,
+	cond conditions.StrategyConditionsMap,
+	info *releaseInfo,
+	condType shipper.StrategyConditionType,
+	reason string,
+	minAvailablePercent int32,
+) {
+	cond.SetFalse(
+		condType,
+		conditions.StrategyConditionsUpdate{
+			Reason:             WaitingForMinAvailability,
+			Message:            fmt.Sprintf("release %q is not being reduced any further: %s to keep %d%% of the replica count available", info.release.GetName(), reason, minAvailablePercent),
+			Step:               ctx.step,
+			LastTransitionTime: time.Now(),
+		},
+	)
+}
+
 func genTrafficEnforcer(ctx *context, curr, succ *releaseInfo) PipelineStep {
 	return func(strategyStep shipper.RolloutStrategyStep, cond conditions.StrategyConditionsMap) (PipelineContinuation, []StrategyPatch, []ReleaseStrategyStateTransition) {
 		var condType shipper.StrategyConditionType
-		var trafficWeight int32
 		isHead := succ == nil
 		isInitiator := releasesIdentical(ctx.release, curr.release)
 
@@ -284,13 +499,9 @@ func genTrafficEnforcer(ctx *context, curr, succ *releaseInfo) PipelineStep {
 		} else {
 			condType = shipper.StrategyConditionIncumbentAchievedTraffic
 		}
-		if isHead {
-			trafficWeight = strategyStep.Traffic.Contender
-		} else {
-			trafficWeight = strategyStep.Traffic.Incumbent
-		}
+		trafficWeights := trafficWeights(curr.trafficTarget, curr.capacityTarget, strategyStep.Traffic, isHead)
 
-		if achieved, newSpec, reason := checkTraffic(curr.trafficTarget, uint32(trafficWeight)); !achieved {
+		if achieved, newSpec, reason := checkTraffic(curr.trafficTarget, trafficWeights); !achieved {
 			klog.Infof("Release %q %s", controller.MetaKey(curr.release), "hasn't achieved traffic yet")
 
 			patches := make([]StrategyPatch, 0, 2)
@@ -323,13 +534,19 @@ func genTrafficEnforcer(ctx *context, curr, succ *releaseInfo) PipelineStep {
 
 		klog.Infof("Release %q %s", controller.MetaKey(curr.release), "has achieved traffic")
 
+		var reason, message string
+		if trafficutil.TargetNotApplicable(curr.trafficTarget) {
+			reason = trafficutil.NotApplicableReason
+			message = fmt.Sprintf("release %q gets no traffic, so it trivially achieves any weight", curr.release.GetName())
+		}
+
 		cond.SetTrue(
 			condType,
 			conditions.StrategyConditionsUpdate{
 				Step:               ctx.step,
 				LastTransitionTime: time.Now(),
-				Message:            "",
-				Reason:             "",
+				Message:            message,
+				Reason:             reason,
 			},
 		)
 
@@ -0,0 +1,352 @@
+package release
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	capacityutil "github.com/bookingcom/shipper/pkg/util/capacity"
+	podutil "github.com/bookingcom/shipper/pkg/util/pod"
+)
+
+const (
+	simulationCluster = "minikube"
+	// maxSimulationSyncs is how many syncs a rollout simulation gets
+	// to get through each step.
+	maxSimulationSyncs = 20
+)
+
+// rolloutSimulation takes a contender from the first step of a strategy to
+// the last through the strategy executor, playing the part of the
+// installation, capacity and traffic controllers in a single cluster. Pods
+// come up as soon as they're asked for, and traffic weights are achieved as
+// soon as they're set.
+type rolloutSimulation struct {
+	t                 *testing.T
+	strategy          *shipper.RolloutStrategy
+	totalReplicaCount int32
+
+	// readinessGate gives pods the traffic readiness gate, so they're
+	// only Ready while their release gets traffic.
+	readinessGate bool
+
+	// check is called after every sync, to catch states a rollout
+	// should never go through.
+	check func(incumbent, contender *releaseInfo) error
+
+	incumbent, contender *releaseInfo
+}
+
+func newRolloutSimulation(t *testing.T, strategy *shipper.RolloutStrategy, totalReplicaCount int32) *rolloutSimulation {
+	s := &rolloutSimulation{
+		t:                 t,
+		strategy:          strategy,
+		totalReplicaCount: totalReplicaCount,
+	}
+
+	s.incumbent = s.buildReleaseInfo("incumbent", 100, 100)
+	s.contender = s.buildReleaseInfo("contender", 0, 0)
+
+	return s
+}
+
+func (s *rolloutSimulation) buildReleaseInfo(name string, percent int32, weight uint32) *releaseInfo {
+	return &releaseInfo{
+		release: &shipper.Release{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-namespace"},
+		},
+		installationTarget: &shipper.InstallationTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: shipper.InstallationTargetStatus{
+				Conditions: []shipper.TargetCondition{
+					{Type: shipper.TargetConditionTypeReady, Status: corev1.ConditionTrue},
+				},
+			},
+		},
+		capacityTarget: &shipper.CapacityTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: shipper.CapacityTargetSpec{
+				Clusters: []shipper.ClusterCapacityTarget{
+					{Name: simulationCluster, Percent: percent, TotalReplicaCount: s.totalReplicaCount},
+				},
+			},
+		},
+		trafficTarget: &shipper.TrafficTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: shipper.TrafficTargetSpec{
+				Clusters: []shipper.ClusterTrafficTarget{
+					{Name: simulationCluster, Weight: weight},
+				},
+			},
+		},
+	}
+}
+
+// run goes through every step of the strategy, and fails the test if any of
+// them can't be achieved.
+func (s *rolloutSimulation) run() {
+	s.settle(s.incumbent)
+	s.settle(s.contender)
+
+	for step := int32(0); int(step) < len(s.strategy.Steps); step++ {
+		if !s.runStep(step) {
+			s.t.Fatalf("step %d was not achieved after %d syncs: %s",
+				step, maxSimulationSyncs, s.describe())
+		}
+	}
+}
+
+// runStep syncs the rollout on step until it's achieved, and tells whether
+// it was.
+func (s *rolloutSimulation) runStep(step int32) bool {
+	executor := NewStrategyExecutor(s.strategy, step)
+
+	for i := 0; i < maxSimulationSyncs; i++ {
+		complete, patches, _ := executor.Execute(s.incumbent, s.contender, nil)
+		for _, patch := range patches {
+			s.apply(patch)
+		}
+
+		s.settle(s.incumbent)
+		s.settle(s.contender)
+
+		if s.check != nil {
+			if err := s.check(s.incumbent, s.contender); err != nil {
+				s.t.Fatalf("step %d, sync %d: %s", step, i, err)
+			}
+		}
+
+		if complete && len(patches) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *rolloutSimulation) apply(patch StrategyPatch) {
+	switch p := patch.(type) {
+	case *CapacityTargetSpecPatch:
+		ct := s.releaseInfo(p.Name).capacityTarget
+		ct.Spec = *p.NewSpec
+		ct.Generation++
+	case *TrafficTargetSpecPatch:
+		tt := s.releaseInfo(p.Name).trafficTarget
+		tt.Spec = *p.NewSpec
+		tt.Generation++
+	case *ReleaseStrategyStatusPatch:
+		s.releaseInfo(p.Name).release.Status.Strategy = p.NewStrategyStatus
+	default:
+		s.t.Fatalf("unexpected patch %#v", patch)
+	}
+}
+
+func (s *rolloutSimulation) releaseInfo(name string) *releaseInfo {
+	switch name {
+	case s.incumbent.release.Name:
+		return s.incumbent
+	case s.contender.release.Name:
+		return s.contender
+	}
+
+	s.t.Fatalf("unknown release %q", name)
+	return nil
+}
+
+// settle brings the statuses of info's targets up to date with their specs,
+// counting available pods the way the capacity controller does.
+func (s *rolloutSimulation) settle(info *releaseInfo) {
+	ct, tt := info.capacityTarget, info.trafficTarget
+
+	desired := capacityutil.DesiredReplicaCount(ct.Spec.Clusters[0])
+	pods := s.buildPods(desired, tt.Spec.Clusters[0].Weight > 0)
+
+	var available int32
+	for _, pod := range pods {
+		if podutil.IsReady(pod) {
+			available++
+		}
+	}
+
+	ready := corev1.ConditionFalse
+	if available == desired {
+		ready = corev1.ConditionTrue
+	}
+
+	ct.Status = shipper.CapacityTargetStatus{
+		ObservedGeneration: ct.Generation,
+		Clusters: []shipper.ClusterCapacityStatus{
+			{Name: simulationCluster, AvailableReplicas: available},
+		},
+		Conditions: []shipper.TargetCondition{
+			{Type: shipper.TargetConditionTypeReady, Status: ready},
+		},
+	}
+
+	tt.Status = shipper.TrafficTargetStatus{
+		ObservedGeneration: tt.Generation,
+		Clusters: []*shipper.ClusterTrafficStatus{
+			{Name: simulationCluster, AchievedTraffic: tt.Spec.Clusters[0].Weight},
+		},
+		Conditions: []shipper.TargetCondition{
+			{Type: shipper.TargetConditionTypeReady, Status: corev1.ConditionTrue},
+		},
+	}
+}
+
+// buildPods returns count pods whose containers are all ready, and which get
+// traffic if withTraffic.
+func (s *rolloutSimulation) buildPods(count int32, withTraffic bool) []*corev1.Pod {
+	ready := corev1.ConditionTrue
+	var gates []corev1.PodReadinessGate
+	conditions := []corev1.PodCondition{
+		{Type: corev1.ContainersReady, Status: corev1.ConditionTrue},
+	}
+
+	if s.readinessGate {
+		gates = []corev1.PodReadinessGate{{ConditionType: shipper.PodTrafficReadinessGate}}
+
+		traffic := corev1.ConditionFalse
+		if withTraffic {
+			traffic = corev1.ConditionTrue
+		}
+		ready = traffic
+		conditions = append(conditions, corev1.PodCondition{
+			Type:   shipper.PodTrafficReadinessGate,
+			Status: traffic,
+		})
+	}
+
+	conditions = append(conditions, corev1.PodCondition{Type: corev1.PodReady, Status: ready})
+
+	pods := make([]*corev1.Pod, 0, count)
+	for i := int32(0); i < count; i++ {
+		pods = append(pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i)},
+			Spec:       corev1.PodSpec{ReadinessGates: gates},
+			Status:     corev1.PodStatus{Conditions: conditions},
+		})
+	}
+
+	return pods
+}
+
+func (s *rolloutSimulation) describe() string {
+	describe := func(info *releaseInfo) string {
+		return fmt.Sprintf("%s at %d pods and weight %d",
+			info.release.Name,
+			capacityutil.DesiredReplicaCount(info.capacityTarget.Spec.Clusters[0]),
+			info.trafficTarget.Spec.Clusters[0].Weight)
+	}
+
+	var conditions []string
+	if strategy := s.contender.release.Status.Strategy; strategy != nil {
+		for _, cond := range strategy.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				conditions = append(conditions, fmt.Sprintf("%s: %s", cond.Type, cond.Message))
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s, %s, %v", describe(s.incumbent), describe(s.contender), conditions)
+}
+
+func TestExecutorRolloutWithReadinessGate(t *testing.T) {
+	s := newRolloutSimulation(t, &vanguard, 10)
+	s.readinessGate = true
+	s.run()
+
+	if pods := capacityutil.DesiredReplicaCount(s.contender.capacityTarget.Spec.Clusters[0]); pods != 10 {
+		t.Fatalf("expected contender to end up with 10 pods, got %d", pods)
+	}
+}
+
+func TestExecutorInterleavedRolloutKeepsIncumbentPodsForItsTraffic(t *testing.T) {
+	strategy := vanguard.DeepCopy()
+	strategy.CapacitySequencing = shipper.InterleavedCapacitySequencing
+	maxSurge := intstr.FromInt(3)
+	strategy.MaxSurge = &maxSurge
+
+	s := newRolloutSimulation(t, strategy, 10)
+	s.check = func(incumbent, contender *releaseInfo) error {
+		weight := incumbent.trafficTarget.Spec.Clusters[0].Weight
+		totalWeight := weight + contender.trafficTarget.Spec.Clusters[0].Weight
+		pods := capacityutil.DesiredReplicaCount(incumbent.capacityTarget.Spec.Clusters[0])
+		if needed := trafficPods(weight, totalWeight, 10); pods < needed {
+			return fmt.Errorf("incumbent is down to %d pods, but needs %d for weight %d out of %d",
+				pods, needed, weight, totalWeight)
+		}
+		return nil
+	}
+	s.run()
+
+	if pods := capacityutil.DesiredReplicaCount(s.incumbent.capacityTarget.Spec.Clusters[0]); pods != 0 {
+		t.Fatalf("expected incumbent to end up with no pods, got %d", pods)
+	}
+	if pods := capacityutil.DesiredReplicaCount(s.contender.capacityTarget.Spec.Clusters[0]); pods != 10 {
+		t.Fatalf("expected contender to end up with 10 pods, got %d", pods)
+	}
+}
+
+func TestExecutorInterleavedRolloutKeepsMinAvailability(t *testing.T) {
+	const totalReplicaCount = 10
+	minAvailablePercent := int32(80)
+	maxSurge := intstr.FromInt(3)
+
+	// The incumbent loses all of its traffic on the first step, and the
+	// last one leaves fewer pods than the minimum availability allows
+	// for, so the incumbent has to keep some of its own.
+	strategy := &shipper.RolloutStrategy{
+		CapacitySequencing:  shipper.InterleavedCapacitySequencing,
+		MaxSurge:            &maxSurge,
+		MinAvailablePercent: &minAvailablePercent,
+		Steps: []shipper.RolloutStrategyStep{
+			{
+				Name:     "traffic",
+				Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(100), Contender: intstr.FromInt(50)},
+				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+			},
+			{
+				Name:     "capacity",
+				Capacity: shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(50)},
+				Traffic:  shipper.RolloutStrategyStepValue{Incumbent: intstr.FromInt(0), Contender: intstr.FromInt(100)},
+			},
+		},
+	}
+
+	s := newRolloutSimulation(t, strategy, totalReplicaCount)
+	s.check = func(incumbent, contender *releaseInfo) error {
+		available := incumbent.capacityTarget.Status.Clusters[0].AvailableReplicas +
+			contender.capacityTarget.Status.Clusters[0].AvailableReplicas
+		if needed := podsForPercent(minAvailablePercent, totalReplicaCount); available < needed {
+			return fmt.Errorf("only %d pods are available, but %d are needed", available, needed)
+		}
+		return nil
+	}
+
+	s.settle(s.incumbent)
+	s.settle(s.contender)
+
+	if !s.runStep(0) {
+		t.Fatalf("step 0 was not achieved: %s", s.describe())
+	}
+	if s.runStep(1) {
+		t.Fatalf("expected step 1 to be held back by minimum availability: %s", s.describe())
+	}
+
+	for _, cond := range s.contender.release.Status.Strategy.Conditions {
+		if cond.Type == shipper.StrategyConditionIncumbentAchievedCapacity {
+			if cond.Reason != WaitingForMinAvailability {
+				t.Fatalf("expected incumbent capacity to wait for min availability, got %q: %s",
+					cond.Reason, cond.Message)
+			}
+			return
+		}
+	}
+
+	t.Fatalf("expected an %s condition", shipper.StrategyConditionIncumbentAchievedCapacity)
+}
@@ -2,6 +2,7 @@ package rolloutblock
 
 import (
 	"fmt"
+	"reflect"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -48,6 +49,9 @@ type Controller struct {
 	rolloutblockWorkqueue workqueue.RateLimitingInterface
 	releaseWorkqueue      workqueue.RateLimitingInterface
 	applicationWorkqueue  workqueue.RateLimitingInterface
+
+	// now is time.Now, and only there for tests to replace it.
+	now func() time.Time
 }
 
 // NewController returns a new RolloutBlock controller.
@@ -87,6 +91,8 @@ func NewController(
 			shipperworkqueue.NewDefaultControllerRateLimiter(),
 			"rolloutblock_controller_applications",
 		),
+
+		now: time.Now,
 	}
 
 	klog.Info("Setting up event handlers")
@@ -111,7 +117,10 @@ func NewController(
 
 	rolloutBlockInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
-			AddFunc:    controller.onAddRolloutBlock,
+			AddFunc: controller.onAddRolloutBlock,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				controller.onUpdateRolloutBlock(oldObj, newObj)
+			},
 			DeleteFunc: controller.onDeleteRolloutBlock,
 		})
 
@@ -190,6 +199,28 @@ func (c *Controller) onAddRolloutBlock(obj interface{}) {
 	c.rolloutblockWorkqueue.Add(key)
 }
 
+func (c *Controller) onUpdateRolloutBlock(oldObj interface{}, newObj interface{}) {
+	oldRB, ok := oldObj.(*shipper.RolloutBlock)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("not a shipper.RolloutBlock: %#v", oldObj))
+		return
+	}
+
+	newRB, ok := newObj.(*shipper.RolloutBlock)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("not a shipper.RolloutBlock: %#v", newObj))
+		return
+	}
+
+	// Only a change in the time window or schedule can change when the
+	// block is active. Status updates are our own.
+	if reflect.DeepEqual(oldRB.Spec, newRB.Spec) {
+		return
+	}
+
+	c.onAddRolloutBlock(newRB)
+}
+
 func (c *Controller) onDeleteRolloutBlock(obj interface{}) {
 	rb, ok := obj.(*shipper.RolloutBlock)
 	if !ok {
@@ -412,11 +443,21 @@ func (c *Controller) syncRolloutBlock(key string) error {
 		return err
 	}
 
+	now := c.now()
+	rolloutBlock.Status.Active = rolloutblock.IsActive(rolloutBlock, now)
+
 	_, err = c.shipperClientset.ShipperV1alpha1().RolloutBlocks(rolloutBlock.Namespace).Update(rolloutBlock)
 	if err != nil {
 		return shippererrors.NewKubeclientUpdateError(rolloutBlock, err).
 			WithShipperKind("RolloutBlock")
 	}
 
+	// Blocks with a time window or a schedule go in and out of effect
+	// on their own, so we come back to them when that might happen.
+	if next, ok := rolloutblock.NextTransition(rolloutBlock, now); ok {
+		klog.V(4).Infof("RolloutBlock %q might change at %s", key, next.Format(time.RFC3339))
+		c.rolloutblockWorkqueue.AddAfter(key, next.Sub(now))
+	}
+
 	return nil
 }
@@ -64,6 +64,7 @@ func TestNewRolloutBlockWithoutOverrides(t *testing.T) {
 	f.objects = append(f.objects, rolloutblock, app, rel)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = ""
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -83,6 +84,7 @@ func TestNewRolloutBlockWithOverrides(t *testing.T) {
 	f.objects = append(f.objects, rolloutBlock)
 
 	expectedRolloutBlock := rolloutBlock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testReleaseName)
 
@@ -102,6 +104,7 @@ func TestAddApplicationToRolloutBlockStatus(t *testing.T) {
 	f.objects = append(f.objects, app)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -122,6 +125,7 @@ func TestAddApplicationAndReleaseToRolloutBlockStatus(t *testing.T) {
 	f.objects = append(f.objects, app, rel)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testReleaseName)
 
@@ -142,6 +146,7 @@ func TestAddReleaseToRolloutBlockStatus(t *testing.T) {
 	f.objects = append(f.objects, app, rel)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = ""
 	expectedRolloutBlock.Status.Overrides.Release = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testReleaseName)
 
@@ -163,6 +168,7 @@ func TestRemoveAppFromRolloutBlockStatus(t *testing.T) {
 	app.Annotations[shipper.RolloutBlocksOverrideAnnotation] = ""
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = ""
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -185,6 +191,7 @@ func TestRemoveReleaseFromRolloutBlockStatus(t *testing.T) {
 	rel.Annotations[shipper.RolloutBlocksOverrideAnnotation] = ""
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -203,6 +210,7 @@ func TestNewGlobalRolloutBlockWithoutOverrides(t *testing.T) {
 	f.objects = append(f.objects, rolloutblock, app, rel)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = ""
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -222,6 +230,7 @@ func TestNewGlobalRolloutBlockWithOverrides(t *testing.T) {
 	f.objects = append(f.objects, rolloutBlock)
 
 	expectedRolloutBlock := rolloutBlock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testReleaseName)
 
@@ -241,6 +250,7 @@ func TestAddApplicationToGlobalRolloutBlockStatus(t *testing.T) {
 	f.objects = append(f.objects, app)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -261,6 +271,7 @@ func TestAddApplicationAndReleaseToGlobalRolloutBlockStatus(t *testing.T) {
 	f.objects = append(f.objects, app, rel)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testReleaseName)
 
@@ -281,6 +292,7 @@ func TestAddReleaseToGlobalRolloutBlockStatus(t *testing.T) {
 	f.objects = append(f.objects, app, rel)
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = ""
 	expectedRolloutBlock.Status.Overrides.Release = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testReleaseName)
 
@@ -302,6 +314,7 @@ func TestRemoveAppFromGlobalRolloutBlockStatus(t *testing.T) {
 	app.Annotations[shipper.RolloutBlocksOverrideAnnotation] = ""
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = ""
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -324,6 +337,7 @@ func TestRemoveReleaseFromGlobalRolloutBlockStatus(t *testing.T) {
 	rel.Annotations[shipper.RolloutBlocksOverrideAnnotation] = ""
 
 	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
 	expectedRolloutBlock.Status.Overrides.Application = fmt.Sprintf("%s/%s", shippertesting.TestNamespace, testAppName)
 	expectedRolloutBlock.Status.Overrides.Release = ""
 
@@ -331,6 +345,54 @@ func TestRemoveReleaseFromGlobalRolloutBlockStatus(t *testing.T) {
 	f.run()
 }
 
+func TestRolloutBlockBeforeStartTimeIsInactive(t *testing.T) {
+	f := newFixture(t)
+
+	rolloutblock := newRolloutBlock(testRolloutBlockName, shippertesting.TestNamespace)
+	startTime := metav1.NewTime(time.Now().Add(time.Hour))
+	rolloutblock.Spec.StartTime = &startTime
+	f.objects = append(f.objects, rolloutblock)
+
+	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = false
+
+	f.expectRolloutBlockUpdate(expectedRolloutBlock)
+	f.run()
+}
+
+func TestRolloutBlockWithinTimeWindowIsActive(t *testing.T) {
+	f := newFixture(t)
+
+	rolloutblock := newRolloutBlock(testRolloutBlockName, shippertesting.TestNamespace)
+	startTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	endTime := metav1.NewTime(time.Now().Add(time.Hour))
+	rolloutblock.Spec.StartTime = &startTime
+	rolloutblock.Spec.EndTime = &endTime
+	f.objects = append(f.objects, rolloutblock)
+
+	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = true
+
+	f.expectRolloutBlockUpdate(expectedRolloutBlock)
+	f.run()
+}
+
+func TestRolloutBlockAfterEndTimeIsInactive(t *testing.T) {
+	f := newFixture(t)
+
+	rolloutblock := newRolloutBlock(testRolloutBlockName, shippertesting.TestNamespace)
+	rolloutblock.Status.Active = true
+	endTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	rolloutblock.Spec.EndTime = &endTime
+	f.objects = append(f.objects, rolloutblock)
+
+	expectedRolloutBlock := rolloutblock.DeepCopy()
+	expectedRolloutBlock.Status.Active = false
+
+	f.expectRolloutBlockUpdate(expectedRolloutBlock)
+	f.run()
+}
+
 func (f *fixture) expectRolloutBlockUpdate(rb *shipper.RolloutBlock) {
 	gvr := shipper.SchemeGroupVersion.WithResource("rolloutblocks")
 	action := kubetesting.NewUpdateAction(gvr, rb.GetNamespace(), rb)
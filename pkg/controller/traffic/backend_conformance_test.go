@@ -0,0 +1,377 @@
+package traffic
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+// maxConformanceSyncs is how many rounds of syncing every release a backend
+// gets to reach the desired weights in a conformance scenario.
+const maxConformanceSyncs = 5
+
+type conformanceRelease struct {
+	weight   uint32
+	podCount podStatus
+}
+
+type conformanceExpectation struct {
+	Ready          bool
+	AchievedWeight uint32
+}
+
+type conformanceScenario struct {
+	name         string
+	releases     []conformanceRelease
+	expectations []conformanceExpectation
+}
+
+// conformanceHarness adapts a TrafficBackend to the conformance scenarios.
+// objects builds the initial state of an application cluster, where pods in
+// podCount.withTraffic are already getting traffic, returning the objects for
+// the kubernetes and the dynamic clients. settle plays the part of whatever
+// reacts to the changes the backend makes in the cluster, like the endpoints
+// controller does for the pod label shifter. unsupported lists the scenarios
+// a backend can't pass by design. endpointSlices makes the application
+// cluster serve EndpointSlices.
+type conformanceHarness struct {
+	name           string
+	backend        TrafficBackend
+	objects        func(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object)
+	settle         func(client *kubefake.Clientset) error
+	unsupported    []string
+	endpointSlices bool
+}
+
+// conformanceScenarios are the traffic shifting scenarios every backend needs
+// to agree on. They never have releases whose weight is limited by their
+// number of pods, as only some backends care about that.
+var conformanceScenarios = []conformanceScenario{
+	{
+		name:         "empty release",
+		releases:     []conformanceRelease{{weight: 0}},
+		expectations: []conformanceExpectation{{Ready: true}},
+	},
+	{
+		name:         "release without weight",
+		releases:     []conformanceRelease{{weight: 0, podCount: podStatus{withoutTraffic: 1}}},
+		expectations: []conformanceExpectation{{Ready: true}},
+	},
+	{
+		name:         "new release",
+		releases:     []conformanceRelease{{weight: 1, podCount: podStatus{withoutTraffic: 10}}},
+		expectations: []conformanceExpectation{{Ready: true, AchievedWeight: 1}},
+	},
+	{
+		name:         "completed release",
+		releases:     []conformanceRelease{{weight: 1, podCount: podStatus{withTraffic: 10}}},
+		expectations: []conformanceExpectation{{Ready: true, AchievedWeight: 1}},
+	},
+	{
+		name:         "release progressing up",
+		releases:     []conformanceRelease{{weight: 1, podCount: podStatus{withTraffic: 5, withoutTraffic: 5}}},
+		expectations: []conformanceExpectation{{Ready: true, AchievedWeight: 1}},
+	},
+	{
+		name:         "release progressing down",
+		releases:     []conformanceRelease{{weight: 0, podCount: podStatus{withTraffic: 5}}},
+		expectations: []conformanceExpectation{{Ready: true}},
+	},
+	{
+		name: "drain incumbent and replenish contender",
+		releases: []conformanceRelease{
+			{weight: 0, podCount: podStatus{withTraffic: 10}},
+			{weight: 1, podCount: podStatus{withoutTraffic: 10}},
+		},
+		expectations: []conformanceExpectation{
+			{Ready: true},
+			{Ready: true, AchievedWeight: 1},
+		},
+	},
+	{
+		name: "several achieved releases",
+		releases: []conformanceRelease{
+			{weight: 10, podCount: podStatus{withTraffic: 5}},
+			{weight: 10, podCount: podStatus{withTraffic: 5}},
+			{weight: 10, podCount: podStatus{withTraffic: 5}},
+			{weight: 10, podCount: podStatus{withTraffic: 5}},
+			{weight: 10, podCount: podStatus{withTraffic: 5}},
+		},
+		expectations: []conformanceExpectation{
+			{Ready: true, AchievedWeight: 10},
+			{Ready: true, AchievedWeight: 10},
+			{Ready: true, AchievedWeight: 10},
+			{Ready: true, AchievedWeight: 10},
+			{Ready: true, AchievedWeight: 10},
+		},
+	},
+	{
+		name: "several releases with different weights",
+		releases: []conformanceRelease{
+			{weight: 10, podCount: podStatus{withoutTraffic: 10}},
+			{weight: 20, podCount: podStatus{withTraffic: 20}},
+		},
+		expectations: []conformanceExpectation{
+			{Ready: true, AchievedWeight: 10},
+			{Ready: true, AchievedWeight: 20},
+		},
+	},
+}
+
+var conformanceHarnesses = []conformanceHarness{
+	{
+		name:    "pod label shifter",
+		backend: podLabelShifter{},
+		objects: podLabelShifterObjects,
+		settle:  podLabelShifterSettle,
+	},
+	{
+		name:           "pod label shifter with EndpointSlices",
+		backend:        podLabelShifter{},
+		objects:        podLabelShifterSliceObjects,
+		settle:         podLabelShifterSliceSettle,
+		endpointSlices: true,
+	},
+	{
+		name:    "istio",
+		backend: istioBackend{},
+		objects: istioObjects,
+		settle:  func(*kubefake.Clientset) error { return nil },
+	},
+	{
+		name:    "smi",
+		backend: smiBackend{},
+		objects: smiObjects,
+		settle:  func(*kubefake.Clientset) error { return nil },
+	},
+	{
+		name:    "ingress-nginx",
+		backend: ingressNginxBackend{},
+		objects: ingressNginxObjects,
+		settle:  func(*kubefake.Clientset) error { return nil },
+		// ingress-nginx has a single canary per Ingress.
+		unsupported: []string{"several achieved releases"},
+	},
+	{
+		name:    "readiness-gate",
+		backend: readinessGateBackend{},
+		objects: readinessGateBackendObjects,
+		settle:  readinessGateBackendSettle,
+	},
+	{
+		name:    "none",
+		backend: noTrafficBackend{},
+		objects: noTrafficObjects,
+		settle:  func(*kubefake.Clientset) error { return nil },
+	},
+}
+
+func TestTrafficBackendConformance(t *testing.T) {
+	for _, harness := range conformanceHarnesses {
+		for _, scenario := range conformanceScenarios {
+			t.Run(fmt.Sprintf("%s/%s", harness.name, scenario.name), func(t *testing.T) {
+				for _, unsupported := range harness.unsupported {
+					if unsupported == scenario.name {
+						t.Skipf("%s does not support this scenario", harness.name)
+					}
+				}
+
+				runConformanceScenario(t, harness, scenario)
+			})
+		}
+	}
+}
+
+func runConformanceScenario(t *testing.T, harness conformanceHarness, scenario conformanceScenario) {
+	releases := make([]string, 0, len(scenario.releases))
+	pods := make(map[string]podStatus)
+	weights := make(map[string]uint32)
+	for i, release := range scenario.releases {
+		name := fmt.Sprintf("release-%d", i)
+		releases = append(releases, name)
+		pods[name] = release.podCount
+		weights[name] = release.weight
+	}
+
+	kubeObjects, dynamicObjects := harness.objects(releases, pods)
+	client := kubefake.NewSimpleClientset(kubeObjects...)
+	dynamicClient := newMeshDynamicClient(dynamicObjects...)
+	client.Resources = ingressResources
+	if harness.endpointSlices {
+		client.Resources = append(client.Resources, endpointSliceResources...)
+	}
+
+	var statuses []TrafficStatus
+	for i := 0; i < maxConformanceSyncs; i++ {
+		var err error
+		statuses, err = syncConformanceReleases(harness.backend, client, dynamicClient, releases, weights)
+		if err != nil {
+			t.Fatalf("unexpected error syncing releases: %s", err)
+		}
+
+		if err := harness.settle(client); err != nil {
+			t.Fatalf("unexpected error settling cluster: %s", err)
+		}
+
+		ready := true
+		for _, status := range statuses {
+			ready = ready && status.Ready
+		}
+
+		if ready {
+			break
+		}
+	}
+
+	for i, expected := range scenario.expectations {
+		actual := conformanceExpectation{
+			Ready:          statuses[i].Ready,
+			AchievedWeight: statuses[i].AchievedWeight,
+		}
+
+		if eq, diff := shippertesting.DeepEqualDiff(expected, actual); !eq {
+			t.Errorf("release %q got a different traffic status than expected:\n%s", releases[i], diff)
+		}
+	}
+}
+
+// syncConformanceReleases syncs every release once, against a snapshot of the
+// cluster taken before any of them is synced, just like the controller sees
+// the cluster through informers.
+func syncConformanceReleases(
+	backend TrafficBackend,
+	client *kubefake.Clientset,
+	dynamicClient dynamic.Interface,
+	releases []string,
+	weights map[string]uint32,
+) ([]TrafficStatus, error) {
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	(&Controller{}).subscribeToAppClusterEvents(informerFactory)
+
+	endpointSlices := servesEndpointSlices(client)
+	if endpointSlices {
+		informerFactory.Discovery().V1beta1().EndpointSlices().Informer()
+	}
+
+	ct := ClusterTraffic{
+		Cluster:         shippertesting.TestCluster,
+		Namespace:       shippertesting.TestNamespace,
+		AppName:         shippertesting.TestApp,
+		ReleaseWeights:  weights,
+		Clientset:       client,
+		InformerFactory: informerFactory,
+		EndpointSlices:  endpointSlices,
+		Ingresses:       servesIngresses(client),
+		DynamicClient:   dynamicClient,
+	}
+
+	if b, ok := backend.(informerBackend); ok {
+		if _, err := b.informers(&ct); err != nil {
+			return nil, err
+		}
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	statuses := make([]TrafficStatus, 0, len(releases))
+	for _, release := range releases {
+		releaseCt := ct
+		releaseCt.ReleaseName = release
+
+		status, err := backend.Sync(&releaseCt)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func podLabelShifterObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	objects := []runtime.Object{buildService(shippertesting.TestApp)}
+
+	endpoints := buildEndpoints(shippertesting.TestApp)
+	for _, release := range releases {
+		podsWithTraffic := buildPods(shippertesting.TestApp, release, pods[release].withTraffic, withTraffic)
+		podsWithoutTraffic := buildPods(shippertesting.TestApp, release, pods[release].withoutTraffic, noTraffic)
+
+		for _, pod := range podsWithTraffic {
+			endpoints = shiftPodInEndpoints(pod, endpoints)
+		}
+
+		objects = addPodsToList(objects, podsWithTraffic)
+		objects = addPodsToList(objects, podsWithoutTraffic)
+	}
+
+	return append(objects, endpoints), nil
+}
+
+// podLabelShifterSettle rebuilds the app's Endpoints from the labels in its
+// pods.
+func podLabelShifterSettle(client *kubefake.Clientset) error {
+	podList, err := client.CoreV1().Pods(shippertesting.TestNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	endpoints := buildEndpoints(shippertesting.TestApp)
+	for i := range podList.Items {
+		endpoints = shiftPodInEndpoints(&podList.Items[i], endpoints)
+	}
+
+	_, err = client.CoreV1().Endpoints(endpoints.Namespace).Update(endpoints)
+	return err
+}
+
+// podLabelShifterSliceObjects is podLabelShifterObjects on a cluster that
+// has the app's endpoints in an EndpointSlice instead of Endpoints, so that
+// backends looking at the latter fail.
+func podLabelShifterSliceObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	objects, _ := podLabelShifterObjects(releases, pods)
+	endpoints := objects[len(objects)-1].(*corev1.Endpoints)
+	objects[len(objects)-1] = buildEndpointSlice(endpoints)
+
+	return objects, nil
+}
+
+// podLabelShifterSliceSettle rebuilds the app's EndpointSlice from the labels
+// in its pods.
+func podLabelShifterSliceSettle(client *kubefake.Clientset) error {
+	podList, err := client.CoreV1().Pods(shippertesting.TestNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	endpoints := buildEndpoints(shippertesting.TestApp)
+	for i := range podList.Items {
+		endpoints = shiftPodInEndpoints(&podList.Items[i], endpoints)
+	}
+
+	slice := buildEndpointSlice(endpoints)
+	_, err = client.DiscoveryV1beta1().EndpointSlices(slice.Namespace).Update(slice)
+	return err
+}
+
+// noTrafficObjects builds an application without any Service, just its pods.
+func noTrafficObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	var objects []runtime.Object
+	for _, release := range releases {
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withoutTraffic, noTraffic))
+	}
+
+	return objects, nil
+}
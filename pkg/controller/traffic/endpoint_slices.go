@@ -0,0 +1,110 @@
+package traffic
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+// servesEndpointSlices tells whether an application cluster serves
+// discovery.k8s.io/v1beta1 EndpointSlices. Older clusters don't, and any
+// failure to find out is taken to mean the same, so we fall back to
+// Endpoints.
+func servesEndpointSlices(client kubernetes.Interface) bool {
+	return servesResource(client, discoveryv1beta1.SchemeGroupVersion, "EndpointSlice")
+}
+
+// servesResource tells whether an application cluster serves kind in gv.
+// Failures to find out are logged, and taken to mean it doesn't.
+func servesResource(client kubernetes.Interface, gv schema.GroupVersion, kind string) bool {
+	resources, err := client.Discovery().ServerResourcesForGroupVersion(gv.String())
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			klog.Warningf("Failed to discover %s, assuming %s is not served: %s", gv, kind, err)
+		}
+
+		return false
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getPodReadiness returns which pods behind svc are ready and which are not,
+// according to its EndpointSlices if the application cluster serves them,
+// or to its Endpoints otherwise.
+func getPodReadiness(
+	informerFactory kubeinformers.SharedInformerFactory,
+	svc *corev1.Service,
+	endpointSlices bool,
+) (map[string]bool, error) {
+	if endpointSlices {
+		selector := labels.Set{discoveryv1beta1.LabelServiceName: svc.Name}.AsSelector()
+		slices, err := informerFactory.Discovery().V1beta1().EndpointSlices().Lister().
+			EndpointSlices(svc.Namespace).List(selector)
+		if err != nil {
+			return nil, shippererrors.NewKubeclientListError(
+				discoveryv1beta1.SchemeGroupVersion.WithKind("EndpointSlice"),
+				svc.Namespace, selector, err)
+		}
+
+		return endpointSlicesPodReadiness(slices), nil
+	}
+
+	endpoints, err := informerFactory.Core().V1().Endpoints().Lister().
+		Endpoints(svc.Namespace).Get(svc.Name)
+	if err != nil {
+		return nil, shippererrors.NewKubeclientGetError(svc.Namespace, svc.Name, err).
+			WithCoreV1Kind("Endpoints")
+	}
+
+	return endpointsPodReadiness(endpoints), nil
+}
+
+// endpointsPodReadiness returns which pods are ready and which are not
+// according to endpoints.
+func endpointsPodReadiness(endpoints *corev1.Endpoints) map[string]bool {
+	podReadiness := make(map[string]bool)
+	for _, subset := range endpoints.Subsets {
+		markAddressReadiness(podReadiness, subset.Addresses, true)
+		markAddressReadiness(podReadiness, subset.NotReadyAddresses, false)
+	}
+
+	return podReadiness
+}
+
+// endpointSlicesPodReadiness aggregates the endpoints in all of a Service's
+// slices into which pods are ready and which are not. A pod listed in more
+// than one slice, as happens with one slice per address type, is ready if
+// any of them says so. An unknown readiness counts as ready, as the
+// EndpointSlice API asks of its consumers.
+func endpointSlicesPodReadiness(slices []*discoveryv1beta1.EndpointSlice) map[string]bool {
+	podReadiness := make(map[string]bool)
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			target := endpoint.TargetRef
+			// Same as with Endpoints, we don't know what to do
+			// with anything but Pods.
+			if target == nil || target.Kind != "Pod" {
+				continue
+			}
+
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+			podReadiness[target.Name] = podReadiness[target.Name] || ready
+		}
+	}
+
+	return podReadiness
+}
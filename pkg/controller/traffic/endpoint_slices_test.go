@@ -0,0 +1,66 @@
+package traffic
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+func sliceEndpoint(kind, name string, ready *bool) discoveryv1beta1.Endpoint {
+	return discoveryv1beta1.Endpoint{
+		Conditions: discoveryv1beta1.EndpointConditions{Ready: ready},
+		TargetRef:  &corev1.ObjectReference{Kind: kind, Name: name},
+	}
+}
+
+func TestEndpointSlicesPodReadiness(t *testing.T) {
+	ready, notReady := true, false
+
+	slices := []*discoveryv1beta1.EndpointSlice{
+		{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{
+				sliceEndpoint("Pod", "ready", &ready),
+				sliceEndpoint("Pod", "not-ready", &notReady),
+				sliceEndpoint("Pod", "unknown", nil),
+				sliceEndpoint("Pod", "dual-stack", &notReady),
+				sliceEndpoint("Node", "not-a-pod", &ready),
+				{Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready}},
+			},
+		},
+		{
+			AddressType: discoveryv1beta1.AddressTypeIPv6,
+			Endpoints: []discoveryv1beta1.Endpoint{
+				sliceEndpoint("Pod", "dual-stack", &ready),
+			},
+		},
+	}
+
+	expected := map[string]bool{
+		"ready":      true,
+		"not-ready":  false,
+		"unknown":    true,
+		"dual-stack": true,
+	}
+
+	podReadiness := endpointSlicesPodReadiness(slices)
+	if eq, diff := shippertesting.DeepEqualDiff(expected, podReadiness); !eq {
+		t.Errorf("pod readiness differs from expected:\n%s", diff)
+	}
+}
+
+func TestServesEndpointSlices(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	if servesEndpointSlices(client) {
+		t.Errorf("expected a cluster without EndpointSlices not to serve them")
+	}
+
+	client.Resources = endpointSliceResources
+	if !servesEndpointSlices(client) {
+		t.Errorf("expected a cluster with EndpointSlices to serve them")
+	}
+}
@@ -0,0 +1,401 @@
+package traffic
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+// ingressNginxBackend shifts traffic with ingress-nginx canary Ingresses.
+// The installer points every Ingress in the chart, the primary Ingresses, at
+// a backend Service that selects only the pods of the release that installed
+// them, and creates a release-specific canary copy of each of them.
+//
+// The release that should get most of the traffic is served by the primary
+// Ingress, and the other one, if any, by its canary Ingress with a
+// canary-weight matching its share of the traffic. When a different release
+// needs to be primary, its canary first gets all the traffic, then the
+// primary Ingress is swapped to the canary's spec, and then the canary goes
+// back to getting nothing.
+//
+// ingress-nginx only honours one canary per Ingress, the oldest one, so
+// canaries without traffic are deleted, except for the most recent one, which
+// belongs to the release most likely to need it next. Releases that need a
+// canary they don't have get one copied from the primary Ingress.
+type ingressNginxBackend struct{}
+
+func (ingressNginxBackend) Sync(ct *ClusterTraffic) (TrafficStatus, error) {
+	svc, err := getProductionService(ct.InformerFactory, ct.Namespace, ct.AppName)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	podsShifted, err := enableReleasePods(ct)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	totalWeight := uint32(0)
+	var weighted []string
+	for _, release := range sortedReleases(ct.ReleaseWeights) {
+		totalWeight += ct.ReleaseWeights[release]
+		if ct.ReleaseWeights[release] > 0 {
+			weighted = append(weighted, release)
+		}
+	}
+
+	// There's nothing sensible to route to when no release wants
+	// traffic, so we leave whatever Ingresses we had in place.
+	if totalWeight == 0 {
+		return TrafficStatus{Ready: podsShifted == 0}, nil
+	}
+
+	if len(weighted) > 2 {
+		return TrafficStatus{}, shippererrors.NewUnrecoverableError(fmt.Errorf(
+			"ingress-nginx traffic backend can split traffic between at most 2 releases, but %d want traffic",
+			len(weighted)))
+	}
+
+	primaries, canaries, err := getAppIngresses(ct)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	if len(primaries) == 0 {
+		return TrafficStatus{
+			Reason:  InProgress,
+			Message: fmt.Sprintf("no Ingress found for application %q", ct.AppName),
+		}, nil
+	}
+
+	status := TrafficStatus{
+		Ready:          podsShifted == 0,
+		AchievedWeight: math.MaxUint32,
+	}
+
+	for _, primary := range primaries {
+		achievedWeight, upToDate, err := syncIngressNginxCanaries(ct, svc, primary, canaries, weighted, totalWeight)
+		if err != nil {
+			return TrafficStatus{}, err
+		}
+
+		status.Ready = status.Ready && upToDate
+		if achievedWeight < status.AchievedWeight {
+			status.AchievedWeight = achievedWeight
+		}
+	}
+
+	if !status.Ready {
+		status.Reason = InProgress
+	}
+
+	return status, nil
+}
+
+// informers returns the Ingress informer, provided the cluster serves them.
+func (ingressNginxBackend) informers(ct *ClusterTraffic) ([]cache.SharedIndexInformer, error) {
+	if !ct.Ingresses {
+		return nil, shippererrors.NewUnrecoverableError(fmt.Errorf(
+			"cluster %q does not serve %s Ingresses, which the ingress-nginx traffic backend needs",
+			ct.Cluster, networkingv1beta1.SchemeGroupVersion))
+	}
+
+	return []cache.SharedIndexInformer{ct.InformerFactory.Networking().V1beta1().Ingresses().Informer()}, nil
+}
+
+// servesIngresses tells whether an application cluster serves
+// networking.k8s.io/v1beta1 Ingresses.
+func servesIngresses(client kubernetes.Interface) bool {
+	return servesResource(client, networkingv1beta1.SchemeGroupVersion, "Ingress")
+}
+
+// getAppIngresses returns the primary Ingresses of the application, sorted
+// by name, and its canary Ingresses, by name.
+func getAppIngresses(ct *ClusterTraffic) ([]*networkingv1beta1.Ingress, map[string]*networkingv1beta1.Ingress, error) {
+	appSelector := labels.Set{shipper.AppLabel: ct.AppName}.AsSelector()
+	ingresses, err := ct.InformerFactory.Networking().V1beta1().Ingresses().Lister().
+		Ingresses(ct.Namespace).List(appSelector)
+	if err != nil {
+		return nil, nil, shippererrors.NewKubeclientListError(
+			networkingv1beta1.SchemeGroupVersion.WithKind("Ingress"),
+			ct.Namespace, appSelector, err)
+	}
+
+	var primaries []*networkingv1beta1.Ingress
+	canaries := make(map[string]*networkingv1beta1.Ingress)
+	for _, ingress := range ingresses {
+		if ingress.Labels[shipper.LBLabel] == shipper.LBForRelease {
+			canaries[ingress.Name] = ingress
+		} else {
+			primaries = append(primaries, ingress)
+		}
+	}
+
+	sort.Slice(primaries, func(i, j int) bool {
+		return primaries[i].Name < primaries[j].Name
+	})
+
+	return primaries, canaries, nil
+}
+
+// syncIngressNginxCanaries moves the primary Ingress and its canaries
+// towards the desired weights. It returns the weight ct.ReleaseName got
+// through them before any changes were made, and whether they were already
+// as desired.
+func syncIngressNginxCanaries(
+	ct *ClusterTraffic,
+	svc *corev1.Service,
+	primary *networkingv1beta1.Ingress,
+	canaries map[string]*networkingv1beta1.Ingress,
+	weighted []string,
+	totalWeight uint32,
+) (uint32, bool, error) {
+	releases := sortedReleases(ct.ReleaseWeights)
+
+	current := ingressPrimaryRelease(primary, svc.Name, releases)
+
+	releaseCanaries := make(map[string]*networkingv1beta1.Ingress)
+	observed := make(map[string]int)
+	newestCanary := ""
+	for _, release := range releases {
+		canary, ok := canaries[trafficutil.ReleaseScopedName(primary.Name, release)]
+		if !ok {
+			continue
+		}
+
+		releaseCanaries[release] = canary
+		observed[release] = ingressCanaryWeight(canary)
+
+		if newestCanary == "" || releaseCanaries[newestCanary].CreationTimestamp.Before(&canary.CreationTimestamp) {
+			newestCanary = release
+		}
+	}
+
+	achievedWeight := ingressAchievedWeight(ct.ReleaseName, current, observed, totalWeight)
+
+	// The current primary keeps its place as long as it wants
+	// traffic. Otherwise, the release that wants the most traffic takes
+	// over.
+	desiredPrimary := current
+	if ct.ReleaseWeights[current] == 0 {
+		desiredPrimary = weighted[0]
+		for _, release := range weighted {
+			if ct.ReleaseWeights[release] > ct.ReleaseWeights[desiredPrimary] {
+				desiredPrimary = release
+			}
+		}
+	}
+
+	desired := make(map[string]int)
+	if desiredPrimary != current {
+		desired[desiredPrimary] = 100
+	} else {
+		for _, release := range weighted {
+			if release != current {
+				desired[release] = int(math.Round(float64(ct.ReleaseWeights[release]) * 100 / float64(totalWeight)))
+			}
+		}
+	}
+
+	upToDate := true
+
+	// The primary can only be swapped once its successor's canary has
+	// been getting all the traffic, so nobody notices.
+	if desiredPrimary != current {
+		upToDate = false
+		if canary, ok := releaseCanaries[desiredPrimary]; ok && observed[desiredPrimary] == 100 {
+			updated := primary.DeepCopy()
+			updated.Spec = *canary.Spec.DeepCopy()
+			_, err := ct.Clientset.NetworkingV1beta1().Ingresses(ct.Namespace).Update(updated)
+			if err != nil {
+				return 0, false, shippererrors.NewKubeclientUpdateError(updated, err).
+					WithKind(networkingv1beta1.SchemeGroupVersion.WithKind("Ingress"))
+			}
+		}
+	}
+
+	for _, release := range releases {
+		canary, hasCanary := releaseCanaries[release]
+		weight := desired[release]
+
+		if weight == 0 && !hasCanary {
+			continue
+		} else if weight == 0 && release != newestCanary {
+			upToDate = false
+			err := ct.Clientset.NetworkingV1beta1().Ingresses(ct.Namespace).Delete(canary.Name, &metav1.DeleteOptions{})
+			if err != nil && !kerrors.IsNotFound(err) {
+				return 0, false, shippererrors.NewKubeclientDeleteError(ct.Namespace, canary.Name, err).
+					WithKind(networkingv1beta1.SchemeGroupVersion.WithKind("Ingress"))
+			}
+		} else if !hasCanary {
+			upToDate = false
+			if err := createCanaryIngress(ct, svc, primary, current, release, weight); err != nil {
+				return 0, false, err
+			}
+		} else if observed[release] != weight {
+			upToDate = false
+			updated := canary.DeepCopy()
+			if updated.Annotations == nil {
+				updated.Annotations = map[string]string{}
+			}
+			updated.Annotations[trafficutil.IngressNginxCanaryAnnotation] = "true"
+			updated.Annotations[trafficutil.IngressNginxCanaryWeightAnnotation] = strconv.Itoa(weight)
+			_, err := ct.Clientset.NetworkingV1beta1().Ingresses(ct.Namespace).Update(updated)
+			if err != nil {
+				return 0, false, shippererrors.NewKubeclientUpdateError(updated, err).
+					WithKind(networkingv1beta1.SchemeGroupVersion.WithKind("Ingress"))
+			}
+		}
+	}
+
+	return achievedWeight, upToDate, nil
+}
+
+// ingressPrimaryRelease returns the release whose backend Service the
+// primary Ingress routes requests to, if any.
+func ingressPrimaryRelease(primary *networkingv1beta1.Ingress, serviceName string, releases []string) string {
+	backends := ingressBackends(&primary.Spec)
+	for _, release := range releases {
+		backendName := trafficutil.ReleaseScopedName(serviceName, release)
+		for _, backend := range backends {
+			if backend.ServiceName == backendName {
+				return release
+			}
+		}
+	}
+
+	return ""
+}
+
+// ingressCanaryWeight returns the percentage of requests ingress-nginx sends
+// to a canary Ingress.
+func ingressCanaryWeight(canary *networkingv1beta1.Ingress) int {
+	if canary.Annotations[trafficutil.IngressNginxCanaryAnnotation] != "true" {
+		return 0
+	}
+
+	weight, err := strconv.Atoi(canary.Annotations[trafficutil.IngressNginxCanaryWeightAnnotation])
+	if err != nil || weight < 0 {
+		return 0
+	} else if weight > 100 {
+		return 100
+	}
+
+	return weight
+}
+
+// ingressAchievedWeight returns the weight release gets from its canary, and
+// whatever the canaries leave to the primary if it is the primary release.
+func ingressAchievedWeight(release, primary string, canaryWeights map[string]int, totalWeight uint32) uint32 {
+	percentage := canaryWeights[release]
+	if release == primary {
+		leftover := 100
+		for _, weight := range canaryWeights {
+			leftover -= weight
+		}
+
+		if leftover > 0 {
+			percentage += leftover
+		}
+	}
+
+	if percentage > 100 {
+		percentage = 100
+	}
+
+	return uint32(math.Round(float64(percentage) / 100 * float64(totalWeight)))
+}
+
+// createCanaryIngress creates a canary Ingress for release from the primary
+// Ingress, for releases that lost the one the installer created for them.
+func createCanaryIngress(
+	ct *ClusterTraffic,
+	svc *corev1.Service,
+	primary *networkingv1beta1.Ingress,
+	primaryRelease, release string,
+	weight int,
+) error {
+	anchorConfigMap, err := getReleaseAnchor(ct, release)
+	if err != nil {
+		return err
+	}
+
+	annotations := make(map[string]string, len(primary.Annotations)+2)
+	for key, value := range primary.Annotations {
+		annotations[key] = value
+	}
+	annotations[trafficutil.IngressNginxCanaryAnnotation] = "true"
+	annotations[trafficutil.IngressNginxCanaryWeightAnnotation] = strconv.Itoa(weight)
+
+	canary := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      trafficutil.ReleaseScopedName(primary.Name, release),
+			Namespace: ct.Namespace,
+			Labels: map[string]string{
+				shipper.AppLabel:     ct.AppName,
+				shipper.ReleaseLabel: release,
+				shipper.LBLabel:      shipper.LBForRelease,
+			},
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				anchor.ConfigMapAnchorToOwnerReference(anchorConfigMap),
+			},
+		},
+		Spec: *primary.Spec.DeepCopy(),
+	}
+
+	from := svc.Name
+	if primaryRelease != "" {
+		from = trafficutil.ReleaseScopedName(svc.Name, primaryRelease)
+	}
+	to := trafficutil.ReleaseScopedName(svc.Name, release)
+	for _, backend := range ingressBackends(&canary.Spec) {
+		if backend.ServiceName == from {
+			backend.ServiceName = to
+		}
+	}
+
+	// Every release syncs the canaries of all the others, so another one
+	// might have beaten us to it since our informer last heard of it.
+	_, err = ct.Clientset.NetworkingV1beta1().Ingresses(ct.Namespace).Create(canary)
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return shippererrors.NewKubeclientCreateError(canary, err).
+			WithKind(networkingv1beta1.SchemeGroupVersion.WithKind("Ingress"))
+	}
+
+	return nil
+}
+
+// ingressBackends returns pointers to every backend in spec.
+func ingressBackends(spec *networkingv1beta1.IngressSpec) []*networkingv1beta1.IngressBackend {
+	var backends []*networkingv1beta1.IngressBackend
+	if spec.Backend != nil {
+		backends = append(backends, spec.Backend)
+	}
+
+	for i := range spec.Rules {
+		if spec.Rules[i].HTTP == nil {
+			continue
+		}
+
+		for j := range spec.Rules[i].HTTP.Paths {
+			backends = append(backends, &spec.Rules[i].HTTP.Paths[j].Backend)
+		}
+	}
+
+	return backends
+}
@@ -0,0 +1,311 @@
+package traffic
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+const testIngressName = "test-ingress"
+
+var ingressCreationTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// buildPrimaryIngress returns an Ingress for the test application that
+// routes requests to release's backend Service.
+func buildPrimaryIngress(release string) *networkingv1beta1.Ingress {
+	svc := buildService(shippertesting.TestApp)
+
+	return &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testIngressName,
+			Namespace: shippertesting.TestNamespace,
+			Labels: map[string]string{
+				shipper.AppLabel: shippertesting.TestApp,
+			},
+		},
+		Spec: networkingv1beta1.IngressSpec{
+			Backend: &networkingv1beta1.IngressBackend{
+				ServiceName: trafficutil.ReleaseScopedName(svc.Name, release),
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+}
+
+// buildCanaryIngress returns release's canary Ingress, created the given
+// number of minutes after the primary one.
+func buildCanaryIngress(release string, weight int, minutes int) *networkingv1beta1.Ingress {
+	canary := buildPrimaryIngress(release)
+	canary.Name = trafficutil.ReleaseScopedName(testIngressName, release)
+	canary.CreationTimestamp = metav1.NewTime(ingressCreationTime.Add(time.Duration(minutes) * time.Minute))
+	canary.Labels[shipper.ReleaseLabel] = release
+	canary.Labels[shipper.LBLabel] = shipper.LBForRelease
+	canary.Annotations = map[string]string{
+		trafficutil.IngressNginxCanaryAnnotation:       "true",
+		trafficutil.IngressNginxCanaryWeightAnnotation: strconv.Itoa(weight),
+	}
+
+	return canary
+}
+
+// ingressNginxObjects builds the objects for the conformance scenarios, as
+// the installer would have left them: the primary Ingress routes requests to
+// the first release with pods getting traffic, and every release has a
+// canary Ingress without traffic, newer releases having newer ones.
+func ingressNginxObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	objects := []runtime.Object{buildService(shippertesting.TestApp)}
+
+	primary := ""
+	for i, release := range releases {
+		objects = append(objects, buildAnchor(release), buildCanaryIngress(release, 0, i+1))
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withTraffic, withTraffic))
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withoutTraffic, noTraffic))
+
+		if primary == "" && pods[release].withTraffic > 0 {
+			primary = release
+		}
+	}
+
+	if primary == "" {
+		primary = releases[0]
+	}
+
+	return append(objects, buildPrimaryIngress(primary)), nil
+}
+
+func getTestIngresses(t *testing.T, client *kubefake.Clientset) map[string]*networkingv1beta1.Ingress {
+	list, err := client.NetworkingV1beta1().Ingresses(shippertesting.TestNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list Ingresses: %s", err)
+	}
+
+	ingresses := make(map[string]*networkingv1beta1.Ingress)
+	for i := range list.Items {
+		ingresses[list.Items[i].Name] = &list.Items[i]
+	}
+
+	return ingresses
+}
+
+// TestIngressNginxBackendSwapsPrimary verifies that a release only becomes
+// primary after its canary gets all the traffic, that its canary goes back
+// to getting nothing afterwards, and that canaries nobody needs are deleted.
+func TestIngressNginxBackendSwapsPrimary(t *testing.T) {
+	incumbent, contender, aborted := "incumbent", "contender", "aborted"
+
+	kubeObjects := []runtime.Object{
+		buildService(shippertesting.TestApp),
+		buildPrimaryIngress(incumbent),
+		buildCanaryIngress(aborted, 0, 1),
+		buildCanaryIngress(contender, 0, 2),
+	}
+	for _, release := range []string{incumbent, contender, aborted} {
+		kubeObjects = append(kubeObjects, buildAnchor(release))
+		kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, release, 1, withTraffic))
+	}
+
+	client := kubefake.NewSimpleClientset(kubeObjects...)
+	weights := map[string]uint32{incumbent: 0, contender: 100, aborted: 0}
+
+	expectedRounds := []struct {
+		status         TrafficStatus
+		primaryRelease string
+		canaries       map[string]string
+	}{
+		{
+			// The contender's canary gets all the traffic, and
+			// the aborted release's is deleted.
+			status:         TrafficStatus{Reason: InProgress},
+			primaryRelease: incumbent,
+			canaries:       map[string]string{contender: "100"},
+		},
+		{
+			// The primary is swapped to the contender.
+			status:         TrafficStatus{AchievedWeight: 100, Reason: InProgress},
+			primaryRelease: contender,
+			canaries:       map[string]string{contender: "100"},
+		},
+		{
+			// The contender's canary goes back to nothing, but
+			// sticks around as the newest one.
+			status:         TrafficStatus{AchievedWeight: 100, Reason: InProgress},
+			primaryRelease: contender,
+			canaries:       map[string]string{contender: "0"},
+		},
+		{
+			status:         TrafficStatus{Ready: true, AchievedWeight: 100},
+			primaryRelease: contender,
+			canaries:       map[string]string{contender: "0"},
+		},
+	}
+
+	svc := buildService(shippertesting.TestApp)
+	for i, expected := range expectedRounds {
+		informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+		(&Controller{}).subscribeToAppClusterEvents(informerFactory)
+
+		ct := &ClusterTraffic{
+			Cluster:         shippertesting.TestCluster,
+			Namespace:       shippertesting.TestNamespace,
+			AppName:         shippertesting.TestApp,
+			ReleaseName:     contender,
+			ReleaseWeights:  weights,
+			Clientset:       client,
+			InformerFactory: informerFactory,
+			Ingresses:       true,
+		}
+		if _, err := (ingressNginxBackend{}).informers(ct); err != nil {
+			t.Fatalf("round %d: unexpected error: %s", i, err)
+		}
+
+		stopCh := make(chan struct{})
+		informerFactory.Start(stopCh)
+		informerFactory.WaitForCacheSync(stopCh)
+
+		status, err := ingressNginxBackend{}.Sync(ct)
+		close(stopCh)
+
+		if err != nil {
+			t.Fatalf("round %d: unexpected error: %s", i, err)
+		}
+
+		if eq, diff := shippertesting.DeepEqualDiff(expected.status, status); !eq {
+			t.Errorf("round %d: status differs from expected:\n%s", i, diff)
+		}
+
+		ingresses := getTestIngresses(t, client)
+
+		primary := ingresses[testIngressName]
+		expectedBackend := trafficutil.ReleaseScopedName(svc.Name, expected.primaryRelease)
+		if primary.Spec.Backend.ServiceName != expectedBackend {
+			t.Errorf("round %d: expected primary Ingress to route to %q, got %q",
+				i, expectedBackend, primary.Spec.Backend.ServiceName)
+		}
+
+		canaries := make(map[string]string)
+		for _, release := range []string{incumbent, contender, aborted} {
+			canary, ok := ingresses[trafficutil.ReleaseScopedName(testIngressName, release)]
+			if ok {
+				canaries[release] = canary.Annotations[trafficutil.IngressNginxCanaryWeightAnnotation]
+			}
+		}
+
+		if eq, diff := shippertesting.DeepEqualDiff(expected.canaries, canaries); !eq {
+			t.Errorf("round %d: canary weights differ from expected:\n%s", i, diff)
+		}
+	}
+}
+
+// TestIngressNginxBackendRecreatesCanary verifies that a release that needs a
+// canary it doesn't have gets one copied from the primary Ingress, routing
+// requests to its own backend Service.
+func TestIngressNginxBackendRecreatesCanary(t *testing.T) {
+	incumbent, contender := "incumbent", "contender"
+
+	kubeObjects := []runtime.Object{
+		buildService(shippertesting.TestApp),
+		buildPrimaryIngress(incumbent),
+		buildAnchor(incumbent),
+		buildAnchor(contender),
+	}
+
+	client := kubefake.NewSimpleClientset(kubeObjects...)
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	(&Controller{}).subscribeToAppClusterEvents(informerFactory)
+
+	ct := &ClusterTraffic{
+		Cluster:         shippertesting.TestCluster,
+		Namespace:       shippertesting.TestNamespace,
+		AppName:         shippertesting.TestApp,
+		ReleaseName:     incumbent,
+		ReleaseWeights:  map[string]uint32{incumbent: 75, contender: 25},
+		Clientset:       client,
+		InformerFactory: informerFactory,
+		Ingresses:       true,
+	}
+	if _, err := (ingressNginxBackend{}).informers(ct); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	status, err := ingressNginxBackend{}.Sync(ct)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := TrafficStatus{AchievedWeight: 100, Reason: InProgress}
+	if eq, diff := shippertesting.DeepEqualDiff(expected, status); !eq {
+		t.Errorf("status differs from expected:\n%s", diff)
+	}
+
+	name := trafficutil.ReleaseScopedName(testIngressName, contender)
+	canary, ok := getTestIngresses(t, client)[name]
+	if !ok {
+		t.Fatalf("expected canary Ingress %q to be created", name)
+	}
+
+	svc := buildService(shippertesting.TestApp)
+	expectedBackend := trafficutil.ReleaseScopedName(svc.Name, contender)
+	if canary.Spec.Backend.ServiceName != expectedBackend {
+		t.Errorf("expected canary Ingress to route to %q, got %q",
+			expectedBackend, canary.Spec.Backend.ServiceName)
+	}
+
+	expectedAnnotations := map[string]string{
+		trafficutil.IngressNginxCanaryAnnotation:       "true",
+		trafficutil.IngressNginxCanaryWeightAnnotation: "25",
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedAnnotations, canary.Annotations); !eq {
+		t.Errorf("canary Ingress annotations differ from expected:\n%s", diff)
+	}
+
+	expectedOwner := fmt.Sprintf("%s-anchor", contender)
+	if len(canary.OwnerReferences) != 1 || canary.OwnerReferences[0].Name != expectedOwner {
+		t.Errorf("expected canary Ingress to be owned by %q, got %v", expectedOwner, canary.OwnerReferences)
+	}
+}
+
+// TestIngressNginxBackendNeedsIngresses verifies that the ingress-nginx
+// backend only watches Ingresses in clusters serving them, and refuses to
+// work in the others instead of waiting for an informer that would never
+// sync.
+func TestIngressNginxBackendNeedsIngresses(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	if servesIngresses(client) {
+		t.Fatalf("expected a cluster without Ingress resources not to serve them")
+	}
+
+	ct := &ClusterTraffic{
+		Cluster:         shippertesting.TestCluster,
+		InformerFactory: kubeinformers.NewSharedInformerFactory(client, 0),
+	}
+	if _, err := (ingressNginxBackend{}).informers(ct); err == nil || shippererrors.ShouldRetry(err) {
+		t.Fatalf("expected an unrecoverable error, got %v", err)
+	}
+
+	client.Resources = ingressResources
+	ct.Ingresses = servesIngresses(client)
+	informers, err := (ingressNginxBackend{}).informers(ct)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(informers) != 1 {
+		t.Fatalf("expected the Ingress informer, got %d informers", len(informers))
+	}
+}
@@ -0,0 +1,215 @@
+package traffic
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+var (
+	istioVirtualServiceGVK  = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1alpha3", Kind: "VirtualService"}
+	istioDestinationRuleGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1alpha3", Kind: "DestinationRule"}
+
+	istioVirtualServiceGVR  = istioVirtualServiceGVK.GroupVersion().WithResource("virtualservices")
+	istioDestinationRuleGVR = istioDestinationRuleGVK.GroupVersion().WithResource("destinationrules")
+)
+
+// istioBackend shifts traffic with Istio. Every release gets a subset in a
+// DestinationRule, selecting its pods by shipper.ReleaseLabel, and a route
+// in a VirtualService weighted by the percentage of requests it should get.
+// Both objects are named after the application's production Service, and
+// are owned by Shipper: any routes added by hand are overwritten.
+//
+// As the VirtualService decides where requests go, every pod of a release is
+// labeled to be part of the production Service, and weights are achieved
+// exactly no matter how many pods each release has.
+type istioBackend struct{}
+
+func (istioBackend) Sync(ct *ClusterTraffic) (TrafficStatus, error) {
+	if ct.DynamicClient == nil {
+		return TrafficStatus{}, shippererrors.NewUnrecoverableError(
+			fmt.Errorf("istio traffic backend requires a dynamic client for cluster %q", ct.Cluster))
+	}
+
+	svc, err := getProductionService(ct.InformerFactory, ct.Namespace, ct.AppName)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	podsShifted, err := enableReleasePods(ct)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	totalWeight := uint32(0)
+	for _, weight := range ct.ReleaseWeights {
+		totalWeight += weight
+	}
+
+	// There's nothing sensible to route to when no release wants
+	// traffic, so we leave whatever routes we had in place.
+	if totalWeight == 0 {
+		return TrafficStatus{Ready: podsShifted == 0}, nil
+	}
+
+	if err := ensureIstioDestinationRule(ct, svc); err != nil {
+		return TrafficStatus{}, err
+	}
+
+	desiredPercentages := istioRoutePercentages(ct.ReleaseWeights)
+	achievedPercentage, routesUpToDate, err := ensureIstioVirtualService(ct, svc, desiredPercentages)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	status := TrafficStatus{
+		AchievedWeight: uint32(math.Round(float64(achievedPercentage) / 100 * float64(totalWeight))),
+		Ready:          routesUpToDate && podsShifted == 0,
+	}
+
+	if !status.Ready {
+		status.Reason = InProgress
+	}
+
+	return status, nil
+}
+
+// istioRoutePercentages turns release weights into percentages that add up
+// to exactly 100, as Istio requires, by handing out the points lost to
+// rounding down to the releases with the largest remainders.
+func istioRoutePercentages(weights map[string]uint32) map[string]int64 {
+	releases := sortedReleases(weights)
+
+	totalWeight := uint32(0)
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	percentages := make(map[string]int64, len(weights))
+	remainders := make(map[string]float64, len(weights))
+	assigned := int64(0)
+	for _, release := range releases {
+		exact := float64(weights[release]) * 100 / float64(totalWeight)
+		percentages[release] = int64(math.Floor(exact))
+		remainders[release] = exact - math.Floor(exact)
+		assigned += percentages[release]
+	}
+
+	byRemainder := make([]string, len(releases))
+	copy(byRemainder, releases)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+
+	for i := 0; assigned < 100; i++ {
+		percentages[byRemainder[i%len(byRemainder)]]++
+		assigned++
+	}
+
+	return percentages
+}
+
+func ensureIstioDestinationRule(ct *ClusterTraffic, svc *corev1.Service) error {
+	subsets := make([]interface{}, 0, len(ct.ReleaseWeights))
+	for _, release := range sortedReleases(ct.ReleaseWeights) {
+		subsets = append(subsets, map[string]interface{}{
+			"name": release,
+			"labels": map[string]interface{}{
+				shipper.ReleaseLabel: release,
+			},
+		})
+	}
+
+	spec := map[string]interface{}{
+		"host":    svc.Name,
+		"subsets": subsets,
+	}
+
+	_, _, err := ensureMeshObject(ct, istioDestinationRuleGVK, istioDestinationRuleGVR, svc.Name, spec)
+	return err
+}
+
+// ensureIstioVirtualService makes sure the VirtualService routes requests
+// according to percentages. It returns the percentage of requests routed to
+// ct.ReleaseName before any changes were made, and whether the routes were
+// already as desired.
+func ensureIstioVirtualService(
+	ct *ClusterTraffic,
+	svc *corev1.Service,
+	percentages map[string]int64,
+) (int64, bool, error) {
+	routes := make([]interface{}, 0, len(percentages))
+	for _, release := range sortedReleases(ct.ReleaseWeights) {
+		if percentages[release] == 0 {
+			continue
+		}
+
+		routes = append(routes, map[string]interface{}{
+			"destination": map[string]interface{}{
+				"host":   svc.Name,
+				"subset": release,
+			},
+			"weight": percentages[release],
+		})
+	}
+
+	spec := map[string]interface{}{
+		"hosts": []interface{}{svc.Name},
+		"http": []interface{}{
+			map[string]interface{}{
+				"route": routes,
+			},
+		},
+	}
+
+	existing, upToDate, err := ensureMeshObject(ct, istioVirtualServiceGVK, istioVirtualServiceGVR, svc.Name, spec)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return istioRoutePercentage(existing, svc.Name, ct.ReleaseName), upToDate, nil
+}
+
+// istioRoutePercentage returns the weight of the route to release in a
+// VirtualService.
+func istioRoutePercentage(vs *unstructured.Unstructured, host, release string) int64 {
+	if vs == nil {
+		return 0
+	}
+
+	httpRoutes, _, _ := unstructured.NestedSlice(vs.Object, "spec", "http")
+	if len(httpRoutes) == 0 {
+		return 0
+	}
+
+	httpRoute, ok := httpRoutes[0].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	routes, _, _ := unstructured.NestedSlice(httpRoute, "route")
+	for _, r := range routes {
+		route, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		routeHost, _, _ := unstructured.NestedString(route, "destination", "host")
+		subset, _, _ := unstructured.NestedString(route, "destination", "subset")
+		if routeHost != host || subset != release {
+			continue
+		}
+
+		weight, _ := normalizeMeshValue(route["weight"]).(int64)
+		return weight
+	}
+
+	return 0
+}
@@ -0,0 +1,225 @@
+package traffic
+
+import (
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+)
+
+func buildVirtualService(host string, percentages map[string]int64) *unstructured.Unstructured {
+	releases := make([]string, 0, len(percentages))
+	for release := range percentages {
+		releases = append(releases, release)
+	}
+	sort.Strings(releases)
+
+	routes := []interface{}{}
+	for _, release := range releases {
+		routes = append(routes, map[string]interface{}{
+			"destination": map[string]interface{}{
+				"host":   host,
+				"subset": release,
+			},
+			"weight": percentages[release],
+		})
+	}
+
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(istioVirtualServiceGVK)
+	vs.SetNamespace(shippertesting.TestNamespace)
+	vs.SetName(host)
+	vs.Object["spec"] = map[string]interface{}{
+		"hosts": []interface{}{host},
+		"http": []interface{}{
+			map[string]interface{}{"route": routes},
+		},
+	}
+
+	return vs
+}
+
+// istioObjects builds the objects for the conformance scenarios. Every
+// release has an anchor, pods start out labeled for traffic, and releases
+// with pods getting traffic are routed requests in proportion to them.
+func istioObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	objects := []runtime.Object{buildService(shippertesting.TestApp)}
+
+	podsWithTraffic := make(map[string]uint32)
+	total := 0
+	for _, release := range releases {
+		objects = append(objects, buildAnchor(release))
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withTraffic, withTraffic))
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withoutTraffic, noTraffic))
+
+		podsWithTraffic[release] = uint32(pods[release].withTraffic)
+		total += pods[release].withTraffic
+	}
+
+	if total == 0 {
+		return objects, nil
+	}
+
+	svc := buildService(shippertesting.TestApp)
+	percentages := istioRoutePercentages(podsWithTraffic)
+	for release, percentage := range percentages {
+		if percentage == 0 {
+			delete(percentages, release)
+		}
+	}
+
+	return objects, []runtime.Object{buildVirtualService(svc.Name, percentages)}
+}
+
+func TestIstioRoutePercentages(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  map[string]uint32
+		expected map[string]int64
+	}{
+		{
+			name:     "single release",
+			weights:  map[string]uint32{"a": 1},
+			expected: map[string]int64{"a": 100},
+		},
+		{
+			name:     "exact split",
+			weights:  map[string]uint32{"a": 1, "b": 3},
+			expected: map[string]int64{"a": 25, "b": 75},
+		},
+		{
+			name:     "largest remainder wins",
+			weights:  map[string]uint32{"a": 10, "b": 20},
+			expected: map[string]int64{"a": 33, "b": 67},
+		},
+		{
+			name:     "ties broken by release name",
+			weights:  map[string]uint32{"a": 1, "b": 1, "c": 1},
+			expected: map[string]int64{"a": 34, "b": 33, "c": 33},
+		},
+		{
+			name:     "release without weight",
+			weights:  map[string]uint32{"a": 0, "b": 5},
+			expected: map[string]int64{"a": 0, "b": 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := istioRoutePercentages(tt.weights)
+			if eq, diff := shippertesting.DeepEqualDiff(tt.expected, actual); !eq {
+				t.Errorf("percentages differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestIstioBackendReadsBackVirtualService verifies that the istio backend
+// reports the weight from the VirtualService as it found it, and only
+// considers a release ready once the VirtualService has caught up with the
+// desired weights. Both the VirtualService and the DestinationRule are owned
+// by the anchors of every release, so they go away with the application.
+func TestIstioBackendReadsBackVirtualService(t *testing.T) {
+	svc := buildService(shippertesting.TestApp)
+	incumbent, contender := "incumbent", "contender"
+
+	kubeObjects := []runtime.Object{svc, buildAnchor(incumbent), buildAnchor(contender)}
+	kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, incumbent, 2, withTraffic))
+	kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, contender, 1, withTraffic))
+
+	client := kubefake.NewSimpleClientset(kubeObjects...)
+	dynamicClient := newMeshDynamicClient(
+		buildVirtualService(svc.Name, map[string]int64{incumbent: 100}),
+	)
+
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	(&Controller{}).subscribeToAppClusterEvents(informerFactory)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	ct := &ClusterTraffic{
+		Cluster:         shippertesting.TestCluster,
+		Namespace:       shippertesting.TestNamespace,
+		AppName:         shippertesting.TestApp,
+		ReleaseName:     contender,
+		ReleaseWeights:  map[string]uint32{incumbent: 90, contender: 10},
+		Clientset:       client,
+		InformerFactory: informerFactory,
+		DynamicClient:   dynamicClient,
+	}
+
+	status, err := istioBackend{}.Sync(ct)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := TrafficStatus{Reason: InProgress}
+	if eq, diff := shippertesting.DeepEqualDiff(expected, status); !eq {
+		t.Errorf("status differs from expected:\n%s", diff)
+	}
+
+	vs, err := dynamicClient.Resource(istioVirtualServiceGVR).
+		Namespace(shippertesting.TestNamespace).Get(svc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get VirtualService: %s", err)
+	}
+
+	expectedVS := buildVirtualService(svc.Name, map[string]int64{incumbent: 90, contender: 10})
+	if eq, diff := shippertesting.DeepEqualDiff(expectedVS.Object["spec"], vs.Object["spec"]); !eq {
+		t.Errorf("VirtualService spec differs from expected:\n%s", diff)
+	}
+
+	expectedOwners := []metav1.OwnerReference{
+		anchor.ConfigMapAnchorToOwnerReference(buildAnchor(contender)),
+		anchor.ConfigMapAnchorToOwnerReference(buildAnchor(incumbent)),
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedOwners, vs.GetOwnerReferences()); !eq {
+		t.Errorf("VirtualService owner references differ from expected:\n%s", diff)
+	}
+
+	dr, err := dynamicClient.Resource(istioDestinationRuleGVR).
+		Namespace(shippertesting.TestNamespace).Get(svc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get DestinationRule: %s", err)
+	}
+
+	subsets, _, _ := unstructured.NestedSlice(dr.Object, "spec", "subsets")
+	expectedSubsets := []interface{}{
+		map[string]interface{}{
+			"name":   contender,
+			"labels": map[string]interface{}{shipper.ReleaseLabel: contender},
+		},
+		map[string]interface{}{
+			"name":   incumbent,
+			"labels": map[string]interface{}{shipper.ReleaseLabel: incumbent},
+		},
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedSubsets, subsets); !eq {
+		t.Errorf("DestinationRule subsets differ from expected:\n%s", diff)
+	}
+
+	if eq, diff := shippertesting.DeepEqualDiff(expectedOwners, dr.GetOwnerReferences()); !eq {
+		t.Errorf("DestinationRule owner references differ from expected:\n%s", diff)
+	}
+
+	status, err = istioBackend{}.Sync(ct)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected = TrafficStatus{Ready: true, AchievedWeight: 10}
+	if eq, diff := shippertesting.DeepEqualDiff(expected, status); !eq {
+		t.Errorf("status differs from expected:\n%s", diff)
+	}
+}
@@ -0,0 +1,208 @@
+package traffic
+
+import (
+	"reflect"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+)
+
+// enableReleasePods labels every pod in ct.ReleaseName to be part of the
+// production Service, and returns how many of them needed it.
+func enableReleasePods(ct *ClusterTraffic) (int, error) {
+	releaseSelector := labels.Set{
+		shipper.AppLabel:     ct.AppName,
+		shipper.ReleaseLabel: ct.ReleaseName,
+	}.AsSelector()
+	pods, err := ct.InformerFactory.Core().V1().Pods().Lister().
+		Pods(ct.Namespace).List(releaseSelector)
+	if err != nil {
+		return 0, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Pod"),
+			ct.Namespace, releaseSelector, err)
+	}
+
+	var podsToEnable []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Labels[shipper.PodTrafficStatusLabel] != shipper.Enabled {
+			podsToEnable = append(podsToEnable, pod)
+		}
+	}
+
+	if len(podsToEnable) == 0 {
+		return 0, nil
+	}
+
+	err = shiftPodLabels(ct.Clientset, map[string][]*corev1.Pod{
+		shipper.Enabled: podsToEnable,
+	})
+
+	return len(podsToEnable), err
+}
+
+// getReleaseAnchor returns the anchor of release, which objects generated
+// for it by traffic backends are owned by.
+func getReleaseAnchor(ct *ClusterTraffic, release string) (*corev1.ConfigMap, error) {
+	name := anchor.ReleaseAnchorName(release)
+	configMap, err := ct.InformerFactory.Core().V1().ConfigMaps().Lister().
+		ConfigMaps(ct.Namespace).Get(name)
+	if err != nil {
+		return nil, shippererrors.NewKubeclientGetError(ct.Namespace, name, err).
+			WithCoreV1Kind("ConfigMap")
+	}
+
+	return configMap, nil
+}
+
+// getMeshObjectOwners returns owner references to the anchors of every
+// release sharing the mesh objects of the application. The objects are
+// shared by all of them, so they are only garbage collected once the last
+// release is gone. Releases whose anchor is already gone are skipped, but
+// ct.ReleaseName's anchor must exist.
+func getMeshObjectOwners(ct *ClusterTraffic) ([]metav1.OwnerReference, error) {
+	releases := sortedReleases(ct.ReleaseWeights)
+	if _, ok := ct.ReleaseWeights[ct.ReleaseName]; !ok {
+		releases = append(releases, ct.ReleaseName)
+		sort.Strings(releases)
+	}
+
+	lister := ct.InformerFactory.Core().V1().ConfigMaps().Lister().
+		ConfigMaps(ct.Namespace)
+	ownerReferences := make([]metav1.OwnerReference, 0, len(releases))
+	for _, release := range releases {
+		name := anchor.ReleaseAnchorName(release)
+		anchorConfigMap, err := lister.Get(name)
+		if kerrors.IsNotFound(err) && release != ct.ReleaseName {
+			continue
+		} else if err != nil {
+			return nil, shippererrors.NewKubeclientGetError(ct.Namespace, name, err).
+				WithCoreV1Kind("ConfigMap")
+		}
+
+		ownerReferences = append(ownerReferences,
+			anchor.ConfigMapAnchorToOwnerReference(anchorConfigMap))
+	}
+
+	return ownerReferences, nil
+}
+
+// ensureMeshObject creates or updates the named object so its spec contains
+// the keys in spec and it is owned by the anchors of the application's
+// releases. It returns the object as it was before any changes, if it
+// existed, and whether it was already up to date.
+func ensureMeshObject(
+	ct *ClusterTraffic,
+	gvk schema.GroupVersionKind,
+	gvr schema.GroupVersionResource,
+	name string,
+	spec map[string]interface{},
+) (*unstructured.Unstructured, bool, error) {
+	ownerReferences, err := getMeshObjectOwners(ct)
+	if err != nil {
+		return nil, false, err
+	}
+
+	client := ct.DynamicClient.Resource(gvr).Namespace(ct.Namespace)
+
+	existing, err := client.Get(name, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, false, shippererrors.NewKubeclientGetError(ct.Namespace, name, err).
+			WithKind(gvk)
+	} else if err != nil {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetNamespace(ct.Namespace)
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{shipper.AppLabel: ct.AppName})
+		obj.SetOwnerReferences(ownerReferences)
+		obj.Object["spec"] = spec
+
+		if _, err := client.Create(obj, metav1.CreateOptions{}); err != nil {
+			return nil, false, shippererrors.NewKubeclientCreateError(obj, err).
+				WithKind(gvk)
+		}
+
+		return nil, false, nil
+	}
+
+	existingSpec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+	if existingSpec == nil {
+		existingSpec = map[string]interface{}{}
+	}
+
+	upToDate := true
+	for key, value := range spec {
+		if !reflect.DeepEqual(normalizeMeshValue(existingSpec[key]), normalizeMeshValue(value)) {
+			upToDate = false
+			break
+		}
+	}
+
+	ownersUpToDate := equality.Semantic.DeepEqual(existing.GetOwnerReferences(), ownerReferences)
+	if upToDate && ownersUpToDate {
+		return existing, true, nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.SetOwnerReferences(ownerReferences)
+	for key, value := range spec {
+		existingSpec[key] = value
+	}
+	updated.Object["spec"] = existingSpec
+
+	if _, err := client.Update(updated, metav1.UpdateOptions{}); err != nil {
+		return nil, false, shippererrors.NewKubeclientUpdateError(updated, err).
+			WithKind(gvk)
+	}
+
+	return existing, upToDate, nil
+}
+
+// normalizeMeshValue converts numbers to int64, as they can come back from
+// the API server as either integers or floats depending on how they were
+// decoded.
+func normalizeMeshValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			normalized[key] = normalizeMeshValue(value)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			normalized = append(normalized, normalizeMeshValue(value))
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+func sortedReleases(weights map[string]uint32) []string {
+	releases := make([]string, 0, len(weights))
+	for release := range weights {
+		releases = append(releases, release)
+	}
+
+	sort.Strings(releases)
+
+	return releases
+}
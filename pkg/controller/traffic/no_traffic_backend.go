@@ -0,0 +1,19 @@
+package traffic
+
+import (
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+// noTrafficBackend is for applications that get no traffic at all, so there
+// is nothing to shift: every release trivially achieves its weight, and the
+// strategy only drives capacity.
+type noTrafficBackend struct{}
+
+func (noTrafficBackend) Sync(ct *ClusterTraffic) (TrafficStatus, error) {
+	return TrafficStatus{
+		Ready:          true,
+		AchievedWeight: ct.ReleaseWeights[ct.ReleaseName],
+		Reason:         trafficutil.NotApplicableReason,
+		Message:        "application gets no traffic, so there is none to shift",
+	}, nil
+}
@@ -3,10 +3,15 @@ package traffic
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	shippererrors "github.com/bookingcom/shipper/pkg/errors"
@@ -21,6 +26,236 @@ type patchOperation struct {
 	Value string `json:"value"`
 }
 
+// podLabelShifter is the default TrafficBackend. It shifts traffic by
+// labeling pods so the application's production Service selects them, and
+// measures achieved weight by how many of them are ready in its Endpoints, or
+// its EndpointSlices where the application cluster serves them.
+type podLabelShifter struct{}
+
+func (podLabelShifter) Sync(ct *ClusterTraffic) (TrafficStatus, error) {
+	return syncPodTraffic(ct, podLabelTrafficStatus, shiftPodLabels)
+}
+
+func (podLabelShifter) informers(ct *ClusterTraffic) ([]cache.SharedIndexInformer, error) {
+	return nodeInformers(ct), nil
+}
+
+// nodeInformers returns the Node informer, which syncPodTraffic needs to
+// tell the zones pods run in.
+func nodeInformers(ct *ClusterTraffic) []cache.SharedIndexInformer {
+	return []cache.SharedIndexInformer{ct.InformerFactory.Core().V1().Nodes().Informer()}
+}
+
+// syncPodTraffic implements Sync for backends that shift traffic by marking
+// the pods that should get it, so that the production Services' Endpoints
+// only include those as ready. podTrafficStatus tells how pods are marked,
+// and shiftPods marks them.
+//
+// Applications can have several production Services, all selecting pods the
+// same way. Which pods to shift doesn't depend on them, but each Service
+// achieves its own weight as pods make it into its endpoints, and the release
+// is only ready once all of them are.
+func syncPodTraffic(
+	ct *ClusterTraffic,
+	podTrafficStatus podTrafficStatusFunc,
+	shiftPods func(kubernetes.Interface, map[string][]*corev1.Pod) error,
+) (TrafficStatus, error) {
+	appPods, services, servicePodReadiness, err := getClusterObjects(ct)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	nodes, err := ct.InformerFactory.Core().V1().Nodes().Lister().List(labels.Everything())
+	if err != nil {
+		return TrafficStatus{}, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Node"),
+			"", labels.Everything(), err)
+	}
+
+	nodeZones := buildNodeZones(nodes)
+
+	status := TrafficStatus{
+		Ready:          true,
+		AchievedWeight: math.MaxUint32,
+	}
+
+	// trafficStatus is the status of the first Service that is not
+	// ready, which is the one to explain why the release isn't.
+	var trafficStatus trafficShiftingStatus
+	var notReadyService *corev1.Service
+	for _, svc := range services {
+		svcStatus := buildTrafficShiftingStatus(
+			ct.AppName, ct.ReleaseName,
+			ct.ReleaseWeights,
+			servicePodReadiness[svc.Name], appPods,
+			nodeZones,
+			podTrafficStatus)
+
+		// The weight achieved through a single Service is already
+		// all there is to know.
+		if len(services) > 1 {
+			status.Services = append(status.Services, shipper.ServiceTrafficStatus{
+				Name:            svc.Name,
+				AchievedTraffic: svcStatus.achievedTrafficWeight,
+			})
+		}
+
+		if svcStatus.achievedTrafficWeight < status.AchievedWeight {
+			status.AchievedWeight = svcStatus.achievedTrafficWeight
+		}
+
+		if notReadyService == nil && !svcStatus.ready {
+			trafficStatus = svcStatus
+			notReadyService = svc
+			status.Ready = false
+		}
+	}
+
+	if status.Ready {
+		return status, nil
+	}
+
+	// Only mention the Service that isn't ready when there's more than
+	// one of them to choose from.
+	var inService string
+	if len(services) > 1 {
+		inService = fmt.Sprintf(" for Service %q", notReadyService.Name)
+	}
+
+	if trafficStatus.podsToShift != nil {
+		// If we have pods to shift, our job can only be done after the
+		// change is made and observed, so we definitely still in
+		// progress.
+		err := shiftPods(ct.Clientset, trafficStatus.podsToShift)
+		if err != nil {
+			return status, err
+		}
+
+		status.Reason = InProgress
+	} else if trafficStatus.podsNotReady > 0 {
+		// All the pods have been shifted, made it to endpoints, but
+		// some aren't ready.
+		status.Reason = PodsNotReady
+		status.Message = fmt.Sprintf(
+			"%d/%d pods designated to receive traffic%s are not ready",
+			trafficStatus.podsNotReady, trafficStatus.podsLabeled, inService)
+	} else {
+		// All the pods have been shifted, but not enough of them are
+		// ready, and there are none not ready in endpoints, which
+		// means that they haven't made it there yet, or that the
+		// service selector does not match any pods.
+		status.Reason = PodsNotInEndpoints
+		status.Message = fmt.Sprintf(
+			"%d/%d pods designated to receive traffic%s are not yet in endpoints",
+			trafficStatus.podsLabeled-trafficStatus.podsReady, trafficStatus.podsLabeled, inService)
+	}
+
+	return status, nil
+}
+
+// getClusterObjects returns the pods of an application and its production
+// Services, along with which pods are ready behind each of them, by Service
+// name.
+func getClusterObjects(ct *ClusterTraffic) ([]*corev1.Pod, []*corev1.Service, map[string]map[string]bool, error) {
+	ns, appName := ct.Namespace, ct.AppName
+	appSelector := labels.Set{shipper.AppLabel: appName}.AsSelector()
+	appPods, err := ct.InformerFactory.Core().V1().Pods().Lister().
+		Pods(ns).List(appSelector)
+	if err != nil {
+		return nil, nil, nil, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Pod"),
+			ns, appSelector, err)
+	}
+
+	services, err := getProductionServices(ct.InformerFactory, ns, appName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	servicePodReadiness := make(map[string]map[string]bool, len(services))
+	for _, svc := range services {
+		podReadiness, err := getPodReadiness(ct.InformerFactory, svc, ct.EndpointSlices)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		servicePodReadiness[svc.Name] = podReadiness
+	}
+
+	return appPods, services, servicePodReadiness, nil
+}
+
+// getProductionService returns the Service labeled as the production load
+// balancer for an application, for backends that only support one.
+func getProductionService(
+	informerFactory kubeinformers.SharedInformerFactory,
+	ns, appName string,
+) (*corev1.Service, error) {
+	services, serviceSelector, err := listProductionServices(informerFactory, ns, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) != 1 {
+		return nil, shippererrors.NewUnexpectedObjectCountFromSelectorError(
+			serviceSelector, corev1.SchemeGroupVersion.WithKind("Service"), 1, len(services))
+	}
+
+	return services[0], nil
+}
+
+// getProductionServices returns all the Services labeled as production load
+// balancers for an application, sorted by name. There must be at least one.
+func getProductionServices(
+	informerFactory kubeinformers.SharedInformerFactory,
+	ns, appName string,
+) ([]*corev1.Service, error) {
+	services, serviceSelector, err := listProductionServices(informerFactory, ns, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		return nil, shippererrors.NewUnexpectedObjectCountFromSelectorError(
+			serviceSelector, corev1.SchemeGroupVersion.WithKind("Service"), 1, 0)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	return services, nil
+}
+
+func listProductionServices(
+	informerFactory kubeinformers.SharedInformerFactory,
+	ns, appName string,
+) ([]*corev1.Service, labels.Selector, error) {
+	serviceSelector := labels.Set(map[string]string{
+		shipper.AppLabel: appName,
+		shipper.LBLabel:  shipper.LBForProduction,
+	}).AsSelector()
+	services, err := informerFactory.Core().V1().Services().Lister().
+		Services(ns).List(serviceSelector)
+	if err != nil {
+		return nil, nil, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Service"), ns, serviceSelector, err)
+	}
+
+	return services, serviceSelector, nil
+}
+
+// podLabelTrafficStatus returns the value of pod's PodTrafficStatusLabel, or
+// shipper.Disabled if it has none.
+func podLabelTrafficStatus(pod *corev1.Pod) string {
+	v, ok := pod.Labels[shipper.PodTrafficStatusLabel]
+	if !ok {
+		return shipper.Disabled
+	}
+
+	return v
+}
+
 // shiftPodLabels ensures that the pods in podsToShift have the
 // shipper.PodTrafficStatusLabel label set to the specified values.
 func shiftPodLabels(
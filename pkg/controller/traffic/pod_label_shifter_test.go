@@ -8,6 +8,7 @@ import (
 	shippertesting "github.com/bookingcom/shipper/pkg/testing"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -85,3 +86,57 @@ func pod(name string, labels map[string]string) *corev1.Pod {
 		},
 	}
 }
+
+// TestSyncPodTrafficMultipleServices verifies that a release is only ready
+// once pods with traffic are ready behind every production Service, and that
+// the weight achieved through each of them is reported.
+func TestSyncPodTrafficMultipleServices(t *testing.T) {
+	const release = "release"
+
+	pods := buildPods(shippertesting.TestApp, release, 2, withTraffic)
+
+	http := buildService(shippertesting.TestApp)
+	grpc := buildService(shippertesting.TestApp)
+	grpc.Name = fmt.Sprintf("%s-grpc", shippertesting.TestApp)
+
+	httpEndpoints := buildEndpoints(shippertesting.TestApp)
+	for _, pod := range pods {
+		httpEndpoints = shiftPodInEndpoints(pod, httpEndpoints)
+	}
+
+	// The gRPC Service's endpoints haven't caught up with the second pod
+	// yet.
+	grpcEndpoints := shiftPodInEndpoints(pods[0], buildEndpoints(shippertesting.TestApp))
+	grpcEndpoints.Name = grpc.Name
+
+	objects := addPodsToList([]runtime.Object{http, grpc, httpEndpoints, grpcEndpoints}, pods)
+	client := kubefake.NewSimpleClientset(objects...)
+
+	statuses, err := syncConformanceReleases(
+		podLabelShifter{}, client, newMeshDynamicClient(),
+		[]string{release}, map[string]uint32{release: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	status := statuses[0]
+	if status.Ready {
+		t.Errorf("expected release not to be ready")
+	}
+
+	if status.Reason != PodsNotInEndpoints {
+		t.Errorf("expected reason %q, got %q", PodsNotInEndpoints, status.Reason)
+	}
+
+	expectedServices := []shipper.ServiceTrafficStatus{
+		{Name: grpc.Name, AchievedTraffic: 5},
+		{Name: http.Name, AchievedTraffic: 10},
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedServices, status.Services); !eq {
+		t.Errorf("service traffic differs from expected:\n%s", diff)
+	}
+
+	if status.AchievedWeight != 5 {
+		t.Errorf("expected the lowest weight of all Services to be achieved, got %d", status.AchievedWeight)
+	}
+}
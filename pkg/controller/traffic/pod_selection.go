@@ -0,0 +1,136 @@
+package traffic
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	podutil "github.com/bookingcom/shipper/pkg/util/pod"
+)
+
+// selectPodsToShift picks count pods out of candidates to be given the
+// newStatus traffic label. Pods that already carry it are never candidates,
+// so they are never churned.
+//
+// The choice is deterministic, so consecutive syncs over the same pods agree
+// on it, and is made one pod at a time so every pick takes the previous
+// ones into account:
+//
+//   - pods that are not ready are the last to get traffic and the first to
+//     lose it, as that does not change how much traffic is being served;
+//   - pods getting traffic are spread across zones first and nodes second,
+//     so enabling picks pods where the fewest pods get traffic, and
+//     disabling picks them where the most do;
+//   - among equals, the longest running pods are enabled first, and the
+//     most recently started ones are disabled first;
+//   - pod names break any remaining ties.
+//
+// enabled holds the pods that currently get traffic, and nodeZones the zone
+// of every node we know about.
+func selectPodsToShift(
+	candidates []*corev1.Pod,
+	enabled []*corev1.Pod,
+	newStatus string,
+	count int,
+	nodeZones map[string]string,
+) []*corev1.Pod {
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+
+	if count <= 0 {
+		return nil
+	}
+
+	zoneCount := make(map[string]int)
+	nodeCount := make(map[string]int)
+	for _, pod := range enabled {
+		zoneCount[nodeZones[pod.Spec.NodeName]]++
+		nodeCount[pod.Spec.NodeName]++
+	}
+
+	enabling := newStatus == shipper.Enabled
+	remaining := make([]*corev1.Pod, len(candidates))
+	copy(remaining, candidates)
+
+	selected := make([]*corev1.Pod, 0, count)
+	for len(selected) < count {
+		best := 0
+		for i := 1; i < len(remaining); i++ {
+			if preferPod(remaining[i], remaining[best], enabling, zoneCount, nodeCount, nodeZones) {
+				best = i
+			}
+		}
+
+		pod := remaining[best]
+		selected = append(selected, pod)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+
+		delta := 1
+		if !enabling {
+			delta = -1
+		}
+		zoneCount[nodeZones[pod.Spec.NodeName]] += delta
+		nodeCount[pod.Spec.NodeName] += delta
+	}
+
+	return selected
+}
+
+// preferPod returns whether a should be shifted before b, following the
+// policy described in selectPodsToShift.
+func preferPod(
+	a, b *corev1.Pod,
+	enabling bool,
+	zoneCount, nodeCount map[string]int,
+	nodeZones map[string]string,
+) bool {
+	if aReady, bReady := podutil.IsReady(a), podutil.IsReady(b); aReady != bReady {
+		return aReady == enabling
+	}
+
+	aZone, bZone := zoneCount[nodeZones[a.Spec.NodeName]], zoneCount[nodeZones[b.Spec.NodeName]]
+	if aZone != bZone {
+		return (aZone < bZone) == enabling
+	}
+
+	aNode, bNode := nodeCount[a.Spec.NodeName], nodeCount[b.Spec.NodeName]
+	if aNode != bNode {
+		return (aNode < bNode) == enabling
+	}
+
+	if aStart, bStart := podStartTime(a), podStartTime(b); !aStart.Equal(bStart) {
+		return aStart.Before(bStart) == enabling
+	}
+
+	return a.Name < b.Name
+}
+
+// podStartTime returns when pod was started by its kubelet, or when it was
+// created if it hasn't been started yet.
+func podStartTime(pod *corev1.Pod) time.Time {
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time
+	}
+
+	return pod.CreationTimestamp.Time
+}
+
+// buildNodeZones returns the zone of every node, as reported by its topology
+// labels. Nodes without any are left out.
+func buildNodeZones(nodes []*corev1.Node) map[string]string {
+	nodeZones := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		zone, ok := node.Labels[corev1.LabelZoneFailureDomainStable]
+		if !ok {
+			zone, ok = node.Labels[corev1.LabelZoneFailureDomain]
+		}
+
+		if ok {
+			nodeZones[node.Name] = zone
+		}
+	}
+
+	return nodeZones
+}
@@ -0,0 +1,172 @@
+package traffic
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+var podStartBase = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// buildPlacedPod returns a pod on node that was started the given number of
+// minutes after podStartBase.
+func buildPlacedPod(name, node string, ready bool, minutes int) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+
+	startTime := metav1.NewTime(podStartBase.Add(time.Duration(minutes) * time.Minute))
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: shippertesting.TestNamespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+		},
+		Status: corev1.PodStatus{
+			StartTime: &startTime,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: readyStatus},
+			},
+		},
+	}
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+
+	return names
+}
+
+func TestSelectPodsToShift(t *testing.T) {
+	nodeZones := buildNodeZones([]*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-a1", Labels: map[string]string{corev1.LabelZoneFailureDomainStable: "zone-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-a2", Labels: map[string]string{corev1.LabelZoneFailureDomainStable: "zone-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-b1", Labels: map[string]string{corev1.LabelZoneFailureDomain: "zone-b"}}},
+	})
+
+	tests := []struct {
+		name       string
+		candidates []*corev1.Pod
+		enabled    []*corev1.Pod
+		newStatus  string
+		count      int
+		expected   []string
+	}{
+		{
+			name: "enabling prefers ready pods",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-a1", false, 0),
+				buildPlacedPod("b", "node-a1", true, 1),
+			},
+			newStatus: shipper.Enabled,
+			count:     1,
+			expected:  []string{"b"},
+		},
+		{
+			name: "enabling spreads across zones, then nodes",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-a1", true, 0),
+				buildPlacedPod("b", "node-a1", true, 1),
+				buildPlacedPod("c", "node-a2", true, 2),
+				buildPlacedPod("d", "node-b1", true, 3),
+			},
+			enabled: []*corev1.Pod{
+				buildPlacedPod("e", "node-a1", true, 0),
+			},
+			newStatus: shipper.Enabled,
+			count:     3,
+			expected:  []string{"d", "c", "a"},
+		},
+		{
+			name: "enabling prefers longest running pods",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-a1", true, 5),
+				buildPlacedPod("b", "node-a1", true, 1),
+			},
+			newStatus: shipper.Enabled,
+			count:     1,
+			expected:  []string{"b"},
+		},
+		{
+			name: "disabling prefers pods that are not ready",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-b1", true, 0),
+				buildPlacedPod("b", "node-a1", false, 0),
+				buildPlacedPod("c", "node-a1", true, 0),
+			},
+			newStatus: shipper.Disabled,
+			count:     1,
+			expected:  []string{"b"},
+		},
+		{
+			name: "disabling thins out the most crowded zone",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-a1", true, 0),
+				buildPlacedPod("b", "node-a2", true, 1),
+				buildPlacedPod("c", "node-b1", true, 2),
+			},
+			newStatus: shipper.Disabled,
+			count:     2,
+			expected:  []string{"b", "c"},
+		},
+		{
+			name: "disabling prefers most recently started pods",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-a1", true, 1),
+				buildPlacedPod("b", "node-a1", true, 5),
+				buildPlacedPod("c", "node-a1", true, 3),
+			},
+			newStatus: shipper.Disabled,
+			count:     2,
+			expected:  []string{"b", "c"},
+		},
+		{
+			name: "never selects more pods than there are",
+			candidates: []*corev1.Pod{
+				buildPlacedPod("a", "node-a1", true, 0),
+			},
+			newStatus: shipper.Enabled,
+			count:     3,
+			expected:  []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled := tt.enabled
+			if tt.newStatus == shipper.Disabled {
+				enabled = tt.candidates
+			}
+
+			selected := selectPodsToShift(tt.candidates, enabled, tt.newStatus, tt.count, nodeZones)
+			if eq, diff := shippertesting.DeepEqualDiff(tt.expected, podNames(selected)); !eq {
+				t.Errorf("selected pods differ from expected:\n%s", diff)
+			}
+
+			// Consecutive syncs need to agree on which pods to
+			// pick, no matter what order the lister returns them
+			// in.
+			reversed := make([]*corev1.Pod, 0, len(tt.candidates))
+			for i := len(tt.candidates) - 1; i >= 0; i-- {
+				reversed = append(reversed, tt.candidates[i])
+			}
+
+			selected = selectPodsToShift(reversed, enabled, tt.newStatus, tt.count, nodeZones)
+			if eq, diff := shippertesting.DeepEqualDiff(tt.expected, podNames(selected)); !eq {
+				t.Errorf("selected pods differ from expected when candidates are reversed:\n%s", diff)
+			}
+		})
+	}
+}
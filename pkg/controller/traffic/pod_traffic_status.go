@@ -0,0 +1,85 @@
+package traffic
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+)
+
+// publishPodTrafficStatus records in ct.ReleaseName's anchor whether new pods
+// of the release can start out with traffic enabled, so the pod webhook in
+// the application cluster can label them even when Shipper is not around to
+// do it. That is only the case when the release's traffic is fully settled
+// and every one of its pods is getting traffic.
+func publishPodTrafficStatus(ct *ClusterTraffic, status TrafficStatus) error {
+	name := anchor.ReleaseAnchorName(ct.ReleaseName)
+	configMap, err := ct.InformerFactory.Core().V1().ConfigMaps().Lister().
+		ConfigMaps(ct.Namespace).Get(name)
+	if kerrors.IsNotFound(err) {
+		// The release is not installed yet, so there are no pods
+		// to speak of.
+		return nil
+	} else if err != nil {
+		return shippererrors.NewKubeclientGetError(ct.Namespace, name, err).
+			WithCoreV1Kind("ConfigMap")
+	}
+
+	settled := false
+	if status.Ready {
+		settled, err = releasePodsEnabled(ct)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, published := configMap.Data[anchor.PodTrafficStatus]
+	if settled == published {
+		return nil
+	}
+
+	configMap = configMap.DeepCopy()
+	if settled {
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[anchor.PodTrafficStatus] = shipper.Enabled
+	} else {
+		delete(configMap.Data, anchor.PodTrafficStatus)
+	}
+
+	_, err = ct.Clientset.CoreV1().ConfigMaps(ct.Namespace).Update(configMap)
+	if err != nil {
+		return shippererrors.NewKubeclientUpdateError(configMap, err).
+			WithCoreV1Kind("ConfigMap")
+	}
+
+	return nil
+}
+
+// releasePodsEnabled returns whether ct.ReleaseName has pods, and all of them
+// are labeled to get traffic.
+func releasePodsEnabled(ct *ClusterTraffic) (bool, error) {
+	releaseSelector := labels.Set{
+		shipper.AppLabel:     ct.AppName,
+		shipper.ReleaseLabel: ct.ReleaseName,
+	}.AsSelector()
+	pods, err := ct.InformerFactory.Core().V1().Pods().Lister().
+		Pods(ct.Namespace).List(releaseSelector)
+	if err != nil {
+		return false, shippererrors.NewKubeclientListError(
+			corev1.SchemeGroupVersion.WithKind("Pod"),
+			ct.Namespace, releaseSelector, err)
+	}
+
+	for _, pod := range pods {
+		if pod.Labels[shipper.PodTrafficStatusLabel] != shipper.Enabled {
+			return false, nil
+		}
+	}
+
+	return len(pods) > 0, nil
+}
@@ -0,0 +1,84 @@
+package traffic
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+)
+
+// TestPublishPodTrafficStatus verifies that a release's anchor only says new
+// pods should get traffic when the release is ready and all of its pods are
+// already getting it.
+func TestPublishPodTrafficStatus(t *testing.T) {
+	const release = "release"
+
+	tests := []struct {
+		name           string
+		ready          bool
+		podsEnabled    int
+		podsDisabled   int
+		published      bool
+		expectedStatus string
+	}{
+		{"ready with all pods enabled", true, 2, 0, false, shipper.Enabled},
+		{"ready with some pods disabled", true, 1, 1, true, ""},
+		{"ready without pods", true, 0, 0, true, ""},
+		{"not ready with all pods enabled", false, 2, 0, true, ""},
+		{"already published", true, 2, 0, true, shipper.Enabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaseAnchor := buildAnchor(release)
+			if tt.published {
+				releaseAnchor.Data[anchor.PodTrafficStatus] = shipper.Enabled
+			}
+
+			kubeObjects := []runtime.Object{releaseAnchor}
+			kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, release, tt.podsEnabled, withTraffic))
+			kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, release, tt.podsDisabled, noTraffic))
+
+			client := kubefake.NewSimpleClientset(kubeObjects...)
+			informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+			(&Controller{}).subscribeToAppClusterEvents(informerFactory)
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			informerFactory.Start(stopCh)
+			informerFactory.WaitForCacheSync(stopCh)
+
+			err := publishPodTrafficStatus(&ClusterTraffic{
+				Cluster:         shippertesting.TestCluster,
+				Namespace:       shippertesting.TestNamespace,
+				AppName:         shippertesting.TestApp,
+				ReleaseName:     release,
+				Clientset:       client,
+				InformerFactory: informerFactory,
+			}, TrafficStatus{Ready: tt.ready})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			configMap, err := client.CoreV1().ConfigMaps(shippertesting.TestNamespace).
+				Get(releaseAnchor.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get anchor: %s", err)
+			}
+
+			if status := configMap.Data[anchor.PodTrafficStatus]; status != tt.expectedStatus {
+				t.Errorf("expected published pod traffic status %q, got %q", tt.expectedStatus, status)
+			}
+
+			if _, ok := configMap.Data[anchor.InstallationTargetUID]; !ok {
+				t.Errorf("expected anchor to keep its installation target UID")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package traffic
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+)
+
+// readinessGateBackend shifts traffic just like podLabelShifter, but marks
+// pods through the shipper.PodTrafficReadinessGate condition instead of a
+// label. The installer gives pods a readiness gate on that condition and
+// takes the traffic label out of the production Service's selector, so pods
+// only get traffic while the condition is true, and selectors never change.
+type readinessGateBackend struct{}
+
+func (readinessGateBackend) Sync(ct *ClusterTraffic) (TrafficStatus, error) {
+	return syncPodTraffic(ct, podReadinessGateTrafficStatus, shiftPodReadinessGates)
+}
+
+func (readinessGateBackend) informers(ct *ClusterTraffic) ([]cache.SharedIndexInformer, error) {
+	return nodeInformers(ct), nil
+}
+
+// podReadinessGateTrafficStatus returns shipper.Enabled if pod's traffic
+// readiness gate condition is true, and shipper.Disabled otherwise.
+func podReadinessGateTrafficStatus(pod *corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == shipper.PodTrafficReadinessGate && cond.Status == corev1.ConditionTrue {
+			return shipper.Enabled
+		}
+	}
+
+	return shipper.Disabled
+}
+
+// shiftPodReadinessGates ensures that the pods in podsToShift have their
+// traffic readiness gate condition matching the specified values.
+func shiftPodReadinessGates(
+	clientset kubernetes.Interface,
+	podsToShift map[string][]*corev1.Pod,
+) error {
+	for value, pods := range podsToShift {
+		for _, pod := range pods {
+			if podReadinessGateTrafficStatus(pod) == value {
+				continue
+			}
+
+			patch := patchPodReadinessGateCondition(value)
+			_, err := clientset.CoreV1().Pods(pod.Namespace).
+				Patch(pod.Name, types.StrategicMergePatchType, patch, "status")
+			if err != nil {
+				return shippererrors.
+					NewKubeclientPatchError(pod.Namespace, pod.Name, err).
+					WithCoreV1Kind("Pod")
+			}
+		}
+	}
+
+	return nil
+}
+
+// patchPodReadinessGateCondition returns a strategic merge patch for a pod's
+// status that sets its traffic readiness gate condition to true if value is
+// shipper.Enabled, and to false otherwise. Pod conditions are merged by
+// type, so any other conditions are left alone.
+func patchPodReadinessGateCondition(value string) []byte {
+	status := corev1.ConditionFalse
+	if value == shipper.Enabled {
+		status = corev1.ConditionTrue
+	}
+
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.PodCondition{
+				{
+					Type:               shipper.PodTrafficReadinessGate,
+					Status:             status,
+					LastTransitionTime: metav1.Now(),
+				},
+			},
+		},
+	}
+
+	// Much like patchPodTrafficStatusLabel, there is nothing in here
+	// that could fail to be serialized.
+	patchBytes, _ := json.Marshal(patch)
+
+	return patchBytes
+}
@@ -0,0 +1,138 @@
+package traffic
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+)
+
+// buildReadinessGatePods returns pods with their traffic readiness gate
+// condition set as requested. Their traffic label is always disabled, so
+// anything looking at it instead gets it wrong.
+func buildReadinessGatePods(release string, count int, withTraffic bool) []*corev1.Pod {
+	status := corev1.ConditionFalse
+	if withTraffic {
+		status = corev1.ConditionTrue
+	}
+
+	pods := buildPods(shippertesting.TestApp, release, count, noTraffic)
+	for _, pod := range pods {
+		pod.Status.Conditions = []corev1.PodCondition{
+			{Type: shipper.PodTrafficReadinessGate, Status: status},
+		}
+	}
+
+	return pods
+}
+
+// readinessGateEndpoints builds the app's Endpoints as the endpoints
+// controller would with a Service selecting every pod: pods with their
+// traffic readiness gate condition set are ready, and all others are not.
+func readinessGateEndpoints(pods []*corev1.Pod) *corev1.Endpoints {
+	endpoints := buildEndpoints(shippertesting.TestApp)
+	for _, pod := range pods {
+		address := corev1.EndpointAddress{
+			TargetRef: &corev1.ObjectReference{
+				Kind:      "Pod",
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+			},
+		}
+
+		if podReadinessGateTrafficStatus(pod) == shipper.Enabled {
+			endpoints.Subsets[0].Addresses = append(endpoints.Subsets[0].Addresses, address)
+		} else {
+			endpoints.Subsets[0].NotReadyAddresses = append(endpoints.Subsets[0].NotReadyAddresses, address)
+		}
+	}
+
+	return endpoints
+}
+
+func readinessGateBackendObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	svc := buildService(shippertesting.TestApp)
+	delete(svc.Spec.Selector, shipper.PodTrafficStatusLabel)
+	objects := []runtime.Object{svc}
+
+	var appPods []*corev1.Pod
+	for _, release := range releases {
+		appPods = append(appPods, buildReadinessGatePods(release, pods[release].withTraffic, withTraffic)...)
+		appPods = append(appPods, buildReadinessGatePods(release, pods[release].withoutTraffic, noTraffic)...)
+	}
+
+	objects = addPodsToList(objects, appPods)
+
+	return append(objects, readinessGateEndpoints(appPods)), nil
+}
+
+// readinessGateBackendSettle rebuilds the app's Endpoints from the traffic
+// readiness gate conditions of its pods.
+func readinessGateBackendSettle(client *kubefake.Clientset) error {
+	podList, err := client.CoreV1().Pods(shippertesting.TestNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+
+	_, err = client.CoreV1().Endpoints(shippertesting.TestNamespace).Update(readinessGateEndpoints(pods))
+	return err
+}
+
+// TestReadinessGateBackendLeavesLabelsAlone verifies that the readiness-gate
+// backend shifts traffic through pod conditions, without touching the
+// traffic label Services might select on.
+func TestReadinessGateBackendLeavesLabelsAlone(t *testing.T) {
+	const release = "release"
+
+	kubeObjects, _ := readinessGateBackendObjects([]string{release}, map[string]podStatus{
+		release: {withoutTraffic: 2},
+	})
+	client := kubefake.NewSimpleClientset(kubeObjects...)
+
+	statuses, err := syncConformanceReleases(
+		readinessGateBackend{}, client, newMeshDynamicClient(),
+		[]string{release}, map[string]uint32{release: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if statuses[0].Reason != InProgress {
+		t.Errorf("expected release to be in progress, got %q", statuses[0].Reason)
+	}
+
+	podList, err := client.CoreV1().Pods(shippertesting.TestNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list pods: %s", err)
+	}
+
+	for _, pod := range podList.Items {
+		if status := podReadinessGateTrafficStatus(&pod); status != shipper.Enabled {
+			t.Errorf("expected pod %q to have its traffic condition set, got %q", pod.Name, status)
+		}
+
+		if label := pod.Labels[shipper.PodTrafficStatusLabel]; label != shipper.Disabled {
+			t.Errorf("expected pod %q traffic label to be left alone, got %q", pod.Name, label)
+		}
+
+		conditions := 0
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == shipper.PodTrafficReadinessGate {
+				conditions++
+			}
+		}
+
+		if conditions != 1 {
+			t.Errorf("expected pod %q to have exactly one traffic condition, got %d", pod.Name, conditions)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package traffic
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+var (
+	smiTrafficSplitGVK = schema.GroupVersionKind{Group: "split.smi-spec.io", Version: "v1alpha2", Kind: "TrafficSplit"}
+	smiTrafficSplitGVR = smiTrafficSplitGVK.GroupVersion().WithResource("trafficsplits")
+)
+
+// smiBackend shifts traffic with an SMI TrafficSplit, as understood by
+// Linkerd and other SMI-compatible service meshes. Every release gets a
+// backend Service, generated from the application's production Service with
+// a selector scoped to the release's pods, and the TrafficSplit, named after
+// the production Service, weights requests between them.
+//
+// Backend Services are owned by their release's anchor, so they go away
+// together with the release once the janitor removes the anchor. The
+// TrafficSplit is owned by Shipper: any backends added by hand are
+// overwritten.
+type smiBackend struct{}
+
+func (smiBackend) Sync(ct *ClusterTraffic) (TrafficStatus, error) {
+	if ct.DynamicClient == nil {
+		return TrafficStatus{}, shippererrors.NewUnrecoverableError(
+			fmt.Errorf("smi traffic backend requires a dynamic client for cluster %q", ct.Cluster))
+	}
+
+	svc, err := getProductionService(ct.InformerFactory, ct.Namespace, ct.AppName)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	podsShifted, err := enableReleasePods(ct)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	totalWeight := uint32(0)
+	for _, weight := range ct.ReleaseWeights {
+		totalWeight += weight
+	}
+
+	// There's nothing sensible to split traffic between when no
+	// release wants it, so we leave whatever backends we had in place.
+	if totalWeight == 0 {
+		return TrafficStatus{Ready: podsShifted == 0}, nil
+	}
+
+	backendsUpToDate := true
+	for _, release := range sortedReleases(ct.ReleaseWeights) {
+		if ct.ReleaseWeights[release] == 0 {
+			continue
+		}
+
+		upToDate, err := ensureReleaseBackendService(ct, svc, release)
+		if err != nil {
+			return TrafficStatus{}, err
+		}
+
+		backendsUpToDate = backendsUpToDate && upToDate
+	}
+
+	achievedWeight, splitUpToDate, err := ensureTrafficSplit(ct, svc)
+	if err != nil {
+		return TrafficStatus{}, err
+	}
+
+	status := TrafficStatus{
+		AchievedWeight: achievedWeight,
+		Ready:          backendsUpToDate && splitUpToDate && podsShifted == 0,
+	}
+
+	if !status.Ready {
+		status.Reason = InProgress
+	}
+
+	return status, nil
+}
+
+// ensureReleaseBackendService makes sure release has a backend Service
+// selecting only its own pods, with the same ports as the production
+// Service, and returns whether it was already as desired.
+func ensureReleaseBackendService(ct *ClusterTraffic, svc *corev1.Service, release string) (bool, error) {
+	anchorConfigMap, err := getReleaseAnchor(ct, release)
+	if err != nil {
+		return false, err
+	}
+
+	selector := make(map[string]string, len(svc.Spec.Selector)+1)
+	for key, value := range svc.Spec.Selector {
+		selector[key] = value
+	}
+	selector[shipper.ReleaseLabel] = release
+
+	var ports []corev1.ServicePort
+	for _, port := range svc.Spec.Ports {
+		port.NodePort = 0
+		ports = append(ports, port)
+	}
+
+	ownerReferences := []metav1.OwnerReference{
+		anchor.ConfigMapAnchorToOwnerReference(anchorConfigMap),
+	}
+
+	name := trafficutil.ReleaseScopedName(svc.Name, release)
+	existing, err := ct.InformerFactory.Core().V1().Services().Lister().
+		Services(ct.Namespace).Get(name)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return false, shippererrors.NewKubeclientGetError(ct.Namespace, name, err).
+			WithCoreV1Kind("Service")
+	} else if err != nil {
+		backend := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ct.Namespace,
+				Labels: map[string]string{
+					shipper.AppLabel:     ct.AppName,
+					shipper.ReleaseLabel: release,
+					shipper.LBLabel:      shipper.LBForRelease,
+				},
+				OwnerReferences: ownerReferences,
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: selector,
+				Ports:    ports,
+			},
+		}
+
+		// Every release of the application syncs the backends of all
+		// the others, so another one might have beaten us to it since
+		// our informer last heard of the Service.
+		_, err := ct.Clientset.CoreV1().Services(ct.Namespace).Create(backend)
+		if err != nil && !kerrors.IsAlreadyExists(err) {
+			return false, shippererrors.NewKubeclientCreateError(backend, err).
+				WithCoreV1Kind("Service")
+		}
+
+		return false, nil
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec.Selector, selector) &&
+		equality.Semantic.DeepEqual(existing.Spec.Ports, ports) &&
+		equality.Semantic.DeepEqual(existing.OwnerReferences, ownerReferences) {
+		return true, nil
+	}
+
+	backend := existing.DeepCopy()
+	backend.Spec.Selector = selector
+	backend.Spec.Ports = ports
+	backend.OwnerReferences = ownerReferences
+
+	if _, err := ct.Clientset.CoreV1().Services(ct.Namespace).Update(backend); err != nil {
+		return false, shippererrors.NewKubeclientUpdateError(backend, err).
+			WithCoreV1Kind("Service")
+	}
+
+	return false, nil
+}
+
+// ensureTrafficSplit makes sure the TrafficSplit weights the backend
+// Services of every release according to ct.ReleaseWeights. It returns the
+// weight of ct.ReleaseName's backend before any changes were made, and
+// whether the backends were already as desired.
+func ensureTrafficSplit(ct *ClusterTraffic, svc *corev1.Service) (uint32, bool, error) {
+	backends := make([]interface{}, 0, len(ct.ReleaseWeights))
+	for _, release := range sortedReleases(ct.ReleaseWeights) {
+		if ct.ReleaseWeights[release] == 0 {
+			continue
+		}
+
+		backends = append(backends, map[string]interface{}{
+			"service": trafficutil.ReleaseScopedName(svc.Name, release),
+			"weight":  int64(ct.ReleaseWeights[release]),
+		})
+	}
+
+	spec := map[string]interface{}{
+		"service":  svc.Name,
+		"backends": backends,
+	}
+
+	existing, upToDate, err := ensureMeshObject(ct, smiTrafficSplitGVK, smiTrafficSplitGVR, svc.Name, spec)
+	if err != nil {
+		return 0, false, err
+	}
+
+	backendName := trafficutil.ReleaseScopedName(svc.Name, ct.ReleaseName)
+	return trafficSplitWeight(existing, backendName), upToDate, nil
+}
+
+// trafficSplitWeight returns the weight of backend in a TrafficSplit.
+func trafficSplitWeight(split *unstructured.Unstructured, backend string) uint32 {
+	if split == nil {
+		return 0
+	}
+
+	backends, _, _ := unstructured.NestedSlice(split.Object, "spec", "backends")
+	for _, b := range backends {
+		entry, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		service, _, _ := unstructured.NestedString(entry, "service")
+		if service != backend {
+			continue
+		}
+
+		weight, _ := normalizeMeshValue(entry["weight"]).(int64)
+		if weight < 0 {
+			return 0
+		}
+
+		return uint32(weight)
+	}
+
+	return 0
+}
@@ -0,0 +1,193 @@
+package traffic
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippertesting "github.com/bookingcom/shipper/pkg/testing"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+func buildAnchor(release string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s%s", release, anchor.AnchorSuffix),
+			Namespace: shippertesting.TestNamespace,
+			UID:       types.UID(fmt.Sprintf("%s-uid", release)),
+			Labels: map[string]string{
+				shipper.AppLabel:     shippertesting.TestApp,
+				shipper.ReleaseLabel: release,
+			},
+		},
+		Data: map[string]string{
+			anchor.InstallationTargetUID: release,
+		},
+	}
+}
+
+func buildTrafficSplit(service string, weights map[string]int64) *unstructured.Unstructured {
+	backends := []interface{}{}
+	for _, release := range sortedReleases(toUint32Weights(weights)) {
+		backends = append(backends, map[string]interface{}{
+			"service": trafficutil.ReleaseScopedName(service, release),
+			"weight":  weights[release],
+		})
+	}
+
+	split := &unstructured.Unstructured{}
+	split.SetGroupVersionKind(smiTrafficSplitGVK)
+	split.SetNamespace(shippertesting.TestNamespace)
+	split.SetName(service)
+	split.Object["spec"] = map[string]interface{}{
+		"service":  service,
+		"backends": backends,
+	}
+
+	return split
+}
+
+func toUint32Weights(weights map[string]int64) map[string]uint32 {
+	converted := make(map[string]uint32, len(weights))
+	for release, weight := range weights {
+		converted[release] = uint32(weight)
+	}
+
+	return converted
+}
+
+// smiObjects builds the objects for the conformance scenarios. Every release
+// has an anchor, and releases with pods getting traffic are weighted in the
+// TrafficSplit by how many of them there are.
+func smiObjects(releases []string, pods map[string]podStatus) ([]runtime.Object, []runtime.Object) {
+	svc := buildService(shippertesting.TestApp)
+	objects := []runtime.Object{svc}
+
+	weights := make(map[string]int64)
+	for _, release := range releases {
+		objects = append(objects, buildAnchor(release))
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withTraffic, withTraffic))
+		objects = addPodsToList(objects, buildPods(shippertesting.TestApp, release, pods[release].withoutTraffic, noTraffic))
+
+		if pods[release].withTraffic > 0 {
+			weights[release] = int64(pods[release].withTraffic)
+		}
+	}
+
+	if len(weights) == 0 {
+		return objects, nil
+	}
+
+	return objects, []runtime.Object{buildTrafficSplit(svc.Name, weights)}
+}
+
+// TestSMIBackendCreatesReleaseBackends verifies that the smi backend creates
+// a backend Service per release, owned by the release's anchor and selecting
+// only its pods, and splits traffic between them with a TrafficSplit owned by
+// the anchors of every release.
+func TestSMIBackendCreatesReleaseBackends(t *testing.T) {
+	svc := buildService(shippertesting.TestApp)
+	svc.Spec.Type = corev1.ServiceTypeNodePort
+	svc.Spec.Ports = []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}}
+	incumbent, contender := "incumbent", "contender"
+
+	kubeObjects := []runtime.Object{svc, buildAnchor(incumbent), buildAnchor(contender)}
+	kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, incumbent, 2, withTraffic))
+	kubeObjects = addPodsToList(kubeObjects, buildPods(shippertesting.TestApp, contender, 1, noTraffic))
+
+	client := kubefake.NewSimpleClientset(kubeObjects...)
+	dynamicClient := newMeshDynamicClient(
+		buildTrafficSplit(svc.Name, map[string]int64{incumbent: 100}),
+	)
+
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	(&Controller{}).subscribeToAppClusterEvents(informerFactory)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	ct := &ClusterTraffic{
+		Cluster:         shippertesting.TestCluster,
+		Namespace:       shippertesting.TestNamespace,
+		AppName:         shippertesting.TestApp,
+		ReleaseName:     contender,
+		ReleaseWeights:  map[string]uint32{incumbent: 90, contender: 10},
+		Clientset:       client,
+		InformerFactory: informerFactory,
+		DynamicClient:   dynamicClient,
+	}
+
+	status, err := smiBackend{}.Sync(ct)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := TrafficStatus{Reason: InProgress}
+	if eq, diff := shippertesting.DeepEqualDiff(expected, status); !eq {
+		t.Errorf("status differs from expected:\n%s", diff)
+	}
+
+	for _, release := range []string{incumbent, contender} {
+		name := trafficutil.ReleaseScopedName(svc.Name, release)
+		backend, err := client.CoreV1().Services(shippertesting.TestNamespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get backend Service for release %q: %s", release, err)
+		}
+
+		expectedBackend := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: shippertesting.TestNamespace,
+				Labels: map[string]string{
+					shipper.AppLabel:     shippertesting.TestApp,
+					shipper.ReleaseLabel: release,
+					shipper.LBLabel:      shipper.LBForRelease,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					anchor.ConfigMapAnchorToOwnerReference(buildAnchor(release)),
+				},
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{
+					shipper.AppLabel:              shippertesting.TestApp,
+					shipper.PodTrafficStatusLabel: shipper.Enabled,
+					shipper.ReleaseLabel:          release,
+				},
+				Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+			},
+		}
+		if eq, diff := shippertesting.DeepEqualDiff(expectedBackend, backend); !eq {
+			t.Errorf("backend Service for release %q differs from expected:\n%s", release, diff)
+		}
+	}
+
+	split, err := dynamicClient.Resource(smiTrafficSplitGVR).
+		Namespace(shippertesting.TestNamespace).Get(svc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get TrafficSplit: %s", err)
+	}
+
+	expectedSplit := buildTrafficSplit(svc.Name, map[string]int64{incumbent: 90, contender: 10})
+	if eq, diff := shippertesting.DeepEqualDiff(expectedSplit.Object["spec"], split.Object["spec"]); !eq {
+		t.Errorf("TrafficSplit spec differs from expected:\n%s", diff)
+	}
+
+	expectedOwners := []metav1.OwnerReference{
+		anchor.ConfigMapAnchorToOwnerReference(buildAnchor(contender)),
+		anchor.ConfigMapAnchorToOwnerReference(buildAnchor(incumbent)),
+	}
+	if eq, diff := shippertesting.DeepEqualDiff(expectedOwners, split.GetOwnerReferences()); !eq {
+		t.Errorf("TrafficSplit owner references differ from expected:\n%s", diff)
+	}
+}
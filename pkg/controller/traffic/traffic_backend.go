@@ -0,0 +1,124 @@
+package traffic
+
+import (
+	"fmt"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shippererrors "github.com/bookingcom/shipper/pkg/errors"
+	trafficutil "github.com/bookingcom/shipper/pkg/util/traffic"
+)
+
+// TrafficBackend shifts traffic between the releases of an application in a
+// single application cluster.
+type TrafficBackend interface {
+	// Sync reports the traffic weight achieved by ct.ReleaseName, and
+	// makes whatever changes are needed in the cluster to move towards
+	// the desired weights in ct.ReleaseWeights.
+	Sync(ct *ClusterTraffic) (TrafficStatus, error)
+}
+
+// informerBackend is implemented by traffic backends that need informers
+// beyond the ones the controller keeps in every application cluster. They're
+// only asked for in clusters the backend is used in, so clusters that never
+// use it don't have to serve or cache the resources involved.
+type informerBackend interface {
+	// informers asks ct.InformerFactory for the informers the backend
+	// needs, and returns them.
+	informers(ct *ClusterTraffic) ([]cache.SharedIndexInformer, error)
+}
+
+// ClusterTraffic holds everything a TrafficBackend needs to know about the
+// traffic a release should get in an application cluster.
+type ClusterTraffic struct {
+	Cluster     string
+	Namespace   string
+	AppName     string
+	ReleaseName string
+
+	// ReleaseWeights holds the desired weight of every release of the
+	// application in this cluster, including ReleaseName's.
+	ReleaseWeights map[string]uint32
+
+	Clientset       kubernetes.Interface
+	InformerFactory kubeinformers.SharedInformerFactory
+
+	// EndpointSlices tells whether InformerFactory has EndpointSlices
+	// to look at, which are then preferred to Endpoints.
+	EndpointSlices bool
+
+	// Ingresses tells whether the cluster serves networking.k8s.io/v1beta1
+	// Ingresses.
+	Ingresses bool
+
+	// DynamicClient is used by backends that manage objects Clientset
+	// knows nothing about, such as service mesh configuration.
+	DynamicClient dynamic.Interface
+}
+
+// DynamicClientBuilderFunc returns a dynamic client for an application
+// cluster.
+type DynamicClientBuilderFunc func(clusterName string, restConfig *rest.Config) (dynamic.Interface, error)
+
+// TrafficStatus is what a TrafficBackend reports after syncing a release. A
+// backend that is not ready yet explains why in Reason and Message, which end
+// up in the cluster's Ready condition. Ready backends leave them empty, unless
+// traffic is not applicable at all.
+type TrafficStatus struct {
+	Ready          bool
+	AchievedWeight uint32
+	Reason         string
+	Message        string
+
+	// Services holds the weight achieved through each production
+	// Service when there are several of them, for backends that look at
+	// them one by one.
+	Services []shipper.ServiceTrafficStatus
+}
+
+func newTrafficBackends() map[string]TrafficBackend {
+	return map[string]TrafficBackend{
+		shipper.PodLabelsTrafficBackend:     podLabelShifter{},
+		shipper.IstioTrafficBackend:         istioBackend{},
+		shipper.SMITrafficBackend:           smiBackend{},
+		shipper.IngressNginxTrafficBackend:  ingressNginxBackend{},
+		shipper.ReadinessGateTrafficBackend: readinessGateBackend{},
+		shipper.NoTrafficBackend:            noTrafficBackend{},
+	}
+}
+
+// backendForCluster picks the traffic backend for an application in a
+// cluster, as decided by trafficutil.BackendName. Applications and clusters
+// that do not exist in the management cluster have no say in it.
+func (c *Controller) backendForCluster(namespace, appName, clusterName string) (TrafficBackend, error) {
+	app, err := c.applicationLister.Applications(namespace).Get(appName)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, shippererrors.NewKubeclientGetError(namespace, appName, err).
+			WithShipperKind("Application")
+	} else if err != nil {
+		app = nil
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, shippererrors.NewKubeclientGetError("", clusterName, err).
+			WithShipperKind("Cluster")
+	} else if err != nil {
+		cluster = nil
+	}
+
+	name := trafficutil.BackendName(app, cluster)
+	backend, ok := c.backends[name]
+	if !ok {
+		return nil, shippererrors.NewUnrecoverableError(
+			fmt.Errorf("unknown traffic backend %q", name))
+	}
+
+	return backend, nil
+}
@@ -5,15 +5,19 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -53,8 +57,40 @@ type Controller struct {
 	clusterClientStore   clusterclientstore.Interface
 	trafficTargetsLister listers.TrafficTargetLister
 	trafficTargetsSynced cache.InformerSynced
+	applicationLister    listers.ApplicationLister
+	applicationsSynced   cache.InformerSynced
+	clusterLister        listers.ClusterLister
+	clustersSynced       cache.InformerSynced
 	workqueue            workqueue.RateLimitingInterface
 	recorder             record.EventRecorder
+
+	backends                 map[string]TrafficBackend
+	dynamicClientBuilderFunc DynamicClientBuilderFunc
+
+	// clusterAPIs holds the optional APIs each application cluster was
+	// found to serve when its event handlers were registered.
+	clusterAPIs    map[string]servedAPIs
+	clusterAPIsMut sync.RWMutex
+
+	// dynamicClients holds the dynamic client built for each application
+	// cluster, along with the config it was built from.
+	dynamicClients    map[string]cachedDynamicClient
+	dynamicClientsMut sync.Mutex
+}
+
+// cachedDynamicClient is a dynamic client built from config. The client
+// store replaces the config of a cluster whenever it changes, so a client is
+// only reused for as long as the config it came from is current.
+type cachedDynamicClient struct {
+	config *rest.Config
+	client dynamic.Interface
+}
+
+// servedAPIs tells which of the APIs traffic backends can make use of, but
+// not every cluster serves, an application cluster serves.
+type servedAPIs struct {
+	endpointSlices bool
+	ingresses      bool
 }
 
 // NewController returns a new TrafficTarget controller.
@@ -62,11 +98,14 @@ func NewController(
 	shipperclientset shipperclient.Interface,
 	shipperInformerFactory informers.SharedInformerFactory,
 	store clusterclientstore.Interface,
+	dynamicClientBuilderFunc DynamicClientBuilderFunc,
 	recorder record.EventRecorder,
 ) *Controller {
 
 	// Obtain references to shared index informers for the TrafficTarget type.
 	trafficTargetInformer := shipperInformerFactory.Shipper().V1alpha1().TrafficTargets()
+	applicationInformer := shipperInformerFactory.Shipper().V1alpha1().Applications()
+	clusterInformer := shipperInformerFactory.Shipper().V1alpha1().Clusters()
 
 	controller := &Controller{
 		shipperclientset:   shipperclientset,
@@ -74,8 +113,18 @@ func NewController(
 
 		trafficTargetsLister: trafficTargetInformer.Lister(),
 		trafficTargetsSynced: trafficTargetInformer.Informer().HasSynced,
+		applicationLister:    applicationInformer.Lister(),
+		applicationsSynced:   applicationInformer.Informer().HasSynced,
+		clusterLister:        clusterInformer.Lister(),
+		clustersSynced:       clusterInformer.Informer().HasSynced,
 		workqueue:            workqueue.NewNamedRateLimitingQueue(shipperworkqueue.NewDefaultControllerRateLimiter(), "traffic_controller_traffictargets"),
 		recorder:             recorder,
+
+		backends:                 newTrafficBackends(),
+		dynamicClientBuilderFunc: dynamicClientBuilderFunc,
+
+		clusterAPIs:    make(map[string]servedAPIs),
+		dynamicClients: make(map[string]cachedDynamicClient),
 	}
 
 	klog.Info("Setting up event handlers")
@@ -101,6 +150,11 @@ func NewController(
 // Endpoints object anyway. In case a new or deleted pod does change traffic
 // shifting in any way, the update to the traffic target itself will trigger a
 // new evaluation of all traffic targets for an app.
+//
+// Clusters serving EndpointSlices get them watched as well, just like
+// Endpoints. Only then is their informer created, as one for a resource the
+// cluster doesn't serve would never sync. Whether clusters serve Ingresses is
+// found out here too, for the ingress-nginx backend to know.
 func (c *Controller) registerAppClusterEventHandlers(informerFactory kubeinformers.SharedInformerFactory, clusterName string) {
 	informerFactory.Core().V1().Endpoints().Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: filters.BelongsToApp,
@@ -120,12 +174,94 @@ func (c *Controller) registerAppClusterEventHandlers(informerFactory kubeinforme
 			DeleteFunc: c.enqueueTrafficTargetFromPod,
 		},
 	})
+
+	clientset, err := c.clusterClientStore.GetClient(clusterName, AgentName)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf(
+			"cannot find out which APIs cluster %q serves: %s", clusterName, err))
+		return
+	}
+
+	apis := servedAPIs{
+		endpointSlices: servesEndpointSlices(clientset),
+		ingresses:      servesIngresses(clientset),
+	}
+
+	c.clusterAPIsMut.Lock()
+	c.clusterAPIs[clusterName] = apis
+	c.clusterAPIsMut.Unlock()
+
+	if !apis.endpointSlices {
+		return
+	}
+
+	enqueueFromEndpointSlice := func(obj interface{}) {
+		c.enqueueAllTrafficTargetsFromEndpointSlice(informerFactory, obj)
+	}
+
+	informerFactory.Discovery().V1beta1().EndpointSlices().Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: belongsToService,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueueFromEndpointSlice,
+			DeleteFunc: enqueueFromEndpointSlice,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				enqueueFromEndpointSlice(newObj)
+			},
+		},
+	})
+}
+
+// servedAPIs returns the optional APIs clusterName was found to serve.
+func (c *Controller) servedAPIs(clusterName string) servedAPIs {
+	c.clusterAPIsMut.RLock()
+	defer c.clusterAPIsMut.RUnlock()
+
+	return c.clusterAPIs[clusterName]
+}
+
+// usesEndpointSlices tells whether traffic in a cluster should be looked at
+// through EndpointSlices rather than Endpoints. Until their informer syncs,
+// Endpoints are still good enough.
+func (c *Controller) usesEndpointSlices(clusterName string, informerFactory kubeinformers.SharedInformerFactory) bool {
+	return c.servedAPIs(clusterName).endpointSlices &&
+		informerFactory.Discovery().V1beta1().EndpointSlices().Informer().HasSynced()
 }
 
+// syncBackendInformers asks for the informers backend needs in ct.Cluster,
+// if it's an informerBackend, and tells whether they have synced. Informers
+// asked for for the first time only start syncing then.
+func (c *Controller) syncBackendInformers(backend TrafficBackend, ct *ClusterTraffic) (bool, error) {
+	b, ok := backend.(informerBackend)
+	if !ok {
+		return true, nil
+	}
+
+	informers, err := b.informers(ct)
+	if err != nil {
+		return false, err
+	}
+
+	if err := c.clusterClientStore.StartInformers(ct.Cluster); err != nil {
+		return false, err
+	}
+
+	for _, informer := range informers {
+		if !informer.HasSynced() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// subscribeToAppClusterEvents asks for the informers every traffic backend
+// needs. Release anchors are ConfigMaps, whose informer the janitor
+// controller has in every application cluster as well.
 func (c *Controller) subscribeToAppClusterEvents(informerFactory kubeinformers.SharedInformerFactory) {
 	informerFactory.Core().V1().Pods().Informer()
 	informerFactory.Core().V1().Services().Informer()
 	informerFactory.Core().V1().Endpoints().Informer()
+	informerFactory.Core().V1().ConfigMaps().Informer()
 }
 
 // Run will set up the event handlers for types we are interested in, as well as
@@ -139,7 +275,7 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 	klog.V(2).Info("Starting Traffic controller")
 	defer klog.V(2).Info("Shutting down Traffic controller")
 
-	if ok := cache.WaitForCacheSync(stopCh, c.trafficTargetsSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.trafficTargetsSynced, c.applicationsSynced, c.clustersSynced); !ok {
 		runtime.HandleError(fmt.Errorf("failed to wait for caches to sync"))
 		return
 	}
@@ -330,14 +466,31 @@ func (c *Controller) processTrafficTargetOnCluster(
 		"")
 
 	var achievedTraffic uint32
+	var serviceTraffic []shipper.ServiceTrafficStatus
 	defer func() {
 		status.AchievedTraffic = achievedTraffic
+		status.Services = serviceTraffic
 
 		diff.Append(trafficutil.SetClusterTrafficCondition(status, *operationalCond))
 		diff.Append(trafficutil.SetClusterTrafficCondition(status, *readyCond))
 		c.reportConditionChange(tt, ClusterTrafficConditionChanged, diff)
 	}()
 
+	appName := tt.Labels[shipper.AppLabel]
+	releaseName := tt.Labels[shipper.ReleaseLabel]
+
+	backend, err := c.backendForCluster(tt.Namespace, appName, spec.Name)
+	if err != nil {
+		operationalCond = trafficutil.NewClusterTrafficCondition(
+			shipper.ClusterConditionTypeOperational,
+			corev1.ConditionFalse,
+			InternalError,
+			err.Error(),
+		)
+
+		return err
+	}
+
 	clientset, err := c.clusterClientStore.GetClient(spec.Name, AgentName)
 	if err != nil {
 		operationalCond = trafficutil.NewClusterTrafficCondition(
@@ -350,10 +503,19 @@ func (c *Controller) processTrafficTargetOnCluster(
 		return err
 	}
 
-	appName := tt.Labels[shipper.AppLabel]
-	releaseName := tt.Labels[shipper.ReleaseLabel]
+	informerFactory, err := c.clusterClientStore.GetInformerFactory(spec.Name)
+	if err != nil {
+		operationalCond = trafficutil.NewClusterTrafficCondition(
+			shipper.ClusterConditionTypeOperational,
+			corev1.ConditionFalse,
+			InternalError,
+			err.Error(),
+		)
 
-	appPods, endpoints, err := c.getClusterObjects(spec.Name, tt.Namespace, appName)
+		return err
+	}
+
+	dynamicClient, err := c.getDynamicClient(spec.Name)
 	if err != nil {
 		operationalCond = trafficutil.NewClusterTrafficCondition(
 			shipper.ClusterConditionTypeOperational,
@@ -372,121 +534,106 @@ func (c *Controller) processTrafficTargetOnCluster(
 		"",
 	)
 
-	trafficStatus := buildTrafficShiftingStatus(
-		spec.Name, appName, releaseName,
-		clusterReleaseWeights,
-		endpoints, appPods)
+	ct := &ClusterTraffic{
+		Cluster:         spec.Name,
+		Namespace:       tt.Namespace,
+		AppName:         appName,
+		ReleaseName:     releaseName,
+		ReleaseWeights:  clusterReleaseWeights[spec.Name],
+		Clientset:       clientset,
+		InformerFactory: informerFactory,
+		EndpointSlices:  c.usesEndpointSlices(spec.Name, informerFactory),
+		Ingresses:       c.servedAPIs(spec.Name).ingresses,
+		DynamicClient:   dynamicClient,
+	}
 
-	// achievedTraffic is used by the defer at the top of this func
-	achievedTraffic = trafficStatus.achievedTrafficWeight
+	synced, err := c.syncBackendInformers(backend, ct)
+	if err != nil {
+		readyCond = trafficutil.NewClusterTrafficCondition(
+			shipper.ClusterConditionTypeReady,
+			corev1.ConditionFalse,
+			InternalError,
+			err.Error(),
+		)
 
-	if trafficStatus.ready {
+		return err
+	} else if !synced {
+		err := shippererrors.NewRecoverableError(fmt.Errorf(
+			"waiting for the informers of the traffic backend to sync in cluster %q", spec.Name))
 		readyCond = trafficutil.NewClusterTrafficCondition(
 			shipper.ClusterConditionTypeReady,
-			corev1.ConditionTrue,
-			"",
-			"",
+			corev1.ConditionFalse,
+			InProgress,
+			err.Error(),
 		)
 
-		return nil
+		return err
 	}
 
-	if trafficStatus.podsToShift != nil {
-		// If we have pods to shift, our job can only be done after the
-		// change is made and observed, so we definitely still in
-		// progress.
-		err := shiftPodLabels(clientset, trafficStatus.podsToShift)
-		if err != nil {
-			readyCond = trafficutil.NewClusterTrafficCondition(
-				shipper.ClusterConditionTypeReady,
-				corev1.ConditionFalse,
-				InternalError,
-				err.Error(),
-			)
-
-			return err
-		}
+	trafficStatus, err := backend.Sync(ct)
+
+	// achievedTraffic and serviceTraffic are used by the defer at the
+	// top of this func
+	achievedTraffic = trafficStatus.AchievedWeight
+	serviceTraffic = trafficStatus.Services
 
+	if err != nil {
 		readyCond = trafficutil.NewClusterTrafficCondition(
 			shipper.ClusterConditionTypeReady,
 			corev1.ConditionFalse,
-			InProgress,
-			"",
+			InternalError,
+			err.Error(),
 		)
-	} else if trafficStatus.podsNotReady > 0 {
-		// All the pods have been shifted, made it to endpoints, but
-		// some aren't ready.
-		msg := fmt.Sprintf(
-			"%d/%d pods designated to receive traffic are not ready",
-			trafficStatus.podsNotReady, trafficStatus.podsLabeled)
+
+		return err
+	}
+
+	if trafficStatus.Ready {
+		// Backends have no reason to give for being ready, unless
+		// traffic doesn't even apply.
 		readyCond = trafficutil.NewClusterTrafficCondition(
 			shipper.ClusterConditionTypeReady,
-			corev1.ConditionFalse,
-			PodsNotReady,
-			msg,
+			corev1.ConditionTrue,
+			trafficStatus.Reason,
+			trafficStatus.Message,
 		)
 	} else {
-		// All the pods have been shifted, but not enough of them are
-		// ready, and there are none not ready in endpoints, which
-		// means that they haven't made it there yet, or that the
-		// service selector does not match any pods.
-		msg := fmt.Sprintf(
-			"%d/%d pods designated to receive traffic are not yet in endpoints",
-			trafficStatus.podsLabeled-trafficStatus.podsReady, trafficStatus.podsLabeled)
 		readyCond = trafficutil.NewClusterTrafficCondition(
 			shipper.ClusterConditionTypeReady,
 			corev1.ConditionFalse,
-			PodsNotInEndpoints,
-			msg,
+			trafficStatus.Reason,
+			trafficStatus.Message,
 		)
 	}
 
-	return nil
+	return publishPodTrafficStatus(ct, trafficStatus)
 }
 
-func (c *Controller) getClusterObjects(cluster, ns, appName string) ([]*corev1.Pod, *corev1.Endpoints, error) {
-	informerFactory, err := c.clusterClientStore.GetInformerFactory(cluster)
+// getDynamicClient returns a dynamic client for clusterName, only building
+// one if the cluster has none yet or its config changed since.
+func (c *Controller) getDynamicClient(clusterName string) (dynamic.Interface, error) {
+	config, err := c.clusterClientStore.GetConfig(clusterName)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	appSelector := labels.Set{shipper.AppLabel: appName}.AsSelector()
-	appPods, err := informerFactory.Core().V1().Pods().Lister().
-		Pods(ns).List(appSelector)
-	if err != nil {
-		return nil, nil, shippererrors.NewKubeclientListError(
-			corev1.SchemeGroupVersion.WithKind("Pod"),
-			ns, appSelector, err)
-	}
-
-	serviceSelector := labels.Set(map[string]string{
-		shipper.AppLabel: appName,
-		shipper.LBLabel:  shipper.LBForProduction,
-	}).AsSelector()
-	serviceGVK := corev1.SchemeGroupVersion.WithKind("Service")
-	services, err := informerFactory.Core().V1().Services().Lister().
-		Services(ns).List(serviceSelector)
-	if err != nil {
-		return nil, nil, shippererrors.NewKubeclientListError(
-			serviceGVK, ns, serviceSelector, err)
-	}
+	c.dynamicClientsMut.Lock()
+	defer c.dynamicClientsMut.Unlock()
 
-	if len(services) != 1 {
-		err := shippererrors.NewUnexpectedObjectCountFromSelectorError(
-			serviceSelector, serviceGVK, 1, len(services))
-		return nil, nil, err
+	if cached, ok := c.dynamicClients[clusterName]; ok && cached.config == config {
+		return cached.client, nil
 	}
 
-	svc := services[0]
-
-	endpoints, err := informerFactory.Core().V1().Endpoints().Lister().
-		Endpoints(svc.Namespace).Get(svc.Name)
+	// The client store is just like an informer cache: it's a shared
+	// pointer to a read-only struct, so copy it before handing it over.
+	client, err := c.dynamicClientBuilderFunc(clusterName, rest.CopyConfig(config))
 	if err != nil {
-		return nil, nil, shippererrors.NewKubeclientGetError(svc.Namespace, svc.Name, err).
-			WithCoreV1Kind("Endpoints")
+		return nil, shippererrors.NewClusterClientBuild(clusterName, err)
 	}
 
-	return appPods, endpoints, nil
+	c.dynamicClients[clusterName] = cachedDynamicClient{config: config, client: client}
+
+	return client, nil
 }
 
 // enqueueTrafficTarget takes a TrafficTarget resource and converts it into a
@@ -531,6 +678,51 @@ func (c *Controller) enqueueAllTrafficTargets(obj interface{}) {
 	}
 }
 
+// enqueueAllTrafficTargetsFromEndpointSlice enqueues all traffic targets for
+// the app owning the Service an EndpointSlice belongs to. EndpointSlices don't
+// necessarily carry their Service's labels, so we need to look at the Service
+// itself.
+func (c *Controller) enqueueAllTrafficTargetsFromEndpointSlice(
+	informerFactory kubeinformers.SharedInformerFactory,
+	obj interface{},
+) {
+	slice, ok := obj.(*discoveryv1beta1.EndpointSlice)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("not a discoveryv1beta1.EndpointSlice: %#v", obj))
+		return
+	}
+
+	svcName := slice.Labels[discoveryv1beta1.LabelServiceName]
+	svc, err := informerFactory.Core().V1().Services().Lister().
+		Services(slice.Namespace).Get(svcName)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf(
+				"cannot get service '%s/%s': %s", slice.Namespace, svcName, err))
+		}
+
+		return
+	}
+
+	if !filters.BelongsToApp(svc) {
+		return
+	}
+
+	c.enqueueAllTrafficTargets(svc)
+}
+
+// belongsToService tells whether obj is an EndpointSlice of a Service.
+func belongsToService(obj interface{}) bool {
+	kubeobj, ok := obj.(metav1.Object)
+	if !ok {
+		return false
+	}
+
+	_, ok = kubeobj.GetLabels()[discoveryv1beta1.LabelServiceName]
+
+	return ok
+}
+
 func (c *Controller) enqueueTrafficTargetFromPod(obj interface{}) {
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
@@ -12,6 +12,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
@@ -249,6 +251,45 @@ func TestTrafficShiftingWithPodsNotReady(t *testing.T) {
 	)
 }
 
+// TestDynamicClientIsReusedPerCluster verifies that the traffic controller
+// builds a single dynamic client per application cluster, and only builds a
+// new one once the cluster's config changes.
+func TestDynamicClientIsReusedPerCluster(t *testing.T) {
+	f := shippertesting.NewControllerTestFixture()
+	cluster := f.AddNamedCluster(clusterA)
+
+	builds := 0
+	controller := NewController(
+		f.ShipperClient,
+		f.ShipperInformerFactory,
+		f.ClusterClientStore,
+		func(clusterName string, _ *rest.Config) (dynamic.Interface, error) {
+			builds++
+			return cluster.DynamicClient, nil
+		},
+		f.Recorder,
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := controller.getDynamicClient(clusterA); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if builds != 1 {
+		t.Errorf("expected a single dynamic client to be built, got %d", builds)
+	}
+
+	cluster.Config = &rest.Config{Host: "https://new.example.com"}
+	if _, err := controller.getDynamicClient(clusterA); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if builds != 2 {
+		t.Errorf("expected a new dynamic client after the config changed, got %d builds", builds)
+	}
+}
+
 func runTrafficControllerTest(
 	t *testing.T,
 	objectsByCluster map[string][]runtime.Object,
@@ -372,6 +413,12 @@ func runController(f *shippertesting.ControllerTestFixture) {
 		f.ShipperClient,
 		f.ShipperInformerFactory,
 		f.ClusterClientStore,
+		func(clusterName string, _ *rest.Config) (dynamic.Interface, error) {
+			if client := f.Clusters[clusterName].DynamicClient; client != nil {
+				return client, nil
+			}
+			return nil, nil
+		},
 		f.Recorder,
 	)
 
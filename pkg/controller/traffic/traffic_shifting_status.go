@@ -14,6 +14,10 @@ import (
 
 type clusterReleaseWeights map[string]map[string]uint32
 
+// podTrafficStatusFunc returns the traffic status a pod is marked with, which
+// is shipper.Enabled for pods that should get traffic.
+type podTrafficStatusFunc func(pod *corev1.Pod) string
+
 type trafficShiftingStatus struct {
 	ready                 bool
 	achievedTrafficWeight uint32
@@ -26,28 +30,26 @@ type trafficShiftingStatus struct {
 // buildTrafficShiftingStatus looks at the current state of a cluster regarding
 // the progression of traffic shifting. It's concerned with how many of the
 // available pods have been labeled to receive traffic, how many are actually
-// ready according to the state of the Endpoints object, and the currently
+// ready according to podReadiness, and the currently
 // achieved weight for a release. If the current state is different from the
 // desired one, it also returns which pods need to receive which labels to move
-// forward.
+// forward, picked according to the zones in nodeZones. Whether a pod is
+// currently marked to get traffic is up to podTrafficStatus.
 func buildTrafficShiftingStatus(
-	cluster, appName, releaseName string,
-	clusterReleaseWeights clusterReleaseWeights,
-	endpoints *corev1.Endpoints,
+	appName, releaseName string,
+	releaseTargetWeights map[string]uint32,
+	podReadiness map[string]bool,
 	appPods []*corev1.Pod,
+	nodeZones map[string]string,
+	podTrafficStatus podTrafficStatusFunc,
 ) trafficShiftingStatus {
-	releaseTargetWeights, ok := clusterReleaseWeights[cluster]
-	if !ok {
-		return trafficShiftingStatus{}
-	}
-
 	releaseSelector := labels.Set(map[string]string{
 		shipper.AppLabel:     appName,
 		shipper.ReleaseLabel: releaseName,
 	}).AsSelector()
 
 	podsByTrafficStatus, podsInRelease, podsReady, podsNotReady := summarizePods(
-		appPods, endpoints, releaseSelector)
+		appPods, podReadiness, releaseSelector, podTrafficStatus)
 
 	releaseTargetWeight := releaseTargetWeights[releaseName]
 	totalTargetWeight := uint32(0)
@@ -68,7 +70,7 @@ func buildTrafficShiftingStatus(
 
 	var podsToShift map[string][]*corev1.Pod
 	if !ready {
-		podsToShift = buildPodsToShift(podsByTrafficStatus, podsToLabel)
+		podsToShift = buildPodsToShift(podsByTrafficStatus, podsToLabel, nodeZones)
 	}
 
 	var achievedPercentage float64
@@ -90,10 +92,12 @@ func buildTrafficShiftingStatus(
 }
 
 // buildPodsToShift returns a map of which label has to applied to which pods
-// so we have the correct amount of pods labeled to receive traffic.
+// so we have the correct amount of pods labeled to receive traffic. See
+// selectPodsToShift for how pods are picked.
 func buildPodsToShift(
 	podsByTrafficStatus map[string][]*corev1.Pod,
 	podsToLabel int,
+	nodeZones map[string]string,
 ) map[string][]*corev1.Pod {
 	var oldStatus, newStatus string
 	var podsToTake int
@@ -114,13 +118,13 @@ func buildPodsToShift(
 		newStatus = shipper.Enabled
 	}
 
-	if podsToTake > len(podsByTrafficStatus[oldStatus]) {
-		podsToTake = len(podsByTrafficStatus[oldStatus])
-	}
-
-	if podsToTake > 0 {
+	pods := selectPodsToShift(
+		podsByTrafficStatus[oldStatus],
+		podsByTrafficStatus[shipper.Enabled],
+		newStatus, podsToTake, nodeZones)
+	if len(pods) > 0 {
 		return map[string][]*corev1.Pod{
-			newStatus: podsByTrafficStatus[oldStatus][:podsToTake],
+			newStatus: pods,
 		}
 	}
 
@@ -129,14 +133,16 @@ func buildPodsToShift(
 
 // summarizePods returns an aggregated summary of the current state of pods:
 // which pods are labeled to receive (or not receive) traffic, how many belong
-// to the specified release, and how many are ready according to the Endpoints
-// object.
+// to the specified release, and how many are ready according to podReadiness.
+// Pods that are not ready only count if they are marked to get traffic, as
+// endpoints may list others when their Service selects every pod.
 func summarizePods(
 	pods []*corev1.Pod,
-	endpoints *corev1.Endpoints,
+	podReadiness map[string]bool,
 	releaseSelector labels.Selector,
+	podTrafficStatus podTrafficStatusFunc,
 ) (map[string][]*corev1.Pod, int, int, int) {
-	podsInRelease := make(map[string]struct{})
+	podsInRelease := make(map[string]bool)
 	podsByTrafficStatus := make(map[string][]*corev1.Pod)
 
 	sort.Slice(pods, func(i, j int) bool {
@@ -148,26 +154,15 @@ func summarizePods(
 			continue
 		}
 
-		podsInRelease[pod.Name] = struct{}{}
-
-		v, ok := pod.Labels[shipper.PodTrafficStatusLabel]
-		if !ok {
-			v = shipper.Disabled
-		}
-
+		v := podTrafficStatus(pod)
+		podsInRelease[pod.Name] = v == shipper.Enabled
 		podsByTrafficStatus[v] = append(podsByTrafficStatus[v], pod)
 	}
 
-	podReadiness := make(map[string]bool)
-	for _, subset := range endpoints.Subsets {
-		markAddressReadiness(podReadiness, subset.Addresses, true)
-		markAddressReadiness(podReadiness, subset.NotReadyAddresses, false)
-	}
-
 	podsReady := 0
 	podsNotReady := 0
 	for podName, podReady := range podReadiness {
-		_, belongsToRelease := podsInRelease[podName]
+		enabled, belongsToRelease := podsInRelease[podName]
 
 		if !belongsToRelease {
 			continue
@@ -175,7 +170,7 @@ func summarizePods(
 
 		if podReady {
 			podsReady++
-		} else {
+		} else if enabled {
 			podsNotReady++
 		}
 	}
@@ -30,105 +30,9 @@ type trafficShiftingStatusTestExpectation struct {
 	PodsToShift           podsToShift
 }
 
-func TestTrafficShiftingEmptyRelease(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release: release{weight: 0, podCount: podStatus{}},
-			Ready:   true,
-		},
-	})
-}
-
-func TestTrafficShiftingNoWeightRelease(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release: release{weight: 0, podCount: podStatus{withoutTraffic: 1}},
-			Ready:   true,
-		},
-	})
-}
-
-func TestTrafficShiftingNewRelease(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release:     release{weight: 1, podCount: podStatus{withoutTraffic: 10}},
-			Ready:       false,
-			PodsToShift: podsToShift{10, 0},
-		},
-	})
-}
-
-func TestTrafficShiftingCompletedRelease(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release:               release{weight: 1, podCount: podStatus{withTraffic: 10}},
-			Ready:                 true,
-			AchievedTrafficWeight: 1,
-			PodsReady:             10,
-			PodsLabeled:           10,
-		},
-	})
-}
-
-func TestTrafficShiftingReleaseProgressionUp(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release:               release{weight: 1, podCount: podStatus{withTraffic: 5, withoutTraffic: 5}},
-			Ready:                 false,
-			AchievedTrafficWeight: 1,
-			PodsReady:             5,
-			PodsLabeled:           5,
-			PodsToShift:           podsToShift{5, 0},
-		},
-	})
-}
-
-func TestTrafficShiftingReleaseProgressionDown(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release:               release{weight: 0, podCount: podStatus{withTraffic: 5}},
-			Ready:                 false,
-			AchievedTrafficWeight: 0,
-			PodsReady:             5,
-			PodsLabeled:           5,
-			PodsToShift:           podsToShift{0, 5},
-		},
-	})
-}
-
-func TestTrafficShiftingReleaseProgressionDrainIncumbentReplenishContender(t *testing.T) {
-	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
-		{
-			Release:               release{weight: 0, podCount: podStatus{withTraffic: 10}},
-			Ready:                 false,
-			AchievedTrafficWeight: 1,
-			PodsReady:             10,
-			PodsLabeled:           10,
-			PodsToShift:           podsToShift{0, 10},
-		},
-		{
-			Release:               release{weight: 1, podCount: podStatus{withoutTraffic: 10}},
-			Ready:                 false,
-			AchievedTrafficWeight: 0,
-			PodsToShift:           podsToShift{10, 0},
-		},
-	})
-}
-
-func TestTrafficShiftingReleaseSeveralAchievedReleases(t *testing.T) {
-	var expectations []trafficShiftingStatusTestExpectation
-	for i := 0; i < 5; i++ {
-		expectations = append(expectations, trafficShiftingStatusTestExpectation{
-			Release:               release{weight: 10, podCount: podStatus{withTraffic: 5}},
-			Ready:                 true,
-			AchievedTrafficWeight: 10,
-			PodsReady:             5,
-			PodsLabeled:           5,
-		})
-	}
-
-	runBuildTestTrafficShiftingStatus(t, expectations)
-}
+// The scenarios in this file depend on how the number of pods in each release
+// limits the weights the pod label shifter can achieve. Scenarios every
+// backend should agree on live in backend_conformance_test.go.
 
 func TestTrafficShiftingReleaseSeveralReleasesDifferentWeights(t *testing.T) {
 	runBuildTestTrafficShiftingStatus(t, []trafficShiftingStatusTestExpectation{
@@ -226,13 +130,11 @@ func TestTrafficShiftingPodsLabeledButNotReady(t *testing.T) {
 	}
 
 	trafficStatus := buildTrafficShiftingStatus(
-		shippertesting.TestCluster, shippertesting.TestApp, releaseName,
-		clusterReleaseWeights{
-			shippertesting.TestCluster: map[string]uint32{
-				releaseName: releaseWeight,
-			},
+		shippertesting.TestApp, releaseName,
+		map[string]uint32{
+			releaseName: releaseWeight,
 		},
-		endpoints, appPods,
+		endpointsPodReadiness(endpoints), appPods, nil, podLabelTrafficStatus,
 	)
 
 	assertTrafficShiftingStatusExpectation(t, releaseName,
@@ -264,13 +166,11 @@ func TestTrafficShiftingUnmanagedPods(t *testing.T) {
 
 	endpoints := buildEndpoints(shippertesting.TestApp)
 	trafficStatus := buildTrafficShiftingStatus(
-		shippertesting.TestCluster, shippertesting.TestApp, releaseName,
-		clusterReleaseWeights{
-			shippertesting.TestCluster: map[string]uint32{
-				releaseName: releaseWeight,
-			},
+		shippertesting.TestApp, releaseName,
+		map[string]uint32{
+			releaseName: releaseWeight,
 		},
-		endpoints, appPods,
+		endpointsPodReadiness(endpoints), appPods, nil, podLabelTrafficStatus,
 	)
 
 	assertTrafficShiftingStatusExpectation(t, releaseName,
@@ -327,9 +227,9 @@ func runBuildTestTrafficShiftingStatus(
 		tt := trafficTargets[i]
 		relName := tt.Labels[shipper.ReleaseLabel]
 		trafficStatus := buildTrafficShiftingStatus(
-			shippertesting.TestCluster, shippertesting.TestApp, relName,
-			clusterReleaseWeights,
-			endpoints, appPods,
+			shippertesting.TestApp, relName,
+			clusterReleaseWeights[shippertesting.TestCluster],
+			endpointsPodReadiness(endpoints), appPods, nil, podLabelTrafficStatus,
 		)
 
 		assertTrafficShiftingStatusExpectation(t, relName, expectation, trafficStatus)
@@ -7,8 +7,13 @@ import (
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 	shippertesting "github.com/bookingcom/shipper/pkg/testing"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
 )
 
 const (
@@ -186,6 +191,66 @@ func buildEndpoints(app string) *corev1.Endpoints {
 	}
 }
 
+// buildEndpointSlice returns an EndpointSlice with the same endpoints as
+// endpoints, as the EndpointSlice controller would mirror them.
+func buildEndpointSlice(endpoints *corev1.Endpoints) *discoveryv1beta1.EndpointSlice {
+	slice := &discoveryv1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-abcde", endpoints.Name),
+			Namespace: endpoints.Namespace,
+			Labels: map[string]string{
+				discoveryv1beta1.LabelServiceName: endpoints.Name,
+			},
+		},
+		AddressType: discoveryv1beta1.AddressTypeIPv4,
+	}
+
+	for _, subset := range endpoints.Subsets {
+		slice.Endpoints = appendSliceEndpoints(slice.Endpoints, subset.Addresses, true)
+		slice.Endpoints = appendSliceEndpoints(slice.Endpoints, subset.NotReadyAddresses, false)
+	}
+
+	return slice
+}
+
+func appendSliceEndpoints(
+	sliceEndpoints []discoveryv1beta1.Endpoint,
+	addresses []corev1.EndpointAddress,
+	ready bool,
+) []discoveryv1beta1.Endpoint {
+	for _, address := range addresses {
+		ready := ready
+		sliceEndpoints = append(sliceEndpoints, discoveryv1beta1.Endpoint{
+			Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready},
+			TargetRef:  address.TargetRef,
+		})
+	}
+
+	return sliceEndpoints
+}
+
+// endpointSliceResources are the resources a fake discovery client reports
+// for a cluster serving EndpointSlices.
+var endpointSliceResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: discoveryv1beta1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{Name: "endpointslices", Namespaced: true, Kind: "EndpointSlice"},
+		},
+	},
+}
+
+// ingressResources are the resources a fake discovery client reports for a
+// cluster serving networking.k8s.io/v1beta1 Ingresses.
+var ingressResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: networkingv1beta1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{Name: "ingresses", Namespaced: true, Kind: "Ingress"},
+		},
+	},
+}
+
 var podId int
 
 func buildPods(app, release string, count int, withTraffic bool) []*corev1.Pod {
@@ -232,3 +297,20 @@ func addPodsToList(objects []runtime.Object, pods []*corev1.Pod) []runtime.Objec
 
 	return objects
 }
+
+// newMeshDynamicClient returns a fake dynamic client that knows about the
+// service mesh objects managed by traffic backends.
+func newMeshDynamicClient(objects ...runtime.Object) *fakedynamic.FakeDynamicClient {
+	scheme := runtime.NewScheme()
+	gvks := []schema.GroupVersionKind{
+		istioVirtualServiceGVK,
+		istioDestinationRuleGVK,
+		smiTrafficSplitGVK,
+	}
+	for _, gvk := range gvks {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+
+	return fakedynamic.NewSimpleDynamicClient(scheme, objects...)
+}
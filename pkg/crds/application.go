@@ -35,6 +35,35 @@ var Application = &apiextensionv1beta1.CustomResourceDefinition{
 						},
 						Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
 							"template": environmentValidation,
+							"trafficBackend": apiextensionv1beta1.JSONSchemaProps{
+								Type: "string",
+							},
+							"rollbackTo": apiextensionv1beta1.JSONSchemaProps{
+								Type: "string",
+							},
+							"dependsOn": apiextensionv1beta1.JSONSchemaProps{
+								Type: "array",
+								Items: &apiextensionv1beta1.JSONSchemaPropsOrArray{
+									Schema: &apiextensionv1beta1.JSONSchemaProps{
+										Type: "object",
+										Required: []string{
+											"name",
+										},
+										Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+											"namespace": apiextensionv1beta1.JSONSchemaProps{
+												Type: "string",
+											},
+											"name": apiextensionv1beta1.JSONSchemaProps{
+												Type: "string",
+											},
+											"step": apiextensionv1beta1.JSONSchemaProps{
+												Type:    "integer",
+												Minimum: &zero,
+											},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
@@ -50,6 +50,9 @@ var Cluster = &apiextensionv1beta1.CustomResourceDefinition{
 									},
 								},
 							},
+							"trafficBackend": apiextensionv1beta1.JSONSchemaProps{
+								Type: "string",
+							},
 							"scheduler": apiextensionv1beta1.JSONSchemaProps{
 								Type: "object",
 								Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
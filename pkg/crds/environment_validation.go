@@ -4,6 +4,11 @@ import (
 	apiextensionv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 )
 
+// stepValuePattern matches the strategy step values that are strings: either
+// percentages, like "50%", or numbers of pods, like "3". Minimum and Maximum
+// only apply to the values that are numbers, and Pattern only to strings.
+const stepValuePattern = `^[0-9]+%?$`
+
 var environmentValidation = apiextensionv1beta1.JSONSchemaProps{
 	Type: "object",
 	Required: []string{
@@ -62,6 +67,21 @@ var environmentValidation = apiextensionv1beta1.JSONSchemaProps{
 				"steps",
 			},
 			Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+				"capacitySequencing": apiextensionv1beta1.JSONSchemaProps{
+					Type: "string",
+					Enum: []apiextensionv1beta1.JSON{
+						{Raw: []byte(`"ContenderFirst"`)},
+						{Raw: []byte(`"Interleaved"`)},
+					},
+				},
+				"maxSurge": apiextensionv1beta1.JSONSchemaProps{
+					XIntOrString: true,
+				},
+				"minAvailablePercent": apiextensionv1beta1.JSONSchemaProps{
+					Type:    "integer",
+					Minimum: &zero,
+					Maximum: &hundred,
+				},
 				"steps": apiextensionv1beta1.JSONSchemaProps{
 					Type: "array",
 					Items: &apiextensionv1beta1.JSONSchemaPropsOrArray{
@@ -76,6 +96,9 @@ var environmentValidation = apiextensionv1beta1.JSONSchemaProps{
 								"name": apiextensionv1beta1.JSONSchemaProps{
 									Type: "string",
 								},
+								"requiresApproval": apiextensionv1beta1.JSONSchemaProps{
+									Type: "boolean",
+								},
 								"capacity": apiextensionv1beta1.JSONSchemaProps{
 									Type: "object",
 									Required: []string{
@@ -84,14 +107,16 @@ var environmentValidation = apiextensionv1beta1.JSONSchemaProps{
 									},
 									Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
 										"incumbent": apiextensionv1beta1.JSONSchemaProps{
-											Type:    "integer",
-											Minimum: &zero,
-											Maximum: &hundred,
+											XIntOrString: true,
+											Minimum:      &zero,
+											Maximum:      &hundred,
+											Pattern:      stepValuePattern,
 										},
 										"contender": apiextensionv1beta1.JSONSchemaProps{
-											Type:    "integer",
-											Minimum: &zero,
-											Maximum: &hundred,
+											XIntOrString: true,
+											Minimum:      &zero,
+											Maximum:      &hundred,
+											Pattern:      stepValuePattern,
 										},
 									},
 								},
@@ -103,12 +128,14 @@ var environmentValidation = apiextensionv1beta1.JSONSchemaProps{
 									},
 									Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
 										"incumbent": apiextensionv1beta1.JSONSchemaProps{
-											Type:    "integer",
-											Minimum: &zero,
+											XIntOrString: true,
+											Minimum:      &zero,
+											Pattern:      stepValuePattern,
 										},
 										"contender": apiextensionv1beta1.JSONSchemaProps{
-											Type:    "integer",
-											Minimum: &zero,
+											XIntOrString: true,
+											Minimum:      &zero,
+											Pattern:      stepValuePattern,
 										},
 									},
 								},
@@ -121,5 +148,62 @@ var environmentValidation = apiextensionv1beta1.JSONSchemaProps{
 		"values": apiextensionv1beta1.JSONSchemaProps{
 			Type: "object",
 		},
+		"valuesFrom": apiextensionv1beta1.JSONSchemaProps{
+			Type: "array",
+			Items: &apiextensionv1beta1.JSONSchemaPropsOrArray{
+				Schema: &apiextensionv1beta1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+						"configMapRef": valuesFromReferenceValidation,
+						"secretRef":    valuesFromReferenceValidation,
+					},
+				},
+			},
+		},
+		"overrides": overridesValidation,
+	},
+}
+
+var overridesValidation = apiextensionv1beta1.JSONSchemaProps{
+	Type: "array",
+	Items: &apiextensionv1beta1.JSONSchemaPropsOrArray{
+		Schema: &apiextensionv1beta1.JSONSchemaProps{
+			Type: "object",
+			Required: []string{
+				"values",
+			},
+			Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+				"region": apiextensionv1beta1.JSONSchemaProps{
+					Type: "string",
+				},
+				"cluster": apiextensionv1beta1.JSONSchemaProps{
+					Type: "string",
+				},
+				"clusterSelector": apiextensionv1beta1.JSONSchemaProps{
+					Type: "object",
+				},
+				"values": apiextensionv1beta1.JSONSchemaProps{
+					Type: "object",
+				},
+			},
+		},
+	},
+}
+
+var valuesFromReferenceValidation = apiextensionv1beta1.JSONSchemaProps{
+	Type: "object",
+	Required: []string{
+		"name",
+	},
+	Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+		"name": apiextensionv1beta1.JSONSchemaProps{
+			Type: "string",
+		},
+		"key": apiextensionv1beta1.JSONSchemaProps{
+			Type: "string",
+		},
+		"optional": apiextensionv1beta1.JSONSchemaProps{
+			Type: "boolean",
+		},
 	},
 }
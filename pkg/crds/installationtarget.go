@@ -83,6 +83,13 @@ var InstallationTarget = &apiextensionv1beta1.CustomResourceDefinition{
 							"values": apiextensionv1beta1.JSONSchemaProps{
 								Type: "object",
 							},
+							"valuesSecretRef": apiextensionv1beta1.JSONSchemaProps{
+								Type: "object",
+								Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+									"name": apiextensionv1beta1.JSONSchemaProps{Type: "string"},
+								},
+							},
+							"overrides": overridesValidation,
 						},
 					},
 				},
@@ -53,6 +53,32 @@ var RolloutBlock = &apiextensionv1beta1.CustomResourceDefinition{
 									},
 								},
 							},
+							"startTime": apiextensionv1beta1.JSONSchemaProps{
+								Type:   "string",
+								Format: "date-time",
+							},
+							"endTime": apiextensionv1beta1.JSONSchemaProps{
+								Type:   "string",
+								Format: "date-time",
+							},
+							"schedule": apiextensionv1beta1.JSONSchemaProps{
+								Type: "object",
+								Required: []string{
+									"cron",
+									"duration",
+								},
+								Properties: map[string]apiextensionv1beta1.JSONSchemaProps{
+									"cron": apiextensionv1beta1.JSONSchemaProps{
+										Type: "string",
+									},
+									"duration": apiextensionv1beta1.JSONSchemaProps{
+										Type: "string",
+									},
+									"timeZone": apiextensionv1beta1.JSONSchemaProps{
+										Type: "string",
+									},
+								},
+							},
 						},
 					},
 				},
@@ -66,6 +92,13 @@ var RolloutBlock = &apiextensionv1beta1.CustomResourceDefinition{
 				JSONPath:    ".spec.message",
 				Priority:    0,
 			},
+			apiextensionv1beta1.CustomResourceColumnDefinition{
+				Name:        "Active",
+				Type:        "boolean",
+				Description: "Whether this rollout block is in effect right now.",
+				JSONPath:    ".status.active",
+				Priority:    0,
+			},
 			apiextensionv1beta1.CustomResourceColumnDefinition{
 				Name:        "Author Type",
 				Type:        "string",
@@ -20,3 +20,55 @@ func IsApplicationAnnotationNotFoundError(err error) bool {
 	_, ok := err.(*ApplicationAnnotationError)
 	return ok
 }
+
+type ValuesFromError struct {
+	kind string
+	ns   string
+	name string
+	key  string
+	err  error
+}
+
+func (e ValuesFromError) Error() string {
+	return fmt.Sprintf(`failed to read values from key %q of %s "%s/%s": %s`, e.key, e.kind, e.ns, e.name, e.err)
+}
+
+// ShouldRetry returns true as the referenced object might be created or fixed
+// without any change to the application.
+func (e ValuesFromError) ShouldRetry() bool {
+	return true
+}
+
+func NewValuesFromError(kind, ns, name, key string, err error) ValuesFromError {
+	return ValuesFromError{kind: kind, ns: ns, name: name, key: key, err: err}
+}
+
+func IsValuesFromError(err error) bool {
+	_, ok := err.(ValuesFromError)
+	return ok
+}
+
+type RollbackError struct {
+	appName string
+	target  string
+	err     error
+}
+
+func (e RollbackError) Error() string {
+	return fmt.Sprintf(`cannot roll application %q back to %q: %s`, e.appName, e.target, e.err)
+}
+
+// ShouldRetry returns false as the application's spec.rollbackTo has to
+// change for the rollback to go any different.
+func (e RollbackError) ShouldRetry() bool {
+	return false
+}
+
+func NewRollbackError(appName, target string, err error) RollbackError {
+	return RollbackError{appName: appName, target: target, err: err}
+}
+
+func IsRollbackError(err error) bool {
+	_, ok := err.(RollbackError)
+	return ok
+}
@@ -138,6 +138,11 @@ func NewRenderManifestError(err error) RenderManifestError {
 	return RenderManifestError{err}
 }
 
+func IsRenderManifestError(err error) bool {
+	_, ok := err.(RenderManifestError)
+	return ok
+}
+
 type ChartVersionResolveError struct {
 	ChartError
 	err error
@@ -247,3 +252,65 @@ func NewChartRepoInternalError(err error) ChartRepoInternalError {
 		err: err,
 	}
 }
+
+type ChartRepoUnauthorizedError struct {
+	url string
+	err error
+}
+
+func (e ChartRepoUnauthorizedError) Error() string {
+	return fmt.Sprintf(
+		"unauthorized to fetch %q: %s",
+		e.url,
+		e.err,
+	)
+}
+
+// ShouldRetry returns true as credentials might be fixed or rotated without
+// any change to the object that needs them.
+func (e ChartRepoUnauthorizedError) ShouldRetry() bool {
+	return true
+}
+
+func IsChartRepoUnauthorizedError(err error) bool {
+	_, ok := err.(ChartRepoUnauthorizedError)
+	return ok
+}
+
+func NewChartRepoUnauthorizedError(url string, err error) ChartRepoUnauthorizedError {
+	return ChartRepoUnauthorizedError{
+		url: url,
+		err: err,
+	}
+}
+
+type ChartVerificationError struct {
+	ChartError
+	err error
+}
+
+func (e ChartVerificationError) Error() string {
+	return fmt.Sprintf(
+		"failed to verify chart [name: %q, version: %q, repo: %q]: %s",
+		e.chartName, e.chartVersion, e.chartRepo,
+		e.err)
+}
+
+// ShouldRetry returns true as the provenance file might just not have been
+// uploaded yet, or the keyring might be fixed without any change to the
+// release.
+func (e ChartVerificationError) ShouldRetry() bool {
+	return true
+}
+
+func IsChartVerificationError(err error) bool {
+	_, ok := err.(ChartVerificationError)
+	return ok
+}
+
+func NewChartVerificationError(chartspec *shipper.Chart, err error) ChartVerificationError {
+	return ChartVerificationError{
+		ChartError: newChartError(chartspec),
+		err:        err,
+	}
+}
@@ -6,6 +6,7 @@
 package instrumentedclient
 
 import (
+	"crypto/tls"
 	"io"
 	"net"
 	"net/http"
@@ -65,14 +66,18 @@ var (
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
-	roundTripper = promhttp.InstrumentRoundTripperCounter(
+	roundTripper = instrumentRoundTripper(httpTransport)
+)
+
+func instrumentRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return promhttp.InstrumentRoundTripperCounter(
 		reqCounter,
 		promhttp.InstrumentRoundTripperDuration(
 			reqDuration,
-			instrumentRoundTripperTrace(httpTransport),
+			instrumentRoundTripperTrace(next),
 		),
 	)
-)
+}
 
 // DefaultClient is an instrumented http.Client with pre-set timeouts.
 var DefaultClient = &http.Client{
@@ -89,6 +94,19 @@ func NewClient() *http.Client {
 	}
 }
 
+// NewClientWithTLSConfig returns a new instrumented http.Client with the same
+// timeouts as DefaultClient, but using tlsConfig for its connections. Use it
+// when talking to servers that require client certificates or a custom CA.
+func NewClientWithTLSConfig(tlsConfig *tls.Config) *http.Client {
+	transport := httpTransport.Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: instrumentRoundTripper(transport),
+		Timeout:   HTTPRequestResponseTimeout,
+	}
+}
+
 // Get issues a GET request using DefaultClient.
 func Get(url string) (*http.Response, error) {
 	return DefaultClient.Get(url)
@@ -5,6 +5,8 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 
@@ -17,6 +19,10 @@ type ControllerTestFixture struct {
 	ShipperClient          *shipperfake.Clientset
 	ShipperInformerFactory shipperinformers.SharedInformerFactory
 
+	// KubeClient and KubeInformerFactory are for the management cluster.
+	KubeClient          *kubefake.Clientset
+	KubeInformerFactory kubeinformers.SharedInformerFactory
+
 	Clusters           map[string]*FakeCluster
 	ClusterClientStore *FakeClusterClientStore
 
@@ -30,12 +36,19 @@ func NewControllerTestFixture() *ControllerTestFixture {
 	shipperInformerFactory := shipperinformers.NewSharedInformerFactory(
 		shipperClient, NoResyncPeriod)
 
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(
+		kubeClient, NoResyncPeriod)
+
 	store := NewFakeClusterClientStore(map[string]*FakeCluster{})
 
 	return &ControllerTestFixture{
 		ShipperClient:          shipperClient,
 		ShipperInformerFactory: shipperInformerFactory,
 
+		KubeClient:          kubeClient,
+		KubeInformerFactory: kubeInformerFactory,
+
 		Clusters:           make(map[string]*FakeCluster),
 		ClusterClientStore: store,
 
@@ -71,5 +84,7 @@ func (f *ControllerTestFixture) DynamicClientBuilder(
 func (f *ControllerTestFixture) Run(stopCh chan struct{}) {
 	f.ShipperInformerFactory.Start(stopCh)
 	f.ShipperInformerFactory.WaitForCacheSync(stopCh)
+	f.KubeInformerFactory.Start(stopCh)
+	f.KubeInformerFactory.WaitForCacheSync(stopCh)
 	f.ClusterClientStore.Run(stopCh)
 }
@@ -8,6 +8,7 @@ import (
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 )
 
 type FakeCluster struct {
@@ -16,6 +17,7 @@ type FakeCluster struct {
 	Client          *kubefake.Clientset
 	DynamicClient   *fakedynamic.FakeDynamicClient
 	InformerFactory informers.SharedInformerFactory
+	Config          *rest.Config
 }
 
 func NewNamedFakeCluster(name string) *FakeCluster {
@@ -24,6 +26,7 @@ func NewNamedFakeCluster(name string) *FakeCluster {
 		Name:            name,
 		Client:          client,
 		InformerFactory: informers.NewSharedInformerFactory(client, NoResyncPeriod),
+		Config:          &rest.Config{},
 	}
 }
 
@@ -17,6 +17,8 @@ type FakeClusterClientStore struct {
 
 	subscriptionCallbacks []clusterclientstore.SubscriptionRegisterFunc
 	eventHandlerCallbacks []clusterclientstore.EventHandlerRegisterFunc
+
+	stopCh <-chan struct{}
 }
 
 func NewFakeClusterClientStore(clusters map[string]*FakeCluster) *FakeClusterClientStore {
@@ -36,6 +38,8 @@ func (s *FakeClusterClientStore) AddEventHandlerCallback(c clusterclientstore.Ev
 }
 
 func (s *FakeClusterClientStore) Run(stopCh <-chan struct{}) {
+	s.stopCh = stopCh
+
 	for name, cluster := range s.clusters {
 		informerFactory := cluster.InformerFactory
 
@@ -62,9 +66,27 @@ func (s *FakeClusterClientStore) GetClient(clusterName string, ua string) (kuber
 }
 
 func (s *FakeClusterClientStore) GetConfig(clusterName string) (*rest.Config, error) {
+	if cluster, ok := s.clusters[clusterName]; ok && cluster.Config != nil {
+		return cluster.Config, nil
+	}
+
 	return &rest.Config{}, nil
 }
 
 func (s *FakeClusterClientStore) GetInformerFactory(clusterName string) (informers.SharedInformerFactory, error) {
 	return s.clusters[clusterName].InformerFactory, nil
 }
+
+// StartInformers starts the informers asked for since Run, and unlike the
+// real thing, waits for them to sync so tests don't have to.
+func (s *FakeClusterClientStore) StartInformers(clusterName string) error {
+	cluster, ok := s.clusters[clusterName]
+	if !ok {
+		return fmt.Errorf("no informer factory for cluster %q", clusterName)
+	}
+
+	cluster.InformerFactory.Start(s.stopCh)
+	cluster.InformerFactory.WaitForCacheSync(s.stopCh)
+
+	return nil
+}
@@ -137,6 +137,7 @@ func FilterActions(actions []kubetesting.Action) []kubetesting.Action {
 				"installationtargets",
 				"pods",
 				"releases",
+				"releaseapprovals",
 				"rolloutblocks",
 				"secrets",
 				"services",
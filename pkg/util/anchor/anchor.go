@@ -13,6 +13,11 @@ import (
 const (
 	AnchorSuffix          = "-anchor"
 	InstallationTargetUID = "InstallationTargetUID"
+
+	// PodTrafficStatus is the key under which the traffic controller
+	// publishes the traffic status new pods of the release should start
+	// with. It is only set once the release's traffic is fully settled.
+	PodTrafficStatus = "PodTrafficStatus"
 )
 
 func BelongsToInstallationTarget(configMap *corev1.ConfigMap) bool {
@@ -51,5 +56,11 @@ func CreateConfigMapAnchor(it *shipper.InstallationTarget) *corev1.ConfigMap {
 }
 
 func CreateAnchorName(it *shipper.InstallationTarget) string {
-	return fmt.Sprintf("%s%s", it.Name, AnchorSuffix)
+	return ReleaseAnchorName(it.Name)
+}
+
+// ReleaseAnchorName returns the name of the anchor of a release. Installation
+// targets are named after their releases, and so are their anchors.
+func ReleaseAnchorName(releaseName string) string {
+	return fmt.Sprintf("%s%s", releaseName, AnchorSuffix)
 }
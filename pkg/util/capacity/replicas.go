@@ -0,0 +1,20 @@
+package capacity
+
+import (
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	"github.com/bookingcom/shipper/pkg/util/replicas"
+)
+
+// DesiredReplicaCount returns how many pods spec asks for: its replicas,
+// capped at its total replica count, when it has them, or its percentage of
+// the total replica count otherwise.
+func DesiredReplicaCount(spec shipper.ClusterCapacityTarget) int32 {
+	if spec.Replicas != nil {
+		if *spec.Replicas > spec.TotalReplicaCount {
+			return spec.TotalReplicaCount
+		}
+		return *spec.Replicas
+	}
+
+	return int32(replicas.CalculateDesiredReplicaCount(uint(spec.TotalReplicaCount), float64(spec.Percent)))
+}
@@ -6,7 +6,11 @@ const (
 
 	CreateReleaseFailed                 = "CreateReleaseFailed"
 	ChartVersionResolutionFailed        = "ChartVersionResolutionFailed"
+	ChartRepoUnauthorized               = "ChartRepoUnauthorized"
 	BrokenReleaseGeneration             = "BrokenReleaseGeneration"
 	BrokenApplicationObservedGeneration = "BrokenApplicationObservedGeneration"
 	StrategyExecutionFailed             = "StrategyExecutionFailed"
+	ValuesFromResolutionFailed          = "ValuesFromResolutionFailed"
+	RollbackRequested                   = "RollbackRequested"
+	RollbackFailed                      = "RollbackFailed"
 )
@@ -0,0 +1,56 @@
+package pod
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+// IsReady returns whether pod is running and passing its readiness checks.
+// Pods on their way out are never considered ready.
+//
+// The shipper.PodTrafficReadinessGate readiness gate is left out: pods of
+// applications using the readiness-gate traffic backend only pass it once
+// they get traffic, but they're up and able to serve it well before that, and
+// counting them as unready would keep their release from ever getting any.
+func IsReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+
+	if conditionTrue(pod, corev1.PodReady) {
+		return true
+	} else if !HasTrafficReadinessGate(&pod.Spec) || !conditionTrue(pod, corev1.ContainersReady) {
+		return false
+	}
+
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType != shipper.PodTrafficReadinessGate && !conditionTrue(pod, gate.ConditionType) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasTrafficReadinessGate returns whether pods with spec have a readiness
+// gate on shipper.PodTrafficReadinessGate.
+func HasTrafficReadinessGate(spec *corev1.PodSpec) bool {
+	for _, gate := range spec.ReadinessGates {
+		if gate.ConditionType == shipper.PodTrafficReadinessGate {
+			return true
+		}
+	}
+
+	return false
+}
+
+func conditionTrue(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == conditionType {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
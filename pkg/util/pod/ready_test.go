@@ -0,0 +1,102 @@
+package pod
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func TestIsReady(t *testing.T) {
+	trafficGate := corev1.PodReadinessGate{ConditionType: shipper.PodTrafficReadinessGate}
+	otherGate := corev1.PodReadinessGate{ConditionType: "example.com/other"}
+	now := metav1.Now()
+
+	tests := []struct {
+		name       string
+		gates      []corev1.PodReadinessGate
+		conditions map[corev1.PodConditionType]corev1.ConditionStatus
+		deleting   bool
+		expected   bool
+	}{
+		{
+			"ready",
+			nil,
+			map[corev1.PodConditionType]corev1.ConditionStatus{corev1.PodReady: corev1.ConditionTrue},
+			false,
+			true,
+		},
+		{
+			"not ready",
+			nil,
+			map[corev1.PodConditionType]corev1.ConditionStatus{
+				corev1.ContainersReady: corev1.ConditionTrue,
+				corev1.PodReady:        corev1.ConditionFalse,
+			},
+			false,
+			false,
+		},
+		{
+			"on its way out",
+			nil,
+			map[corev1.PodConditionType]corev1.ConditionStatus{corev1.PodReady: corev1.ConditionTrue},
+			true,
+			false,
+		},
+		{
+			"waiting for traffic",
+			[]corev1.PodReadinessGate{trafficGate},
+			map[corev1.PodConditionType]corev1.ConditionStatus{
+				corev1.ContainersReady:          corev1.ConditionTrue,
+				shipper.PodTrafficReadinessGate: corev1.ConditionFalse,
+				corev1.PodReady:                 corev1.ConditionFalse,
+			},
+			false,
+			true,
+		},
+		{
+			"waiting for traffic with containers not ready",
+			[]corev1.PodReadinessGate{trafficGate},
+			map[corev1.PodConditionType]corev1.ConditionStatus{
+				corev1.ContainersReady:          corev1.ConditionFalse,
+				shipper.PodTrafficReadinessGate: corev1.ConditionFalse,
+				corev1.PodReady:                 corev1.ConditionFalse,
+			},
+			false,
+			false,
+		},
+		{
+			"waiting for traffic and another readiness gate",
+			[]corev1.PodReadinessGate{trafficGate, otherGate},
+			map[corev1.PodConditionType]corev1.ConditionStatus{
+				corev1.ContainersReady:          corev1.ConditionTrue,
+				shipper.PodTrafficReadinessGate: corev1.ConditionFalse,
+				otherGate.ConditionType:         corev1.ConditionFalse,
+				corev1.PodReady:                 corev1.ConditionFalse,
+			},
+			false,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{ReadinessGates: tt.gates}}
+			for conditionType, status := range tt.conditions {
+				pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
+					Type:   conditionType,
+					Status: status,
+				})
+			}
+			if tt.deleting {
+				pod.DeletionTimestamp = &now
+			}
+
+			if ready := IsReady(pod); ready != tt.expected {
+				t.Errorf("expected ready to be %t, got %t", tt.expected, ready)
+			}
+		})
+	}
+}
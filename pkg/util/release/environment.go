@@ -1,6 +1,8 @@
 package release
 
 import (
+	"fmt"
+
 	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
 )
 
@@ -24,3 +26,11 @@ func IsLastStrategyStep(rel *shipper.Release) bool {
 	numSteps := len(rel.Spec.Environment.Strategy.Steps)
 	return targetStep == int32(numSteps-1)
 }
+
+// ValuesSecretName returns the name of the Secret holding the values resolved
+// from the valuesFrom of the release named relName in namespace. These
+// Secrets all live in Shipper's own namespace, so their names include the
+// release's namespace.
+func ValuesSecretName(namespace, relName string) string {
+	return fmt.Sprintf("%s.%s-values", namespace, relName)
+}
@@ -22,18 +22,3 @@ func CalculateDesiredReplicaCount(totalReplicaCount uint, desiredCapacityPercent
 	desiredReplicaCount := math.Ceil(float64(totalReplicaCount) * float64(desiredCapacityPercentage) / 100)
 	return uint(desiredReplicaCount)
 }
-
-// AchievedDesiredCapacity verifies whether the given currentReplicaCount
-// and totalReplicaCount match the given desiredCapacityPercentage.
-//
-// Please note desiredPercentage might be a value between 0 and 100. In the
-// case the informed desiredPercentage value is greater than 100, this
-// function will panic; it is the caller's responsibility to check if the
-// value falls in the 0-100 range.
-func AchievedDesiredReplicaPercentage(totalReplicaCount, currentReplicaCount, desiredPercentage int32) bool {
-	if desiredPercentage > 100 {
-		panic("Programmer error: desiredPercentage should be a value between 0 and 100 inclusive")
-	}
-
-	return uint(currentReplicaCount) == CalculateDesiredReplicaCount(uint(totalReplicaCount), float64(desiredPercentage))
-}
@@ -1,6 +1,8 @@
 package rolloutblock
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -32,7 +34,9 @@ func BlocksRollout(rolloutBlockLister shipperlisters.RolloutBlockLister, obj met
 		return false, events, err
 	}
 
-	existingBlocks := NewObjectNameListFromRolloutBlocksList(append(nsBlocks, globalBlocks...))
+	blocks := append(nsBlocks, globalBlocks...)
+	existingBlocks := NewObjectNameListFromRolloutBlocksList(blocks)
+	activeBlocks := NewObjectNameListFromRolloutBlocksList(ActiveBlocks(blocks, time.Now()))
 	obsoleteBlocks := overrides.Diff(existingBlocks)
 
 	if len(obsoleteBlocks) > 0 {
@@ -42,7 +46,7 @@ func BlocksRollout(rolloutBlockLister shipperlisters.RolloutBlockLister, obj met
 
 	obj.SetAnnotations(annotations)
 
-	effectiveBlocks := existingBlocks.Diff(overrides)
+	effectiveBlocks := activeBlocks.Diff(overrides)
 
 	if len(effectiveBlocks) == 0 {
 		if len(overrides) > 0 {
@@ -62,23 +66,38 @@ func BlocksRollout(rolloutBlockLister shipperlisters.RolloutBlockLister, obj met
 	}
 }
 
-func GetAllBlocks(rolloutBlockLister shipperlisters.RolloutBlockLister, obj metav1.Object) (ObjectNameList, ObjectNameList, error) {
+// GetAllBlocks returns the blocks obj overrides, the blocks that exist for
+// it, and which of those are in effect.
+func GetAllBlocks(rolloutBlockLister shipperlisters.RolloutBlockLister, obj metav1.Object) (ObjectNameList, ObjectNameList, ObjectNameList, error) {
 	annotations := obj.GetAnnotations()
 	overrides := NewObjectNameList(annotations[shipper.RolloutBlocksOverrideAnnotation])
 	nsBlocks, err := rolloutBlockLister.RolloutBlocks(obj.GetNamespace()).List(labels.Everything())
 	if err != nil {
-		return overrides, nil, err
+		return overrides, nil, nil, err
 	}
 	globalBlocks, err := rolloutBlockLister.RolloutBlocks(shipper.GlobalRolloutBlockNamespace).List(labels.Everything())
 	if err != nil {
-		return overrides, nil, err
+		return overrides, nil, nil, err
+	}
+	blocks := append(nsBlocks, globalBlocks...)
+	existingBlocks := NewObjectNameListFromRolloutBlocksList(blocks)
+	activeBlocks := NewObjectNameListFromRolloutBlocksList(ActiveBlocks(blocks, time.Now()))
+	return overrides, existingBlocks, activeBlocks, nil
+}
+
+// ActiveBlocks returns the blocks out of rbs that are in effect at now.
+func ActiveBlocks(rbs []*shipper.RolloutBlock, now time.Time) []*shipper.RolloutBlock {
+	active := make([]*shipper.RolloutBlock, 0, len(rbs))
+	for _, rb := range rbs {
+		if IsActive(rb, now) {
+			active = append(active, rb)
+		}
 	}
-	existingBlocks := NewObjectNameListFromRolloutBlocksList(append(nsBlocks, globalBlocks...))
-	return overrides, existingBlocks, nil
+	return active
 }
 
-func ValidateBlocks(existing, overrides ObjectNameList) error {
-	effectiveBlocks := existing.Diff(overrides)
+func ValidateBlocks(active, overrides ObjectNameList) error {
+	effectiveBlocks := active.Diff(overrides)
 	if len(effectiveBlocks) > 0 {
 		return shippererrors.NewRolloutBlockError(effectiveBlocks.String())
 	}
@@ -92,3 +111,19 @@ func ValidateAnnotations(existing, overrides ObjectNameList) error {
 	}
 	return nil
 }
+
+// ActivityChanged tells whether a RolloutBlock update has it going in or out
+// of effect, so whatever it blocks needs another look.
+func ActivityChanged(oldObj, newObj interface{}) bool {
+	oldRB, ok := oldObj.(*shipper.RolloutBlock)
+	if !ok {
+		return false
+	}
+
+	newRB, ok := newObj.(*shipper.RolloutBlock)
+	if !ok {
+		return false
+	}
+
+	return oldRB.Status.Active != newRB.Status.Active
+}
@@ -0,0 +1,297 @@
+package rolloutblock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+// cronSearchYears bounds how far ahead a cron expression is looked up, so
+// expressions that never fire, like "0 0 30 2 *", don't loop forever.
+const cronSearchYears = 5
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// cronSchedule is a parsed five field cron expression. Each field is a
+// bitset of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Like in cron, a day matches if either its day of month or day
+	// of week does, unless one of them is "*".
+	domStar, dowStar bool
+
+	location *time.Location
+}
+
+// parseCron parses expr, which has the same format as a crontab entry's
+// schedule, and evaluates it in location.
+func parseCron(expr string, location *time.Location) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q should have 5 fields, but has %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{location: location}
+	var err error
+
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute in %q: %s", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour in %q: %s", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day of month in %q: %s", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid month in %q: %s", expr, err)
+	}
+	// Sunday is both 0 and 7.
+	if s.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("invalid day of week in %q: %s", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges like
+// "1-5", and steps like "*/15" or "8-18/2" into a bitset.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		var first, last int
+		switch {
+		case rng == "*":
+			first, last = min, max
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if first, err = parseCronValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			if last, err = parseCronValue(bounds[1], min, max, names); err != nil {
+				return 0, err
+			}
+			if first > last {
+				return 0, fmt.Errorf("range %q goes backwards", rng)
+			}
+		default:
+			var err error
+			if first, err = parseCronValue(rng, min, max, names); err != nil {
+				return 0, err
+			}
+			last = first
+			// Like in cron, "5/10" means every 10 from 5 on.
+			if step > 1 {
+				last = max
+			}
+		}
+
+		for v := first; v <= last; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	} else if n < min || n > max {
+		return 0, fmt.Errorf("%d is not between %d and %d", n, min, max)
+	}
+
+	return n, nil
+}
+
+// next returns the first minute after t that s matches, if there is one in
+// the next few years.
+func (s *cronSchedule) next(t time.Time) (time.Time, bool) {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case !s.matchesDay(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+
+		// Around daylight saving time changes, going to the next
+		// hour of the day can land before t.
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+
+	return time.Time{}, false
+}
+
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// scheduleWindows returns when the windows of schedule start, and how long
+// each of them lasts.
+func scheduleWindows(schedule *shipper.RolloutBlockSchedule) (*cronSchedule, time.Duration, error) {
+	location := time.UTC
+	if schedule.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(schedule.TimeZone)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid time zone %q: %s", schedule.TimeZone, err)
+		}
+	}
+
+	cron, err := parseCron(schedule.Cron, location)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	duration := schedule.Duration.Duration
+	if duration <= 0 {
+		return nil, 0, fmt.Errorf("schedule duration must be positive, got %s", duration)
+	}
+
+	return cron, duration, nil
+}
+
+// currentWindow returns the end of the earliest window of cron that now is
+// in, if it's in any.
+func currentWindow(cron *cronSchedule, duration time.Duration, now time.Time) (time.Time, bool) {
+	start, ok := cron.next(now.Add(-duration))
+	if !ok || start.After(now) {
+		return time.Time{}, false
+	}
+
+	return start.Add(duration), true
+}
+
+// ValidateSchedule checks that the time window and schedule in spec make
+// sense.
+func ValidateSchedule(spec shipper.RolloutBlockSpec) error {
+	if spec.StartTime != nil && spec.EndTime != nil && !spec.EndTime.After(spec.StartTime.Time) {
+		return fmt.Errorf("rollout block endTime %s is not after its startTime %s",
+			spec.EndTime.Format(time.RFC3339), spec.StartTime.Format(time.RFC3339))
+	}
+
+	if spec.Schedule != nil {
+		if _, _, err := scheduleWindows(spec.Schedule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsActive tells whether rb is in effect at now. Blocks with a schedule that
+// can't be parsed are always in effect, as it's safer to block rollouts than
+// to let them through by mistake.
+func IsActive(rb *shipper.RolloutBlock, now time.Time) bool {
+	spec := rb.Spec
+	if spec.StartTime != nil && now.Before(spec.StartTime.Time) {
+		return false
+	} else if spec.EndTime != nil && !now.Before(spec.EndTime.Time) {
+		return false
+	} else if spec.Schedule == nil {
+		return true
+	}
+
+	cron, duration, err := scheduleWindows(spec.Schedule)
+	if err != nil {
+		return true
+	}
+
+	_, ok := currentWindow(cron, duration, now)
+	return ok
+}
+
+// NextTransition returns the next time after now that rb might go in or out
+// of effect, if there is one.
+func NextTransition(rb *shipper.RolloutBlock, now time.Time) (time.Time, bool) {
+	var candidates []time.Time
+
+	spec := rb.Spec
+	if spec.StartTime != nil && now.Before(spec.StartTime.Time) {
+		candidates = append(candidates, spec.StartTime.Time)
+	}
+	if spec.EndTime != nil {
+		if !now.Before(spec.EndTime.Time) {
+			return time.Time{}, false
+		}
+		candidates = append(candidates, spec.EndTime.Time)
+	}
+
+	if spec.Schedule != nil {
+		if cron, duration, err := scheduleWindows(spec.Schedule); err == nil {
+			if end, ok := currentWindow(cron, duration, now); ok {
+				candidates = append(candidates, end)
+			} else if start, ok := cron.next(now); ok {
+				candidates = append(candidates, start)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return time.Time{}, false
+	}
+
+	next := candidates[0]
+	for _, t := range candidates[1:] {
+		if t.Before(next) {
+			next = t
+		}
+	}
+
+	return next, true
+}
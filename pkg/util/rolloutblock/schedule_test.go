@@ -0,0 +1,296 @@
+package rolloutblock
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("failed to parse time %q: %s", value, err)
+	}
+	return parsed
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"0 0 * * FOO",
+		"0 0 * jan-foo *",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseCron(expr, time.UTC); err == nil {
+				t.Errorf("expected parsing %q to fail, but it didn't", expr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skipf("no time zone data: %s", err)
+	}
+
+	tests := []struct {
+		Name     string
+		Expr     string
+		Location *time.Location
+		After    string
+		Expected string
+	}{
+		{
+			"every 15 minutes during office hours",
+			"*/15 9-17 * * MON-FRI",
+			time.UTC,
+			"2026-10-16T17:50:00Z",
+			"2026-10-19T09:00:00Z",
+		},
+		{
+			"strictly after the given time",
+			"0 16 * * FRI",
+			time.UTC,
+			"2026-10-16T16:00:00Z",
+			"2026-10-23T16:00:00Z",
+		},
+		{
+			"day of month or day of week",
+			"0 0 1 * MON",
+			time.UTC,
+			"2026-10-16T12:00:00Z",
+			"2026-10-19T00:00:00Z",
+		},
+		{
+			"sunday as 7",
+			"0 12 * * 7",
+			time.UTC,
+			"2026-10-16T12:00:00Z",
+			"2026-10-18T12:00:00Z",
+		},
+		{
+			"in a time zone",
+			"0 16 * * FRI",
+			amsterdam,
+			"2026-10-16T12:00:00Z",
+			"2026-10-16T14:00:00Z",
+		},
+		{
+			"skips a time that doesn't exist on a daylight saving time change",
+			"30 2 * * *",
+			amsterdam,
+			"2026-03-28T12:00:00Z",
+			"2026-03-30T00:30:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cron, err := parseCron(tt.Expr, tt.Location)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %s", tt.Expr, err)
+			}
+
+			next, ok := cron.next(mustParseTime(t, tt.After))
+			if !ok {
+				t.Fatalf("expected %q to fire after %s, but it didn't", tt.Expr, tt.After)
+			}
+
+			if expected := mustParseTime(t, tt.Expected); !next.Equal(expected) {
+				t.Errorf("expected %q to fire next at %s, got %s", tt.Expr, expected, next.UTC())
+			}
+		})
+	}
+
+	cron, err := parseCron("0 0 30 2 *", time.UTC)
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if next, ok := cron.next(mustParseTime(t, "2026-10-16T12:00:00Z")); ok {
+		t.Errorf("expected February 30th to never come, got %s", next)
+	}
+}
+
+func TestIsActiveAndNextTransition(t *testing.T) {
+	weekendFreeze := &shipper.RolloutBlockSchedule{
+		Cron:     "0 16 * * FRI",
+		Duration: metav1.Duration{Duration: 64 * time.Hour},
+	}
+	startTime := metav1.NewTime(mustParseTime(t, "2026-10-17T00:00:00Z"))
+	endTime := metav1.NewTime(mustParseTime(t, "2026-10-18T00:00:00Z"))
+
+	tests := []struct {
+		Name           string
+		Spec           shipper.RolloutBlockSpec
+		Now            string
+		ExpectedActive bool
+		ExpectedNext   string
+	}{
+		{
+			"without a time window",
+			shipper.RolloutBlockSpec{},
+			"2026-10-16T12:00:00Z",
+			true,
+			"",
+		},
+		{
+			"before its start time",
+			shipper.RolloutBlockSpec{StartTime: &startTime, EndTime: &endTime},
+			"2026-10-16T12:00:00Z",
+			false,
+			"2026-10-17T00:00:00Z",
+		},
+		{
+			"between its start and end times",
+			shipper.RolloutBlockSpec{StartTime: &startTime, EndTime: &endTime},
+			"2026-10-17T12:00:00Z",
+			true,
+			"2026-10-18T00:00:00Z",
+		},
+		{
+			"at its end time",
+			shipper.RolloutBlockSpec{StartTime: &startTime, EndTime: &endTime},
+			"2026-10-18T00:00:00Z",
+			false,
+			"",
+		},
+		{
+			"before a scheduled window",
+			shipper.RolloutBlockSpec{Schedule: weekendFreeze},
+			"2026-10-16T15:59:00Z",
+			false,
+			"2026-10-16T16:00:00Z",
+		},
+		{
+			"at the start of a scheduled window",
+			shipper.RolloutBlockSpec{Schedule: weekendFreeze},
+			"2026-10-16T16:00:00Z",
+			true,
+			"2026-10-19T08:00:00Z",
+		},
+		{
+			"in the middle of a scheduled window",
+			shipper.RolloutBlockSpec{Schedule: weekendFreeze},
+			"2026-10-18T12:00:00Z",
+			true,
+			"2026-10-19T08:00:00Z",
+		},
+		{
+			"at the end of a scheduled window",
+			shipper.RolloutBlockSpec{Schedule: weekendFreeze},
+			"2026-10-19T08:00:00Z",
+			false,
+			"2026-10-23T16:00:00Z",
+		},
+		{
+			"in a scheduled window cut short by its end time",
+			shipper.RolloutBlockSpec{Schedule: weekendFreeze, EndTime: &endTime},
+			"2026-10-17T12:00:00Z",
+			true,
+			"2026-10-18T00:00:00Z",
+		},
+		{
+			"with a schedule that can't be parsed",
+			shipper.RolloutBlockSpec{Schedule: &shipper.RolloutBlockSchedule{Cron: "whenever"}},
+			"2026-10-16T12:00:00Z",
+			true,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			rb := &shipper.RolloutBlock{Spec: tt.Spec}
+			now := mustParseTime(t, tt.Now)
+
+			if active := IsActive(rb, now); active != tt.ExpectedActive {
+				t.Errorf("expected active to be %t, got %t", tt.ExpectedActive, active)
+			}
+
+			next, ok := NextTransition(rb, now)
+			if tt.ExpectedNext == "" {
+				if ok {
+					t.Errorf("expected no next transition, got %s", next)
+				}
+				return
+			}
+
+			if expected := mustParseTime(t, tt.ExpectedNext); !ok || !next.Equal(expected) {
+				t.Errorf("expected next transition at %s, got %s (%t)", expected, next, ok)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	startTime := metav1.NewTime(mustParseTime(t, "2026-10-17T00:00:00Z"))
+	endTime := metav1.NewTime(mustParseTime(t, "2026-10-18T00:00:00Z"))
+
+	tests := []struct {
+		Name  string
+		Spec  shipper.RolloutBlockSpec
+		Valid bool
+	}{
+		{
+			"without a time window",
+			shipper.RolloutBlockSpec{},
+			true,
+		},
+		{
+			"with a time window",
+			shipper.RolloutBlockSpec{StartTime: &startTime, EndTime: &endTime},
+			true,
+		},
+		{
+			"with an end time before its start time",
+			shipper.RolloutBlockSpec{StartTime: &endTime, EndTime: &startTime},
+			false,
+		},
+		{
+			"with a schedule",
+			shipper.RolloutBlockSpec{Schedule: &shipper.RolloutBlockSchedule{
+				Cron:     "0 16 * * FRI",
+				Duration: metav1.Duration{Duration: time.Hour},
+			}},
+			true,
+		},
+		{
+			"with a schedule without a duration",
+			shipper.RolloutBlockSpec{Schedule: &shipper.RolloutBlockSchedule{
+				Cron: "0 16 * * FRI",
+			}},
+			false,
+		},
+		{
+			"with a schedule in an unknown time zone",
+			shipper.RolloutBlockSpec{Schedule: &shipper.RolloutBlockSchedule{
+				Cron:     "0 16 * * FRI",
+				Duration: metav1.Duration{Duration: time.Hour},
+				TimeZone: "Middle/Earth",
+			}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := ValidateSchedule(tt.Spec)
+			if tt.Valid && err != nil {
+				t.Errorf("expected spec to be valid, got %s", err)
+			} else if !tt.Valid && err == nil {
+				t.Errorf("expected spec to be invalid, but it wasn't")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package traffic
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+const (
+	// IngressNginxCanaryAnnotation marks an Ingress as a canary of the
+	// Ingress with the same hosts and paths for ingress-nginx.
+	IngressNginxCanaryAnnotation = "nginx.ingress.kubernetes.io/canary"
+	// IngressNginxCanaryWeightAnnotation holds the percentage of requests
+	// ingress-nginx sends to a canary Ingress.
+	IngressNginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+)
+
+// NotApplicableReason is the reason of the Ready condition of clusters where
+// an application gets no traffic, as it uses shipper.NoTrafficBackend.
+const NotApplicableReason = "TrafficNotApplicable"
+
+// DefaultBackend is the traffic backend used for applications and clusters
+// that don't explicitly pick one.
+const DefaultBackend = shipper.PodLabelsTrafficBackend
+
+// BackendName returns the traffic backend for an application in a cluster:
+// the application's choice wins over the cluster's, and both fall back to
+// DefaultBackend. Either of them can be nil.
+func BackendName(app *shipper.Application, cluster *shipper.Cluster) string {
+	if app != nil && app.Spec.TrafficBackend != "" {
+		return app.Spec.TrafficBackend
+	}
+
+	if cluster != nil && cluster.Spec.TrafficBackend != "" {
+		return cluster.Spec.TrafficBackend
+	}
+
+	return DefaultBackend
+}
+
+// NotApplicable tells whether app declares that it gets no traffic.
+func NotApplicable(app *shipper.Application) bool {
+	return app.Spec.TrafficBackend == shipper.NoTrafficBackend
+}
+
+// TargetNotApplicable tells whether traffic is not applicable in any of the
+// clusters tt has a status for.
+func TargetNotApplicable(tt *shipper.TrafficTarget) bool {
+	if len(tt.Status.Clusters) == 0 {
+		return false
+	}
+
+	for _, cluster := range tt.Status.Clusters {
+		notApplicable := false
+		for _, cond := range cluster.Conditions {
+			if cond.Type == shipper.ClusterConditionTypeReady {
+				notApplicable = cond.Reason == NotApplicableReason
+			}
+		}
+
+		if !notApplicable {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReleaseScopedName returns the name of an object generated from the object
+// called name to serve only release, such as the backend Service of a
+// release generated from the production Service. Names that would be too
+// long for a Service are truncated, and made unique again with a hash of the
+// full name.
+func ReleaseScopedName(name, release string) string {
+	name = fmt.Sprintf("%s-%s", name, release)
+	if len(name) <= validation.DNS1035LabelMaxLength {
+		return name
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	suffix := fmt.Sprintf("-%08x", hash.Sum32())
+
+	return name[:validation.DNS1035LabelMaxLength-len(suffix)] + suffix
+}
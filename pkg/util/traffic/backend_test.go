@@ -0,0 +1,81 @@
+package traffic
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+)
+
+func TestReleaseScopedName(t *testing.T) {
+	short := ReleaseScopedName("app-prod", "app-deadbeef-0")
+	if short != "app-prod-app-deadbeef-0" {
+		t.Errorf("expected short names to be kept as they are, got %q", short)
+	}
+
+	service := strings.Repeat("s", 40)
+	release := strings.Repeat("r", 40)
+	long := ReleaseScopedName(service, release)
+	if len(long) > validation.DNS1035LabelMaxLength {
+		t.Errorf("expected name to be at most %d characters long, got %q",
+			validation.DNS1035LabelMaxLength, long)
+	}
+
+	other := ReleaseScopedName(service, release+"x")
+	if long == other {
+		t.Errorf("expected truncated names for different releases to differ, got %q for both", long)
+	}
+}
+
+func buildClusterTrafficStatus(name, readyReason string) *shipper.ClusterTrafficStatus {
+	return &shipper.ClusterTrafficStatus{
+		Name: name,
+		Conditions: []shipper.ClusterTrafficCondition{
+			{Type: shipper.ClusterConditionTypeOperational, Status: "True"},
+			{Type: shipper.ClusterConditionTypeReady, Status: "True", Reason: readyReason},
+		},
+	}
+}
+
+func TestTargetNotApplicable(t *testing.T) {
+	tests := []struct {
+		name     string
+		clusters []*shipper.ClusterTrafficStatus
+		expected bool
+	}{
+		{
+			name:     "no clusters",
+			expected: false,
+		},
+		{
+			name: "not applicable anywhere",
+			clusters: []*shipper.ClusterTrafficStatus{
+				buildClusterTrafficStatus("a", NotApplicableReason),
+				buildClusterTrafficStatus("b", NotApplicableReason),
+			},
+			expected: true,
+		},
+		{
+			name: "applicable somewhere",
+			clusters: []*shipper.ClusterTrafficStatus{
+				buildClusterTrafficStatus("a", NotApplicableReason),
+				buildClusterTrafficStatus("b", ""),
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &shipper.TrafficTarget{
+				Status: shipper.TrafficTargetStatus{Clusters: tt.clusters},
+			}
+
+			if got := TargetNotApplicable(target); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
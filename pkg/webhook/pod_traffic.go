@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admission "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kubeinformers "k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+)
+
+const (
+	PodTrafficAgentName = "pod-webhook"
+
+	// MutatePodsPath is where PodTrafficWebhook serves its admission
+	// reviews.
+	MutatePodsPath = "/mutate-pods"
+)
+
+// PodTrafficWebhook runs in application clusters. It labels new pods of
+// releases whose traffic is fully settled so they get traffic right away,
+// without waiting for the traffic controller to notice them. This keeps
+// pods recreated by their ReplicaSets in production Services even when
+// Shipper can't reach the cluster.
+type PodTrafficWebhook struct {
+	configMapsLister corev1listers.ConfigMapLister
+	configMapsSynced cache.InformerSynced
+
+	bindAddr string
+	bindPort string
+
+	tlsCertFile       string
+	tlsPrivateKeyFile string
+}
+
+// NewPodTrafficWebhook returns a PodTrafficWebhook that reads the traffic
+// status the traffic controller publishes in release anchors. The informer
+// factory is expected to be restricted to objects with a release label, as
+// there is no need to cache every ConfigMap in the cluster.
+func NewPodTrafficWebhook(
+	bindAddr, bindPort, tlsPrivateKeyFile, tlsCertFile string,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+) *PodTrafficWebhook {
+	configMapsInformer := kubeInformerFactory.Core().V1().ConfigMaps()
+
+	return &PodTrafficWebhook{
+		configMapsLister: configMapsInformer.Lister(),
+		configMapsSynced: configMapsInformer.Informer().HasSynced,
+
+		bindAddr: bindAddr,
+		bindPort: bindPort,
+
+		tlsPrivateKeyFile: tlsPrivateKeyFile,
+		tlsCertFile:       tlsCertFile,
+	}
+}
+
+func (c *PodTrafficWebhook) Run(stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(MutatePodsPath, adaptHandler(c.mutatePodHandlerFunc))
+
+	server := &http.Server{
+		Addr:    c.bindAddr + ":" + c.bindPort,
+		Handler: mux,
+	}
+
+	if !cache.WaitForCacheSync(stopCh, c.configMapsSynced) {
+		klog.Fatalf("failed to wait for caches to sync")
+		return
+	}
+
+	go func() {
+		var serverError error
+		if c.tlsCertFile == "" || c.tlsPrivateKeyFile == "" {
+			serverError = server.ListenAndServe()
+		} else {
+			serverError = server.ListenAndServeTLS(c.tlsCertFile, c.tlsPrivateKeyFile)
+		}
+
+		if serverError != nil && serverError != http.ErrServerClosed {
+			klog.Fatalf("failed to start shipper-pod-webhook: %v", serverError)
+		}
+	}()
+
+	klog.V(2).Info("Started the pod WebHook")
+
+	<-stopCh
+
+	klog.V(2).Info("Shutting down the pod WebHook")
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		klog.Errorf(`HTTP server Shutdown: %v`, err)
+	}
+}
+
+// mutatePodHandlerFunc never rejects a pod: at worst, it gets created as it
+// would be without the webhook, and the traffic controller labels it later.
+func (c *PodTrafficWebhook) mutatePodHandlerFunc(review *admission.AdmissionReview) *admission.AdmissionResponse {
+	request := review.Request
+	response := &admission.AdmissionResponse{
+		Allowed: true,
+	}
+
+	if request.Operation != admission.Create || request.Kind.Kind != "Pod" {
+		return response
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		klog.Errorf("failed to decode Pod in namespace %q: %s", request.Namespace, err)
+		return response
+	}
+
+	patch, err := c.podTrafficStatusPatch(request.Namespace, &pod)
+	if err != nil {
+		klog.Errorf("failed to decide traffic status of new Pod in namespace %q: %s", request.Namespace, err)
+		return response
+	}
+
+	if patch != nil {
+		patchType := admission.PatchTypeJSONPatch
+		response.Patch = patch
+		response.PatchType = &patchType
+	}
+
+	return response
+}
+
+// podTrafficStatusPatch returns a JSON Patch setting the traffic status label
+// of pod to the one its release's anchor says new pods should start with, or
+// nil if it should be left alone.
+func (c *PodTrafficWebhook) podTrafficStatusPatch(namespace string, pod *corev1.Pod) ([]byte, error) {
+	releaseName, ok := pod.Labels[shipper.ReleaseLabel]
+	if !ok {
+		return nil, nil
+	}
+
+	if _, ok := pod.Labels[shipper.PodTrafficStatusLabel]; ok {
+		// Whoever created the pod already made up their mind.
+		return nil, nil
+	}
+
+	configMap, err := c.configMapsLister.ConfigMaps(namespace).Get(anchor.ReleaseAnchorName(releaseName))
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	status, ok := configMap.Data[anchor.PodTrafficStatus]
+	if !ok {
+		return nil, nil
+	}
+
+	patch := []map[string]string{
+		{
+			"op":    "add",
+			"path":  fmt.Sprintf("/metadata/labels/%s", shipper.PodTrafficStatusLabel),
+			"value": status,
+		},
+	}
+
+	return json.Marshal(patch)
+}
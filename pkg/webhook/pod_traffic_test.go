@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	admission "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	"github.com/bookingcom/shipper/pkg/util/anchor"
+)
+
+const testNamespace = "test-namespace"
+
+func buildPodReview(t *testing.T, labels map[string]string) *admission.AdmissionReview {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "test-app-",
+			Labels:       labels,
+		},
+	}
+
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatalf("failed to encode Pod: %s", err)
+	}
+
+	return &admission.AdmissionReview{
+		Request: &admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: testNamespace,
+			Operation: admission.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
+
+func TestMutatePodHandlerFunc(t *testing.T) {
+	settled := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      anchor.ReleaseAnchorName("settled"),
+			Namespace: testNamespace,
+		},
+		Data: map[string]string{
+			anchor.InstallationTargetUID: "settled",
+			anchor.PodTrafficStatus:      shipper.Enabled,
+		},
+	}
+	shifting := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      anchor.ReleaseAnchorName("shifting"),
+			Namespace: testNamespace,
+		},
+		Data: map[string]string{
+			anchor.InstallationTargetUID: "shifting",
+		},
+	}
+
+	client := kubefake.NewSimpleClientset(settled, shifting)
+	informerFactory := kubeinformers.NewSharedInformerFactory(client, 0)
+	wh := NewPodTrafficWebhook("", "", "", "", informerFactory)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	expectedPatch := `[{"op":"add","path":"/metadata/labels/shipper-traffic-status","value":"enabled"}]`
+
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		expectedPatch string
+	}{
+		{
+			"settled release",
+			map[string]string{shipper.ReleaseLabel: "settled"},
+			expectedPatch,
+		},
+		{
+			"release still shifting traffic",
+			map[string]string{shipper.ReleaseLabel: "shifting"},
+			"",
+		},
+		{
+			"unknown release",
+			map[string]string{shipper.ReleaseLabel: "unknown"},
+			"",
+		},
+		{
+			"traffic status already set",
+			map[string]string{
+				shipper.ReleaseLabel:          "settled",
+				shipper.PodTrafficStatusLabel: shipper.Disabled,
+			},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := wh.mutatePodHandlerFunc(buildPodReview(t, tt.labels))
+
+			if !response.Allowed {
+				t.Fatalf("expected pod to be allowed, got %v", response.Result)
+			}
+
+			if patch := string(response.Patch); patch != tt.expectedPatch {
+				t.Errorf("expected patch %q, got %q", tt.expectedPatch, patch)
+			}
+
+			if tt.expectedPatch != "" && (response.PatchType == nil || *response.PatchType != admission.PatchTypeJSONPatch) {
+				t.Errorf("expected a JSON patch type, got %v", response.PatchType)
+			}
+		})
+	}
+}
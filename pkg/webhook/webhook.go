@@ -14,6 +14,7 @@ import (
 
 	admission "k8s.io/api/admission/v1beta1"
 	kubeclient "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
@@ -36,6 +37,8 @@ type Webhook struct {
 	shipperClientset    clientset.Interface
 	rolloutBlocksLister listers.RolloutBlockLister
 	rolloutBlocksSynced cache.InformerSynced
+	releasesLister      listers.ReleaseLister
+	releasesSynced      cache.InformerSynced
 
 	bindAddr string
 	bindPort string
@@ -61,11 +64,14 @@ func NewWebhook(
 	heartbeatPeriod time.Duration,
 ) *Webhook {
 	rolloutBlocksInformer := shipperInformerFactory.Shipper().V1alpha1().RolloutBlocks()
+	releasesInformer := shipperInformerFactory.Shipper().V1alpha1().Releases()
 
 	return &Webhook{
 		shipperClientset:    shipperClientset,
 		rolloutBlocksLister: rolloutBlocksInformer.Lister(),
 		rolloutBlocksSynced: rolloutBlocksInformer.Informer().HasSynced,
+		releasesLister:      releasesInformer.Lister(),
+		releasesSynced:      releasesInformer.Informer().HasSynced,
 
 		bindAddr: bindAddr,
 		bindPort: bindPort,
@@ -86,7 +92,7 @@ func (c *Webhook) Run(stopCh <-chan struct{}) {
 		Handler: mux,
 	}
 
-	if !cache.WaitForCacheSync(stopCh, c.rolloutBlocksSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.rolloutBlocksSynced, c.releasesSynced) {
 		klog.Fatalf("failed to wait for caches to sync")
 		return
 	}
@@ -248,6 +254,15 @@ func (c *Webhook) validateHandlerFunc(review *admission.AdmissionReview) *admiss
 	case "RolloutBlock":
 		var rolloutBlock shipper.RolloutBlock
 		err = json.Unmarshal(request.Object.Raw, &rolloutBlock)
+		if err == nil {
+			err = rolloutblock.ValidateSchedule(rolloutBlock.Spec)
+		}
+	case "ReleaseApproval":
+		var releaseApproval shipper.ReleaseApproval
+		err = json.Unmarshal(request.Object.Raw, &releaseApproval)
+		if err == nil {
+			err = c.validateReleaseApproval(request, releaseApproval)
+		}
 	}
 
 	if err != nil {
@@ -265,7 +280,7 @@ func (c *Webhook) validateHandlerFunc(review *admission.AdmissionReview) *admiss
 
 func (c *Webhook) validateRelease(request *admission.AdmissionRequest, release shipper.Release) error {
 	var err error
-	overrides, existingBlocks, err := rolloutblock.GetAllBlocks(c.rolloutBlocksLister, &release)
+	overrides, existingBlocks, activeBlocks, err := rolloutblock.GetAllBlocks(c.rolloutBlocksLister, &release)
 	if err != nil {
 		return err
 	}
@@ -274,7 +289,7 @@ func (c *Webhook) validateRelease(request *admission.AdmissionRequest, release s
 	}
 	switch request.Operation {
 	case kubeclient.Create:
-		err = rolloutblock.ValidateBlocks(existingBlocks, overrides)
+		err = rolloutblock.ValidateBlocks(activeBlocks, overrides)
 	case kubeclient.Update:
 		var oldRelease shipper.Release
 		err = json.Unmarshal(request.OldObject.Raw, &oldRelease)
@@ -284,7 +299,7 @@ func (c *Webhook) validateRelease(request *admission.AdmissionRequest, release s
 
 		// validate against rollout blocks
 		if !reflect.DeepEqual(release.Spec, oldRelease.Spec) {
-			err = rolloutblock.ValidateBlocks(existingBlocks, overrides)
+			err = rolloutblock.ValidateBlocks(activeBlocks, overrides)
 		}
 
 		// make sure the environment wasn't changed
@@ -296,9 +311,42 @@ func (c *Webhook) validateRelease(request *admission.AdmissionRequest, release s
 	return err
 }
 
+func (c *Webhook) validateReleaseApproval(request *admission.AdmissionRequest, releaseApproval shipper.ReleaseApproval) error {
+	if request.Operation == kubeclient.Create {
+		// Approvers speak for themselves only.
+		if approver := request.UserInfo.Username; releaseApproval.Spec.Approver != approver {
+			return fmt.Errorf("the ReleaseApproval approver must be the user creating it, %q, not %q",
+				approver, releaseApproval.Spec.Approver)
+		}
+
+		_, err := c.releasesLister.Releases(request.Namespace).Get(releaseApproval.Spec.Release)
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("the ReleaseApproval release %q does not exist in namespace %q",
+				releaseApproval.Spec.Release, request.Namespace)
+		}
+
+		return err
+	} else if request.Operation != kubeclient.Update {
+		return nil
+	}
+
+	var oldReleaseApproval shipper.ReleaseApproval
+	if err := json.Unmarshal(request.OldObject.Raw, &oldReleaseApproval); err != nil {
+		return err
+	}
+
+	// Releases record who approved them, so approvals can't be handed
+	// over to someone else, or to another release, after the fact.
+	if !reflect.DeepEqual(releaseApproval.Spec, oldReleaseApproval.Spec) {
+		return fmt.Errorf("the ReleaseApproval spec must not be changed; consider creating a new one")
+	}
+
+	return nil
+}
+
 func (c *Webhook) validateApplication(request *admission.AdmissionRequest, application shipper.Application) error {
 	var err error
-	overrides, existingBlocks, err := rolloutblock.GetAllBlocks(c.rolloutBlocksLister, &application)
+	overrides, existingBlocks, activeBlocks, err := rolloutblock.GetAllBlocks(c.rolloutBlocksLister, &application)
 	if err != nil {
 		return err
 	}
@@ -307,7 +355,7 @@ func (c *Webhook) validateApplication(request *admission.AdmissionRequest, appli
 	}
 	switch request.Operation {
 	case kubeclient.Create:
-		err = rolloutblock.ValidateBlocks(existingBlocks, overrides)
+		err = rolloutblock.ValidateBlocks(activeBlocks, overrides)
 	case kubeclient.Update:
 		var oldApp shipper.Application
 		err = json.Unmarshal(request.OldObject.Raw, &oldApp)
@@ -316,7 +364,7 @@ func (c *Webhook) validateApplication(request *admission.AdmissionRequest, appli
 		}
 
 		if !reflect.DeepEqual(application.Spec, oldApp.Spec) {
-			err = rolloutblock.ValidateBlocks(existingBlocks, overrides)
+			err = rolloutblock.ValidateBlocks(activeBlocks, overrides)
 		}
 	}
 
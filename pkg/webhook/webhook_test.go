@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	shipper "github.com/bookingcom/shipper/pkg/apis/shipper/v1alpha1"
+	shipperfake "github.com/bookingcom/shipper/pkg/client/clientset/versioned/fake"
+	shipperinformers "github.com/bookingcom/shipper/pkg/client/informers/externalversions"
+	"github.com/bookingcom/shipper/pkg/metrics/prometheus"
+)
+
+func buildReleaseApprovalRequest(
+	t *testing.T,
+	operation admission.Operation,
+	username string,
+	spec, oldSpec shipper.ReleaseApprovalSpec,
+) *admission.AdmissionRequest {
+	encode := func(spec shipper.ReleaseApprovalSpec) []byte {
+		raw, err := json.Marshal(shipper.ReleaseApproval{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-approval", Namespace: testNamespace},
+			Spec:       spec,
+		})
+		if err != nil {
+			t.Fatalf("failed to encode ReleaseApproval: %s", err)
+		}
+		return raw
+	}
+
+	request := &admission.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: shipper.SchemeGroupVersion.Group, Version: "v1alpha1", Kind: "ReleaseApproval"},
+		Namespace: testNamespace,
+		Operation: operation,
+		UserInfo:  authenticationv1.UserInfo{Username: username},
+		Object:    runtime.RawExtension{Raw: encode(spec)},
+	}
+	if operation == admission.Update {
+		request.OldObject = runtime.RawExtension{Raw: encode(oldSpec)}
+	}
+
+	return request
+}
+
+func TestValidateReleaseApproval(t *testing.T) {
+	release := &shipper.Release{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-release", Namespace: testNamespace},
+	}
+
+	client := shipperfake.NewSimpleClientset(release)
+	informerFactory := shipperinformers.NewSharedInformerFactory(client, 0)
+	wh := NewWebhook("", "", "", "", client, informerFactory,
+		prometheus.WebhookMetric{}, time.Minute)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	spec := shipper.ReleaseApprovalSpec{Release: "test-release", Step: 1, Approver: "jdoe"}
+	forged := spec
+	forged.Approver = "someone-else"
+	missing := spec
+	missing.Release = "missing-release"
+
+	tests := []struct {
+		name      string
+		operation admission.Operation
+		username  string
+		spec      shipper.ReleaseApprovalSpec
+		oldSpec   shipper.ReleaseApprovalSpec
+		allowed   bool
+	}{
+		{"create by the approver", admission.Create, "jdoe", spec, shipper.ReleaseApprovalSpec{}, true},
+		{"create on behalf of someone else", admission.Create, "jdoe", forged, shipper.ReleaseApprovalSpec{}, false},
+		{"create for a missing release", admission.Create, "jdoe", missing, shipper.ReleaseApprovalSpec{}, false},
+		{"update keeping the spec", admission.Update, "admin", spec, spec, true},
+		{"update changing the approver", admission.Update, "admin", forged, spec, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := buildReleaseApprovalRequest(t, tt.operation, tt.username, tt.spec, tt.oldSpec)
+			response := wh.validateHandlerFunc(&admission.AdmissionReview{Request: request})
+
+			if response.Allowed != tt.allowed {
+				t.Fatalf("expected allowed to be %t, got %t: %v", tt.allowed, response.Allowed, response.Result)
+			}
+		})
+	}
+}